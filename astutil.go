@@ -0,0 +1,515 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// structField is a named field of a struct type
+type structField struct {
+	Name string
+	Type string
+	// Comment is the text of the line comment following the field, if any
+	Comment string
+}
+
+// structDecl is a struct type declared along with the fields of a model e.g. the type of one of them
+type structDecl struct {
+	Name   string
+	Doc    string
+	Fields []structField
+}
+
+// parseKey identifies a parse of a Go source in a mode
+type parseKey struct {
+	mode parser.Mode
+	src  string
+}
+
+// maxParsedFiles is the number of syntax trees parsedFiles holds at most. Once it's full it starts over, which
+// bounds the memory of long-running commands like clean watch.
+const maxParsedFiles = 512
+
+// parsedFiles holds the syntax trees of the Go sources parsed by the command, so that the same content is
+// parsed once however many generators and usecases look at it. The syntax trees are shared by the callers,
+// which must not modify them.
+var parsedFiles = make(map[parseKey]*ast.File)
+
+// sourceParses counts the parses of each Go source parsedFiles may hold since the caches were last reset. The
+// benchmarks use it to check that each source is parsed once per command.
+var sourceParses = make(map[parseKey]int)
+
+// parseGoFile parses the Go file fp including its comments
+func parseGoFile(fp string) (*ast.File, error) {
+	b, err := readFile(fp)
+	if err != nil {
+		return nil, err
+	}
+	return parseFile(token.NewFileSet(), fp, b, parser.ParseComments)
+}
+
+// parseFile parses the Go source src of the file fp like parser.ParseFile, measured as the parse phase. A
+// []byte source parsed into an empty fset is parsed only once. Its later parses return the same syntax tree and
+// add the file to fset, where its positions are the same as in the fset it was first parsed into.
+func parseFile(fset *token.FileSet, fp string, src interface{}, mode parser.Mode) (*ast.File, error) {
+	b, ok := src.([]byte)
+	if !ok || fset.Base() != 1 {
+		defer startPhase(phaseParse)()
+		return parser.ParseFile(fset, fp, src, mode)
+	}
+	key := parseKey{mode, string(b)}
+	if f, ok := parsedFiles[key]; ok {
+		fset.AddFile(fp, -1, len(b)).SetLinesForContent(b)
+		return f, nil
+	}
+	defer startPhase(phaseParse)()
+	f, err := parser.ParseFile(fset, fp, b, mode)
+	sourceParses[key]++
+	if err == nil {
+		if len(parsedFiles) >= maxParsedFiles {
+			parsedFiles = make(map[parseKey]*ast.File)
+		}
+		parsedFiles[key] = f
+	}
+	return f, err
+}
+
+// findTypeSpec returns the declaration of the type name in f or nil if it isn't declared in f
+func findTypeSpec(f *ast.File, name string) *ast.TypeSpec {
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			if ts, ok := spec.(*ast.TypeSpec); ok && ts.Name.Name == name {
+				return ts
+			}
+		}
+	}
+	return nil
+}
+
+// interfaceNames returns the names of the exported interface types declared in f
+func interfaceNames(f *ast.File) []string {
+	var names []string
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts, ok := spec.(*ast.TypeSpec)
+			if !ok || !ts.Name.IsExported() {
+				continue
+			}
+			if _, ok := ts.Type.(*ast.InterfaceType); ok {
+				names = append(names, ts.Name.Name)
+			}
+		}
+	}
+	return names
+}
+
+// typeAliases returns the names of the exported type aliases declared in f
+func typeAliases(f *ast.File) []string {
+	var names []string
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			if ts, ok := spec.(*ast.TypeSpec); ok && ts.Name.IsExported() && ts.Assign.IsValid() {
+				names = append(names, ts.Name.Name)
+			}
+		}
+	}
+	return names
+}
+
+// interfaceMethods returns the names of the methods of the interface type name declared in f.
+// The boolean is false if f doesn't declare the interface.
+func interfaceMethods(f *ast.File, name string) ([]string, bool) {
+	ts := findTypeSpec(f, name)
+	if ts == nil {
+		return nil, false
+	}
+	it, ok := ts.Type.(*ast.InterfaceType)
+	if !ok {
+		return nil, false
+	}
+	var methods []string
+	for _, m := range it.Methods.List {
+		for _, n := range m.Names {
+			methods = append(methods, n.Name)
+		}
+	}
+	return methods, true
+}
+
+// structFields parses the Go file fp and returns the named fields of the struct type structName.
+// It returns no fields and a nil error if fp doesn't exist or the struct type isn't found.
+func structFields(fp, structName string) ([]structField, error) {
+	if !fileExists(fp) {
+		return nil, nil
+	}
+	f, err := parseGoFile(fp)
+	if err != nil {
+		return nil, err
+	}
+	ts := findTypeSpec(f, structName)
+	if ts == nil {
+		return nil, nil
+	}
+	st, ok := ts.Type.(*ast.StructType)
+	if !ok {
+		return nil, nil
+	}
+	var fields []structField
+	for _, field := range st.Fields.List {
+		for _, name := range field.Names {
+			fields = append(fields, structField{Name: name.Name, Type: types.ExprString(field.Type)})
+		}
+	}
+	return fields, nil
+}
+
+// importBlock returns the lines of the import specs imports, e.g. "fmt", of an import declaration sorted
+// with the standard library packages first and the other packages in a group of their own
+func importBlock(imports []string) string {
+	var std, other []string
+	for _, imp := range imports {
+		path := imp[strings.Index(imp, "\""):]
+		if strings.Contains(strings.SplitN(path, "/", 2)[0], ".") {
+			other = append(other, imp)
+		} else {
+			std = append(std, imp)
+		}
+	}
+	// Sort by the import paths, like gofmt, rather than by the names of renamed imports
+	byPath := func(imports []string) func(i, j int) bool {
+		return func(i, j int) bool {
+			return imports[i][strings.Index(imports[i], "\""):] < imports[j][strings.Index(imports[j], "\""):]
+		}
+	}
+	sort.Slice(std, byPath(std))
+	sort.Slice(other, byPath(other))
+	var b strings.Builder
+	for _, imp := range std {
+		b.WriteString("\t" + imp + "\n")
+	}
+	if len(std) > 0 && len(other) > 0 {
+		b.WriteString("\n")
+	}
+	for _, imp := range other {
+		b.WriteString("\t" + imp + "\n")
+	}
+	return b.String()
+}
+
+// ensureImport returns the Go source b with path added to its imports unless it's already imported.
+// The import is added to the first parenthesised import declaration, or to a new one after the package clause.
+func ensureImport(b []byte, path string) ([]byte, error) {
+	return ensureNamedImport(b, "", path)
+}
+
+// ensureNamedImport is like ensureImport but imports path as name unless name is empty
+func ensureNamedImport(b []byte, name, path string) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parseFile(fset, "", b, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+	for _, spec := range f.Imports {
+		if spec.Path.Value == strconv.Quote(path) {
+			return b, nil
+		}
+	}
+	spec := strconv.Quote(path)
+	if name != "" {
+		spec = name + " " + spec
+	}
+	var insert string
+	var ix int
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.IMPORT || !gd.Lparen.IsValid() {
+			continue
+		}
+		insert = "\t" + spec + "\n"
+		ix = fset.Position(gd.Rparen).Offset
+		// Keep the imports sorted by inserting path on the line of the first import following it
+		for _, spec := range gd.Specs {
+			is := spec.(*ast.ImportSpec)
+			if p, err := strconv.Unquote(is.Path.Value); err == nil && p > path {
+				ix = fset.Position(is.Pos()).Offset
+				for ix > 0 && b[ix-1] != '\n' {
+					ix--
+				}
+				break
+			}
+		}
+		// Keep the closing parenthesis on its own line
+		if ix > 0 && b[ix-1] != '\n' {
+			insert = "\n" + insert
+		}
+		break
+	}
+	if insert == "" {
+		insert = "\n\nimport (\n\t" + spec + "\n)"
+		ix = fset.Position(f.Name.End()).Offset
+	}
+	newb := make([]byte, 0, len(b)+len(insert))
+	newb = append(newb, b[:ix]...)
+	newb = append(newb, insert...)
+	return append(newb, b[ix:]...), nil
+}
+
+// pruneImports returns the Go source b without the imports of the packages it doesn't refer to. Blank and dot
+// imports are kept.
+func pruneImports(b []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parseFile(fset, "", b, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	used := make(map[string]bool)
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				used[id.Name] = true
+			}
+		}
+		return true
+	})
+	// Remove the unused imports from the last one so that the offsets stay valid
+	for i := len(f.Imports) - 1; i >= 0; i-- {
+		spec := f.Imports[i]
+		name, _ := importName(spec)
+		if name == "_" || name == "." || used[name] {
+			continue
+		}
+		start, end := fset.Position(spec.Pos()).Offset, fset.Position(spec.End()).Offset
+		for start > 0 && (b[start-1] == ' ' || b[start-1] == '\t') {
+			start--
+		}
+		if end < len(b) && b[end] == '\n' {
+			end++
+		}
+		b = append(b[:start:start], b[end:]...)
+	}
+	return b, nil
+}
+
+// implReceiver returns the receiver of the methods of the struct structName declared in the Go source b, or
+// an empty string if it has no methods with a named receiver
+func implReceiver(b []byte, structName string) string {
+	f, err := parseFile(token.NewFileSet(), "", b, 0)
+	if err != nil {
+		return ""
+	}
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Recv == nil || len(fd.Recv.List) != 1 || len(fd.Recv.List[0].Names) != 1 {
+			continue
+		}
+		typ := fd.Recv.List[0].Type
+		if star, ok := typ.(*ast.StarExpr); ok {
+			typ = star.X
+		}
+		if id, ok := typ.(*ast.Ident); ok && id.Name == structName && fd.Recv.List[0].Names[0].Name != "_" {
+			return fd.Recv.List[0].Names[0].Name
+		}
+	}
+	return ""
+}
+
+// importName returns the name the import spec is referred to by and its path. Without an explicit name it's
+// assumed to be the last element of the path other than a major version e.g. yaml for gopkg.in/yaml.v3 and
+// validator for github.com/go-playground/validator/v10.
+func importName(spec *ast.ImportSpec) (string, string) {
+	path, err := strconv.Unquote(spec.Path.Value)
+	if err != nil {
+		path = spec.Path.Value
+	}
+	if spec.Name != nil {
+		return spec.Name.Name, path
+	}
+	elems := strings.Split(path, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && isMajorVersion(name) {
+		name = elems[len(elems)-2]
+	}
+	if ix := strings.Index(name, ".v"); ix > 0 && isMajorVersion(name[ix+1:]) {
+		name = name[:ix]
+	}
+	return name, path
+}
+
+// isMajorVersion reports whether s is a major version of a module e.g. v2
+func isMajorVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	for _, r := range s[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// typeDeclFile returns the path of the Go file in dir, other than skip, which declares the type name or
+// an empty string if none of them does. The files written while --stdout is set are included.
+func typeDeclFile(dir, name, skip string) string {
+	var paths []string
+	if files, err := ioutil.ReadDir(dir); err == nil {
+		for _, fi := range files {
+			paths = append(paths, filepath.Join(dir, fi.Name()))
+		}
+	}
+	for _, fp := range pendingOrder {
+		if filepath.Dir(fp) == filepath.Clean(dir) {
+			paths = append(paths, fp)
+		}
+	}
+	for _, fp := range paths {
+		if fp == skip || filepath.Ext(fp) != ".go" {
+			continue
+		}
+		if f, err := parseGoFile(fp); err == nil && findTypeSpec(f, name) != nil {
+			return fp
+		}
+	}
+	return ""
+}
+
+// prependToMethod inserts code at the beginning of the body of the method of the Go file fp unless the file
+// already contains it
+func prependToMethod(fp, method, code string) error {
+	b, err := readFile(fp)
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(b, []byte(code)) {
+		return nil
+	}
+	fset := token.NewFileSet()
+	f, err := parseFile(fset, fp, b, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Recv == nil || fd.Name.Name != method || fd.Body == nil {
+			continue
+		}
+		ix := fset.Position(fd.Body.Lbrace).Offset + 1
+		if ix < len(b) && b[ix] == '\n' {
+			ix++
+		}
+		newb := append(append(append([]byte{}, b[:ix]...), code...), b[ix:]...)
+		return writeFile(fp, newb)
+	}
+	return fmt.Errorf("the method %s isn't declared in %s", method, fp)
+}
+
+// fieldLine returns the line declaring the field of a struct
+func fieldLine(field structField) string {
+	line := "\t" + field.Name
+	if field.Type != "" {
+		line += " " + field.Type
+	}
+	if field.Comment != "" {
+		line += " // " + field.Comment
+	}
+	return line + "\n"
+}
+
+// addStructDecls adds the structs decls to the end of the Go file fp unless the folder of fp already declares them
+func addStructDecls(fp string, decls []structDecl) error {
+	b, err := readFile(fp)
+	if err != nil {
+		return err
+	}
+	newb := bytes.TrimRight(b, "\n")
+	for _, d := range decls {
+		if typeDeclFile(filepath.Dir(fp), d.Name, "") != "" {
+			continue
+		}
+		newb = append(newb, fmt.Sprintf("\n\n%s\ntype %s struct {\n", d.Doc, d.Name)...)
+		for _, field := range d.Fields {
+			newb = append(newb, fieldLine(field)...)
+		}
+		newb = append(newb, "}"...)
+	}
+	if len(newb) == len(bytes.TrimRight(b, "\n")) {
+		return nil
+	}
+	return writeFile(fp, append(newb, '\n'))
+}
+
+// addStructFields adds fields to the end of the struct structName declared in the Go file fp unless
+// the struct already declares them. A field without a type is embedded.
+func addStructFields(fp, structName string, fields []structField) error {
+	b, err := readFile(fp)
+	if err != nil {
+		return err
+	}
+	fset := token.NewFileSet()
+	f, err := parseFile(fset, fp, b, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	ts := findTypeSpec(f, structName)
+	if ts == nil {
+		return fmt.Errorf("the struct %s isn't declared in %s", structName, fp)
+	}
+	st, ok := ts.Type.(*ast.StructType)
+	if !ok {
+		return fmt.Errorf("%s isn't a struct", structName)
+	}
+	declared := make(map[string]bool)
+	for _, field := range st.Fields.List {
+		for _, name := range field.Names {
+			declared[name.Name] = true
+		}
+		// An embedded field is named after its type
+		if len(field.Names) == 0 {
+			declared[types.ExprString(field.Type)] = true
+		}
+	}
+	var content string
+	for _, field := range fields {
+		// Skip the fields already declared e.g. by another interactor sharing the model
+		if !declared[field.Name] {
+			content += fieldLine(field)
+		}
+	}
+	if content == "" {
+		return nil
+	}
+	// Insert the fields at the beginning of the line of the closing brace
+	ix := fset.Position(st.Fields.Closing).Offset
+	for ix > 0 && b[ix-1] != '\n' {
+		ix--
+	}
+	if ix <= fset.Position(st.Fields.Opening).Offset {
+		return fmt.Errorf("the struct %s must be declared on multiple lines", structName)
+	}
+	newb := append(append(append([]byte{}, b[:ix]...), content...), b[ix:]...)
+	return writeFile(fp, newb)
+}
@@ -0,0 +1,81 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+const (
+	relPathAuth = "usecase/auth/"
+	objAuth     = "auth"
+	// authorizerName is the name of the interface the usecases check the authorization of their actor with
+	authorizerName = "Authorizer"
+	// authField is the name of the field and constructor parameter of an Interactor holding the Authorizer
+	authField = "az"
+	// forbiddenField is the field of the ErrVal ResponseModel of a usecase which is set if the actor isn't authorized
+	forbiddenField = "Forbidden"
+)
+
+// authSource is the content, following the package clause, of the file of the Authorizer
+const authSource = `
+
+// Authorizer is a Clean Architecture Gateway which decides whether the actor of the usecases may run them,
+// e.g. by checking the roles of the authenticated user it's constructed for.
+type Authorizer interface {
+	// Can reports whether the actor may perform action e.g. order.add_item.
+	Can(action string) bool
+}
+
+// allowAll is an implementation of Authorizer which allows every action.
+type allowAll struct{}
+
+// NewAllowAll constructs a new Authorizer which allows every action e.g. until the authorization is implemented.
+func NewAllowAll() Authorizer {
+	return allowAll{}
+}
+
+// Can implements the Authorizer interface method Can by allowing action.
+func (allowAll) Can(action string) bool {
+	return true
+}
+`
+
+// authStub returns the statements of the method of the usecase of the interactor which present its ErrVal
+// ResponseModel with Forbidden set unless the actor is authorized. The Interactor implementation is referred
+// to by self and the usecase returns the ResponseModel rsm with ret.
+func authStub(self, interactor, usecase, ret string) string {
+	return fmt.Sprintf("\t// Authorize the actor\n\tif !%s.%s.Can(%q) {\n\t\trsm := &respmodel.%sErrVal{%s: true}\n\t\t%s.ps.Present%sErrVal(rsm)\n\t\t%s\n\t}\n\n",
+		self, authField, usecaseKey(interactor, usecase), usecase, forbiddenField, self, usecase, ret)
+}
+
+// addAuthorizer adds the Authorizer and its allow-all implementation to the auth folder of the project at
+// basePath unless they exist
+func addAuthorizer(basePath string) error {
+	fp := filepath.FromSlash(basePath + relPathAuth + objAuth + ".go")
+	if fileExists(fp) {
+		return nil
+	}
+	if err := mkdirAll(filepath.Dir(fp)); err != nil {
+		return err
+	}
+	return writeFile(fp, []byte(packageClause(basePath+relPathAuth, objAuth)+authSource))
+}
+
+// injectAuthorizer adds a field and a constructor parameter of the Authorizer to the Interactor implementation
+// of the interactor in b, the content of fp, unless it already has them
+func injectAuthorizer(b []byte, fp, interactor string) ([]byte, error) {
+	return injectDependency(b, fp, interactor, authField, objAuth+"."+authorizerName, relPathAuth)
+}
+
+// addForbiddenField adds the Forbidden field to the ErrVal ResponseModel of the usecase of the interactor of
+// the project at basePath
+func addForbiddenField(basePath, usecase, interactor string) error {
+	fp := modelFile(basePath, relPathRespModel, usecase, interactor)
+	if fp == "" {
+		return nil
+	}
+	return addStructFields(fp, exportedName(usecase)+"ErrVal", []structField{{Name: forbiddenField, Type: "bool"}})
+}
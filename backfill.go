@@ -0,0 +1,74 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// backfillRelPaths holds the folders of the object and the models each layer which can be added to an existing
+// interactor is backfilled with. The Presenter's file is what the models are added along with, see
+// addUsecaseToObject, so an interactor without a Presenter has no models either.
+var backfillRelPaths = map[string][]string{
+	objPresenter: {relPathPresenter, relPathReqModel, relPathRespModel, relPathViewModel},
+	objView:      {relPathView, relPathViewModel},
+}
+
+// backfillLayer adds the object of type objType, which is either the Presenter or the View, named after the
+// interactor to an interactor which was added without it, e.g. with --skip view, and backfills it, and the
+// models it refers to, with the methods of each of the usecases the interactor already has. An object which
+// exists only gets the methods and models it's missing. The backfilled usecases are listed.
+func backfillLayer(basePath, objType, interactor string) error {
+	if flatLayout {
+		return fmt.Errorf("the objects of the flat layout can't be added on their own")
+	}
+	interactors, err := interactorUsecases(basePath)
+	if err != nil {
+		return err
+	}
+	it := exportedName(interactor)
+	usecases, ok := interactors[it]
+	if !ok {
+		return fmt.Errorf("the interactor %s doesn't exist", it)
+	}
+	if presenterFp, _ := interactorFiles(basePath, objPresenter, interactor); objType == objView && !fileExists(presenterFp) {
+		return fmt.Errorf("the ViewModels are added along with the Presenter, add it first with clean add presenter to %s", it)
+	}
+	objFp, testFp := interactorFiles(basePath, objType, interactor)
+	added := !fileExists(objFp)
+	if added {
+		addObjToProject(basePath+objRelPaths[objType], objType, interactor, *desc, !fileExists(testFp))
+	}
+	var backfilled []string
+	for _, usecase := range usecases {
+		// A usecase is backfilled unless it already exists in each of the files
+		nNoops, nErrors := len(noops), len(errorMessages)
+		for _, relPath := range backfillRelPaths[objType] {
+			addUsecaseToObject(basePath, relPath, usecase, interactor)
+		}
+		if len(errorMessages) > nErrors {
+			return fmt.Errorf("backfilling the usecase %s failed", usecase)
+		}
+		if len(noops)-nNoops < len(backfillRelPaths[objType]) {
+			backfilled = append(backfilled, usecase)
+		}
+	}
+	if !added && len(backfilled) == 0 {
+		noopf("the %s of %s already exists with all of its usecases", objType, it)
+		return nil
+	}
+	if *jsonOutput {
+		return nil
+	}
+	switch {
+	case added && len(backfilled) > 0:
+		fmt.Printf("Added the %s of %s and backfilled the usecases %s\n", objType, it, strings.Join(backfilled, ", "))
+	case added:
+		fmt.Printf("Added the %s of %s, which has no usecases yet\n", objType, it)
+	default:
+		fmt.Printf("Backfilled the usecases %s in the %s of %s\n", strings.Join(backfilled, ", "), objType, it)
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+const (
+	// bindHTTP is the --bind style of the controller methods binding the RequestModel from an HTTP request
+	bindHTTP = "http"
+	// bindErrorsField is the field of a RequestModel, and of its ErrVal, holding the failures to bind it
+	bindErrorsField = "BindErrors"
+)
+
+// bindConversions holds the conversion of a query parameter v to each of the Go types it can be bound to. The
+// value of the conversion x is converted to the type if it's not x itself.
+var bindConversions = map[string]struct{ call, value string }{
+	"bool":    {"strconv.ParseBool(v)", "x"},
+	"int":     {"strconv.Atoi(v)", "x"},
+	"int8":    {"strconv.ParseInt(v, 10, 8)", "int8(x)"},
+	"int16":   {"strconv.ParseInt(v, 10, 16)", "int16(x)"},
+	"int32":   {"strconv.ParseInt(v, 10, 32)", "int32(x)"},
+	"int64":   {"strconv.ParseInt(v, 10, 64)", "x"},
+	"uint":    {"strconv.ParseUint(v, 10, 0)", "uint(x)"},
+	"uint8":   {"strconv.ParseUint(v, 10, 8)", "uint8(x)"},
+	"uint16":  {"strconv.ParseUint(v, 10, 16)", "uint16(x)"},
+	"uint32":  {"strconv.ParseUint(v, 10, 32)", "uint32(x)"},
+	"uint64":  {"strconv.ParseUint(v, 10, 64)", "x"},
+	"float32": {"strconv.ParseFloat(v, 32)", "float32(x)"},
+	"float64": {"strconv.ParseFloat(v, 64)", "x"},
+}
+
+// verifyBindStyle returns an error if --bind is set to anything else than http
+func verifyBindStyle() error {
+	if *bind != "" && *bind != bindHTTP {
+		return fmt.Errorf("invalid bind %q, the only binding is %s", *bind, bindHTTP)
+	}
+	return nil
+}
+
+// snakeCase returns name in lower case with its words separated by '_' e.g. ProductID becomes product_id
+func snakeCase(name string) string {
+	return strings.ToLower(strings.Join(splitWords(name), "_"))
+}
+
+// bindField returns the statements binding the field of the RequestModel rqm of type typ from the query
+// parameter key. It returns an empty string if the type can't be bound from a query parameter, in which case
+// the field is only bound from the JSON body.
+func bindField(name, typ, key string) string {
+	elem := strings.TrimPrefix(strings.TrimPrefix(typ, "*"), "[]")
+	assign := func(value string) string {
+		switch {
+		case strings.HasPrefix(typ, "[]"):
+			return fmt.Sprintf("rqm.%s = append(rqm.%s, %s)", name, name, value)
+		case !strings.HasPrefix(typ, "*"):
+			return fmt.Sprintf("rqm.%s = %s", name, value)
+		case value == "v" || value == "x":
+			return fmt.Sprintf("rqm.%s = &%s", name, value)
+		}
+		return fmt.Sprintf("y := %s\n\t\t\trqm.%s = &y", value, name)
+	}
+	var conv string
+	switch c, ok := bindConversions[elem]; {
+	case elem == "string":
+		conv = assign("v")
+	case ok:
+		conv = fmt.Sprintf("if x, err := %s; err != nil {\n\t\t\trqm.%s = append(rqm.%s, %q+err.Error())\n\t\t} else {\n\t\t\t%s\n\t\t}", c.call, bindErrorsField, bindErrorsField, key+": ", assign(c.value))
+	default:
+		return ""
+	}
+	if strings.HasPrefix(typ, "[]") {
+		return fmt.Sprintf("\tfor _, v := range q[%q] {\n\t\t%s\n\t}\n", key, conv)
+	}
+	return fmt.Sprintf("\tif v := q.Get(%q); v != \"\" {\n\t\t%s\n\t}\n", key, conv)
+}
+
+// bindBody returns the body of the controller method of the usecase v binding the RequestModel from the JSON
+// body and the query parameters of the HTTP request r and calling the Interactor method with it. The
+// parameters are named by the json tags of the fields or otherwise in snake case e.g. product_id. The failures
+// to bind the fields are collected in the BindErrors of the RequestModel so that the Validator turns them
+// into the ErrVal presented like any other invalid input. It also returns whether strconv is used.
+func bindBody(st *ast.StructType, self, v string) (string, bool) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\trqm := &reqmodel.%s{}\n", v)
+	b.WriteString("\tif strings.HasPrefix(r.Header.Get(\"Content-Type\"), \"application/json\") {\n")
+	fmt.Fprintf(&b, "\t\tif err := json.NewDecoder(r.Body).Decode(rqm); err != nil && err != io.EOF {\n\t\t\trqm.%s = append(rqm.%s, \"body: \"+err.Error())\n\t\t}\n\t}\n", bindErrorsField, bindErrorsField)
+	var params strings.Builder
+	usesStrconv := false
+	for _, field := range st.Fields.List {
+		key := ""
+		if field.Tag != nil {
+			tag := reflect.StructTag(strings.Trim(field.Tag.Value, "`"))
+			key = strings.Split(tag.Get("json"), ",")[0]
+		}
+		for _, id := range field.Names {
+			if !id.IsExported() || id.Name == bindErrorsField || key == "-" {
+				continue
+			}
+			k := key
+			if k == "" {
+				k = snakeCase(id.Name)
+			}
+			typ := types.ExprString(field.Type)
+			stmt := bindField(id.Name, typ, k)
+			if stmt == "" {
+				fmt.Fprintf(&params, "\t// %s is only bound from the JSON body\n", id.Name)
+				continue
+			}
+			if strings.Contains(stmt, "strconv.") {
+				usesStrconv = true
+			}
+			params.WriteString(stmt)
+		}
+	}
+	if params.Len() > 0 {
+		b.WriteString("\tq := r.URL.Query()\n")
+		b.WriteString(params.String())
+	}
+	fmt.Fprintf(&b, "\t%s.ia.%s(rqm)\n", self, v)
+	return b.String(), usesStrconv
+}
+
+// addHTTPBinding binds the RequestModel of the usecase from the HTTP request in the controller method of the
+// usecase, replacing its TODO, unless the method was already implemented. The RequestModel and its ErrVal get the BindErrors field
+// and the Validate method of the usecase returns the ErrVal if there are any.
+func addHTTPBinding(basePath, usecase, interactor string) error {
+	v := exportedName(usecase)
+	reqFp := modelFile(basePath, relPathReqModel, usecase, interactor)
+	if reqFp == "" {
+		return fmt.Errorf("the RequestModel %s doesn't exist", v)
+	}
+	if err := addStructFields(reqFp, v, []structField{{Name: bindErrorsField, Type: "[]string `json:\"-\"`", Comment: "the failures to bind the request"}}); err != nil {
+		return err
+	}
+	if fp := modelFile(basePath, relPathRespModel, usecase, interactor); fp != "" && selected("respmodel") {
+		if err := addStructFields(fp, v+"ErrVal", []structField{{Name: bindErrorsField, Type: "[]string"}}); err != nil {
+			return err
+		}
+	}
+	if fp := filepath.FromSlash(basePath + relPathValidator + fileName(interactor) + ".go"); selected(objValidator) && fileExists(fp) {
+		check := fmt.Sprintf("\tif len(rqm.%s) > 0 {\n\t\treturn &respmodel.%sErrVal{%s: rqm.%s}\n\t}\n", bindErrorsField, v, bindErrorsField, bindErrorsField)
+		if err := prependToMethod(fp, "Validate"+v, check); err != nil {
+			return err
+		}
+	}
+	if !selected(objController) {
+		return nil
+	}
+	rf, err := parseGoFile(reqFp)
+	if err != nil {
+		return err
+	}
+	var st *ast.StructType
+	if ts := findTypeSpec(rf, v); ts != nil {
+		st, _ = ts.Type.(*ast.StructType)
+	}
+	if st == nil {
+		return fmt.Errorf("the RequestModel %s isn't a struct", v)
+	}
+	fp := filepath.FromSlash(basePath + relPathController + fileName(interactor) + ".go")
+	b, err := readFile(fp)
+	if err != nil {
+		return err
+	}
+	_, lcObjName := fileObjNames(b, objController, interactor)
+	fset := token.NewFileSet()
+	f, err := parseFile(fset, fp, b, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Recv == nil || fd.Name.Name != v || fd.Body == nil || len(fd.Recv.List[0].Names) == 0 {
+			continue
+		}
+		if recv := types.ExprString(fd.Recv.List[0].Type); recv != "*"+lcObjName {
+			continue
+		}
+		if len(fd.Body.List) > 0 {
+			noopf("the controller method %s is already implemented", v)
+			return nil
+		}
+		body, usesStrconv := bindBody(st, fd.Recv.List[0].Names[0].Name, v)
+		start, end := fset.Position(fd.Body.Lbrace).Offset+1, fset.Position(fd.Body.Rbrace).Offset
+		newb := append(append(append([]byte{}, b[:start]...), "\n"+body...), b[end:]...)
+		imports := []string{"encoding/json", "io", "strings", projectBaseImportPath + "clean/" + strings.TrimSuffix(relPathReqModel, "/")}
+		if usesStrconv {
+			imports = append(imports, "strconv")
+		}
+		for _, path := range imports {
+			if newb, err = ensureImport(newb, path); err != nil {
+				return err
+			}
+		}
+		return writeFile(fp, newb)
+	}
+	return fmt.Errorf("the controller method %s isn't declared in %s", v, fp)
+}
@@ -0,0 +1,66 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/build/constraint"
+	"path/filepath"
+	"strings"
+)
+
+// verifyBuildTag returns an error if --build-tag isn't a valid build constraint expression e.g. dev or
+// integration && !race
+func verifyBuildTag() error {
+	if *buildTag == "" {
+		return nil
+	}
+	if _, err := constraint.Parse("//go:build " + *buildTag); err != nil {
+		return fmt.Errorf("invalid --build-tag %q: %s", *buildTag, err.Error())
+	}
+	return nil
+}
+
+// buildConstraint returns the build constraint lines, e.g. //go:build dev, of the Go source b, which are the
+// ones above its package clause, or nil if it has none
+func buildConstraint(b []byte) []byte {
+	var lines []byte
+	for _, line := range bytes.SplitAfter(b, []byte("\n")) {
+		trimmed := bytes.TrimSpace(line)
+		if bytes.HasPrefix(trimmed, []byte("package ")) {
+			break
+		}
+		if constraint.IsGoBuild(string(trimmed)) || constraint.IsPlusBuild(string(trimmed)) {
+			lines = append(lines, append(trimmed, '\n')...)
+		}
+	}
+	return lines
+}
+
+// withBuildConstraint returns the Go source b starting with the build constraint lines c, followed by the
+// blank line separating them from the package clause, unless b already has a build constraint
+func withBuildConstraint(b, c []byte) []byte {
+	if len(c) == 0 || len(buildConstraint(b)) > 0 {
+		return b
+	}
+	return append(append(append([]byte{}, c...), '\n'), b...)
+}
+
+// constrained returns the content b about to be written to the Go file fp with the build constraint it must
+// keep. A new file gets the constraint of --build-tag, and an existing one keeps its own constraint even if b
+// was spliced or regenerated without it.
+func constrained(fp string, b []byte) []byte {
+	if filepath.Ext(fp) != ".go" {
+		return b
+	}
+	old, err := readFile(fp)
+	if err != nil {
+		if *buildTag == "" || len(b) == 0 {
+			return b
+		}
+		return withBuildConstraint(b, []byte("//go:build "+strings.TrimSpace(*buildTag)+"\n"))
+	}
+	return withBuildConstraint(b, buildConstraint(old))
+}
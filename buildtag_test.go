@@ -0,0 +1,76 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildConstraint(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"none", "// Package view provides ...\npackage view\n", ""},
+		{"go:build", "//go:build dev\n\npackage view\n", "//go:build dev\n"},
+		{"plus build", "//go:build integration\n// +build integration\n\npackage view\n", "//go:build integration\n// +build integration\n"},
+		{"below a header", "// Copyright 2024 me.\n\n//go:build dev\n\n// Package view provides ...\npackage view\n", "//go:build dev\n"},
+		{"below the package clause", "package view\n\n//go:build dev\n", ""},
+	}
+	for _, tt := range tests {
+		if got := string(buildConstraint([]byte(tt.src))); got != tt.want {
+			t.Errorf("%s: buildConstraint() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if got := string(withBuildConstraint([]byte("package view\n"), []byte("//go:build dev\n"))); got != "//go:build dev\n\npackage view\n" {
+		t.Errorf("withBuildConstraint() = %q", got)
+	}
+	if got := string(withBuildConstraint([]byte("//go:build ci\n\npackage view\n"), []byte("//go:build dev\n"))); got != "//go:build ci\n\npackage view\n" {
+		t.Errorf("withBuildConstraint() replaced the constraint of the source: %q", got)
+	}
+}
+
+// TestBuildTagSurvivesUsecaseAdditions asserts that the build constraint of the files created with
+// --build-tag, and one written by hand, are kept when usecases are added to them
+func TestBuildTagSurvivesUsecaseAdditions(t *testing.T) {
+	p := newTestProject(t)
+	p.clean("add", "interactor", "Order", "--build-tag", "dev")
+	files := []string{
+		"clean/ifadapter/controller/order.go",
+		"clean/ifadapter/presenter/order.go",
+		"clean/ifadapter/view/order.go",
+		"clean/usecase/interactor/order.go",
+		"clean/usecase/reqmodel/validator/order.go",
+	}
+	const fp = "clean/ifadapter/view/order.go"
+	p.write(fp, "// Copyright 2024 me.\n\n"+strings.Replace(p.read(fp), "//go:build dev\n", "//go:build dev && !race\n// +build dev,!race\n", 1))
+
+	p.clean("add", "usecase", "AddItem", "to", "Order")
+	p.clean("add", "usecase", "RemoveItem", "to", "Order")
+	for _, relPath := range files {
+		src := p.read(relPath)
+		want := "//go:build dev\n\n"
+		if relPath == fp {
+			want = "// Copyright 2024 me.\n\n//go:build dev && !race\n// +build dev,!race\n\n"
+		}
+		if !strings.HasPrefix(src, want) {
+			t.Errorf("%s doesn't start with %q:\n%s", relPath, want, src)
+		}
+		if strings.Count(src, "//go:build") != 1 {
+			t.Errorf("%s has %d //go:build lines", relPath, strings.Count(src, "//go:build"))
+		}
+		if !strings.Contains(src, "AddItem") || !strings.Contains(src, "RemoveItem") {
+			t.Errorf("the usecases weren't added to %s", relPath)
+		}
+	}
+
+	if testing.Short() {
+		return
+	}
+	for _, args := range [][]string{{"vet", "./..."}, {"vet", "-tags", "dev", "./..."}} {
+		if out, err := p.goRun(args...); err != nil {
+			t.Errorf("go %s fails: %s\n%s", strings.Join(args, " "), err.Error(), out)
+		}
+	}
+}
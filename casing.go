@@ -0,0 +1,96 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// caseCollisions returns the usecases of the interactor which differ from the usecase only in case, e.g. addItem
+// or Additem for AddItem, as descriptions like "Additem in the controller, interactor and reqmodel" naming the
+// objects and models whose methods or structs collide. Adding the usecase would add near-duplicates of them.
+func caseCollisions(basePath, usecase, interactor string) []string {
+	v := exportedName(usecase)
+	var names []string
+	layers := make(map[string][]string)
+	collide := func(declared, expected []string, layer string) {
+		for _, name := range declared {
+			for _, e := range expected {
+				if name == e || !strings.EqualFold(name, e) {
+					continue
+				}
+				// The usecase sits between the prefix and the suffix of the layer, e.g. Present and ErrVal
+				ix := strings.Index(e, v)
+				existing := name[ix : ix+len(v)]
+				names = appendUnique(names, existing)
+				layers[existing] = appendUnique(layers[existing], layer)
+			}
+		}
+	}
+	for _, objType := range objTypes {
+		fp := objectFilePath(basePath, objType, interactor)
+		b, err := readFile(fp)
+		if err != nil {
+			continue
+		}
+		f, err := parseGoFile(fp)
+		if err != nil {
+			continue
+		}
+		ifName, _ := fileObjNames(b, objType, interactor)
+		methods, _ := interfaceMethods(f, ifName)
+		collide(methods, usecaseMethods(objType, v), objType)
+	}
+	if !flatLayout && !verticalLayout {
+		for _, relPath := range []string{relPathReqModel, relPathRespModel, relPathViewModel} {
+			f, err := parseGoFile(filepath.FromSlash(basePath + relPath + fileName(interactor) + ".go"))
+			if err != nil {
+				continue
+			}
+			collide(structNames(f), usecaseModels(relPath, v), dirNameFromRelPath(relPath))
+		}
+	}
+	var collisions []string
+	for _, name := range names {
+		l := layers[name]
+		list := l[0]
+		if len(l) > 1 {
+			list = strings.Join(l[:len(l)-1], ", ") + " and " + l[len(l)-1]
+		}
+		collisions = append(collisions, fmt.Sprintf("%s in the %s", name, list))
+	}
+	return collisions
+}
+
+// confirmCaseCollisions warns about the usecases of the interactor which differ from the usecase only in case
+// and reports whether the usecase should be added anyway. It asks for confirmation if
+// stdin is a terminal which the usecases aren't read from. Otherwise the usecase is only added if --force is
+// set.
+func confirmCaseCollisions(basePath, usecase, interactor string, fromStdin bool) bool {
+	collisions := caseCollisions(basePath, usecase, interactor)
+	if len(collisions) == 0 {
+		return true
+	}
+	v := exportedName(usecase)
+	fmt.Printf("The usecase %s of %s differs only in case from %s\n", v, exportedName(interactor), strings.Join(collisions, ", "))
+	if *force {
+		return true
+	}
+	if fromStdin || !isTerminal(os.Stdin) {
+		failf("The usecase %s wasn't added to %s to avoid near-duplicates. Use the existing name, or add it anyway with --force\n\n", v, exportedName(interactor))
+		return false
+	}
+	p := &prompter{in: bufio.NewReader(os.Stdin)}
+	answer, err := p.ask(fmt.Sprintf("Add %s anyway? [y/N]", v), validOneOf("y", "Y", "n", "N", ""))
+	if err != nil || strings.ToLower(answer) != "y" {
+		fmt.Printf("The usecase %s wasn't added to %s\n\n", v, exportedName(interactor))
+		noopf("the usecase %s wasn't added to %s", v, exportedName(interactor))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestCaseCollision asserts that a usecase differing only in case from an existing one isn't added unless
+// --force is set, and that the layers it collides in are listed
+func TestCaseCollision(t *testing.T) {
+	p := newTestProject(t)
+	p.clean("add", "interactor", "Cart")
+	p.clean("add", "usecase", "CheckOut", "to", "Cart")
+
+	before := concatenated(p.files("clean"))
+	stdout, stderr, code := p.run("add", "usecase", "Checkout", "to", "Cart")
+	if code == 0 {
+		t.Fatalf("the near-duplicate usecase was added: %s%s", stdout, stderr)
+	}
+	out := stdout + stderr
+	for _, want := range []string{"The usecase Checkout of Cart differs only in case from CheckOut in the controller, presenter, view, interactor, validator", "wasn't added to Cart", "--force"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("the output doesn't contain %q:\n%s", want, out)
+		}
+	}
+	if concatenated(p.files("clean")) != before {
+		t.Errorf("the refused usecase changed the project")
+	}
+
+	// The same usecase is a noop rather than a collision
+	if out := p.clean("add", "usecase", "CheckOut", "to", "Cart"); strings.Contains(out, "differs only in case") {
+		t.Errorf("the existing usecase was reported as a collision:\n%s", out)
+	}
+
+	out = p.clean("add", "usecase", "Checkout", "to", "Cart", "--force")
+	if !strings.Contains(out, "differs only in case from CheckOut") {
+		t.Errorf("--force didn't warn about the collision:\n%s", out)
+	}
+	if src := p.read("clean/usecase/interactor/cart.go"); !strings.Contains(src, "\tCheckout(rqm *reqmodel.Checkout)") || !strings.Contains(src, "\tCheckOut(rqm *reqmodel.CheckOut)") {
+		t.Errorf("--force didn't add the usecase next to the existing one:\n%s", src)
+	}
+}
+
+func TestCaseCollisions(t *testing.T) {
+	p := newTestProject(t)
+	p.clean("add", "interactor", "Cart")
+	p.clean("add", "usecase", "CheckOut", "to", "Cart")
+	basePath := p.path("clean") + "/"
+	if got := caseCollisions(basePath, "CHECKOUT", "Cart"); len(got) == 0 {
+		t.Errorf("caseCollisions() found no collision of CHECKOUT")
+	}
+	for _, usecase := range []string{"CheckOut", "Pay"} {
+		if got := caseCollisions(basePath, usecase, "Cart"); len(got) != 0 {
+			t.Errorf("caseCollisions() of %s = %q, want none", usecase, got)
+		}
+	}
+}
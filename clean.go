@@ -8,143 +8,386 @@ import (
 	"errors"
 	"flag"
 	"fmt"
-	"html/template"
-	"io"
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"io/ioutil"
 	"os"
-	"os/user"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The folders of the layers in the clean folder, which the layout.[layer] settings of the configuration file
+// override, see setLayout
+var (
+	relPathEntity      = "entity/"
+	relPathController  = "ifadapter/controller/"
+	relPathPresenter   = "ifadapter/presenter/"
+	relPathView        = "ifadapter/view/"
+	relPathViewModel   = "ifadapter/view/viewmodel/"
+	relPathInteractor  = "usecase/interactor/"
+	relPathReqModel    = "usecase/reqmodel/"
+	relPathValidator   = "usecase/reqmodel/validator/"
+	relPathRespModel   = "usecase/respmodel/"
+	relPathGateway     = "ifadapter/gateway/"
+	relPathGatewayPort = "usecase/gateway/"
 )
 
 const (
-	helpAddSyntax           = "Usage: clean add [object]\n\nThe objects are:\n\n\tinteractor\tadd interactor e.g. Order\n\tusecase\tadd usecase e.g. AddItem\n\nUse \"clean help add [object]\" for more information about an object.\n\n"
-	helpAddUsecaseSyntax    = "Usage: clean add usecase [usecase] to [interactor]\n\n\tusecase\tname of usecase e.g. AddItem\n\tinteractor\tname of interactor e.g. Order\n\n"
-	helpAddInteractorSyntax = "Usage: clean add interactor [name]\n\n\tname\tname of interactor e.g. Order\n\n"
-	helpInitSyntax          = "Usage: Use \"clean init\" to initialise a new project in the current folder, i.e. to generate the required boilerplate folders and files. It also sets the Clean Work Directory to the folder in which this command is used.\n\n"
-	helpUsage               = "Clean is a tool for generating Clean Architecture boilerplate code.\n\nUsage:\n\n\tclean [verb]\n\nThe verbs are:\n\n\tadd\tadd e.g. new usecase\n\tinit\tinitialise a new Clean Architecture project. Warning! Generates files and folders\n\tset\tset current working directory\n\nUse \"clean help [verb]\" for more information about a verb.\n\nCreated by Tobias Strandberg.\n\n"
-	helpSetSyntax           = "Usage: clean set folder\n\nSet the Clean Work Directory to your current directory. The Clean Work Directory is used by Clean to determine in which folders on the hard drive to add interactors and usecases when using e.g. the \"clean add\" command\n\n"
-	relPathController       = "ifadapter/controller/"
-	relPathPresenter        = "ifadapter/presenter/"
-	relPathView             = "ifadapter/view/"
-	relPathViewModel        = "ifadapter/view/viewmodel/"
-	relPathInteractor       = "usecase/interactor/"
-	relPathReqModel         = "usecase/reqmodel/"
-	relPathValidator        = "usecase/reqmodel/validator/"
-	relPathRespModel        = "usecase/respmodel/"
-	verbAdd                 = "add"
-	verbInit                = "init"
-	verbSet                 = "set"
-	verbHelp                = "help"
-	objInteractor           = "interactor"
-	objUsecase              = "usecase"
-	objController           = "controller"
-	objView                 = "view"
-	objPresenter            = "presenter"
-	objValidator            = "validator"
+	verbAdd        = "add"
+	verbInit       = "init"
+	verbSet        = "set"
+	verbHelp       = "help"
+	verbStatus     = "status"
+	verbApply      = "apply"
+	verbWatch      = "watch"
+	verbTodos      = "todos"
+	verbNew        = "new"
+	verbRemove     = "remove"
+	verbFormat     = "format"
+	verbHistory    = "history"
+	verbDiff       = "diff"
+	verbMocks      = "mocks"
+	verbPurge      = "purge"
+	verbExplain    = "explain"
+	verbRegenerate = "regenerate"
+	objInteractor  = "interactor"
+	objUsecase     = "usecase"
+	objController  = "controller"
+	objView        = "view"
+	objPresenter   = "presenter"
+	objValidator   = "validator"
+	objEntity      = "entity"
+	objMethod      = "method"
+	objGateway     = "gateway"
+	// objRoutes is the RegisterRoutes function routing HTTP requests to the controller methods
+	objRoutes = "routes"
+	// objRepository is a Gateway storing the entities of a single type
+	objRepository        = "repository"
+	objGenericRepository = "generic-repository"
 )
 
 var (
 	relPaths              = []string{relPathController, relPathPresenter, relPathView, relPathViewModel, relPathInteractor, relPathReqModel, relPathValidator, relPathRespModel}
 	projectBaseImportPath string
+	packageRelPaths       = map[string]string{objEntity: relPathEntity, objController: relPathController, objGateway: relPathGateway, objPresenter: relPathPresenter, objView: relPathView, "viewmodel": relPathViewModel, objInteractor: relPathInteractor, "reqmodel": relPathReqModel, objValidator: relPathValidator, "respmodel": relPathRespModel, objEvent: relPathEvent}
+	objTypes              = []string{objController, objPresenter, objView, objInteractor, objValidator}
+	objRelPaths           = map[string]string{objController: relPathController, objPresenter: relPathPresenter, objView: relPathView, objInteractor: relPathInteractor, objValidator: relPathValidator}
+	desc                  = flag.String("desc", "", "description added to the doc comments of the generated types e.g. \"order lifecycle management\"")
+	format                = flag.String("format", "text", "output format, either text or json")
+	failOver              = flag.Int("fail-over", -1, "exit with a non-zero status if more than this number of TODOs remain. A negative number disables the check")
+	module                = flag.String("module", "", "module path of the project e.g. example.com/myapp. Defaults to the folder name")
+	globalScope           = flag.Bool("global", false, "write the settings of clean set to the global configuration file ~/.clean/cleanrc")
+	localScope            = flag.Bool("local", false, "write the settings of clean set to the configuration file .cleanrc of the project in the current directory")
+	force                 = flag.Bool("force", false, "initialise the project even if the folder isn't empty, or add a usecase even if the interactor has methods or models differing from its ones only in case")
+	buildTag              = flag.String("build-tag", "", "build constraint of the Go files the command creates e.g. dev, which is written as a //go:build dev line above their package clauses. The files keep their build constraints when they're modified afterwards")
+	folderScheme          = flag.String("layout", "", "initialise the project with the folders of the layers of a scheme, one of clean, the default, and hexagonal, which generates into domain, ports and adapters folders. It sets the layout.[layer] settings of the configuration file")
+	flat                  = flag.Bool("flat", false, "initialise the project in the flat layout, which generates all of the layers into the package of the clean folder as types named after their interactor and layer e.g. OrderController and OrderInteractor. It sets layout=flat in the configuration file")
+	fuzz                  = flag.Bool("fuzz", false, "generate a FuzzValidate[usecase] fuzz target in the Validator's test folder. Requires Go 1.18 or later")
+	explicitErrVal        = flag.Bool("explicit-errval", false, "make the Interactor method return the Validator's ErrVal ResponseModel when the validation fails and nil otherwise")
+	stdout                = flag.Bool("stdout", false, "print the new content of each generated or modified file preceded by a \"==> path <==\" header instead of writing it")
+	only                  = flag.String("only", "", "comma separated list of the objects and models to generate e.g. presenter,viewmodel. The objects and models are controller, presenter, view, viewmodel, interactor, reqmodel, validator and respmodel")
+	skip                  = flag.String("skip", "", "comma separated list of the objects, models and features not to generate e.g. events. The features are events")
+	terse                 = flag.Bool("terse", false, "generate a single line doc comment per type and method instead of the comments explaining them")
+	jsonOutput            = flag.Bool("json", false, "print the outcome of the command, one of changed, noop, error and ok, as JSON")
+	failOnNoop            = flag.Bool("fail-on-noop", false, "exit with status 3 if the command had nothing to do because e.g. the usecase already exists")
+	noHistory             = flag.Bool("no-history", false, "don't log the command in the .clean/history.log file of the project")
+	historyN              = flag.Int("n", 20, "number of entries to show. A negative number shows all of them")
+	interactive           = flag.Bool("interactive", false, "prompt for the missing arguments of the command")
+	idempotentRegenerate  = flag.Bool("idempotent-regenerate", false, "update the signatures of the methods of an existing usecase in the interfaces and their implementations to the ones generated today with the flags of the command, keeping the bodies of the methods")
+	withGateway           = flag.Bool("with-gateway", false, "add a Gateway interface and its implementation to the ifadapter/gateway folder and inject the Gateway into the Interactor")
+	gatewayInUsecase      = flag.Bool("with-gateway-interface-in-usecase", false, "add the Gateway interface to the usecase/gateway folder, where the Interactor depends on it, and its implementation to the ifadapter/gateway folder")
+	mockEngine            = flag.String("mock-engine", "", "engine generating the mocks, either builtin or gomock. gomock adds a //go:generate mockgen directive to the interface files of the interactor and makes clean mocks run mockgen. Defaults to the mocks.engine setting of the configuration file, or builtin")
+	withMocks             = flag.Bool("with-mocks", false, "add a //go:generate mockgen directive for each of the interactor's interfaces to the gen.go file of the interactor folder. \"go generate ./...\" then writes the mocks to the test folders")
+	withValidation        = flag.Bool("with-validation", false, "add a Validate method checking the invariants of the entity and a New constructor returning the entity and the error returned by Validate")
+	createOnly            = flag.Bool("create-only", false, "never modify existing files, only create new ones. The modifications that are skipped are listed and make the command exit with status 4")
+	lintClean             = flag.Bool("lint-clean", false, "start the body of each generated method with a blank assignment of each parameter the body doesn't use, e.g. _ = rqm, so that linters don't flag them")
+	noTest                = flag.Bool("no-test", false, "don't add the skipped tests of the usecase to the test files of the Interactor and the Validator")
+	golden                = flag.Bool("golden", false, "also add a golden file test of the usecase to the test file of the Presenter")
+	allInteractors        = flag.Bool("all", false, "apply the command to all interactors")
+	strict                = flag.Bool("strict", false, "fail the command without writing anything if the generated code has identifiers, imports or references to project packages that can't be resolved")
+	requireCleanGit       = flag.Bool("require-clean-git", false, "refuse to generate anything if any of the files the command would write has uncommitted changes in git. Ignored outside git repositories")
+	driver                = flag.String("driver", "", "database driver the Gateway implementation holds a handle of, one of gorm and sql")
+	generic               = flag.Bool("generic", false, "make the repository an alias of the generic Repository. Requires Go 1.18 or later")
+	timings               = flag.Bool("timings", false, "print the time spent in each phase of the command, e.g. parsing and writing files, to stderr")
+	cpuProfile            = flag.String("cpuprofile", "", "write a CPU profile to this file")
+	memProfile            = flag.String("memprofile", "", "write a memory profile to this file")
+	repair                = flag.Bool("repair", false, "add only the missing files of an interactor whose files partly exist, e.g. its view after the view file was deleted")
+	withIntegrationTest   = flag.Bool("with-integration-test", false, "also add a test to the test folder of the clean folder which constructs the Controller, Interactor, Presenter and View of the interactor")
+	withBenchmarks        = flag.Bool("with-benchmarks", false, "also add a benchmark of the Interactor method of the usecase to the benchmark file of the interactor in the test folder of the Interactor")
+	withClock             = flag.Bool("with-clock", false, "inject the Clock of the lib/clock folder, which is added if it doesn't exist, into the Interactor so that it gets the time from it instead of calling time.Now")
+	withKernel            = flag.Bool("with-kernel", false, "add the shared kernel package, holding the value types shared by the entities e.g. an ID type, to the kernel folder while initialising the project")
+	withIDGen             = flag.Bool("with-idgen", false, "inject the ID Generator of the lib/idgen folder, which is added if it doesn't exist, into the Interactor so that the Interactor methods of its Create usecases generate the IDs with it")
+	proto                 = flag.String("proto", "", "protobuf message, as the path of a .proto file and the name of the message separated by a colon e.g. api/order.proto:AddItemRequest, of the request payload of the usecase. Its fields become the fields of the RequestModel and its nested messages structs of the reqmodel folder")
+	schema                = flag.String("schema", "", "JSON Schema file, in a subset of draft-07, of the request payload of the usecase. Its properties become the fields of the RequestModel and its required properties are checked by the Validate method")
+	guessNameWords        = flag.Bool("guess-words", false, "use the CamelCase form guessed for names entered in lower case that look like several words e.g. AddItem for additem")
+	strictNames           = flag.Bool("strict-names", false, "fail if a name entered in lower case looks like several words e.g. additem instead of warning about it")
+	withUow               = flag.Bool("with-uow", false, "inject the UnitOfWork of the ifadapter/gateway folder, which is added if it doesn't exist, into the Interactor instead of its Gateway. Requires --with-gateway")
+	bind                  = flag.String("bind", "", "binding of the RequestModel in the controller method of the usecase. Set it to http to make the method take an *http.Request and bind the fields from its JSON body and query parameters. Failed conversions are presented as the ErrVal of the usecase")
+	metrics               = flag.Bool("metrics", false, "inject the Prometheus metrics of the lib/metrics folder, which are added if they don't exist, into the Interactor and count the calls and observe the latency of the Interactor method of the usecase")
+	presenterView         = flag.String("view", "", "name of the View the added Presenter holds e.g. WebOrder, which must be one of the Views of the interactor, instead of the View named after the interactor")
+	validatorStyle        = flag.String("validator", "", "style of the Validate method of the usecase. Set it to tags to validate the validate struct tags of the RequestModel's fields with go-playground/validator instead of hand-writing the validation")
+	dryRun                = flag.Bool("dry-run", false, "only list what would be done without doing it")
+	keepSkeleton          = flag.Bool("keep-skeleton", false, "keep the empty folders of the layers, and their test folders, which the project was initialised with")
+	paginated             = flag.Bool("paginated", false, "paginate the usecase, whose RequestModel gets the page to list and whose ResponseModel and ViewModel get the items of the page. Set pagination.style=cursor in the configuration file to paginate by cursor instead of page number")
+	auth                  = flag.Bool("auth", false, "make the Interactor method present the ErrVal ResponseModel, whose Forbidden field is set, unless the Authorizer allows the usecase. The Authorizer is added to the auth folder and injected into the Interactor if it doesn't have it yet")
+	notify                = flag.Bool("notify", false, "make the Interactor method enqueue a notification with the Outbox once its main work is done. The Outbox is added to the outbox folder and injected into the Interactor if it doesn't have it yet")
+	saga                  = flag.String("saga", "", "comma-separated steps of the saga the Interactor method of the usecase runs e.g. ReserveStock,ChargePayment:Payment. Each step is compensated if a later one fails. The Gateway named after the colon of a step is injected into the Interactor")
+	timeout               = flag.Duration("timeout", 0, "latency budget of the usecase e.g. 2s. The Interactor method derives a context with the deadline of the budget, which is declared once per interactor as a constant")
+	unexportedInterface   = flag.Bool("unexported-interface", false, "make the Controller interface unexported e.g. order, implemented by orderImpl, while its constructor stays exported. The interfaces of the other objects are referred to by the layer outside of theirs and stay exported")
+	presenterMapping      = flag.Bool("presenter-only-json", false, "generate Presenter method bodies that assign the ResponseModel fields to the ViewModel fields of the same name and type and call the View")
 )
 
 func main() {
 	// Sets description for this tool
 	flag.Usage = func() {
-		fmt.Printf(helpUsage)
+		fmt.Printf("%s", usageText())
+	}
+	flag.CommandLine.Parse(flagsFirst(os.Args[1:]))
+	defer exitWithOutcome()
+	defer printTimings()
+	stopProfiles, err := startProfiles()
+	if err != nil {
+		failf("Error starting the profiles: %s\n", err.Error())
+		return
+	}
+	defer stopProfiles()
+	if *stdout {
+		defer printPendingFiles()
 	}
-	flag.Parse()
 
 	args := flag.Args()
 	nArgs := len(args)
 	if nArgs == 0 {
-		fmt.Printf(helpUsage)
+		fmt.Printf("%s", usageText())
+		return
+	}
+	// The aliases are resolved before anything else so that the commands only deal with the names
+	if args[0] == verbHelp {
+		var topic []string
+		topic, err = resolveAliases(args[1:])
+		args = append([]string{verbHelp}, topic...)
+	} else {
+		args, err = resolveAliases(args)
+	}
+	if err != nil {
+		usagef("%s", err.Error())
 		return
 	}
 	verb := args[0]
 	if verb == verbHelp {
 		if nArgs == 1 {
-			fmt.Printf(helpUsage)
-			return
-		}
-		switch args[1] {
-		case verbAdd:
-			if nArgs == 2 {
-				fmt.Printf(helpAddSyntax)
-			} else if nArgs == 3 {
-				switch args[2] {
-				case objInteractor:
-					fmt.Printf(helpAddInteractorSyntax)
-				case objUsecase:
-					fmt.Printf(helpAddUsecaseSyntax)
-				default:
-					fmt.Printf("Invalid object entered.\n\nUse \"clean help add\" for more information about valid objects.\n\n")
-				}
-			} else {
-				fmt.Printf("Invalid number of arguments entered.\n\nUse \"clean help add\" for more information.\n\n")
-			}
-		case verbInit:
-			if nArgs == 2 {
-				fmt.Printf(helpInitSyntax)
-			} else {
-				fmt.Printf("Invalid number of arguments entered.\n\nUse \"clean help init\" for more information\n\n")
-			}
-		case verbSet:
-			if nArgs == 2 {
-				fmt.Printf(helpSetSyntax)
-			} else {
-				fmt.Printf("Invalid number of arguments entered.\n\nUse \"clean help set\" for more information\n\n")
-			}
-		default:
-			fmt.Printf("No such verb, call \"clean -h\" for a list of available verbs.\n\n")
+			fmt.Printf("%s", usageText())
+			return
 		}
+		printHelp(strings.Join(args[1:], " "))
+		return
+	}
+	if _, ok := findCommand(verb); !ok {
+		unknownCommandf(verb)
 		return
 	}
 
-	usr, err := user.Current()
+	home, err := os.UserHomeDir()
 	if err != nil {
-		fmt.Printf("Error getting current user: %s\n", err.Error())
+		failf("Error getting the home folder of the current user: %s\n", err.Error())
 		return
 	}
-	confDir := usr.HomeDir + "/" + ".clean"
+	confDir := filepath.ToSlash(home) + "/" + ".clean"
 	confPath := confDir + "/" + "cleanrc"
-	confBytes, err := ioutil.ReadFile(filepath.FromSlash(confPath))
-	if err != nil {
+	if verb == verbEnv {
+		// User entered: clean env. It reads the configuration itself so that it works when parts of it are broken.
+		if nArgs > 1 {
+			usageErrorf(verbEnv)
+			return
+		}
+		ownJSONOutput = *jsonOutput
+		if err := printEnv(projectEnv(filepath.FromSlash(confPath)), *jsonOutput); err != nil {
+			failf("Error printing the configuration: %s\n", err.Error())
+		}
+		return
+	}
+	if verb == verbSetup {
+		// User entered: clean setup
+		if nArgs > 1 {
+			usageErrorf(verbSetup)
+			return
+		}
+		setup(filepath.FromSlash(confDir), filepath.FromSlash(confPath))
+		return
+	}
+	if verb == verbNew {
+		// User entered: clean new project [name]
+		if nArgs != 3 || args[1] != "project" {
+			usageErrorf("new project")
+			return
+		}
+		newProject(filepath.FromSlash(confDir), filepath.FromSlash(confPath), args[2], *module, *force)
+		return
+	}
+	stopPhase := startPhase(phaseConfig)
+	conf, err := readConfig(filepath.FromSlash(confPath))
+	if err != nil && fileExists(filepath.FromSlash(confPath)) {
+		// A malformed configuration file would otherwise make Clean write to the wrong place
+		stopPhase()
+		failf("%s\n", err.Error())
+		return
+	}
+	// The settings of the project configuration file override the global ones
+	var localConfPath string
+	if wd, wdErr := os.Getwd(); wdErr == nil && verb != verbInit {
+		localConfPath = findLocalConfig(wd)
+	}
+	if localConfPath != "" {
+		if err != nil {
+			conf, err = make(map[string]string), nil
+		}
+		if localErr := mergeLocalConfig(conf, localConfPath); localErr != nil {
+			stopPhase()
+			failf("%s\n", localErr.Error())
+			return
+		}
+	}
+	stopPhase()
+	// A configuration file without a Clean Work Directory, e.g. one holding only the layout.[layer] settings,
+	// is initialised like a missing one
+	if _, ok := projectDir(conf); err != nil || (verb == verbInit && !ok) {
 		if verb != verbInit {
-			fmt.Printf("Error reading configuration file. Maybe you haven't created a new Clean Architecture Project by executing 'clean init' yet?\n")
+			failf("Error reading configuration file. Maybe you haven't created a new Clean Architecture Project by executing 'clean init' yet?\n")
 		}
 		if nArgs > 1 {
-			fmt.Printf("Invalid number of arguments entered.\n\nUse \"clean help init\" for more information\n\n")
+			usageErrorf("init")
 			return
 		}
 		initProject(filepath.FromSlash(confDir), filepath.FromSlash(confPath))
 		return
 	}
-	keyValuePairs := bytes.Split(confBytes, []byte("="))
-	if keyValuePairs == nil || len(keyValuePairs) < 2 {
+	baseDir, ok := projectDir(conf)
+	if !ok {
+		failf("Clean Work Directory not configured. Please go to your project folder and either run \"clean init\" or \"clean set folder\"\n\n")
+		return
+	}
+	addInitialisms(strings.Split(conf[confKeyInitialisms], ","))
+	setTypeSuffixes(conf)
+	if err := setLayout(conf); err != nil {
+		failf("%s\n\n", err.Error())
+		return
+	}
+	if err := setLayoutMode(conf); err != nil {
+		failf("%s\n\n", err.Error())
+		return
+	}
+	if err := setLayerPolicies(conf); err != nil {
+		failf("%s\n\n", err.Error())
+		return
+	}
+	projectBasePath = baseDir + "clean/"
+	if conf[confKeyCreateOnly] == "true" {
+		*createOnly = true
+	}
+	if conf[confKeyLintClean] == "true" {
+		*lintClean = true
+	}
+	if *mockEngine == "" {
+		*mockEngine = conf[confKeyMockEngine]
+	}
+	if err := validMockEngine(*mockEngine); err != nil {
+		failf("%s\n\n", err.Error())
+		return
+	}
+	if err := setReceiverStyle(conf[confKeyReceiver]); err != nil {
+		failf("%s\n\n", err.Error())
+		return
+	}
+	if err := setCommentStyle(conf[confKeyComments]); err != nil {
+		failf("%s\n\n", err.Error())
+		return
+	}
+	if err := setPaginationStyle(conf[confKeyPagination]); err != nil {
+		failf("%s\n\n", err.Error())
+		return
+	}
+	if err := setImportReplacements(conf[confKeyModuleReplace]); err != nil {
+		failf("%s\n\n", err.Error())
 		return
 	}
-	baseDir := string(keyValuePairs[1])
-	// Removes the LF character at the end of the string
-	baseDir = strings.TrimRight(baseDir, "\n")
 
-	// Find the first occurrence of 'src' and then assume the import path for the project is what follows after that
+	if mutatingVerbs[verb] && !*noHistory && !*stdout {
+		historyProject = baseDir
+		if maxSize, err := strconv.ParseInt(conf[confKeyHistoryMaxSize], 10, 64); err == nil && maxSize > 0 {
+			historyMaxSize = maxSize
+		}
+	}
+	if (verb == verbAdd || verb == verbRemove || verb == verbApply || verb == verbRegenerate || verb == verbGenerate || verb == verbMocks) && !*stdout {
+		m, err := loadManifest(baseDir, baseDir+"clean/")
+		if err != nil {
+			failf("Error reading the manifest: %s\n", err.Error())
+			return
+		}
+		// Runs once the files kept in memory below are written
+		defer updateManifest(baseDir, baseDir+"clean/", m, commandFlags())
+	}
+	if mutatingVerbs[verb] && *strict && !*requireCleanGit && !*stdout {
+		// Keep the files in memory until it's known whether their references can be resolved
+		*stdout = true
+		defer writePendingFiles()
+	}
+	if mutatingVerbs[verb] && *requireCleanGit && !*stdout {
+		// Keep the files in memory until it's known whether any of them has uncommitted changes
+		*stdout = true
+		defer writeIfCommitted(baseDir)
+	}
+	if mutatingVerbs[verb] && *strict {
+		// Runs before the files are written but after the routes and decorators are updated
+		defer verifyResolved(baseDir)
+	}
+	if verb == verbAdd || verb == verbRemove || verb == verbApply || verb == verbRegenerate || verb == verbGenerate {
+		// Route and decorate any controller and interactor methods the command added or removed
+		defer updateRoutes(baseDir + "clean/")
+		defer updateDecorators(baseDir + "clean/")
+		defer updateViewFactories(baseDir + "clean/")
+		// Pass the dependencies injected into the Interactors to their existing constructor calls
+		defer updateInteractorCalls(baseDir + "clean/")
+	}
+
+	// Use the configured module path if there is one. Otherwise find the first occurrence of 'src' and then
+	// assume the import path for the project is what follows after that
 	// e.g. if baseDir is /users/john/go/src/myproject/ then projectBaseImportPath should be myproject
-	found := false
-	for i := len(baseDir) - 1; i > 0; i-- {
-		if baseDir[i] == 'c' {
-			if i > 1 {
-				if baseDir[i-1] == 'r' && baseDir[i-2] == 's' {
-					projectBaseImportPath = string(baseDir[i+2:])
-					found = true
-					break
+	if refreshModuleConfig(conf, baseDir) {
+		// The module path is kept in the configuration file the project's settings come from
+		fp := filepath.FromSlash(confPath)
+		if localConfPath != "" {
+			fp = localConfPath
+		}
+		err := updateConfig(fp, func(c map[string]string) {
+			for _, k := range []string{confKeyModule, confKeyModuleRoot, confKeyGoModStamp} {
+				if v, ok := conf[k]; ok {
+					c[k] = v
+				} else {
+					delete(c, k)
 				}
 			}
+		})
+		if err != nil {
+			failf("Error updating config file: %s\n", err.Error())
+			return
 		}
 	}
+	found := false
+	if modulePath := conf[confKeyModule]; modulePath != "" {
+		projectBaseImportPath = modulePath + "/"
+		found = true
+	}
+	if !found {
+		projectBaseImportPath, found = gopathImportPath(baseDir)
+	}
 	if !found {
-		fmt.Printf("Clean Work Directory not configured. Please go to your project folder and either run \"clean init\" or \"clean set folder\"\n\n")
+		failf("Clean Work Directory not configured. Please go to your project folder and either run \"clean init\" or \"clean set folder\"\n\n")
 		return
 	}
 
@@ -152,7 +395,7 @@ func main() {
 	switch verb {
 	case verbInit:
 		if nArgs > 1 {
-			fmt.Printf("Invalid number of arguments entered.\n\nUse \"clean help init\" for more information\n\n")
+			usageErrorf("init")
 			return
 		}
 		initProject(filepath.FromSlash(confDir), filepath.FromSlash(confPath))
@@ -160,179 +403,1130 @@ func main() {
 	case verbSet:
 		// User entered: clean set
 		if nArgs == 1 {
-			fmt.Printf(helpSetSyntax)
+			usageErrorf("set")
 			return
 		}
 		if nArgs == 2 {
 			// User entered: clean set jibberish
 			if args[1] != "folder" {
-				fmt.Printf(helpSetSyntax)
+				usageErrorf("set")
 				return
 			}
 			// User entered: clean set folder
 			wd, err := os.Getwd()
 			if err != nil {
-				fmt.Printf("Error determining current working directory\n")
+				failf("Error determining current working directory\n")
+				return
+			}
+			fp, err := configScope(filepath.FromSlash(confPath), wd)
+			if err != nil {
+				failf("%s\n\n", err.Error())
 				return
 			}
 
 			// Check for configuration file
-			if fileExists(filepath.FromSlash(confPath)) {
-				if err := ioutil.WriteFile(
-					filepath.FromSlash(confPath),
-					[]byte("directory="+filepath.FromSlash(wd)+"/"),
-					0700,
-				); err != nil {
-					fmt.Printf("Error creating config file: %s\n", err.Error())
-					return
-				}
-				fmt.Printf("Clean working directory updated successfully\n\n")
-			} else {
+			if fp == filepath.FromSlash(confPath) && !fileExists(fp) {
 				fmt.Printf("No configuration file exists. Use \"clean init\" to initialise a project instead\n\n")
+				return
+			}
+			err = updateConfig(fp, func(c map[string]string) {
+				c[confKeyDirectory] = filepath.FromSlash(wd) + "/"
+				setModuleConfig(c, wd, "")
+			})
+			if err != nil {
+				failf("Error creating config file: %s\n", err.Error())
+				return
+			}
+			fmt.Printf("Clean working directory updated successfully in %s\n\n", fp)
+			return
+		}
+		if nArgs == 3 && args[1] == "module" {
+			// User entered: clean set module [path]
+			modulePath := strings.Trim(args[2], "/")
+			if modulePath == "" || strings.ContainsAny(modulePath, " \t\\") {
+				failf("Invalid module path %q, it must be an import path e.g. example.com/myapp\n\n", args[2])
+				return
+			}
+			wd, err := os.Getwd()
+			if err != nil {
+				failf("Error determining current working directory\n")
+				return
+			}
+			fp, err := configScope(filepath.FromSlash(confPath), wd)
+			if err != nil {
+				failf("%s\n\n", err.Error())
+				return
 			}
+			err = updateConfig(fp, func(c map[string]string) {
+				setModuleConfig(c, filepath.Clean(filepath.FromSlash(baseDir)), modulePath)
+			})
+			if err != nil {
+				failf("Error updating config file: %s\n", err.Error())
+				return
+			}
+			fmt.Printf("Module path set to %s in %s\n\n", modulePath, fp)
+			return
+		}
+		if nArgs > 3 && args[1] == "desc" {
+			// User entered: clean set desc [package] [description]
+			relPath, ok := packageRelPaths[args[2]]
+			if !ok {
+				failf("Invalid package entered. The packages are %s\n\n", strings.Join(sortedKeys(packageRelPaths), ", "))
+				return
+			}
+			if err := setPackageDesc(baseDir+"clean/"+relPath, args[2], strings.Join(args[3:], " ")); err != nil {
+				failf("Error setting the description of %s: %s\n\n", args[2], err.Error())
+				return
+			}
+			fmt.Printf("Description of package %s updated successfully\n\n", args[2])
+			return
+		}
+		usageErrorf("set")
+		return
+	case verbStatus:
+		if nArgs > 1 {
+			usageErrorf("status")
+			return
+		}
+		printStatus(baseDir + "clean/")
+		return
+	case verbExplain:
+		// User entered: clean explain usecase [usecase] in [interactor]
+		if nArgs != 5 || args[1] != objUsecase || strings.ToLower(args[3]) != "in" {
+			usageErrorf("explain")
+			return
+		}
+		if err := checkNames(args[2], args[4]); err != nil {
+			failf("%s\n\n", err.Error())
+			return
+		}
+		if err := verifyTypeSuffixes(baseDir + "clean/"); err != nil {
+			failf("%s\n\n", err.Error())
+			return
+		}
+		steps, err := explainUsecase(baseDir, baseDir+"clean/", exportedName(args[2]), args[4])
+		if err != nil {
+			failf("%s\n\n", err.Error())
+			return
+		}
+		ownJSONOutput = *jsonOutput
+		if err := printExplanation(exportedName(args[2]), args[4], steps, *jsonOutput); err != nil {
+			failf("Error printing the explanation: %s\n\n", err.Error())
+		}
+		return
+	case verbFormat:
+		if nArgs > 1 {
+			usageErrorf("format")
+			return
+		}
+		if err := formatProject(baseDir, baseDir+"clean/"); err != nil {
+			failf("Error formatting the project: %s\n\n", err.Error())
+		}
+		return
+	case verbPurge:
+		if nArgs > 1 {
+			usageErrorf("purge")
+			return
+		}
+		if err := purge(baseDir, *keepSkeleton, *dryRun); err != nil {
+			failf("Error purging the empty folders: %s\n\n", err.Error())
+		}
+		return
+	case verbSnapshot:
+		// User entered: clean snapshot create, clean snapshot list or clean snapshot restore [id]
+		retention, err := snapshotRetention(conf)
+		if err != nil {
+			failf("%s\n\n", err.Error())
+			return
+		}
+		if err := runSnapshot(baseDir, args[1:], retention); err != nil {
+			failf("Error with the snapshot: %s\n\n", err.Error())
+		}
+		return
+	case verbMocks:
+		// User entered: clean mocks [interactor] or clean mocks --all
+		var interactors []string
+		switch {
+		case nArgs == 2 && !*allInteractors:
+			if interactors, err = interactorsFromArg(baseDir+"clean/", args[1]); err != nil {
+				failf("%s\n\n", err.Error())
+				return
+			}
+		case nArgs == 1 && *allInteractors:
+			usecases, err := interactorUsecases(baseDir + "clean/")
+			if err != nil {
+				failf("Error finding the interactors: %s\n\n", err.Error())
+				return
+			}
+			for name := range usecases {
+				interactors = append(interactors, name)
+			}
+			sort.Strings(interactors)
+		default:
+			usageErrorf("mocks")
+			return
+		}
+		if err := generateMocks(baseDir+"clean/", interactors); err != nil {
+			failf("Error regenerating the mocks: %s\n\n", err.Error())
+		}
+		return
+	case verbSync:
+		// User entered: clean sync validator [usecase] in [interactor]
+		if nArgs != 5 || args[1] != objValidator || strings.ToLower(args[3]) != "in" {
+			usageErrorf(verbSync + " " + objValidator)
+			return
+		}
+		if verticalLayout {
+			failf("The %s layout has no Validator, the usecase packages validate their Requests\n\n", layoutVertical)
+			return
+		}
+		if err := verifyTypeSuffixes(baseDir + "clean/"); err != nil {
+			failf("%s\n\n", err.Error())
+			return
+		}
+		if _, err := interactorsFromArg(baseDir+"clean/", args[4]); err != nil {
+			failf("%s\n\n", err.Error())
+			return
+		}
+		if err := syncValidator(baseDir+"clean/", args[2], args[4]); err != nil {
+			failf("Error syncing the validator of %s: %s\n\n", exportedName(args[2]), err.Error())
+		}
+		return
+	case verbDiff:
+		// User entered: clean diff [interactor]
+		if err := diffProject(baseDir, baseDir+"clean/", args[1:]); err != nil {
+			failf("Error diffing the project: %s\n\n", err.Error())
+		}
+		return
+	case verbHistory:
+		if nArgs > 1 {
+			usageErrorf("history")
+			return
+		}
+		entries, err := readHistory(baseDir)
+		if err != nil {
+			failf("Error reading the history log: %s\n\n", err.Error())
+			return
+		}
+		ownJSONOutput = *jsonOutput
+		if err := printHistory(entries, *historyN, *jsonOutput); err != nil {
+			failf("Error printing the history log: %s\n\n", err.Error())
+		}
+		return
+	case verbTodos:
+		if nArgs > 1 {
+			usageErrorf("todos")
+			return
+		}
+		todos, err := findTodos(baseDir, baseDir+"clean/")
+		if err != nil {
+			failf("Error finding the TODOs: %s\n\n", err.Error())
+			return
+		}
+		if err := printTodos(todos, *format); err != nil {
+			failf("Error printing the TODOs: %s\n\n", err.Error())
+			return
+		}
+		if *failOver >= 0 && len(todos) > *failOver {
+			if *format == "json" {
+				// The JSON on stdout tells the count already, only the exit status is left
+				errorMessages = append(errorMessages, fmt.Sprintf("%d TODOs remain, more than the allowed %d", len(todos), *failOver))
+				return
+			}
+			failf("%d TODOs remain, more than the allowed %d\n", len(todos), *failOver)
+			return
+		}
+		return
+	case verbLint:
+		if nArgs > 1 {
+			usageErrorf(verbLint)
+			return
+		}
+		violations, err := lintProject(baseDir, baseDir+"clean/")
+		if err != nil {
+			failf("Error linting the project: %s\n\n", err.Error())
+			return
+		}
+		ownJSONOutput = *jsonOutput
+		if err := printViolations(violations, *jsonOutput); err != nil {
+			failf("Error printing the violations: %s\n\n", err.Error())
+			return
+		}
+		if len(violations) > 0 {
+			// The violations are printed already, only the exit status is left
+			errorMessages = append(errorMessages, fmt.Sprintf("%d violations of the dependency rule", len(violations)))
+		}
+		return
+	case verbRegenerate:
+		// User entered: clean regenerate
+		if nArgs != 1 {
+			usageErrorf("regenerate")
+			return
+		}
+		if err := verifyTypeSuffixes(baseDir + "clean/"); err != nil {
+			failf("%s\n\n", err.Error())
+			return
+		}
+		if err := regenerateFromManifest(baseDir, baseDir+"clean/"); err != nil {
+			failf("Error regenerating the project: %s\n\n", err.Error())
+		}
+		return
+	case verbGenerate:
+		// User entered: clean generate, usually run by go generate ./...
+		if nArgs != 1 {
+			usageErrorf(verbGenerate)
+			return
+		}
+		if err := verifyGoGenerateDir(baseDir); err != nil {
+			failf("%s\n\n", err.Error())
+			return
+		}
+		if err := verifyTypeSuffixes(baseDir + "clean/"); err != nil {
+			failf("%s\n\n", err.Error())
+			return
+		}
+		if changed, err := generateFromDirectives(baseDir + "clean/"); err != nil {
+			failf("Error generating from the directives: %s\n\n", err.Error())
+		} else if !changed && len(errorMessages) == 0 {
+			noopf("the project is up to date with its directives")
+		}
+		return
+	case verbApply, verbWatch:
+		if nArgs != 2 {
+			usageErrorf(verb)
 			return
 		}
-		fmt.Printf(helpSetSyntax)
+		if err := verifyTypeSuffixes(baseDir + "clean/"); err != nil {
+			failf("%s\n\n", err.Error())
+			return
+		}
+		if verb == verbApply {
+			var changed bool
+			if changed, err = applySpec(baseDir+"clean/", args[1]); err == nil && !changed {
+				noopf("the project is up to date with %s", args[1])
+			}
+		} else {
+			err = watchSpec(baseDir, baseDir+"clean/", args[1])
+		}
+		if err != nil {
+			failf("Error applying %s: %s\n\n", args[1], err.Error())
+		}
 		return
 	case verbAdd:
+		if *interactive {
+			// Prompt for the missing arguments
+			if args, err = completeAddArgs(baseDir+"clean/", args); err != nil {
+				failf("Cancelled, nothing was added\n\n")
+				return
+			}
+			nArgs = len(args)
+		}
+		// Refuse to add to objects generated with different type suffixes than the configured ones
+		if nArgs > 2 {
+			if err := verifyTypeSuffixes(baseDir + "clean/"); err != nil {
+				failf("%s\n\n", err.Error())
+				return
+			}
+		}
+		if err := verifyOnly(); err != nil {
+			usagef("%s", err.Error())
+			return
+		}
+		if err := verifyDriver(); err != nil {
+			failf("%s\n\n", err.Error())
+			return
+		}
+		if err := verifyValidatorStyle(); err != nil {
+			failf("%s\n\n", err.Error())
+			return
+		}
+		if err := verifyBindStyle(); err != nil {
+			failf("%s\n\n", err.Error())
+			return
+		}
+		if err := verifyBuildTag(); err != nil {
+			failf("%s\n\n", err.Error())
+			return
+		}
+		if _, err := sagaSteps(baseDir + "clean/"); err != nil {
+			failf("%s\n\n", err.Error())
+			return
+		}
+		object := ""
+		if nArgs > 1 {
+			object = args[1]
+		}
+		if err := verifyLayoutMode(object); err != nil {
+			failf("%s\n\n", err.Error())
+			return
+		}
 		// User entered: clean add
 		if nArgs == 1 {
-			fmt.Printf(helpAddSyntax)
+			usageErrorf("add")
 		} else if nArgs == 2 {
 			// User entered: clean add [object]
 			switch args[1] {
 			case objInteractor:
 				// User entered: clean add interactor
-				fmt.Printf(helpAddInteractorSyntax)
+				usageErrorf("add interactor")
+			case objEntity:
+				// User entered: clean add entity
+				usageErrorf("add entity")
+			case objMethod:
+				// User entered: clean add method
+				usageErrorf("add method")
+			case objPresenter:
+				// User entered: clean add presenter
+				usageErrorf("add presenter")
+			case objView:
+				// User entered: clean add view
+				usageErrorf("add view")
+			case featureEvents:
+				// User entered: clean add events
+				usageErrorf("add events")
+			case objViewFactory:
+				// User entered: clean add viewfactory
+				usageErrorf("add viewfactory")
+			case objDecorator:
+				// User entered: clean add decorator
+				usageErrorf("add decorator")
+			case objUnitOfWork:
+				// User entered: clean add unitofwork
+				if err := addUnitOfWork(baseDir + "clean/"); err != nil {
+					failf("Error adding the unit of work: %s\n\n", err.Error())
+				}
+			case objClock:
+				// User entered: clean add clock
+				if err := addClock(baseDir + "clean/"); err != nil {
+					failf("Error adding the clock: %s\n\n", err.Error())
+				}
+			case objIDGen:
+				// User entered: clean add idgen
+				if err := addIDGen(baseDir + "clean/"); err != nil {
+					failf("Error adding the ID generator: %s\n\n", err.Error())
+				}
+			case objKernel:
+				// User entered: clean add kernel
+				if err := addKernel(baseDir + "clean/"); err != nil {
+					failf("Error adding the kernel: %s\n\n", err.Error())
+				}
+			case objErrors:
+				// User entered: clean add errors
+				if err := addErrorCatalog(baseDir + "clean/"); err != nil {
+					failf("Error adding the error catalog: %s\n\n", err.Error())
+				}
+			case objError:
+				// User entered: clean add error
+				usageErrorf("add error")
+			case objRoutes:
+				// User entered: clean add routes
+				if err := addRoutes(baseDir + "clean/"); err != nil {
+					failf("Error adding the routes: %s\n\n", err.Error())
+				}
+			case objGenericRepository:
+				// User entered: clean add generic-repository
+				if err := addGenericRepository(baseDir, baseDir+"clean/"); err != nil {
+					failf("Error adding the generic repository: %s\n\n", err.Error())
+				}
+			case objRepository:
+				// User entered: clean add repository
+				usageErrorf("add repository")
+			case objGateway:
+				// User entered: clean add gateway
+				usageErrorf("add gateway")
 			case objUsecase:
 				// User entered: clean add usecase
-				fmt.Printf(helpAddUsecaseSyntax)
+				usageErrorf("add usecase")
 			default:
 				// User entered: clean add jibberish
-				fmt.Printf("Invalid object entered.\n\nUse \"clean help add\" for more information about valid objects.\n\n")
+				objectUsageErrorf("add " + args[1])
 			}
 		} else if nArgs == 3 {
 			// User entered: clean add [object]
 			switch args[1] {
 			case objInteractor:
 				// User entered: clean add interactor [name]
-				ext := filepath.Ext(args[2])
-				interactor := string(args[2][:len(args[2])-len(ext)])
-				dir := baseDir + "clean/" + relPathController
-				addObjToProject(dir, objController, interactor, true)
-				dir = baseDir + "clean/" + relPathPresenter
-				addObjToProject(dir, objPresenter, interactor, true)
-				dir = baseDir + "clean/" + relPathView
-				addObjToProject(dir, objView, interactor, true)
-				dir = baseDir + "clean/" + relPathInteractor
-				addObjToProject(dir, objInteractor, interactor, true)
-				dir = baseDir + "clean/" + relPathValidator
-				addObjToProject(dir, objValidator, interactor, true)
+				if err := verifyUow(); err != nil {
+					failf("%s\n\n", err.Error())
+					return
+				}
+				if *withUow {
+					if err := addUnitOfWork(baseDir + "clean/"); err != nil {
+						failf("Error adding the unit of work: %s\n\n", err.Error())
+						return
+					}
+				}
+				specs := namedSpecsFromArg(objInteractor, args[2], false)
+				for _, spec := range specs {
+					addInteractorWithExtras(baseDir+"clean/", spec.Name)
+				}
+			case objEntity:
+				// User entered: clean add entity [name]
+				specs := namedSpecsFromArg(objEntity, args[2], true)
+				for _, spec := range specs {
+					addEntity(baseDir+"clean/", spec.Name, *desc)
+					if len(spec.Fields) > 0 {
+						fp := filepath.FromSlash(baseDir + "clean/" + relPathEntity + fileName(spec.Name) + ".go")
+						if err := addStructFields(fp, exportedName(spec.Name), spec.Fields); err != nil {
+							failf("Error adding the fields of %s: %s\n", spec.Name, err.Error())
+							continue
+						}
+						if err := importKernel(baseDir+"clean/", fp, spec.Fields); err != nil {
+							failf("Error importing the kernel into %s: %s\n", spec.Name, err.Error())
+							continue
+						}
+					}
+					if *withValidation {
+						if err := addEntityValidation(baseDir+"clean/", spec.Name); err != nil {
+							failf("Error adding the validation of %s: %s\n", spec.Name, err.Error())
+						}
+					}
+				}
+			case featureEvents:
+				// User entered: clean add events [interactor]
+				if err := checkName(args[2]); err != nil {
+					failf("%s\n\n", err.Error())
+					return
+				}
+				if err := addEvents(baseDir+"clean/", args[2]); err != nil {
+					failf("Error adding the events of %s: %s\n\n", args[2], err.Error())
+				}
+			case objViewFactory:
+				// User entered: clean add viewfactory [interactor]
+				if err := checkName(args[2]); err != nil {
+					failf("%s\n\n", err.Error())
+					return
+				}
+				if err := addViewFactory(baseDir+"clean/", args[2]); err != nil {
+					failf("Error adding the view factory of %s: %s\n\n", args[2], err.Error())
+				}
+			case objGateway:
+				// User entered: clean add gateway [name]
+				specs := namedSpecsFromArg(objGateway, args[2], false)
+				for _, spec := range specs {
+					addGateway(baseDir+"clean/", spec.Name, *desc, *gatewayInUsecase, *driver)
+				}
+			case objRepository:
+				// User entered: clean add repository [entity]
+				if err := checkName(args[2]); err != nil {
+					failf("%s\n\n", err.Error())
+					return
+				}
+				if err := addRepository(baseDir, baseDir+"clean/", args[2], *generic); err != nil {
+					failf("Error adding the repository of %s: %s\n\n", args[2], err.Error())
+				}
 			case objUsecase:
 				// User entered: clean add usecase [usecase]
-				fmt.Printf(helpAddUsecaseSyntax)
+				usageErrorf("add usecase")
+			case objError:
+				// User entered: clean add error Code=[name]
+				usageErrorf("add error")
 			default:
 				// User entered: clean add jibberish1 jibberish2
-				fmt.Printf("Invalid object entered.\n\nUse \"clean help add\" for more information about valid objects.\n\n")
+				objectUsageErrorf("add " + args[1])
 			}
 		} else if nArgs == 4 {
 			// User entered: clean add [object]
 			switch args[1] {
 			case objInteractor:
 				// User entered: clean add interactor jibberish1 jibberish2
-				fmt.Printf("Invalid number of arguments entered.\n\nUse \"clean help add interactor\" for more information.\n\n")
+				usageErrorf("add interactor")
 			case objUsecase:
 				// User entered: clean add usecase [usecase] to
-				fmt.Printf(helpAddUsecaseSyntax)
+				usageErrorf("add usecase")
+			case objPresenter, objView:
+				// User entered: clean add view to [interactor]
+				if strings.ToLower(args[2]) != "to" {
+					usageErrorf("add " + args[1])
+					return
+				}
+				if err := checkName(args[3]); err != nil {
+					failf("%s\n\n", err.Error())
+					return
+				}
+				if err := backfillLayer(baseDir+"clean/", args[1], args[3]); err != nil {
+					failf("Error adding the %s of %s: %s\n\n", args[1], exportedName(args[3]), err.Error())
+				}
+			case objError:
+				// User entered: clean add error Code=[name] [message]
+				if !strings.HasPrefix(strings.ToLower(args[2]), errCodeArgPrefix) || strings.TrimSpace(args[3]) == "" {
+					usageErrorf("add error")
+					return
+				}
+				name := args[2][len(errCodeArgPrefix):]
+				if err := checkName(name); err != nil {
+					failf("%s\n\n", err.Error())
+					return
+				}
+				if err := addCatalogError(baseDir+"clean/", exportedName(name), strings.TrimSpace(args[3])); err != nil {
+					failf("Error adding the error %s: %s\n\n", exportedName(name), err.Error())
+				}
 			default:
 				// User entered: clean add jibberish1 jibberish2 jibberish3
-				fmt.Printf("Invalid object entered.\n\nUse \"clean help add\" for more information about valid objects.\n\n")
+				objectUsageErrorf("add " + args[1])
 			}
 		} else if nArgs == 5 {
 			// User entered: clean add [object]
 			switch args[1] {
 			case objInteractor:
 				// User entered: clean add interactor jibberish1 jibberish2 jibberish3
-				fmt.Printf("Invalid number of arguments entered.\n\nUse \"clean help add interactor\" for more information.\n\n")
+				usageErrorf("add interactor")
+			case objDecorator:
+				// User entered: clean add decorator [kind] for [interactor]
+				if strings.ToLower(args[3]) != "for" {
+					usageErrorf("add decorator")
+					return
+				}
+				if err := checkName(args[4]); err != nil {
+					failf("%s\n\n", err.Error())
+					return
+				}
+				if err := addDecorator(baseDir, baseDir+"clean/", args[2], args[4]); err != nil {
+					failf("Error adding the %s decorator of %s: %s\n\n", args[2], args[4], err.Error())
+				}
+			case objPresenter:
+				// User entered: clean add presenter [name] to [interactor]
+				if strings.ToLower(args[3]) != "to" {
+					usageErrorf("add presenter")
+					return
+				}
+				if err := checkNames(args[2], args[4]); err != nil {
+					failf("%s\n\n", err.Error())
+					return
+				}
+				if err := addPresenterImpl(baseDir+"clean/", args[2], args[4]); err != nil {
+					failf("Error adding the presenter %s: %s\n\n", args[2], err.Error())
+				}
+			case objView:
+				// User entered: clean add view [name] to [interactor]
+				if strings.ToLower(args[3]) != "to" {
+					usageErrorf("add view")
+					return
+				}
+				if err := checkNames(args[2], args[4]); err != nil {
+					failf("%s\n\n", err.Error())
+					return
+				}
+				if err := addView(baseDir+"clean/", args[2], args[4]); err != nil {
+					failf("Error adding the view %s: %s\n\n", args[2], err.Error())
+				}
 			case objUsecase:
 				// User entered: clean add usecase [usecase] to [interactor]
 				if args[3] == "to" || args[3] == "To" || args[3] == "tO" || args[3] == "TO" {
-					// Remove .go file extension from Object argument
-					ext := filepath.Ext(args[4])
-					interactor := string(args[4][:len(args[4])-len(ext)])
-					for _, v := range relPaths {
-						addUsecaseToObject(baseDir+"clean/", v, args[2], interactor)
+					interactors, err := interactorsFromArg(baseDir+"clean/", args[4])
+					if err != nil {
+						failf("%s\n\nNothing was added\n", err.Error())
+						return
+					}
+					specs := namedSpecsFromArg(objUsecase, args[2], true)
+					if specs == nil {
+						return
+					}
+					if specs, err = withPayload(specs); err != nil {
+						failf("Error reading the request payload: %s\n\nNothing was added\n", err.Error())
+						return
+					}
+					for _, interactor := range interactors {
+						nChanged, nErrors := len(changedFiles), len(errorMessages)
+						for _, spec := range specs {
+							// Refuse near-duplicates of existing usecases unless confirmed
+							if !confirmCaseCollisions(baseDir+"clean/", spec.Name, interactor, args[2] == stdinName) {
+								continue
+							}
+							addUsecaseWithExtras(baseDir+"clean/", spec, interactor)
+							if *idempotentRegenerate {
+								if err := upgradeSignatures(baseDir+"clean/", spec.Name, interactor); err != nil {
+									failf("Error updating the signatures of %s: %s\n", exportedName(spec.Name), err.Error())
+								}
+							}
+						}
+						if len(interactors) > 1 && !*stdout && !*jsonOutput {
+							switch {
+							case len(errorMessages) > nErrors:
+								fmt.Printf("%s: failed\n", exportedName(interactor))
+							case len(changedFiles) > nChanged:
+								fmt.Printf("%s: added\n", exportedName(interactor))
+							default:
+								fmt.Printf("%s: nothing to do\n", exportedName(interactor))
+							}
+						}
 					}
 				} else {
 					// User entered: clean add usecase [usecase] jibberish [interactor]
-					fmt.Printf(helpAddUsecaseSyntax)
+					usageErrorf("add usecase")
 				}
 			default:
 				// User entered: clean add jibberish1 jibberish2 jibberish3 jibberish4
-				fmt.Printf("Invalid object entered.\n\nUse \"clean help add\" for more information about valid objects.\n\n")
+				objectUsageErrorf("add " + args[1])
+			}
+		} else if nArgs == 6 && args[1] == objMethod {
+			// User entered: clean add method [method] to [object] [interactor]
+			if strings.ToLower(args[3]) != "to" || objRelPaths[args[4]] == "" {
+				usageErrorf("add method")
+				return
+			}
+			if err := checkNames(args[2], args[5]); err != nil {
+				failf("%s\n\n", err.Error())
+				return
+			}
+			if err := addMethod(baseDir+"clean/", args[4], exportedName(args[2]), args[5]); err != nil {
+				failf("Error adding the method %s: %s\n\n", args[2], err.Error())
+			}
+		} else if nArgs == 7 && args[1] == objMapper {
+			// User entered: clean add mapper [entity] to [model] in [interactor]
+			if strings.ToLower(args[3]) != "to" || strings.ToLower(args[5]) != "in" {
+				usageErrorf("add mapper")
+				return
+			}
+			if err := checkNames(args[2], args[4], args[6]); err != nil {
+				failf("%s\n\n", err.Error())
+				return
+			}
+			if err := addMapper(baseDir+"clean/", args[2], args[4], args[6]); err != nil {
+				failf("Error adding the mapper of %s to %s: %s\n\n", args[2], args[4], err.Error())
+			}
+		} else {
+			usageErrorf("add")
+		}
+		return
+	case verbRemove:
+		// User entered: clean remove method [method] from [object] [interactor]
+		if nArgs != 6 || args[1] != objMethod || strings.ToLower(args[3]) != "from" || objRelPaths[args[4]] == "" {
+			usageErrorf("remove method")
+			return
+		}
+		if err := checkNames(args[2], args[5]); err != nil {
+			failf("%s\n\n", err.Error())
+			return
+		}
+		if err := verifyTypeSuffixes(baseDir + "clean/"); err != nil {
+			failf("%s\n\n", err.Error())
+			return
+		}
+		if err := removeMethod(baseDir+"clean/", args[4], exportedName(args[2]), args[5]); err != nil {
+			failf("Error removing the method %s: %s\n\n", args[2], err.Error())
+		}
+		return
+	default:
+		unknownCommandf(verb)
+	}
+
+}
+
+// addInteractor adds the controller, presenter, view, interactor and validator objects of the interactor name.
+// The description desc is added to the doc comments of the objects.
+// Only the objects selected by --only are added.
+func addInteractor(basePath, name, desc string) {
+	if flatLayout {
+		addFlatInteractor(basePath, name, desc)
+		return
+	}
+	write, ok := preflightInteractor(basePath, name)
+	if !ok {
+		return
+	}
+	for _, objType := range objTypes {
+		if !selected(objType) {
+			continue
+		}
+		objFp, testFp := interactorFiles(basePath, objType, name)
+		switch {
+		case write[objFp]:
+			addObjToProject(basePath+objRelPaths[objType], objType, name, desc, write[testFp])
+		case write[testFp]:
+			addTestStub(testFp)
+		}
+	}
+}
+
+// addInteractorWithExtras adds the interactor name together with its Gateway if --with-gateway or
+// --with-gateway-interface-in-usecase is set, the Clock if --with-clock is set, the ID Generator if
+// --with-idgen is set and the mockgen directives if --with-mocks is set
+func addInteractorWithExtras(basePath, name string) {
+	nErrors := len(errorMessages)
+	addInteractor(basePath, name, *desc)
+	if len(errorMessages) > nErrors {
+		return
+	}
+	if *withGateway || *gatewayInUsecase {
+		addGateway(basePath, name, *desc, *gatewayInUsecase, *driver)
+	}
+	if *withClock {
+		if err := injectClock(basePath, name); err != nil {
+			failf("Error injecting the clock into %s: %s\n\n", exportedName(name), err.Error())
+		}
+	}
+	if *withIDGen {
+		if err := injectIDGen(basePath, name); err != nil {
+			failf("Error injecting the ID generator into %s: %s\n\n", exportedName(name), err.Error())
+		}
+	}
+	if interactorMockEngine(basePath, name) == mockEngineGomock {
+		// The gomock engine always mocks the interfaces with the directives of their files
+		if err := addMockgenDirectives(basePath, name); err != nil {
+			failf("Error adding the mockgen directives: %s\n\n", err.Error())
+		}
+	} else if *withMocks {
+		if err := addMockDirectives(basePath, name); err != nil {
+			failf("Error adding the mockgen directives: %s\n\n", err.Error())
+		}
+	}
+	if *withIntegrationTest {
+		if err := addIntegrationTest(basePath, name); err != nil {
+			failf("Error adding the integration test of %s: %s\n\n", exportedName(name), err.Error())
+		}
+	}
+}
+
+// addEntity adds the Entity name to the entity folder. The description desc is added to its doc comment.
+func addEntity(basePath, name, desc string) {
+	fp := filepath.FromSlash(basePath + relPathEntity + fileName(name) + ".go")
+	var content string
+	if !fileExists(fp) {
+		content = packageClause(basePath+relPathEntity, objEntity) + "\n"
+	} else {
+		fileBytes, err := readFile(fp)
+		if err != nil {
+			failf("Error reading %s: %s\n", fp, err.Error())
+			return
+		}
+		if ix := bytes.Index(fileBytes, []byte(fmt.Sprintf("type %s struct {", exportedName(name)))); ix != -1 {
+			noopf("the entity %s already exists", exportedName(name))
+			return
+		}
+	}
+	description := "// TODO: Add a description."
+	if strings.TrimSpace(desc) != "" {
+		description = strings.TrimSuffix(commentBlock(desc, ""), "\n")
+	}
+	doc := docComment(description+"\n// A Clean Architecture Entity encapsulates enterprise wide business rules. It's the innermost layer and must not depend on any of the other layers.",
+		fmt.Sprintf("// %s is an Entity.", exportedName(name)))
+	if *terse && strings.TrimSpace(desc) != "" {
+		doc += "\n" + description
+	}
+	content = fmt.Sprintf("%s\n%s\ntype %s struct {\n\t// TODO: Add struct members\n}", content, doc, exportedName(name))
+	if err := writeBytesToFile(fp, content); err != nil {
+		failf("Error writing content to entity file: %s\n", err.Error())
+	}
+}
+
+// layerError is an error adding a usecase to the object or model of an interactor in one of the layers
+type layerError struct {
+	layer string
+	msg   string
+}
+
+// addUsecase adds the usecase to all of the objects and models of the interactor selected by --only. A layer
+// failing doesn't stop the usecase from being added to the other layers, and the layers which failed are
+// summarised after the rest have been processed. The errors of the layers are returned.
+func addUsecase(basePath, usecase, interactor string) []layerError {
+	if flatLayout {
+		addFlatUsecase(basePath, namedSpec{Name: usecase}, interactor)
+		return nil
+	}
+	var failed []layerError
+	var added []string
+	for _, v := range relPaths {
+		if !selected(dirNameFromRelPath(v)) {
+			continue
+		}
+		// Consult the policy of the layer before planning any changes to the existing file
+		fp := filepath.FromSlash(basePath + v + fileName(interactor) + ".go")
+		if fileExists(fp) && !createdFiles[fp] {
+			if ok, reason := policyAllows(fp, false); !ok {
+				policySkippedf(fp, "%s", reason)
+				continue
+			}
+		}
+		nChanged, nErrors := len(changedFiles), len(errorMessages)
+		addUsecaseToObject(basePath, v, usecase, interactor)
+		if v == relPathView {
+			// The other Views of the interactor render its usecases too, see addView
+			for _, view := range viewsOf(basePath, interactor) {
+				addUsecaseToObject(basePath, v, usecase, view)
+			}
+		}
+		if v == relPathPresenter {
+			// The other Presenters of the interactor implement the same interface, see addPresenterImpl
+			for _, presenter := range presentersOf(basePath, interactor) {
+				if err := syncPresenterImpl(basePath, presenter, interactor); err != nil {
+					failf("Error adding the usecase %s to the presenter %s: %s\n", exportedName(usecase), presenter, err.Error())
+				}
+			}
+		}
+		switch {
+		case len(errorMessages) > nErrors:
+			failed = append(failed, layerError{dirNameFromRelPath(v), strings.Join(errorMessages[nErrors:], "; ")})
+		case len(changedFiles) > nChanged:
+			added = append(added, dirNameFromRelPath(v))
+		}
+	}
+	if selected(featureEvents) && hasEvents(basePath, interactor) {
+		if err := addEventOfUsecase(basePath, usecase, interactor); err != nil {
+			failf("Error adding the event of %s: %s\n", usecase, err.Error())
+			failed = append(failed, layerError{featureEvents, err.Error()})
+		}
+	}
+	if len(failed) > 0 && !*jsonOutput {
+		var layers []string
+		for _, le := range failed {
+			layers = append(layers, le.layer)
+		}
+		fmt.Printf("The usecase %s of %s failed in the layers: %s\n", exportedName(usecase), exportedName(interactor), strings.Join(layers, ", "))
+		if len(added) > 0 {
+			fmt.Printf("It was added to the layers: %s\n", strings.Join(added, ", "))
+		}
+	}
+	return failed
+}
+
+// interactorsFromArg returns the interactors of the comma separated list arg e.g. Order,Customer. It returns
+// an error naming the interactors which don't exist in the project at basePath.
+func interactorsFromArg(basePath, arg string) ([]string, error) {
+	var interactors, missing []string
+	for _, v := range strings.Split(arg, ",") {
+		v = strings.TrimSpace(v)
+		// Remove .go file extension from Object argument
+		v = strings.TrimSuffix(v, filepath.Ext(v))
+		if v == "" {
+			continue
+		}
+		if err := checkName(v); err != nil {
+			return nil, err
+		}
+		if !fileExists(interactorFilePath(basePath, v)) {
+			missing = append(missing, v)
+			continue
+		}
+		interactors = append(interactors, v)
+	}
+	switch {
+	case len(missing) == 1:
+		return nil, fmt.Errorf("the interactor %s doesn't exist", missing[0])
+	case len(missing) > 1:
+		return nil, fmt.Errorf("the interactors %s don't exist", strings.Join(missing, ", "))
+	case len(interactors) == 0:
+		return nil, errors.New("no interactor entered")
+	}
+	return interactors, nil
+}
+
+// addUsecaseWithExtras adds the usecase spec to the interactor together with the fields of its
+// RequestModel, its tests unless --no-test is set, its benchmark if --with-benchmarks is set and, if --fuzz
+// is set, its fuzz target
+func addUsecaseWithExtras(basePath string, spec namedSpec, interactor string) {
+	if flatLayout {
+		addFlatUsecase(basePath, spec, interactor)
+		return
+	}
+	if verticalLayout {
+		addVerticalUsecase(basePath, spec, interactor)
+		return
+	}
+	addUsecase(basePath, spec.Name, interactor)
+	if len(spec.Fields)+len(spec.Types) > 0 && selected("reqmodel") {
+		fp := filepath.FromSlash(basePath + relPathReqModel + fileName(interactor) + ".go")
+		if other := typeDeclFile(filepath.FromSlash(basePath+relPathReqModel), exportedName(spec.Name), fp); other != "" {
+			fp = other
+		}
+		fields := spec.Fields
+		if *validatorStyle == validatorTags {
+			fields = tagValidatorFields(fields)
+		}
+		if err := addStructFields(fp, exportedName(spec.Name), fields); err != nil {
+			failf("Error adding the fields of %s: %s\n", spec.Name, err.Error())
+		}
+		if err := addStructDecls(fp, spec.Types); err != nil {
+			failf("Error adding the types of the fields of %s: %s\n", spec.Name, err.Error())
+		}
+		// The ErrVal reports each of the fields, see syncValidator
+		if len(spec.Fields) > 0 && selected(objValidator) && selected("respmodel") {
+			if err := syncValidator(basePath, spec.Name, interactor); err != nil {
+				failf("Error adding the checks of the fields of %s: %s\n", spec.Name, err.Error())
 			}
-		} else {
-			fmt.Printf("Invalid number of arguments entered.\n\nUse \"clean help add\" for more information.\n\n")
 		}
-		return
-	default:
-		//fmt.Printf("Invalid arguments supplied\n\n")
-		fmt.Printf(helpUsage)
 	}
+	if *saga != "" && selected("respmodel") {
+		if err := addSagaState(basePath, spec.Name, interactor); err != nil {
+			failf("Error adding the state of the saga of %s: %s\n", exportedName(spec.Name), err.Error())
+		}
+	}
+	if *auth && selected("respmodel") {
+		if err := addForbiddenField(basePath, spec.Name, interactor); err != nil {
+			failf("Error adding the %s field of %sErrVal: %s\n", forbiddenField, exportedName(spec.Name), err.Error())
+		}
+	}
+	if spec.Schema != nil && selected(objValidator) {
+		fp := filepath.FromSlash(basePath + relPathValidator + fileName(interactor) + ".go")
+		if err := spec.Schema.addRequiredChecks(fp, spec.Name); err != nil {
+			failf("Error adding the checks of the required fields of %s: %s\n", spec.Name, err.Error())
+		}
+	}
+	if *paginated {
+		if err := addPagination(basePath, spec.Name, interactor); err != nil {
+			failf("Error paginating %s: %s\n", spec.Name, err.Error())
+		}
+	}
+	if *bind == bindHTTP {
+		if err := addHTTPBinding(basePath, spec.Name, interactor); err != nil {
+			failf("Error binding the RequestModel of %s: %s\n", spec.Name, err.Error())
+		}
+	}
+	if !*noTest {
+		if err := addUsecaseTests(basePath, spec.Name, interactor); err != nil {
+			failf("Error adding the tests of %s: %s\n", spec.Name, err.Error())
+		}
+	}
+	if *withBenchmarks {
+		if err := addUsecaseBenchmark(basePath, spec.Name, interactor); err != nil {
+			failf("Error adding the benchmark of %s: %s\n", spec.Name, err.Error())
+		}
+	}
+	if *fuzz {
+		if err := addValidatorFuzzTarget(basePath, spec.Name, interactor); err != nil {
+			failf("Error adding the fuzz target: %s\n", err.Error())
+		}
+	}
+}
+
+// selected reports whether the object, model or feature name, e.g. presenter, viewmodel or events, is
+// selected by --only and not excluded by --skip. All of them are selected if neither is set.
+func selected(name string) bool {
+	for _, v := range strings.Split(*skip, ",") {
+		if strings.TrimSpace(v) == name {
+			return false
+		}
+	}
+	if *only == "" {
+		return true
+	}
+	for _, v := range strings.Split(*only, ",") {
+		if strings.TrimSpace(v) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyOnly returns an error if --only or --skip holds anything else than the names of objects, models
+// and features
+func verifyOnly() error {
+	valid := make(map[string]bool)
+	var names []string
+	for _, v := range relPaths {
+		names = append(names, dirNameFromRelPath(v))
+	}
+	names = append(names, featureEvents)
+	for _, v := range names {
+		valid[v] = true
+	}
+	// The flags are checked in a fixed order so that the same invalid objects are always reported the same way
+	for _, f := range [][2]string{{"only", *only}, {"skip", *skip}} {
+		flagName, list := f[0], f[1]
+		if list == "" {
+			continue
+		}
+		for _, v := range strings.Split(list, ",") {
+			if !valid[strings.TrimSpace(v)] {
+				return fmt.Errorf("invalid object %q in --%s, the objects are %s", strings.TrimSpace(v), flagName, strings.Join(names, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// buildImports returns the import paths of a new file of the object of the layer e.g. controller, whose
+// project packages are imported from importPath e.g. example.com/app/clean/. Only the packages the object
+// refers to before it has any usecases are imported, so that it compiles. See usecaseImports.
+func buildImports(layer, importPath string) []string {
+	pkg := func(relPath string) string {
+		return importPath + strings.TrimSuffix(relPath, "/")
+	}
+	switch layer {
+	case objController:
+		if verticalLayout {
+			// The controller gets the Usecase of each usecase package along with the usecase
+			return nil
+		}
+		return []string{"errors", pkg(relPathInteractor)}
+	case objPresenter:
+		return []string{"errors", pkg(relPathView)}
+	case objInteractor:
+		imports := []string{"errors", pkg(relPathPresenter), pkg(relPathValidator)}
+		if relPath := gatewayPortRelPath(); relPath != "" {
+			imports = append(imports, pkg(relPath))
+		}
+		return imports
+	}
+	return nil
+}
+
+// usecaseImports returns the import paths of the packages the methods of a usecase of the object of the layer
+// refer to, which are added to its imports along with the first usecase. See buildImports.
+func usecaseImports(layer, importPath, usecase, interactor string) []string {
+	pkg := func(relPath string) string {
+		return importPath + strings.TrimSuffix(relPath, "/")
+	}
+	switch layer {
+	case objPresenter:
+		// The ViewModels are only imported once a Presenter method maps to them, see --presenter-only-json
+		_, _, rsmImport := responseTypes(usecase, interactor)
+		return []string{rsmImport}
+	case objView:
+		return []string{pkg(relPathViewModel)}
+	case objInteractor:
+		return []string{pkg(relPathReqModel)}
+	case objValidator:
+		return []string{pkg(relPathReqModel), pkg(relPathRespModel)}
+	}
+	return nil
+}
 
+// importDecl returns the import declaration of the import paths, which follows a package clause
+func importDecl(paths []string) string {
+	var specs []string
+	for _, path := range paths {
+		specs = append(specs, strconv.Quote(path))
+	}
+	return "\n\nimport (\n" + importBlock(specs) + ")"
 }
 
-func addObjToProject(dir, objType, objName string, hasTestFolder bool) {
+func addObjToProject(dir, objType, objName, desc string, hasTestFolder bool) {
 	// TODO: Remove filename from function signature. The Filename should be the objName + .go
 	ext := filepath.Ext(objName)
 	var withoutExtFn string
 	if ext != ".go" {
 		ext = ".go"
-		withoutExtFn = objName
+		withoutExtFn = fileName(objName)
 	} else {
-		withoutExtFn = string(objName[:len(objName)-len(ext)])
+		objName = string(objName[:len(objName)-len(ext)])
+		withoutExtFn = fileName(objName)
 	}
 
 	fp := filepath.FromSlash(dir + withoutExtFn + ext)
+	if fileExists(fp) {
+		// The interface may be unexported, see --unexported-interface
+		if f, err := parseGoFile(fp); err == nil && (findTypeSpec(f, typeName(objType, objName)) != nil || findTypeSpec(f, unexportedName(typeName(objType, objName))) != nil) {
+			noopf("the interactor %s already exists", exportedName(objName))
+			return
+		}
+	}
 	if !fileExists(fp) {
-		c := fmt.Sprintf("// Package %s provides ... \npackage %s", objType, objType)
+		c := packageClause(dir, objType)
 		if err := writeBytesToFile(fp, c); err != nil {
 			return
 		}
-		switch objType {
-		case objController:
-			imports := "\n\nimport (\n\t\"%sclean/usecase/interactor\"\n\t\"%sclean/usecase/reqmodel\"\n)"
-			if err := writeBytesToFile(fp, fmt.Sprintf(imports, projectBaseImportPath, projectBaseImportPath)); err != nil {
-				return
-			}
-		case objPresenter:
-			imports := "\n\nimport (\n\t\"%sclean/ifadapter/view\"\n\t\"%sclean/ifadapter/view/viewmodel\"\n\t\"%sclean/usecase/respmodel\"\n)"
-			if err := writeBytesToFile(fp, fmt.Sprintf(imports, projectBaseImportPath, projectBaseImportPath, projectBaseImportPath)); err != nil {
-				return
-			}
-		case objView:
-			imports := "\n\nimport (\n\t\"%sclean/ifadapter/view/viewmodel\"\n)"
-			if err := writeBytesToFile(fp, fmt.Sprintf(imports, projectBaseImportPath)); err != nil {
-				return
-			}
-		case objInteractor:
-			imports := "\n\nimport (\n\t\"errors\"\n\t\"%sclean/ifadapter/presenter\"\n\t\"%sclean/usecase/reqmodel\"\n\t\"%sclean/usecase/reqmodel/validator\"\n\t\"%sclean/usecase/respmodel\"\n)"
-			if err := writeBytesToFile(fp, fmt.Sprintf(imports, projectBaseImportPath, projectBaseImportPath, projectBaseImportPath, projectBaseImportPath)); err != nil {
-				return
-			}
-		case objValidator:
-			imports := "\n\nimport (\n\t\"%sclean/usecase/reqmodel\"\n\t\"%sclean/usecase/respmodel\"\n)"
-			if err := writeBytesToFile(fp, fmt.Sprintf(imports, projectBaseImportPath, projectBaseImportPath)); err != nil {
+		if imports := buildImports(objType, projectBaseImportPath+"clean/"); len(imports) > 0 {
+			if err := writeBytesToFile(fp, importDecl(imports)); err != nil {
 				return
 			}
 		}
 
 	}
 
-	// Lower case first character
-	lcObjName := firstCharToLower(objName)
-	// Upper case first character
-	ucObjName := firstCharToUpper(objName)
+	// Names of the interface and of its implementation. Only the Controller interface may be unexported since
+	// the interfaces of the other objects are referred to by the objects of the layer outside of theirs.
+	ucObjName, lcObjName := objNames(objType, objName, *unexportedInterface && objType == objController)
 	// Upper case first character
 	ucObjType := firstCharToUpper(objType)
 
@@ -341,80 +1535,115 @@ func addObjToProject(dir, objType, objName string, hasTestFolder bool) {
 	switch objType {
 	case objController:
 		tmplData := struct {
-			UcObjName string
-			UcObjType string
-			LcObjName string
+			UcObjName      string
+			UcObjType      string
+			LcObjName      string
+			CtorName       string
+			Desc           string
+			InteractorName string
+			Vertical       bool
 		}{
-			UcObjName: ucObjName,
-			UcObjType: ucObjType,
-			LcObjName: lcObjName,
+			UcObjName:      ucObjName,
+			UcObjType:      ucObjType,
+			LcObjName:      lcObjName,
+			CtorName:       "New" + typeName(objType, objName),
+			Desc:           objDoc(ucObjName, ucObjType, desc),
+			InteractorName: typeName(objInteractor, objName),
+			Vertical:       verticalLayout,
 		}
 		txtTmpl := `
 
-// {{.UcObjName}} is a Clean Architecture {{.UcObjType}} object that wraps its related methods.
-// TODO: Add description of what the interface does
+{{.Desc}}
 type {{.UcObjName}} interface {
 	// TODO add interface methods by using Clean. For more info run "clean help add usecase"
 }
 
 // {{.LcObjName}} is an implementation of {{.UcObjName}}.
 type {{.LcObjName}} struct {
-	ia interactor.{{.UcObjName}}
+{{- if not .Vertical}}
+	ia interactor.{{.InteractorName}}
+{{- end}}
 	// TODO define struct fields
 }
-
-// New{{.UcObjName}} constructs a new {{.UcObjName}} and returns a nil error if successful. Otherwise it returns an error.
-func New{{.UcObjName}}(ia interactor.{{.UcObjName}}) ({{.UcObjName}}, error) {
+{{if .Vertical}}
+// {{.CtorName}} constructs a new {{.UcObjName}} holding the Usecase of each of its usecases and returns a nil error if successful. Otherwise it returns an error.
+func {{.CtorName}}() ({{.UcObjName}}, error) {
+	return &{{.LcObjName}} {
+	}, nil
+}
+{{- else}}
+// {{.CtorName}} constructs a new {{.UcObjName}} and returns a nil error if successful. Otherwise it returns an error.
+func {{.CtorName}}(ia interactor.{{.InteractorName}}) ({{.UcObjName}}, error) {
 	if ia == nil {
 		return nil, errors.New("Error constructing {{.UcObjName}}")
 	}
 	return &{{.LcObjName}} {
 		ia: ia,
 	}, nil
-}`
+}
+{{- end}}`
 		parsedTmpl := template.Must(template.New("new").Parse(txtTmpl))
 		var b bytes.Buffer
-		parsedTmpl.Execute(&b, tmplData)
+		render(&b, parsedTmpl, tmplData)
 		content = b.String()
 
 	case objInteractor:
 		tmplData := struct {
-			UcObjName string
-			UcObjType string
-			LcObjName string
+			UcObjName     string
+			UcObjType     string
+			LcObjName     string
+			Desc          string
+			PresenterName string
+			ValidatorName string
+			GatewayName   string
+			GatewayField  string
 		}{
-			UcObjName: ucObjName,
-			UcObjType: ucObjType,
-			LcObjName: lcObjName,
+			UcObjName:     ucObjName,
+			UcObjType:     ucObjType,
+			LcObjName:     lcObjName,
+			Desc:          objDoc(ucObjName, ucObjType, desc),
+			PresenterName: typeName(objPresenter, objName),
+			ValidatorName: typeName(objValidator, objName),
+		}
+		if gatewayPortRelPath() != "" {
+			tmplData.GatewayName, tmplData.GatewayField = typeName(objGateway, objName), "gw"
+			if *withUow {
+				tmplData.GatewayName, tmplData.GatewayField = uowName, uowField
+			}
 		}
 		txtTmpl := `
 
-// {{.UcObjName}} is a Clean Architecture {{.UcObjType}} object that wraps its related methods.
-// TODO: Add description of what the interface does
+{{.Desc}}
 type {{.UcObjName}} interface {
 	// TODO add interface methods by using Clean. For more info run "clean help add usecase"
 }
 
 // {{.LcObjName}} is an implementation of {{.UcObjName}}.
 type {{.LcObjName}} struct {
-	ps presenter.{{.UcObjName}}
-	val validator.{{.UcObjName}}
+	ps presenter.{{.PresenterName}}
+	val validator.{{.ValidatorName}}
+{{- if .GatewayName}}
+	{{.GatewayField}} gateway.{{.GatewayName}}
+{{- end}}
 	// TODO define struct fields
 }
 
 // New{{.UcObjName}} constructs a new {{.UcObjName}} and returns a nil error if successful. Otherwise it returns an error.
-func New{{.UcObjName}}(ps presenter.{{.UcObjName}}, val validator.{{.UcObjName}}) ({{.UcObjName}}, error) {
-	if ps == nil || val == nil {
+func New{{.UcObjName}}(ps presenter.{{.PresenterName}}, val validator.{{.ValidatorName}}{{if .GatewayName}}, {{.GatewayField}} gateway.{{.GatewayName}}{{end}}) ({{.UcObjName}}, error) {
+	if ps == nil || val == nil{{if .GatewayName}} || {{.GatewayField}} == nil{{end}} {
 		return nil, errors.New("Error constructing {{.UcObjName}}")
 	}
 	return &{{.LcObjName}} {
 		ps: ps,
 		val: val,
+{{- if .GatewayName}}
+		{{.GatewayField}}: {{.GatewayField}},
+{{- end}}
 	}, nil
 }`
 		parsedTmpl := template.Must(template.New("new").Parse(txtTmpl))
 		var b bytes.Buffer
-		parsedTmpl.Execute(&b, tmplData)
+		render(&b, parsedTmpl, tmplData)
 		content = b.String()
 
 	case objPresenter:
@@ -422,27 +1651,30 @@ func New{{.UcObjName}}(ps presenter.{{.UcObjName}}, val validator.{{.UcObjName}}
 			UcObjName string
 			UcObjType string
 			LcObjName string
+			Desc      string
+			ViewName  string
 		}{
 			UcObjName: ucObjName,
 			UcObjType: ucObjType,
 			LcObjName: lcObjName,
+			Desc:      objDoc(ucObjName, ucObjType, desc),
+			ViewName:  typeName(objView, objName),
 		}
 		txtTmpl := `
 
-// {{.UcObjName}} is a Clean Architecture {{.UcObjType}} object that wraps its related methods.
-// TODO: Add description of what the interface does
+{{.Desc}}
 type {{.UcObjName}} interface {
 	// TODO add interface methods by using Clean. For more info run "clean help add usecase"
 }
 
 // {{.LcObjName}} is an implementation of {{.UcObjName}}.
 type {{.LcObjName}} struct {
-	vw	view.{{.UcObjName}}
+	vw	view.{{.ViewName}}
 	// TODO define struct fields
 }
 
 // New{{.UcObjName}} constructs a new {{.UcObjName}} and returns a nil error if successful. Otherwise it returns an error.
-func New{{.UcObjName}}(vw view.{{.UcObjName}}) ({{.UcObjName}}, error) {
+func New{{.UcObjName}}(vw view.{{.ViewName}}) ({{.UcObjName}}, error) {
 	if vw == nil {
 		return nil, errors.New("Error constructing {{.UcObjName}}")
 	}
@@ -452,7 +1684,7 @@ func New{{.UcObjName}}(vw view.{{.UcObjName}}) ({{.UcObjName}}, error) {
 }`
 		parsedTmpl := template.Must(template.New("new").Parse(txtTmpl))
 		var b bytes.Buffer
-		parsedTmpl.Execute(&b, tmplData)
+		render(&b, parsedTmpl, tmplData)
 		content = b.String()
 
 	default:
@@ -460,15 +1692,16 @@ func New{{.UcObjName}}(vw view.{{.UcObjName}}) ({{.UcObjName}}, error) {
 			UcObjName string
 			UcObjType string
 			LcObjName string
+			Desc      string
 		}{
 			UcObjName: ucObjName,
 			UcObjType: ucObjType,
 			LcObjName: lcObjName,
+			Desc:      objDoc(ucObjName, ucObjType, desc),
 		}
 		txtTmpl := `
 
-// {{.UcObjName}} is a Clean Architecture {{.UcObjType}} object that wraps its related methods.
-// TODO: Add description of what the interface does
+{{.Desc}}
 type {{.UcObjName}} interface {
 	// TODO add interface methods by using Clean. For more info run "clean help add usecase"
 }
@@ -484,7 +1717,7 @@ func New{{.UcObjName}}() {{.UcObjName}} {
 }`
 		parsedTmpl := template.Must(template.New("new").Parse(txtTmpl))
 		var b bytes.Buffer
-		parsedTmpl.Execute(&b, tmplData)
+		render(&b, parsedTmpl, tmplData)
 		content = b.String()
 	}
 	if err := writeBytesToFile(fp, content); err != nil {
@@ -495,17 +1728,16 @@ func New{{.UcObjName}}() {{.UcObjName}} {
 		return
 	}
 
-	testFp := filepath.FromSlash(dir + "test/" + withoutExtFn + "_test" + ext)
-	if !fileExists(testFp) {
-		c := "// Package test provides ...\npackage test\n\n"
-		if err := writeBytesToFile(testFp, c); err != nil {
-			return
-		}
-		if err := writeBytesToFile(testFp, "// TODO: Add tests"); err != nil {
-			return
-		}
-	}
+	addTestStub(filepath.FromSlash(dir + "test/" + withoutExtFn + "_test" + ext))
+}
 
+// addTestStub adds the test file testFp of an object with a TODO to add the tests unless it exists
+func addTestStub(testFp string) {
+	if fileExists(testFp) {
+		return
+	}
+	c := "// Package test provides ...\npackage test\n\n// TODO: Add tests\n"
+	writeBytesToFile(testFp, c)
 }
 
 // addUsecaseToObject does multiple things.
@@ -517,170 +1749,360 @@ func New{{.UcObjName}}() {{.UcObjName}} {
 //  + Adds a method by name usecaseName to Interactor interface and implementation
 //  + Adds a method by name usecaseName to Request Model Validator interface and implementation
 func addUsecaseToObject(basePath, relPath, usecaseName, objectName string) {
-	fp := filepath.FromSlash(basePath + relPath + firstCharToLower(objectName) + ".go")
+	fp := filepath.FromSlash(basePath + relPath + fileName(objectName) + ".go")
 	// Check if Object file exists
-	fileExists := false
-	if _, err := os.Stat(fp); err == nil {
-		fileExists = true
-	}
+	exists := fileExists(fp)
 	parentDirName := dirNameFromRelPath(relPath)
 
 	if relPath == relPathReqModel || relPath == relPathRespModel || relPath == relPathViewModel {
 		// Check if Object file exists, otherwise return
-		if _, err := os.Stat(filepath.FromSlash(basePath + relPathPresenter + firstCharToLower(objectName) + ".go")); os.IsNotExist(err) {
+		if !fileExists(filepath.FromSlash(basePath + relPathPresenter + fileName(objectName) + ".go")) {
 			return
 		}
 
 		var contentTmpl string
-		if !fileExists {
-			contentTmpl = fmt.Sprintf("// Package %s provides ...\npackage %s\n", parentDirName, parentDirName)
+		if !exists {
+			contentTmpl = packageClause(basePath+relPath, parentDirName) + "\n"
 		} else {
 			// Check if struct already exists and return if true
-			fileBytes, err := ioutil.ReadFile(fp)
+			fileBytes, err := readFile(fp)
 			if err != nil {
-				fmt.Printf("Error reading %s: %s\n", fp, err.Error())
+				failf("Error reading %s: %s\n", fp, err.Error())
 				return
 			}
-			if ix := bytes.Index(fileBytes, []byte(fmt.Sprintf("type %s struct {", firstCharToUpper(usecaseName)))); ix != -1 {
-				fmt.Printf("Object already exists\n")
+			if ix := bytes.Index(fileBytes, []byte(fmt.Sprintf("type %s struct {", exportedName(usecaseName)))); ix != -1 {
+				noopf("the usecase %s already exists in %s", exportedName(usecaseName), exportedName(objectName))
 				return
 			}
 		}
+		// The models of all interactors share a package, so a usecase of several interactors e.g. Audit
+		// reuses the models declared by the first of them
+		if other := typeDeclFile(filepath.FromSlash(basePath+relPath), exportedName(usecaseName), fp); other != "" {
+			noopf("the %s %s is shared with %s", parentDirName, exportedName(usecaseName), filepath.Base(other))
+			return
+		}
+		v := exportedName(usecaseName)
 		switch relPath {
 		case relPathReqModel:
-			contentTmpl = fmt.Sprintf("%s\n// TODO: Add a description.\n// A Clean Architecture RequestModel is a specific usecase's input. More specifically it's the only input argument for the Interactor method which constitutes the usecase.\ntype %s struct {\n\t// TODO: Add struct members\n}", contentTmpl, firstCharToUpper(usecaseName))
+			doc := docComment("// TODO: Add a description.\n// A Clean Architecture RequestModel is a specific usecase's input. More specifically it's the only input argument for the Interactor method which constitutes the usecase.",
+				fmt.Sprintf("// %s is the input of the usecase %s.", v, v))
+			contentTmpl = fmt.Sprintf("%s\n%s\ntype %s struct {\n\t// TODO: Add struct members\n}", contentTmpl, doc, v)
 
 		case relPathRespModel:
-			contentTmpl = fmt.Sprintf("%s\n// TODO: Add a description.\n// A Clean Architecture ResponseModel is a usecase's specific output. It's used as input to a Presenter method and normally there are more than one ResponseModel corresponding to the same usecase. During the call to the Interactor method all kinds of errors might arise. RequestModel validation errors, authorisation errors and database errors are examples of such outcomes which will all probably require their own ResponseModel.\ntype %s struct {\n\t// TODO: Add struct members\n}\n\n// TODO: Add a description\ntype %sErrVal struct {\n\t// TODO: Add struct members\n}", contentTmpl, firstCharToUpper(usecaseName), firstCharToUpper(usecaseName))
+			doc := docComment("// TODO: Add a description.\n// A Clean Architecture ResponseModel is a usecase's specific output. It's used as input to a Presenter method and normally there are more than one ResponseModel corresponding to the same usecase. During the call to the Interactor method all kinds of errors might arise. RequestModel validation errors, authorisation errors and database errors are examples of such outcomes which will all probably require their own ResponseModel.",
+				fmt.Sprintf("// %s is the output of the usecase %s.", v, v))
+			errValDoc := docComment("// TODO: Add a description", fmt.Sprintf("// %sErrVal is the output of the usecase %s if its input is invalid.", v, v))
+			contentTmpl = fmt.Sprintf("%s\n%s\ntype %s struct {\n\t// TODO: Add struct members\n}\n\n%s\ntype %sErrVal struct {\n\t// TODO: Add struct members\n}", contentTmpl, doc, v, errValDoc, v)
 
 		case relPathViewModel:
-			contentTmpl = fmt.Sprintf("%s\n// TODO: Add a description.\n// A Clean Architecture ViewModel is a Presenter's output. It's used as input to a View method and normally there are more than one ViewModel corresponding to the same usecase to accommodate all outcomes such as validation errors, authorisation errors and database errors in addition to the expected usecase outcome.\ntype %s struct {\n\t// TODO: Add struct members\n}\n\n// TODO: Add a description\ntype %sErrVal struct {\n\t// TODO: Add struct members\n}", contentTmpl, firstCharToUpper(usecaseName), firstCharToUpper(usecaseName))
+			doc := docComment("// TODO: Add a description.\n// A Clean Architecture ViewModel is a Presenter's output. It's used as input to a View method and normally there are more than one ViewModel corresponding to the same usecase to accommodate all outcomes such as validation errors, authorisation errors and database errors in addition to the expected usecase outcome.",
+				fmt.Sprintf("// %s is the data the View renders the outcome of the usecase %s from.", v, v))
+			errValDoc := docComment("// TODO: Add a description", fmt.Sprintf("// %sErrVal is the data the View renders the invalid input of the usecase %s from.", v, v))
+			contentTmpl = fmt.Sprintf("%s\n%s\ntype %s struct {\n\t// TODO: Add struct members\n}\n\n%s\ntype %sErrVal struct {\n\t// TODO: Add struct members\n}", contentTmpl, doc, v, errValDoc, v)
 		}
 		if err := writeBytesToFile(fp, contentTmpl); err != nil {
-			fmt.Printf("Error writing content to reqmodel file: %s\n", err.Error())
+			failf("Error writing content to reqmodel file: %s\n", err.Error())
 		}
 		return
 	}
 
-	if !fileExists {
-		fmt.Printf("Error cannot find the Object file: %s\n\n", fp)
+	if !exists {
+		failf("Error cannot find the Object file: %s\n\n", fp)
 		return
 	}
 
 	//fmt.Printf("\n\nProcessing %s\n", fp)
-	fileBytes, err := ioutil.ReadFile(fp)
+	fileBytes, err := readFile(fp)
 	if err != nil {
-		fmt.Printf("Error reading %s: %s\n", fp, err.Error())
+		failf("Error reading %s: %s\n", fp, err.Error())
 		return
 	}
 
-	ucObjName := firstCharToUpper(objectName)
+	// Names of the interface, its implementation and the receiver of the implementation's methods
+	ucObjName, lcObjName := fileObjNames(fileBytes, parentDirName, objectName)
+	self := fileReceiverName(fileBytes, parentDirName, ucObjName)
 	var newFileBytes []byte
 	switch relPath {
 	case relPathController:
-		v := firstCharToUpper(usecaseName)
+		v := exportedName(usecaseName)
 		// Skip to next fi in the loop in case method already exists
 		if ix := bytes.Index(fileBytes, []byte(fmt.Sprintf("%s(", v))); ix != -1 {
+			noopf("the usecase %s already exists in %s", v, exportedName(objectName))
 			return
 		}
 
-		methodSignature := fmt.Sprintf("\t// %s converts the usecase input from its implementation specific format to a Clean Architecture RequestModel, which it then calls the Interactor with.\n\t// TODO: Add description\n\t%s()\n", v, v)
+		methodSignature := docComment(fmt.Sprintf("\t// %s converts the usecase input from its implementation specific format to a Clean Architecture RequestModel, which it then calls the Interactor with.\n\t// TODO: Add description\n", v),
+			fmt.Sprintf("\t// %s handles the input of the usecase %s.\n", v, v)) + fmt.Sprintf("\t%s()\n", v)
+		params := ""
+		if *bind == bindHTTP {
+			// The body binding the RequestModel is added once its fields are, see addHTTPBinding
+			params = "r *http.Request"
+			methodSignature = strings.Replace(methodSignature, v+"()", v+"("+params+")", 1)
+			if fileBytes, err = ensureImport(fileBytes, "net/http"); err != nil {
+				failf("Error adding the import of net/http: %s\n", err.Error())
+				return
+			}
+		}
 		newFileBytes, err = addMethodSignatureToInterface(fileBytes, fp, methodSignature, ucObjName)
 		if err != nil {
-			fmt.Printf("Error in addMethodSignatureToInterface: %s\n", err.Error())
+			failf("Error in addMethodSignatureToInterface: %s\n", err.Error())
 			return
 		}
-		method := fmt.Sprintf("\n\n// %s implements the %s interface method %s.\nfunc (%s *%s) %s() {\n\t// TODO: Implement interface method\n}", v, firstCharToUpper(objectName), v, firstCharInWord(firstCharToLower(objectName)), firstCharToLower(objectName), v)
-		newFileBytes, err = addMethodToImpl(newFileBytes, method, objectName)
+		body := "\t// TODO: Implement interface method\n"
+		if verticalLayout {
+			// The controller holds the Usecase of the usecase package, see addVerticalUsecase
+			pkg, field := usecasePackage(v), unexportedName(v)
+			body = fmt.Sprintf("\t// TODO: Convert the input to the Request of the usecase\n\t%s.%s.Execute(&%s.Request{})\n", self, field, pkg)
+			newFileBytes, err = injectField(newFileBytes, fp, lcObjName, "New"+typeName(objController, objectName), field, "*"+pkg+".Usecase", layerImportPath(usecasePackageRelPath(objectName, v)))
+			if err != nil {
+				failf("Error injecting the usecase %s into the controller: %s\n", v, err.Error())
+				return
+			}
+		}
+		method := fmt.Sprintf("\n\n// %s implements the %s interface method %s.\nfunc (%s *%s) %s(%s) {\n%s}", v, ucObjName, v, self, lcObjName, v, params, body)
+		newFileBytes, err = addMethodToImpl(newFileBytes, method, lcObjName)
 		if err != nil {
-			fmt.Printf("Error in addMethodToImpl: %s\n", err.Error())
+			failf("Error in addMethodToImpl: %s\n", err.Error())
 			return
 		}
 	case relPathPresenter:
-		v := firstCharToUpper(usecaseName)
+		v := exportedName(usecaseName)
 		// Skip to next fi in the loop in case method already exists
 		if ix := bytes.Index(fileBytes, []byte(fmt.Sprintf("Present%s(", v))); ix != -1 {
+			noopf("the usecase %s already exists in %s", v, exportedName(objectName))
 			return
 		}
 
-		methodSignature := fmt.Sprintf("\t// Present%s converts the usecase output i.e. a ResponseModel to a Clean Architecture ViewModel, which it then calls the View with.\n\t// TODO: Add description\n\tPresent%s(rsm *respmodel.%s)\n\t// Present%sErrVal converts the validation failure ResponseModel to a corresponding ViewModel.\n\t// TODO: Add description\n\tPresent%sErrVal(rsm *respmodel.%sErrVal)\n", v, v, v, v, v, v)
+		// The ResponseModels are those of the respmodel folder or of the usecase package, see responseTypes
+		rsmType, errValType, _ := responseTypes(v, objectName)
+		methodSignature := docComment(fmt.Sprintf("\t// Present%s converts the usecase output i.e. a ResponseModel to a Clean Architecture ViewModel, which it then calls the View with.\n\t// TODO: Add description\n", v),
+			fmt.Sprintf("\t// Present%s presents the output of the usecase %s.\n", v, v)) + fmt.Sprintf("\tPresent%s(rsm *%s)\n", v, rsmType) +
+			docComment(fmt.Sprintf("\t// Present%sErrVal converts the validation failure ResponseModel to a corresponding ViewModel.\n\t// TODO: Add description\n", v),
+				fmt.Sprintf("\t// Present%sErrVal presents the output of the usecase %s if its input is invalid.\n", v, v)) + fmt.Sprintf("\tPresent%sErrVal(rsm *%s)\n", v, errValType)
 		newFileBytes, err = addMethodSignatureToInterface(fileBytes, fp, methodSignature, ucObjName)
 		if err != nil {
-			fmt.Printf("Error in addMethodSignatureToInterface: %s\n", err.Error())
+			failf("Error in addMethodSignatureToInterface: %s\n", err.Error())
 			return
 		}
-		method := fmt.Sprintf("\n\n// Present%s implements the %s interface method Present%s.\nfunc (%s *%s) Present%s(rsm *respmodel.%s) {\n\t// TODO: Implement interface method\n}\n// Present%sErrVal implements the %s interface method Present%sErrVal.\nfunc (%s *%s) Present%sErrVal(rsm *respmodel.%sErrVal) {\n\t// TODO: Implement interface method\n}", v, firstCharToUpper(objectName), v, firstCharInWord(firstCharToLower(objectName)), firstCharToLower(objectName), v, v, v, firstCharToUpper(objectName), v, firstCharInWord(firstCharToLower(objectName)), firstCharToLower(objectName), v, v)
-		newFileBytes, err = addMethodToImpl(newFileBytes, method, objectName)
+		body := "\t// TODO: Implement interface method\n"
+		errValBody := body
+		if *presenterMapping {
+			body, err = presenterMappingBodyForUsecase(basePath, self, v, objectName)
+			if err != nil {
+				failf("Error generating the mapping of %s: %s\n", v, err.Error())
+				return
+			}
+			errValBody, err = presenterMappingBodyForUsecase(basePath, self, v+"ErrVal", objectName)
+			if err != nil {
+				failf("Error generating the mapping of %sErrVal: %s\n", v, err.Error())
+				return
+			}
+		}
+		method := fmt.Sprintf("\n\n// Present%s implements the %s interface method Present%s.\nfunc (%s *%s) Present%s(rsm *%s) {\n%s}\n\n// Present%sErrVal implements the %s interface method Present%sErrVal.\nfunc (%s *%s) Present%sErrVal(rsm *%s) {\n%s}", v, ucObjName, v, self, lcObjName, v, rsmType, body, v, ucObjName, v, self, lcObjName, v, errValType, errValBody)
+		newFileBytes, err = addMethodToImpl(newFileBytes, method, lcObjName)
 		if err != nil {
-			fmt.Printf("Error in addMethodToImpl: %s\n", err.Error())
+			failf("Error in addMethodToImpl: %s\n", err.Error())
 			return
 		}
+		if *presenterMapping {
+			if newFileBytes, err = ensureImport(newFileBytes, projectBaseImportPath+"clean/"+strings.TrimSuffix(relPathViewModel, "/")); err != nil {
+				failf("Error adding the viewmodel import: %s\n", err.Error())
+				return
+			}
+		}
 	case relPathView:
-		v := firstCharToUpper(usecaseName)
+		v := exportedName(usecaseName)
 		// Skip to next fi in the loop in case method already exists
 		if ix := bytes.Index(fileBytes, []byte(fmt.Sprintf("Render%s(", v))); ix != -1 {
+			noopf("the usecase %s already exists in %s", v, exportedName(objectName))
 			return
 		}
 
-		methodSignature := fmt.Sprintf("\t// Render%s renders the View in an application specific format. It builds the View exclusively from the ViewModel.\n\t// TODO: Add description\n\tRender%s(vm *viewmodel.%s)\n\t// Render%sErrVal renders the validation failure View in an application specific format. It builds the View exclusively from the ViewModel.\n\t// TODO: Add description\n\tRender%sErrVal(vm *viewmodel.%sErrVal)\n", v, v, v, v, v, v)
+		methodSignature := docComment(fmt.Sprintf("\t// Render%s renders the View in an application specific format. It builds the View exclusively from the ViewModel.\n\t// TODO: Add description\n", v),
+			fmt.Sprintf("\t// Render%s renders the outcome of the usecase %s.\n", v, v)) + fmt.Sprintf("\tRender%s(vm *viewmodel.%s)\n", v, v) +
+			docComment(fmt.Sprintf("\t// Render%sErrVal renders the validation failure View in an application specific format. It builds the View exclusively from the ViewModel.\n\t// TODO: Add description\n", v),
+				fmt.Sprintf("\t// Render%sErrVal renders the invalid input of the usecase %s.\n", v, v)) + fmt.Sprintf("\tRender%sErrVal(vm *viewmodel.%sErrVal)\n", v, v)
 		newFileBytes, err = addMethodSignatureToInterface(fileBytes, fp, methodSignature, ucObjName)
 		if err != nil {
-			fmt.Printf("Error in addMethodSignatureToInterface: %s\n", err.Error())
+			failf("Error in addMethodSignatureToInterface: %s\n", err.Error())
 			return
 		}
-		method := fmt.Sprintf("\n\n// Render%s implements the %s interface method Render%s.\nfunc (%s *%s) Render%s(vm *viewmodel.%s) {\n\t// TODO: Implement interface method\n}\n\n// Render%sErrVal implements the %s interface method Render%sErrVal.\nfunc (%s *%s) Render%sErrVal(vm *viewmodel.%sErrVal) {\n\t// TODO: Implement interface method\n}", v, firstCharToUpper(objectName), v, firstCharInWord(firstCharToLower(objectName)), firstCharToLower(objectName), v, v, v, firstCharToUpper(objectName), v, firstCharInWord(firstCharToLower(objectName)), firstCharToLower(objectName), v, v)
-		newFileBytes, err = addMethodToImpl(newFileBytes, method, objectName)
+		method := fmt.Sprintf("\n\n// Render%s implements the %s interface method Render%s.\nfunc (%s *%s) Render%s(vm *viewmodel.%s) {\n\t// TODO: Implement interface method\n}\n\n// Render%sErrVal implements the %s interface method Render%sErrVal.\nfunc (%s *%s) Render%sErrVal(vm *viewmodel.%sErrVal) {\n\t// TODO: Implement interface method\n}", v, ucObjName, v, self, lcObjName, v, v, v, ucObjName, v, self, lcObjName, v, v)
+		newFileBytes, err = addMethodToImpl(newFileBytes, method, lcObjName)
 		if err != nil {
-			fmt.Printf("Error in addMethodToImpl: %s\n", err.Error())
+			failf("Error in addMethodToImpl: %s\n", err.Error())
 			return
 		}
 	case relPathInteractor:
-		v := firstCharToUpper(usecaseName)
+		v := exportedName(usecaseName)
 		// Skip to next fi in the loop in case method already exists
 		if ix := bytes.Index(fileBytes, []byte(fmt.Sprintf("%s(", v))); ix != -1 {
+			noopf("the usecase %s already exists in %s", v, exportedName(objectName))
 			return
 		}
 
-		methodSignature := fmt.Sprintf("\t// %s is a Clean Architecture Interactor method whose input is a RequestModel. It orchestrates all of the steps that together constitute the usecase such as RequestModel validation, calling of Gateways for manipulation of databases and more. Given the outcome of the steps taken, it assembles a specific ResponseModel and calls a specific Presenter method with the ResponseModel as input.\n\t// TODO: Add description.\n\t%s(rqm *reqmodel.%s)\n", v, v, v)
+		// With explicit ErrVals the method returns the Validator's result so that the
+		// failed validation branch is visible to, and type-checked for, its callers
+		results, errValReturn, okReturn := "", "return", ""
+		if *explicitErrVal {
+			results, errValReturn, okReturn = fmt.Sprintf(" *respmodel.%sErrVal", v), "return rsm", "\treturn nil\n"
+			for _, path := range []string{layerImportPath(relPathValidator), layerImportPath(relPathRespModel)} {
+				if fileBytes, err = ensureImport(fileBytes, path); err != nil {
+					failf("Error adding the import %s: %s\n", path, err.Error())
+					return
+				}
+			}
+		}
+		methodSignature := docComment(fmt.Sprintf("\t// %s is a Clean Architecture Interactor method whose input is a RequestModel. It orchestrates all of the steps that together constitute the usecase such as RequestModel validation, calling of Gateways for manipulation of databases and more. Given the outcome of the steps taken, it assembles a specific ResponseModel and calls a specific Presenter method with the ResponseModel as input.\n\t// TODO: Add description.\n", v),
+			fmt.Sprintf("\t// %s runs the usecase %s.\n", v, v)) + fmt.Sprintf("\t%s(rqm *reqmodel.%s)%s\n", v, v, results)
 		newFileBytes, err = addMethodSignatureToInterface(fileBytes, fp, methodSignature, ucObjName)
 		if err != nil {
-			fmt.Printf("Error in addMethodSignatureToInterface: %s\n", err.Error())
+			failf("Error in addMethodSignatureToInterface: %s\n", err.Error())
+			return
+		}
+		var prelude string
+		if *metrics {
+			prelude = metricsStub(self, v)
+		}
+		if *timeout > 0 {
+			prelude += timeoutPrelude(objectName)
+		}
+		if *auth {
+			ret := "return"
+			if *explicitErrVal {
+				ret = "return rsm"
+			}
+			prelude += authStub(self, objectName, v, ret)
+			if newFileBytes, err = ensureImport(newFileBytes, layerImportPath(relPathRespModel)); err != nil {
+				failf("Error adding the import of the respmodel: %s\n", err.Error())
+				return
+			}
+		}
+		impl := "\t// TODO: Implement interface method\n"
+		steps, err := sagaSteps(basePath)
+		if err != nil {
+			failf("%s\n", err.Error())
 			return
 		}
-		self := firstCharInWord(firstCharToLower(objectName))
-		method := fmt.Sprintf("\n\n// %s implements the %s interface method %s.\nfunc (%s *%s) %s(rqm *reqmodel.%s) {\n\t// Validate Request Model\n\tif rsm := %s.val.Validate%s(rqm); rsm != nil {\n\t\t%s.ps.Present%sErrVal(rsm)\n\t\treturn\n\t}\n\n\t// TODO: Implement interface method\n}", v, firstCharToUpper(objectName), v, self, firstCharToLower(objectName), v, v, self, v, self, v)
-		newFileBytes, err = addMethodToImpl(newFileBytes, method, objectName)
+		if len(steps) > 0 {
+			ret := "return"
+			if *explicitErrVal {
+				ret = "return rsm"
+			}
+			impl = sagaBody(self, v, steps, ret)
+			if newFileBytes, err = ensureImport(newFileBytes, layerImportPath(relPathRespModel)); err != nil {
+				failf("Error adding the import of the respmodel: %s\n", err.Error())
+				return
+			}
+			if newFileBytes, err = injectSagaGateways(newFileBytes, fp, objectName, steps); err != nil {
+				failf("Error injecting the Gateways of the saga into %s: %s\n", exportedName(objectName), err.Error())
+				return
+			}
+		}
+		var notification string
+		if *notify {
+			ret := "return"
+			if *explicitErrVal {
+				ret = "return nil"
+			}
+			notification = notifyStub(self, objectName, v, ret)
+		}
+		if isCreateUsecase(v) && bytes.Contains(fileBytes, []byte(objIDGen+".Generator")) {
+			notification = idGenExample(self) + notification
+		}
+		method := fmt.Sprintf("\n\n// %s implements the %s interface method %s.\nfunc (%s *%s) %s(rqm *reqmodel.%s)%s {\n%s\t// Validate Request Model\n\tif rsm := %s.val.Validate%s(rqm); rsm != nil {\n\t\t%s.ps.Present%sErrVal(rsm)\n\t\t%s\n\t}\n\n%s%s%s}", v, ucObjName, v, self, lcObjName, v, v, results, prelude, self, v, self, v, errValReturn, impl, notification, okReturn)
+		newFileBytes, err = addMethodToImpl(newFileBytes, method, lcObjName)
 		if err != nil {
-			fmt.Printf("Error in addMethodToImpl: %s\n", err.Error())
+			failf("Error in addMethodToImpl: %s\n", err.Error())
 			return
 		}
+		if *timeout > 0 {
+			if newFileBytes, err = addTimeoutConst(newFileBytes, objectName, *timeout); err != nil {
+				failf("Error adding the timeout of %s: %s\n", v, err.Error())
+				return
+			}
+		}
+		if *metrics {
+			if err := addMetrics(basePath); err != nil {
+				failf("Error adding the metrics: %s\n", err.Error())
+				return
+			}
+			if newFileBytes, err = injectMetrics(newFileBytes, fp, objectName); err != nil {
+				failf("Error injecting the metrics into %s: %s\n", exportedName(objectName), err.Error())
+				return
+			}
+		}
+		if *auth {
+			if err := addAuthorizer(basePath); err != nil {
+				failf("Error adding the authorizer: %s\n", err.Error())
+				return
+			}
+			if newFileBytes, err = injectAuthorizer(newFileBytes, fp, objectName); err != nil {
+				failf("Error injecting the authorizer into %s: %s\n", exportedName(objectName), err.Error())
+				return
+			}
+		}
+		if *notify {
+			if err := addOutbox(basePath); err != nil {
+				failf("Error adding the outbox: %s\n", err.Error())
+				return
+			}
+			if newFileBytes, err = injectOutbox(newFileBytes, fp, objectName); err != nil {
+				failf("Error injecting the outbox into %s: %s\n", exportedName(objectName), err.Error())
+				return
+			}
+		}
 	case relPathValidator:
-		v := firstCharToUpper(usecaseName)
+		v := exportedName(usecaseName)
 		// Skip to next fi in the loop in case method already exists
 		if ix := bytes.Index(fileBytes, []byte(fmt.Sprintf("Validate%s(", v))); ix != -1 {
+			noopf("the usecase %s already exists in %s", v, exportedName(objectName))
 			return
 		}
 
 		methodSignature := fmt.Sprintf("\t// Validate%s validates rqm. If valid it returns nil otherwise an %sErrVal\n\tValidate%s(rqm *reqmodel.%s) *respmodel.%sErrVal\n", v, v, v, v, v)
 		newFileBytes, err = addMethodSignatureToInterface(fileBytes, fp, methodSignature, ucObjName)
 		if err != nil {
-			fmt.Printf("Error in addMethodSignatureToInterface: %s\n", err.Error())
+			failf("Error in addMethodSignatureToInterface: %s\n", err.Error())
 			return
 		}
-		method := fmt.Sprintf("\n\n// Validate%s implements the %s interface method Validate%s.\nfunc (%s *%s) Validate%s(rqm *reqmodel.%s) *respmodel.%sErrVal {\n\t// TODO: Implement interface method\n\treturn nil\n}", v, firstCharToUpper(objectName), v, firstCharInWord(firstCharToLower(objectName)), firstCharToLower(objectName), v, v, v)
-		newFileBytes, err = addMethodToImpl(newFileBytes, method, objectName)
+		method := fmt.Sprintf("\n\n// Validate%s implements the %s interface method Validate%s.\nfunc (%s *%s) Validate%s(rqm *reqmodel.%s) *respmodel.%sErrVal {\n\t// TODO: Implement interface method\n\treturn nil\n}", v, ucObjName, v, self, lcObjName, v, v, v)
+		if *validatorStyle == validatorTags {
+			method = tagValidatorMethod(v, ucObjName, lcObjName, self)
+		}
+		newFileBytes, err = addMethodToImpl(newFileBytes, method, lcObjName)
 		if err != nil {
-			fmt.Printf("Error in addMethodToImpl: %s\n", err.Error())
+			failf("Error in addMethodToImpl: %s\n", err.Error())
+			return
+		}
+		if *validatorStyle == validatorTags {
+			if newFileBytes, err = injectTagValidator(newFileBytes, fp, ucObjName, lcObjName); err != nil {
+				failf("Error adding the tag validator: %s\n", err.Error())
+				return
+			}
+		}
+	}
+	for _, path := range usecaseImports(parentDirName, projectBaseImportPath+"clean/", usecaseName, objectName) {
+		if newFileBytes, err = ensureImport(newFileBytes, path); err != nil {
+			failf("Error adding the import %s: %s\n", path, err.Error())
 			return
 		}
 	}
-	if err := ioutil.WriteFile(fp, newFileBytes, 0700); err != nil {
-		fmt.Printf("Error writing to %s: %s\n", fp, err.Error())
+	if err := writeFile(fp, newFileBytes); err != nil {
+		failf("Error writing to %s: %s\n", fp, err.Error())
 		return
 	}
 }
 
 func dirNameFromRelPath(relPath string) string {
+	// The folder of a layer may be configured, see setLayout
+	switch layer := layerOfRelPath(relPath); layer {
+	case "":
+	case layerGatewayPort:
+		return objGateway
+	default:
+		return layer
+	}
 	// relPath is of the form 'ifadapter/controller/'
 	pieces := strings.Split(relPath, "/")
 	if pieces == nil {
@@ -689,81 +2111,122 @@ func dirNameFromRelPath(relPath string) string {
 	return pieces[len(pieces)-2]
 }
 
-// addMethodSignatureToInterface adds a method to the interface ifName
+// addMethodSignatureToInterface adds a method to the interface ifName, which is located by name so the way it's
+// declared e.g. in a parenthesised type declaration doesn't matter
 func addMethodSignatureToInterface(b []byte, filepath, methodSignature, ifName string) ([]byte, error) {
-	pieces := bytes.SplitAfter(b, []byte(fmt.Sprintf("type %s interface {\n", ifName)))
-	if len(pieces) != 2 {
-		fmt.Printf("%s content not split into two halves\n", filepath)
-		return nil, errors.New("Error splitting b")
-	}
-	methodSignatureBytes := []byte(methodSignature)
-	p1Reader := bytes.NewReader(pieces[0])
-	var w bytes.Buffer
-	if _, err := io.Copy(&w, p1Reader); err != nil {
-		fmt.Printf("Error copying from p1Reader to w: %s\n", err.Error())
+	fset := token.NewFileSet()
+	f, err := parseFile(fset, filepath, b, parser.ParseComments)
+	if err != nil {
 		return nil, err
 	}
-	usecaseReader := bytes.NewReader(methodSignatureBytes)
-	if _, err := io.Copy(&w, usecaseReader); err != nil {
-		fmt.Printf("Error copying from usecaseReader to w: %s\n", err.Error())
-		return nil, err
+	var it *ast.InterfaceType
+	if ts := findTypeSpec(f, ifName); ts != nil {
+		it, _ = ts.Type.(*ast.InterfaceType)
 	}
-	p2Reader := bytes.NewReader(pieces[1])
-	if _, err := io.Copy(&w, p2Reader); err != nil {
-		fmt.Printf("Error copying from p2Reader to w: %s\n", err.Error())
-		return nil, err
+	if it == nil {
+		return nil, fmt.Errorf("the interface %s isn't declared in %s", ifName, filepath)
+	}
+	// The signature is added at the top of the interface, after the line of its opening brace
+	ix := fset.Position(it.Methods.Opening).Offset + 1
+	if nl := bytes.IndexByte(b[ix:], '\n'); nl != -1 && ix+nl < fset.Position(it.Methods.Closing).Offset {
+		ix += nl + 1
+	} else {
+		methodSignature = "\n" + methodSignature
 	}
-	return w.Bytes(), nil
+	newbuf := make([]byte, 0, len(b)+len(methodSignature))
+	newbuf = append(newbuf, b[:ix]...)
+	newbuf = append(newbuf, methodSignature...)
+	newbuf = append(newbuf, b[ix:]...)
+	return newbuf, nil
 }
 
+// addMethodToImpl adds method right after the declaration of the implementation struct of implName.
+// The struct is located by name so other structs declared in the same file don't matter.
 func addMethodToImpl(b []byte, method, implName string) ([]byte, error) {
-	startingIx := bytes.Index(b, []byte(fmt.Sprintf("type %s struct {\n", firstCharToLower(implName))))
-	if startingIx == -1 {
-		fmt.Printf("Implementation %s not found\n", implName)
-		return nil, errors.New("Implementation not found")
+	method = useParams(method)
+	fset := token.NewFileSet()
+	f, err := parseFile(fset, "", b, parser.ParseComments)
+	if err != nil {
+		failf("Error parsing the implementation %s: %s\n", implName, err.Error())
+		return nil, err
 	}
-	leftBracesN := 0
-	rightBracesN := 0
-	found := false
-	implClosingBracketIx := 0
-	for i := startingIx; i < len(b); i++ {
-		if b[i] == '{' {
-			leftBracesN++
+	structName := unexportedName(implName)
+	var decl *ast.GenDecl
+	for _, d := range f.Decls {
+		gd, ok := d.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
 			continue
 		}
-		if b[i] == '}' {
-			rightBracesN++
-			if rightBracesN == leftBracesN && rightBracesN > 0 {
-				implClosingBracketIx = i
-				found = true
-				break
+		for _, spec := range gd.Specs {
+			if ts, ok := spec.(*ast.TypeSpec); ok && ts.Name.Name == structName {
+				if _, ok := ts.Type.(*ast.StructType); ok {
+					decl = gd
+				}
 			}
 		}
 	}
-	if !found {
-		fmt.Printf("Couldn't find the implementation\n")
-		return nil, errors.New("Couldn't find the implementation")
-	}
-	newbuf := make([]byte, len(b)+len(method))
-	for k, v := range b[:implClosingBracketIx+1] {
-		newbuf[k] = v
-	}
-	offset := implClosingBracketIx + 1
-	for i := 0; i < len(method); i++ {
-		newbuf[offset+i] = method[i]
-	}
-	offset = implClosingBracketIx + len(method) + 1
-	for k, v := range b[implClosingBracketIx+1:] {
-		newbuf[offset+k] = v
+	if decl == nil {
+		fmt.Printf("Implementation %s not found\n", implName)
+		return nil, errors.New("Implementation not found")
 	}
+	implEndIx := fset.Position(decl.End()).Offset
+	newbuf := make([]byte, 0, len(b)+len(method))
+	newbuf = append(newbuf, b[:implEndIx]...)
+	newbuf = append(newbuf, method...)
+	newbuf = append(newbuf, b[implEndIx:]...)
 	return newbuf, nil
+}
+
+// flagsFirst returns args with all flags moved in front of the other arguments so
+// that flags can be entered anywhere e.g. "clean add usecase AddItem to Order -flag".
+// Everything after a "--" argument is treated as a non-flag argument.
+func flagsFirst(args []string) []string {
+	var flags, others []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			others = append(others, args[i+1:]...)
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			others = append(others, arg)
+			continue
+		}
+		flags = append(flags, arg)
+		name := strings.TrimLeft(arg, "-")
+		if strings.Contains(name, "=") {
+			continue
+		}
+		// Non-boolean flags take the next argument as their value
+		f := flag.CommandLine.Lookup(name)
+		if f == nil {
+			continue
+		}
+		if bf, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && bf.IsBoolFlag() {
+			continue
+		}
+		if i+1 < len(args) {
+			i++
+			flags = append(flags, args[i])
+		}
+	}
+	return append(append(flags, "--"), others...)
+}
 
-	// Check if the following syntax is possible
-	// for _, v := range b[startingIx:] {
-	// }
+// sortedKeys returns the keys of m in increasing order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 func fileExists(filepath string) bool {
+	if _, ok := pendingFiles[filepath]; ok {
+		return true
+	}
 	if _, err := os.Stat(filepath); os.IsNotExist(err) {
 		// path to confPath does not exist
 		return false
@@ -772,14 +2235,15 @@ func fileExists(filepath string) bool {
 }
 
 func writeBytesToFile(filepath string, content string) error {
-	f, err := os.OpenFile(filepath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0700)
-	defer f.Close()
-	if err != nil {
-		fmt.Printf("Error opening file: %s\n", err.Error())
-		return err
-	}
-	if _, err = f.WriteString(content); err != nil {
-		fmt.Printf("Error writing to file: %s\n", err.Error())
+	defer startPhase(phaseWrite)()
+	if !mayWrite(filepath, false) {
+		return nil
+	}
+	// The file is rewritten with the content appended rather than appended to so that the whole file is
+	// normalized
+	b, _ := readFile(filepath)
+	if err := writeAllowedFile(filepath, append(b, content...)); err != nil {
+		failf("Error writing to file: %s\n", err.Error())
 		return err
 	}
 	return nil
@@ -794,134 +2258,123 @@ func firstCharInWord(word string) string {
 	return ""
 }
 
-// firstCharToLower returns text after lowering its first character
+// firstCharToLower returns text after lowering its first character. It returns an empty string for an empty
+// text, so the names it's given must have been checked by checkName.
 func firstCharToLower(text string) string {
-	// Lower case first character
-	var output string
-	for _, v := range text {
-		s := string(v)
-		ls := strings.ToLower(s)
-		output = ls + text[len(s):]
-		break
+	// Lower case first character, which may be a multi-byte rune e.g. É
+	r, size := utf8.DecodeRuneInString(text)
+	if size == 0 {
+		return ""
 	}
-	return output
+	return string(unicode.ToLower(r)) + text[size:]
 }
 
-// firstCharToUpper returns text after capitalising its first character
+// firstCharToUpper returns text after capitalising its first character. It returns an empty string for an
+// empty text, so the names it's given must have been checked by checkName.
 func firstCharToUpper(text string) string {
-	var output string
-	for _, v := range text {
-		s := string(v)
-		us := strings.ToUpper(s)
-		output = us + text[len(s):]
-		break
+	r, size := utf8.DecodeRuneInString(text)
+	if size == 0 {
+		return ""
 	}
-	return output
+	return string(unicode.ToUpper(r)) + text[size:]
 }
 
 func initProject(confDir, confPath string) {
 	wd, err := os.Getwd()
 	if err != nil {
-		fmt.Printf("Error determining current working directory\n")
+		failf("Error determining current working directory\n")
 		return
 	}
 
 	// Check for configuration file
+	conf := make(map[string]string)
 	if !fileExists(confPath) {
 		// path to confPath does not exist
 		if !mkdir(confDir) {
 			return
 		}
-		if err := ioutil.WriteFile(
-			confPath,
-			[]byte("directory="+filepath.FromSlash(wd)+"/"),
-			0700,
-		); err != nil {
-			fmt.Printf("Error creating config file: %s\n", err.Error())
-			return
-		}
 	} else {
-		if err := ioutil.WriteFile(
-			confPath,
-			[]byte("directory="+filepath.FromSlash(wd)+"/"),
-			0700,
-		); err != nil {
-			fmt.Printf("Error creating config file: %s\n", err.Error())
+		// Keep any other settings of the existing configuration file
+		if conf, err = readConfig(confPath); err != nil {
+			failf("Error reading config file: %s\n", err.Error())
 			return
 		}
 	}
-
-	if !mkdir("clean") {
-		return
-	}
-	if !mkdir("clean/entity") {
-		return
-	}
-	if !mkdir("clean/ifadapter") {
-		return
-	}
-	if !mkdir("clean/ifadapter/controller") {
-		return
-	}
-	if !mkdir("clean/ifadapter/controller/test") {
-		return
-	}
-	if !mkdir("clean/ifadapter/gateway") {
-		return
-	}
-	if !mkdir("clean/ifadapter/gateway/test") {
-		return
-	}
-	if !mkdir("clean/ifadapter/presenter") {
-		return
-	}
-	if !mkdir("clean/ifadapter/presenter/test") {
-		return
-	}
-	if !mkdir("clean/ifadapter/view") {
-		return
+	conf[confKeyDirectory] = filepath.FromSlash(wd) + "/"
+	setModuleConfig(conf, wd, "")
+	if *flat {
+		if *folderScheme != "" && *folderScheme != schemeClean {
+			failf("--flat generates all of the layers into the clean folder, it can't be set together with --layout %s\n", *folderScheme)
+			return
+		}
+		conf[confKeyLayoutMode] = layoutFlat
 	}
-	if !mkdir("clean/ifadapter/view/test") {
-		return
+	if *folderScheme != "" {
+		if err := setFolderScheme(conf, *folderScheme); err != nil {
+			failf("%s\n", err.Error())
+			return
+		}
 	}
-	if !mkdir("clean/ifadapter/view/viewmodel") {
+	// The skeleton depends on the layout
+	if err := setLayout(conf); err != nil {
+		failf("%s\n", err.Error())
 		return
 	}
-	if !mkdir("clean/usecase") {
+	if err := setLayoutMode(conf); err != nil {
+		failf("%s\n", err.Error())
 		return
 	}
-	if !mkdir("clean/usecase/interactor") {
+	if err := writeConfig(confPath, conf); err != nil {
+		failf("Error creating config file: %s\n", err.Error())
 		return
 	}
-	if !mkdir("clean/usecase/interactor/test") {
-		return
+
+	for _, dir := range skeletonDirs() {
+		if !mkdir(dir) {
+			return
+		}
 	}
-	if !mkdir("clean/usecase/reqmodel") {
-		return
+	if *withKernel {
+		if err := addKernel(filepath.FromSlash(wd) + "/clean/"); err != nil {
+			failf("Error adding the kernel: %s\n", err.Error())
+			return
+		}
 	}
-	if !mkdir("clean/usecase/reqmodel/validator") {
+	//fmt.Printf("Base Directory: %s\n", filepath.Base(ex))
+	fmt.Printf("Clean project initialised successfully\n\n")
+}
+
+// newProject creates the folder name, writes a go.mod file declaring modulePath to it and initialises
+// a new project inside it, which also sets the Clean Work Directory to it. If modulePath is empty the
+// folder name is used as module path. Unless force is true the folder must either not exist or be empty.
+func newProject(confDir, confPath, name, modulePath string, force bool) {
+	if files, err := ioutil.ReadDir(name); err == nil && len(files) > 0 && !force {
+		failf("The folder '%s' already exists and isn't empty. Use \"clean new project %s --force\" to initialise a project in it anyway\n\n", name, name)
 		return
 	}
-	if !mkdir("clean/usecase/reqmodel/validator/test") {
+	if err := os.MkdirAll(name, 0700); err != nil {
+		failf("Error creating the folder '%s': %s\n", name, err.Error())
 		return
 	}
-	if !mkdir("clean/usecase/respmodel") {
+	if err := os.Chdir(name); err != nil {
+		failf("Error changing directory to '%s': %s\n", name, err.Error())
 		return
 	}
-
-	if !mkdir("lib") {
-		return
+	if modulePath == "" {
+		modulePath = filepath.Base(name)
 	}
-	if !mkdir("cmd") {
-		return
+	if !fileExists(goModFileName) {
+		if err := ioutil.WriteFile(goModFileName, []byte(goModContent(modulePath)), 0700); err != nil {
+			failf("Error creating %s: %s\n", goModFileName, err.Error())
+			return
+		}
 	}
-	//fmt.Printf("Base Directory: %s\n", filepath.Base(ex))
-	fmt.Printf("Clean project initialised successfully\n\n")
+	initProject(confDir, confPath)
 }
 
 func mkdir(name string) bool {
 	if err := os.Mkdir(name, 0700); err != nil {
-		fmt.Printf("Error creating the folder '%s': %s\n", name, err.Error())
+		failf("Error creating the folder '%s': %s\n", name, err.Error())
 		return false
 	}
 	return true
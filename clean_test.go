@@ -0,0 +1,380 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// update makes the golden tests write the golden files instead of comparing to them
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// cleanBin is the clean binary built by TestMain, which the tests run the way a user does
+var cleanBin string
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "clean")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	cleanBin = filepath.Join(dir, "clean")
+	if out, err := exec.Command("go", "build", "-o", cleanBin, ".").CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error building clean: %s\n%s", err.Error(), out)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// testProject is a project created by clean new project in a temporary folder. Its configuration file is kept
+// in a home folder of its own so that the tests don't touch the one of the user.
+type testProject struct {
+	t *testing.T
+	// home is the home folder clean reads its configuration file from
+	home string
+	// dir is the project folder
+	dir string
+}
+
+// newTestProject creates the project app with clean new project, to which flags e.g. --flat are passed
+func newTestProject(t *testing.T, flags ...string) *testProject {
+	t.Helper()
+	root := t.TempDir()
+	p := &testProject{t: t, home: filepath.Join(root, "home"), dir: filepath.Join(root, "app")}
+	if err := os.Mkdir(p.home, 0700); err != nil {
+		t.Fatal(err)
+	}
+	args := append(append([]string{"--no-history"}, flags...), "new", "project", "app")
+	if stdout, stderr, code := p.runIn(root, "", args...); code != 0 {
+		t.Fatalf("clean %s exited with %d: %s%s", strings.Join(args, " "), code, stdout, stderr)
+	}
+	return p
+}
+
+// runIn runs clean with args in the folder dir, reading stdin, and returns its stdout, its stderr and its exit
+// status
+func (p *testProject) runIn(dir, stdin string, args ...string) (string, string, int) {
+	p.t.Helper()
+	cmd := exec.Command(cleanBin, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "HOME="+p.home)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return stdout.String(), stderr.String(), exitErr.ExitCode()
+	}
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	return stdout.String(), stderr.String(), 0
+}
+
+// run runs clean with args in the project folder like runIn
+func (p *testProject) run(args ...string) (string, string, int) {
+	p.t.Helper()
+	return p.runIn(p.dir, "", args...)
+}
+
+// clean runs clean with args in the project folder and returns its stdout. The test fails unless clean
+// succeeds.
+func (p *testProject) clean(args ...string) string {
+	p.t.Helper()
+	stdout, stderr, code := p.run(args...)
+	if code != 0 {
+		p.t.Fatalf("clean %s exited with %d: %s%s", strings.Join(args, " "), code, stdout, stderr)
+	}
+	return stdout
+}
+
+// goRun runs the go command with args in the project folder in module mode and returns its combined output
+func (p *testProject) goRun(args ...string) (string, error) {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = p.dir
+	cmd.Env = append(os.Environ(), "GO111MODULE=on", "GOFLAGS=-mod=mod")
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// path returns the path of the file relPath of the project e.g. clean/ifadapter/view/order.go
+func (p *testProject) path(relPath string) string {
+	return filepath.Join(p.dir, filepath.FromSlash(relPath))
+}
+
+// read returns the content of the file relPath of the project
+func (p *testProject) read(relPath string) string {
+	p.t.Helper()
+	b, err := ioutil.ReadFile(p.path(relPath))
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	return string(b)
+}
+
+// write writes content to the file relPath of the project
+func (p *testProject) write(relPath, content string) {
+	p.t.Helper()
+	if err := os.MkdirAll(filepath.Dir(p.path(relPath)), 0700); err != nil {
+		p.t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(p.path(relPath), []byte(content), 0600); err != nil {
+		p.t.Fatal(err)
+	}
+}
+
+// exists reports whether the file relPath of the project exists
+func (p *testProject) exists(relPath string) bool {
+	_, err := os.Stat(p.path(relPath))
+	return err == nil
+}
+
+// files returns the files of the project below the folder relDir, keyed by their paths relative to it, along
+// with their content. The content of the project's own settings, e.g. its history, is left out.
+func (p *testProject) files(relDir string) map[string]string {
+	p.t.Helper()
+	files := make(map[string]string)
+	root := p.path(relDir)
+	err := filepath.Walk(root, func(fp string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() {
+			return err
+		}
+		b, err := ioutil.ReadFile(fp)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, fp)
+		files[filepath.ToSlash(rel)] = string(b)
+		return nil
+	})
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	return files
+}
+
+// changedBy runs clean with args in the project folder and returns the files of the project it added or
+// modified, keyed by their paths relative to the project folder, along with their content. The project's own
+// settings in the .clean folder are left out. The test fails unless clean succeeds.
+func (p *testProject) changedBy(args ...string) map[string]string {
+	p.t.Helper()
+	before := p.files("")
+	p.clean(args...)
+	changed := make(map[string]string)
+	for name, content := range p.files("") {
+		if old, ok := before[name]; (!ok || old != content) && !strings.HasPrefix(name, ".clean/") {
+			changed[name] = content
+		}
+	}
+	return changed
+}
+
+// anonymized returns s with the folder of the project replaced by $PROJECT, so that the output of commands
+// run in different projects can be compared
+func (p *testProject) anonymized(s string) string {
+	return strings.Replace(s, p.dir, "$PROJECT", -1)
+}
+
+// concatenated returns the files, keyed by their paths, as a single text in which each file is preceded by a
+// ==> path <== header, like clean --stdout prints them
+func concatenated(files map[string]string) string {
+	var s string
+	for _, name := range sortedKeys(files) {
+		s += "==> " + name + " <==\n" + files[name]
+	}
+	return s
+}
+
+// compareGolden compares got to the golden file testdata/golden/name.golden byte by byte, or writes got to it
+// if -update is set
+func compareGolden(t *testing.T, name, got string) {
+	t.Helper()
+	fp := filepath.Join("testdata", "golden", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(fp), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fp, []byte(got), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(fp)
+	if err != nil {
+		t.Fatalf("%s, run go test -update to write the golden file", err.Error())
+	}
+	if got != string(want) {
+		t.Errorf("the output differs from %s, run go test -update if the change is intended:\n%s", fp, firstDifference(string(want), got))
+	}
+}
+
+// firstDifference describes the first line in which got differs from want
+func firstDifference(want, got string) string {
+	wantLines, gotLines := strings.SplitAfter(want, "\n"), strings.SplitAfter(got, "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return fmt.Sprintf("line %d: want %q, got %q", i+1, w, g)
+		}
+	}
+	return ""
+}
+
+// TestBatchAddIsReproducible runs the same batch add in two projects and asserts that both print the same
+// lines in the same order and write the same files.
+func TestBatchAddIsReproducible(t *testing.T) {
+	var outputs, trees []string
+	for i := 0; i < 2; i++ {
+		p := newTestProject(t)
+		stdout, stderr, code := p.runIn(p.dir, "Order\nCart\nPayment\nShipment\n", "add", "interactor", "-")
+		if code != 0 {
+			t.Fatalf("clean add interactor - exited with %d: %s%s", code, stdout, stderr)
+		}
+		out := p.anonymized(stdout + stderr)
+		out += p.anonymized(p.clean("--json", "add", "usecase", "AddItem", "to", "Order"))
+		out += p.anonymized(p.clean("status"))
+		outputs = append(outputs, out)
+
+		trees = append(trees, p.anonymized(concatenated(p.files("clean"))))
+	}
+	if outputs[0] != outputs[1] {
+		t.Errorf("the output differs between the runs:\n%s\n---\n%s", outputs[0], outputs[1])
+	}
+	if trees[0] != trees[1] {
+		t.Errorf("the generated files differ between the runs")
+	}
+	// The status lists the interactors sorted rather than in the order they were added
+	last := -1
+	for _, name := range []string{"Cart", "Order", "Payment", "Shipment"} {
+		ix := strings.Index(outputs[0], name+" (")
+		if ix < last {
+			t.Errorf("the status of %s isn't listed in order:\n%s", name, outputs[0])
+		}
+		last = ix
+	}
+}
+
+// TestAddMethodToImplWithSeveralStructs asserts that the method is added after the implementation struct
+// when the file declares other structs before and after it
+func TestAddMethodToImplWithSeveralStructs(t *testing.T) {
+	src := `package interactor
+
+// options configures an Order.
+type options struct {
+	retries int
+	nested  struct {
+		limit int
+	}
+}
+
+// order is an implementation of Order.
+type order struct {
+	ps presenter.Order
+}
+
+// audit records the usecases of an Order.
+type audit struct {
+	entries []string
+}
+`
+	method := "\n\n// AddItem implements the Order interface method AddItem.\nfunc (o *order) AddItem() {\n}"
+	b, err := addMethodToImpl([]byte(src), method, "Order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(b)
+	implEnd := strings.Index(got, "\tps presenter.Order\n}") + len("\tps presenter.Order\n}")
+	if ix := strings.Index(got, method); ix != implEnd {
+		t.Errorf("the method isn't added right after the order struct:\n%s", got)
+	}
+	if strings.Index(got, "type audit struct") < implEnd || strings.Index(got, "type options struct") > implEnd {
+		t.Errorf("the other structs moved:\n%s", got)
+	}
+	if _, err := addMethodToImpl([]byte(strings.Replace(src, "type order struct", "type orderImpl struct", 1)), method, "Order"); err == nil {
+		t.Errorf("got no error for a file without the implementation struct")
+	}
+}
+
+// TestAddUsecaseContinuesAfterReadFailure asserts that a usecase is still added to the other layers when
+// reading the file of the presenter fails, and that the failure is reported
+func TestAddUsecaseContinuesAfterReadFailure(t *testing.T) {
+	p := newTestProject(t)
+	p.clean("add", "interactor", "Order")
+	// Reading a folder fails even for root, unlike reading a file without permissions
+	const presenter = "clean/ifadapter/presenter/order.go"
+	if err := os.Remove(p.path(presenter)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(p.path(presenter), 0700); err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr, code := p.run("add", "usecase", "AddItem", "to", "Order")
+	if code == 0 {
+		t.Fatalf("clean add usecase exited with 0: %s%s", stdout, stderr)
+	}
+	out := stdout + stderr
+	for _, want := range []string{"Error reading " + p.path(presenter), "failed in the layers: presenter", "It was added to the layers: controller"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("the output doesn't contain %q:\n%s", want, out)
+		}
+	}
+	for _, relPath := range []string{"clean/ifadapter/controller/order.go", "clean/usecase/interactor/order.go"} {
+		if src := p.read(relPath); !strings.Contains(src, "AddItem(") {
+			t.Errorf("AddItem wasn't added to %s:\n%s", relPath, src)
+		}
+	}
+}
+
+// TestImportsPerLayer enumerates the imports buildImports and usecaseImports return for each layer of an
+// interactor, with and without a gateway
+func TestImportsPerLayer(t *testing.T) {
+	defer func(base string) { projectBaseImportPath = base }(projectBaseImportPath)
+	projectBaseImportPath = "app/"
+	const importPath = "app/clean/"
+	tests := []struct {
+		layer          string
+		withGateway    bool
+		imports        []string
+		usecaseImports []string
+	}{
+		{objController, false, []string{"errors", "app/clean/usecase/interactor"}, nil},
+		{objPresenter, false, []string{"errors", "app/clean/ifadapter/view"}, []string{"app/clean/usecase/respmodel"}},
+		{objView, false, nil, []string{"app/clean/ifadapter/view/viewmodel"}},
+		{objInteractor, false, []string{"errors", "app/clean/ifadapter/presenter", "app/clean/usecase/reqmodel/validator"}, []string{"app/clean/usecase/reqmodel"}},
+		{objInteractor, true, []string{"errors", "app/clean/ifadapter/presenter", "app/clean/usecase/reqmodel/validator", "app/clean/ifadapter/gateway"}, []string{"app/clean/usecase/reqmodel"}},
+		{objValidator, false, nil, []string{"app/clean/usecase/reqmodel", "app/clean/usecase/respmodel"}},
+	}
+	defer func(v bool) { *withGateway = v }(*withGateway)
+	for _, tt := range tests {
+		*withGateway = tt.withGateway
+		if got := buildImports(tt.layer, importPath); strings.Join(got, " ") != strings.Join(tt.imports, " ") {
+			t.Errorf("buildImports(%s) with gateway %v = %q, want %q", tt.layer, tt.withGateway, got, tt.imports)
+		}
+		if got := usecaseImports(tt.layer, importPath, "AddItem", "Order"); strings.Join(got, " ") != strings.Join(tt.usecaseImports, " ") {
+			t.Errorf("usecaseImports(%s) = %q, want %q", tt.layer, got, tt.usecaseImports)
+		}
+	}
+	// A module path without a dot, like app, is grouped with the standard library like goimports does
+	want := "\n\nimport (\n\t\"app/clean/usecase/interactor\"\n\t\"errors\"\n)"
+	if got := importDecl(buildImports(objController, importPath)); got != want {
+		t.Errorf("importDecl() = %q, want %q", got, want)
+	}
+}
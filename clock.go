@@ -0,0 +1,103 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"path/filepath"
+)
+
+const (
+	objClock     = "clock"
+	relPathClock = "lib/clock/"
+	// clockField is the name of the field and constructor parameter of an Interactor holding the Clock
+	clockField = "clk"
+	// fakeClockArg constructs the fake Clock the generated tests pass to the constructors of the Interactors
+	fakeClockArg = "clock.NewFake(time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC))"
+)
+
+// clockSource is the content, following the package clause, of the file of the Clock
+const clockSource = `
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time. Interactors get the time from a Clock instead of calling time.Now so that
+// their tests control it.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+}
+
+// system is the Clock of the system time.
+type system struct{}
+
+// New constructs a new Clock which tells the system time.
+func New() Clock {
+	return system{}
+}
+
+// Now implements the Clock interface method Now.
+func (system) Now() time.Time {
+	return time.Now()
+}
+
+// Fake is a Clock for tests whose time only changes when it's set or advanced.
+type Fake struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFake constructs a new Fake Clock whose time is now.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now implements the Clock interface method Now.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Set sets the time of f to now.
+func (f *Fake) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = now
+}
+
+// Advance moves the time of f forward by d.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+`
+
+// addClock adds the Clock, its system implementation and its fake to the lib/clock folder of the project at
+// basePath
+func addClock(basePath string) error {
+	fp := filepath.FromSlash(basePath + relPathClock + objClock + ".go")
+	if fileExists(fp) {
+		noopf("the clock already exists")
+		return nil
+	}
+	if err := mkdirAll(filepath.Dir(fp)); err != nil {
+		return err
+	}
+	return writeFile(fp, []byte(packageClause(basePath+relPathClock, objClock)+clockSource))
+}
+
+// injectClock adds a field and a constructor parameter of the Clock, which is added if it doesn't exist, to the
+// Interactor implementation of the interactor of the project at basePath unless it already has them
+func injectClock(basePath, interactor string) error {
+	if !fileExists(filepath.FromSlash(basePath + relPathClock + objClock + ".go")) {
+		if err := addClock(basePath); err != nil {
+			return err
+		}
+	}
+	return injectInteractorDependency(basePath, interactor, clockField, objClock+".Clock", relPathClock)
+}
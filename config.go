@@ -0,0 +1,303 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const goModFileName = "go.mod"
+
+// goVersion matches the major and minor version of a Go release e.g. 1.9 of go1.9.2
+var goVersion = regexp.MustCompile(`^go(1\.[0-9]+)`)
+
+const (
+	confKeyDirectory   = "directory"
+	confKeyInitialisms = "naming.initialisms"
+	// confKeyReceiver is the style of the receivers of the generated methods
+	confKeyReceiver = "naming.receiver"
+	// confKeyModule is the module path of the project, which is the base of the generated import paths
+	confKeyModule = "module"
+	// confKeyModuleRoot is the folder holding the go.mod file the module path was resolved from
+	confKeyModuleRoot = "module.root"
+	// confKeyModuleReplace holds the rules replacing the beginnings of generated import paths, see setImportReplacements
+	confKeyModuleReplace = "module.replace"
+	// confKeyGoModStamp identifies the version of the go.mod file the module path was resolved from
+	confKeyGoModStamp = "module.gomod"
+	// confKeyComments is the style of the generated doc comments, which is verbose or terse
+	confKeyComments = "comments.style"
+	// confKeyPagination is the style of the paginated usecases, which is offset or cursor
+	confKeyPagination = "pagination.style"
+	// confKeyHistoryMaxSize is the size in bytes beyond which the history log is rotated
+	confKeyHistoryMaxSize = "history.maxsize"
+	// confKeyCreateOnly makes every command behave as if --create-only was set if it's true
+	confKeyCreateOnly = "safety.create-only"
+	// confKeyLintClean makes every command behave as if --lint-clean was set if it's true
+	confKeyLintClean = "lint.clean"
+	// confKeyMockEngine is the engine generating the mocks of the new interactors, which is builtin or gomock
+	confKeyMockEngine = "mocks.engine"
+	// confKeyPolicy is followed by a layer e.g. policy.interactor and holds the overwrite policy of the layer
+	confKeyPolicy = "policy."
+	// confKeySuffix is followed by the object type e.g. naming.suffix.controller
+	confKeySuffix = "naming.suffix."
+)
+
+// localConfName is the name of the configuration file of a project, in its root folder, whose settings
+// override the ones of the global configuration file ~/.clean/cleanrc
+const localConfName = ".cleanrc"
+
+// findLocalConfig returns the path of the project configuration file in dir or the closest of its parent
+// folders holding one, or an empty string if there is none
+func findLocalConfig(dir string) string {
+	for {
+		fp := filepath.Join(dir, localConfName)
+		if fi, err := os.Stat(fp); err == nil && !fi.IsDir() {
+			return fp
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// mergeLocalConfig overrides the settings of conf with the ones of the project configuration file localPath.
+// The project's folder is the Clean Work Directory unless the file sets another one.
+func mergeLocalConfig(conf map[string]string, localPath string) error {
+	local, err := readConfig(localPath)
+	if err != nil {
+		return err
+	}
+	for k, v := range local {
+		conf[k] = v
+	}
+	if _, ok := local[confKeyDirectory]; !ok {
+		conf[confKeyDirectory] = filepath.Dir(localPath) + string(os.PathSeparator)
+	}
+	return nil
+}
+
+// configScope returns the path of the configuration file clean set writes to, which is the global one
+// globalPath if --global is set and the project configuration file if --local is set. Otherwise it's the
+// project configuration file if wd is inside a project, i.e. it or one of its parents has a project
+// configuration file or it has a clean folder, and the global one otherwise.
+func configScope(globalPath, wd string) (string, error) {
+	switch {
+	case *globalScope && *localScope:
+		return "", fmt.Errorf("--global and --local can't be set together")
+	case *globalScope:
+		return globalPath, nil
+	}
+	if fp := findLocalConfig(wd); fp != "" && !*localScope {
+		return fp, nil
+	}
+	if fi, err := os.Stat(filepath.Join(wd, "clean")); *localScope || (err == nil && fi.IsDir()) {
+		return filepath.Join(wd, localConfName), nil
+	}
+	return globalPath, nil
+}
+
+// updateConfig applies update to the settings of the configuration file fp, which is created if it doesn't
+// exist, and writes them back
+func updateConfig(fp string, update func(conf map[string]string)) error {
+	conf := make(map[string]string)
+	if fileExists(fp) {
+		var err error
+		if conf, err = readConfig(fp); err != nil {
+			return err
+		}
+	}
+	update(conf)
+	return writeConfig(fp, conf)
+}
+
+// readConfig reads the configuration file at confPath and returns its key value pairs.
+// Each line of the configuration file is of the form key=value. The value is everything after the first
+// equals sign, so it may contain more of them, and the whitespace surrounding the key and the value is
+// trimmed. Blank lines and lines starting with # are ignored. It returns an error if any other line has no
+// equals sign or an empty key, rather than leaving the setting out.
+func readConfig(confPath string) (map[string]string, error) {
+	confBytes, err := ioutil.ReadFile(confPath)
+	if err != nil {
+		return nil, err
+	}
+	conf := make(map[string]string)
+	for i, line := range strings.Split(string(confBytes), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pieces := strings.SplitN(line, "=", 2)
+		if len(pieces) != 2 || strings.TrimSpace(pieces[0]) == "" {
+			return nil, fmt.Errorf("the line %d of the configuration file %s isn't of the form key=value: %q", i+1, confPath, line)
+		}
+		conf[strings.TrimSpace(pieces[0])] = strings.TrimSpace(pieces[1])
+	}
+	return conf, nil
+}
+
+// projectDir returns the Clean Work Directory of conf ending with exactly one slash, which the paths of the
+// project are built on e.g. dir + "clean/", whether it was written with a trailing slash or separator or not.
+// It returns false if the directory isn't configured.
+func projectDir(conf map[string]string) (string, bool) {
+	dir, ok := conf[confKeyDirectory]
+	if !ok {
+		return "", false
+	}
+	dir = strings.TrimRight(dir, "\r\n")
+	trimmed := strings.TrimRight(dir, "/"+string(os.PathSeparator))
+	if trimmed == "" && dir != "" {
+		// The root folder
+		return "/", true
+	}
+	return trimmed + "/", true
+}
+
+// writeConfig writes conf to the configuration file at confPath, one key=value pair per line
+func writeConfig(confPath string, conf map[string]string) error {
+	var content string
+	for _, k := range sortedKeys(conf) {
+		content += k + "=" + conf[k] + "\n"
+	}
+	return ioutil.WriteFile(confPath, []byte(content), 0700)
+}
+
+// goModModulePath returns the module path declared by the go.mod file in dir or an empty string if there is none
+func goModModulePath(dir string) string {
+	b, err := ioutil.ReadFile(filepath.Join(dir, goModFileName))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], "\"`")
+		}
+	}
+	return ""
+}
+
+// goModGoVersion returns the minor version of the go directive of the go.mod file of the module dir
+// belongs to e.g. 18 for "go 1.18". It returns an error if there's no go.mod file or it has no go directive.
+func goModGoVersion(dir string) (int, error) {
+	root, _ := findModule(dir)
+	if root == "" {
+		return 0, fmt.Errorf("%s doesn't belong to a module with a go.mod file", dir)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(root, goModFileName))
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "go" {
+			continue
+		}
+		if m := goVersion.FindStringSubmatch("go" + fields[1]); m != nil {
+			return strconv.Atoi(strings.TrimPrefix(m[1], "1."))
+		}
+	}
+	return 0, fmt.Errorf("the go.mod file in %s has no go directive", root)
+}
+
+// goModContent returns the content of a new go.mod file declaring modulePath. The go directive
+// is set to the Go version Clean was built with.
+func goModContent(modulePath string) string {
+	content := fmt.Sprintf("module %s\n", modulePath)
+	if m := goVersion.FindStringSubmatch(runtime.Version()); m != nil {
+		content += fmt.Sprintf("\ngo %s\n", m[1])
+	}
+	return content
+}
+
+// findModule returns the folder holding the go.mod file of the module dir belongs to, found by walking
+// up from dir, and the module path of dir. It returns empty strings if dir doesn't belong to a module.
+func findModule(dir string) (string, string) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", ""
+	}
+	for root := dir; ; root = filepath.Dir(root) {
+		if modulePath := goModModulePath(root); modulePath != "" {
+			rel, err := filepath.Rel(root, dir)
+			if err != nil {
+				return "", ""
+			}
+			if rel != "." {
+				modulePath += "/" + filepath.ToSlash(rel)
+			}
+			return root, modulePath
+		}
+		if filepath.Dir(root) == root {
+			return "", ""
+		}
+	}
+}
+
+// gopathImportPath returns the import path of the project in baseDir derived from its location in the GOPATH,
+// which is what follows the last src folder of baseDir e.g. myproject/ of /users/john/go/src/myproject/. It
+// returns false if baseDir has no src folder.
+func gopathImportPath(baseDir string) (string, bool) {
+	for i := len(baseDir) - 1; i > 1; i-- {
+		if baseDir[i] == 'c' && baseDir[i-1] == 'r' && baseDir[i-2] == 's' {
+			return string(baseDir[i+2:]), true
+		}
+	}
+	return "", false
+}
+
+// goModStamp returns a stamp of the go.mod file in root which changes whenever the file is edited.
+// It returns an empty string if there is no go.mod file in root.
+func goModStamp(root string) string {
+	fi, err := os.Stat(filepath.Join(root, goModFileName))
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d", fi.ModTime().UnixNano(), fi.Size())
+}
+
+// setModuleConfig sets the module path of conf to modulePath, or if empty to the module path of dir
+// resolved from the go.mod file of its module. If neither exists the module path is removed so that the
+// import path is derived from the project's location in the GOPATH. The folder and stamp of the go.mod
+// file are kept in conf so that the module path is only resolved again when the go.mod file changes.
+func setModuleConfig(conf map[string]string, dir, modulePath string) {
+	root := dir
+	if modulePath == "" {
+		root, modulePath = findModule(dir)
+	}
+	if modulePath == "" {
+		delete(conf, confKeyModule)
+		delete(conf, confKeyModuleRoot)
+		delete(conf, confKeyGoModStamp)
+		return
+	}
+	conf[confKeyModule] = modulePath
+	conf[confKeyModuleRoot] = root
+	conf[confKeyGoModStamp] = goModStamp(root)
+}
+
+// refreshModuleConfig resolves the module path of the project in dir again if the go.mod file it was
+// resolved from has changed, or if the project didn't belong to a module and now has a go.mod file.
+// It returns true if conf was changed.
+func refreshModuleConfig(conf map[string]string, dir string) bool {
+	defer startPhase(phaseScan)()
+	root := conf[confKeyModuleRoot]
+	if root == "" {
+		root = dir
+	}
+	if goModStamp(root) == conf[confKeyGoModStamp] {
+		return false
+	}
+	before := conf[confKeyModule] + "\n" + conf[confKeyModuleRoot] + "\n" + conf[confKeyGoModStamp]
+	setModuleConfig(conf, dir, "")
+	return before != conf[confKeyModule]+"\n"+conf[confKeyModuleRoot]+"\n"+conf[confKeyGoModStamp]
+}
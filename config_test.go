@@ -0,0 +1,164 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestProjectDirTrailingSlash reads configurations whose directory is written with and without trailing
+// slashes and asserts that they resolve to the same paths
+func TestProjectDirTrailingSlash(t *testing.T) {
+	tests := []struct {
+		directory, want string
+	}{
+		{"/home/me/app", "/home/me/app/"},
+		{"/home/me/app/", "/home/me/app/"},
+		{"/home/me/app//", "/home/me/app/"},
+		{"/home/me/app/\r", "/home/me/app/"},
+		{"/", "/"},
+	}
+	for _, tt := range tests {
+		confPath := filepath.Join(t.TempDir(), "cleanrc")
+		if err := ioutil.WriteFile(confPath, []byte("directory="+tt.directory+"\nmodule=app\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		conf, err := readConfig(confPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok := projectDir(conf)
+		if !ok || got != tt.want {
+			t.Errorf("projectDir() of %q = %q, %v, want %q", tt.directory, got, ok, tt.want)
+		}
+		if fp := got + "clean/" + relPathInteractor; fp != tt.want+"clean/usecase/interactor/" {
+			t.Errorf("the interactor folder of %q resolves to %s", tt.directory, fp)
+		}
+	}
+	if _, ok := projectDir(map[string]string{}); ok {
+		t.Errorf("projectDir() of a configuration without a directory returned true")
+	}
+}
+
+// TestConfigWithoutTrailingSlash asserts that a project whose configured directory has no trailing slash
+// gets its files at the same paths as one whose directory has it
+func TestConfigWithoutTrailingSlash(t *testing.T) {
+	var trees []string
+	for _, slash := range []bool{true, false} {
+		p := newTestProject(t)
+		confPath := filepath.Join(p.home, ".clean", "cleanrc")
+		b, err := ioutil.ReadFile(confPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conf := string(b)
+		if !strings.Contains(conf, "directory="+p.dir+"/\n") {
+			t.Fatalf("the configuration doesn't hold the project folder with a trailing slash:\n%s", conf)
+		}
+		if !slash {
+			conf = strings.Replace(conf, "directory="+p.dir+"/\n", "directory="+p.dir+"\n", 1)
+		}
+		if err := ioutil.WriteFile(confPath, []byte(conf), 0600); err != nil {
+			t.Fatal(err)
+		}
+		p.clean("add", "interactor", "Order")
+		if p.exists("../appclean") {
+			t.Errorf("the files were added to appclean")
+		}
+		trees = append(trees, concatenated(p.files("clean")))
+	}
+	if trees[0] != trees[1] {
+		t.Errorf("the files differ with and without the trailing slash")
+	}
+	if !strings.Contains(trees[0], "==> usecase/interactor/order.go <==") {
+		t.Errorf("the Interactor wasn't added")
+	}
+}
+
+func TestReadConfigValues(t *testing.T) {
+	tests := []struct {
+		name, line, want string
+	}{
+		{"equals sign", "directory=/data/projects/a=b/shop/", "/data/projects/a=b/shop/"},
+		{"hash", "directory=/data/#1/shop/", "/data/#1/shop/"},
+		{"spaces", "  directory = /data/my projects/shop/  ", "/data/my projects/shop/"},
+		{"unicode", "directory=/données/店/shop/", "/données/店/shop/"},
+		{"crlf", "directory=/data/shop/\r", "/data/shop/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			confPath := filepath.Join(t.TempDir(), "cleanrc")
+			content := "# The Clean Work Directory\n\n" + tt.line + "\nmodule=app\n"
+			if err := ioutil.WriteFile(confPath, []byte(content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			conf, err := readConfig(confPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if conf[confKeyDirectory] != tt.want || conf[confKeyModule] != "app" || len(conf) != 2 {
+				t.Errorf("readConfig() = %q, want the directory %q and the module app", conf, tt.want)
+			}
+			// The value is read back the same after being written
+			if err := writeConfig(confPath, conf); err != nil {
+				t.Fatal(err)
+			}
+			if again, err := readConfig(confPath); err != nil || again[confKeyDirectory] != tt.want {
+				t.Errorf("readConfig() after writeConfig() = %q, %v, want the directory %q", again, err, tt.want)
+			}
+		})
+	}
+	confPath := filepath.Join(t.TempDir(), "cleanrc")
+	if err := ioutil.WriteFile(confPath, []byte("module=app\n/data/shop\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readConfig(confPath); err == nil || !strings.Contains(err.Error(), "the line 2") {
+		t.Errorf("readConfig() of a line without = returned %v, want an error naming the line 2", err)
+	}
+}
+
+// TestProjectFolderWithSpecialCharacters asserts that a project in a folder whose path contains '=', '#',
+// spaces and letters outside ASCII gets its files there
+func TestProjectFolderWithSpecialCharacters(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "a=b #1 données 店")
+	p := &testProject{t: t, home: filepath.Join(root, "home"), dir: filepath.Join(root, "app")}
+	p.write("../home/.keep", "")
+	p.write("go.mod", "module app\n\ngo 1.21\n")
+	p.clean("--no-history", "init")
+	p.clean("add", "interactor", "Order")
+	if !p.exists("clean/usecase/interactor/order.go") {
+		t.Errorf("the Interactor wasn't added to the project folder")
+	}
+	if conf := p.read("../home/.clean/cleanrc"); !strings.Contains(conf, "directory="+p.dir+"/\n") {
+		t.Errorf("the configuration file doesn't hold the project folder:\n%s", conf)
+	}
+}
+
+// BenchmarkRefreshModuleConfigWarm refreshes the module path of a project nested in its module, which is
+// already resolved and whose go.mod file is unchanged, as each command does before it runs
+func BenchmarkRefreshModuleConfigWarm(b *testing.B) {
+	root := b.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(root, goModFileName), []byte("module example.com/app\n\ngo 1.21\n"), 0600); err != nil {
+		b.Fatal(err)
+	}
+	dir := filepath.Join(root, "services", "orders")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		b.Fatal(err)
+	}
+	conf := make(map[string]string)
+	setModuleConfig(conf, dir, "")
+	if conf[confKeyModule] != "example.com/app/services/orders" {
+		b.Fatalf("the module path resolves to %q", conf[confKeyModule])
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if refreshModuleConfig(conf, dir) {
+			b.Fatalf("the module path was resolved again although the go.mod file is unchanged")
+		}
+	}
+}
@@ -0,0 +1,464 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	gofmt "go/format"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	objDecorator = "decorator"
+	// decoratorHeader heads the decorator files, whose usecase methods are added and removed by clean as the
+	// usecases of their interactor change while any other edits are kept
+	decoratorHeader = "// This file was generated by clean, which adds and removes the methods of the usecases as they're\n// added to and removed from the Interactor. Edits to the rest of the file are kept.\n\n"
+	// decoratorHelperHeader heads the helper files of the decorators, which are only generated once
+	decoratorHelperHeader = "// This file was generated by clean.\n\n"
+)
+
+// decoratorKind is a kind of decorator of the Interactors e.g. logging. A decorator of the kind embeds the
+// Interactor interface it wraps and each of its methods runs the code of the kind around the call of the
+// wrapped Interactor's method.
+type decoratorKind struct {
+	// desc completes the doc comment of the decorator e.g. "logs the calls of the usecases of"
+	desc string
+	// minGoVersion is the minor version of the first Go release with the packages the decorator imports
+	minGoVersion int
+	// imports are the import paths the code of the kind refers to
+	imports []string
+	// fields are the fields of the decorator, besides the wrapped Interactor, which are also the leading
+	// parameters of its constructor e.g. "logger *slog.Logger"
+	fields []string
+	// before returns the code run before the call of the usecase method, which must declare any variables
+	// after uses
+	before func(m decoratedMethod) string
+	// after returns the code run after the call of the usecase method, whose results are r0, r1 and so on
+	after func(m decoratedMethod) string
+	// helperFile is the name of a file of the interactor folder holding helpers of the decorators of the
+	// kind, which is added together with the first of them unless it's empty
+	helperFile string
+	// helperSource returns the declarations of the helper file, following its package clause
+	helperSource func() string
+}
+
+// decoratedMethod is a usecase method of a decorator
+type decoratedMethod struct {
+	// interactor is the name of the interactor e.g. Order
+	interactor string
+	// self is the receiver of the method
+	self string
+	// params are the names of the parameters of the method
+	params []string
+	mockMethod
+}
+
+// fails reports whether m can fail i.e. whether it returns an error or an ErrVal
+func (m decoratedMethod) fails() bool {
+	for _, typ := range m.results {
+		if typ == "error" || strings.HasSuffix(typ, "ErrVal") {
+			return true
+		}
+	}
+	return false
+}
+
+// paramOfType returns the name of the first parameter of m of the type typ or an empty string if it has none
+func (m decoratedMethod) paramOfType(typ string) string {
+	for i, t := range m.types {
+		if t == typ {
+			return m.params[i]
+		}
+	}
+	return ""
+}
+
+// metricName returns the name of the metric of the usecase of the interactor with the suffix e.g.
+// order_add_item_calls_total for the calls of the usecase AddItem of Order
+func metricName(interactor, usecase, suffix string) string {
+	return strings.ToLower(strings.Join(append(splitWords(interactor), splitWords(usecase)...), "_")) + "_" + suffix
+}
+
+// decoratorKinds are the kinds of decorators by name
+var decoratorKinds = map[string]decoratorKind{
+	"logging": {
+		desc:         "logs the start, the end and the duration of the usecases of",
+		minGoVersion: 21,
+		imports:      []string{"log/slog", "time"},
+		fields:       []string{"logger *slog.Logger"},
+		before: func(m decoratedMethod) string {
+			return fmt.Sprintf("start := time.Now()\n%s.logger.Info(\"usecase started\", \"interactor\", %q, \"usecase\", %q)\n", m.self, m.interactor, m.name)
+		},
+		after: func(m decoratedMethod) string {
+			return fmt.Sprintf("%s.logger.Info(\"usecase finished\", \"interactor\", %q, \"usecase\", %q, \"duration\", time.Since(start))\n", m.self, m.interactor, m.name)
+		},
+	},
+	"tracing": {
+		desc:    "traces, with an OpenTelemetry span per usecase,",
+		imports: []string{"context"},
+		before: func(m decoratedMethod) string {
+			// Continue the trace of the context the usecase is called with if it has one
+			code := fmt.Sprintf("_, span := startSpan(context.Background(), %q)\n", m.interactor+"."+m.name)
+			if ctx := m.paramOfType("context.Context"); ctx != "" {
+				code = fmt.Sprintf("%s, span := startSpan(%s, %q)\n", ctx, ctx, m.interactor+"."+m.name)
+			}
+			if m.fails() {
+				code += "var err error\n"
+			}
+			return code
+		},
+		after: func(m decoratedMethod) string {
+			if !m.fails() {
+				return "endSpan(span, nil)\n"
+			}
+			var b strings.Builder
+			for i, typ := range m.results {
+				switch {
+				case typ == "error":
+					fmt.Fprintf(&b, "if r%d != nil {\nerr = r%d\n}\n", i, i)
+				case strings.HasSuffix(typ, "ErrVal"):
+					fmt.Fprintf(&b, "if r%d != nil {\nerr = errInvalidInput\n}\n", i)
+				}
+			}
+			b.WriteString("endSpan(span, err)\n")
+			return b.String()
+		},
+		helperFile: "tracing.go",
+		helperSource: func() string {
+			return fmt.Sprintf(`
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the name of the OpenTelemetry tracer of the tracing decorators
+const tracerName = %q
+
+// errInvalidInput is recorded by the tracing decorators if the input of a usecase is invalid.
+var errInvalidInput = errors.New("invalid input")
+
+// startSpan starts the span name, e.g. Order.AddItem, of a usecase with the tracer of the global
+// TracerProvider. The tracing decorators only depend on OpenTelemetry through startSpan and endSpan.
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name)
+}
+
+// endSpan ends span, which records err and is marked as failed unless err is nil.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+`, projectBaseImportPath+"clean/"+strings.TrimSuffix(relPathInteractor, "/"))
+		},
+	},
+	"metrics": {
+		desc:    "counts the calls and measures the latency of the usecases of",
+		imports: []string{"time"},
+		fields:  []string{"metrics Metrics"},
+		before: func(m decoratedMethod) string {
+			return fmt.Sprintf("%s.metrics.IncCalls(%q)\nstart := time.Now()\n", m.self, metricName(m.interactor, m.name, "calls_total"))
+		},
+		after: func(m decoratedMethod) string {
+			return fmt.Sprintf("%s.metrics.ObserveLatency(%q, time.Since(start))\n", m.self, metricName(m.interactor, m.name, "duration_seconds"))
+		},
+		helperFile: "metrics.go",
+		helperSource: func() string {
+			return `
+
+import (
+	"time"
+)
+
+// Metrics records the metrics of the usecases measured by the metrics decorators. Implement it to adapt
+// e.g. Prometheus or expvar. The names of the metrics are derived from the names of the interactor and the
+// usecase e.g. order_add_item_calls_total and order_add_item_duration_seconds for AddItem of Order.
+type Metrics interface {
+	// IncCalls increments the counter name of the calls of a usecase.
+	IncCalls(name string)
+	// ObserveLatency records the duration d of a call of a usecase in the histogram name.
+	ObserveLatency(name string, d time.Duration)
+}
+`
+		},
+	},
+}
+
+// decoratorKindNames returns the names of the kinds of decorators in alphabetical order
+func decoratorKindNames() []string {
+	var names []string
+	for name := range decoratorKinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// decoratorFile returns the path of the file of the decorator of the kind of the interactor e.g.
+// order_logging.go
+func decoratorFile(basePath, kind, interactor string) string {
+	return filepath.FromSlash(basePath + relPathInteractor + fileName(interactor) + "_" + kind + ".go")
+}
+
+// decoratorSource returns the source of the decorator of the kind of the interactor, which has a method
+// per usecase of the interactor's current Interactor interface
+func decoratorSource(basePath, kind, interactor string) ([]byte, error) {
+	dk, ok := decoratorKinds[kind]
+	if !ok {
+		return nil, fmt.Errorf("invalid decorator %q, the decorators are %s", kind, strings.Join(decoratorKindNames(), ", "))
+	}
+	fp := filepath.FromSlash(basePath + relPathInteractor + fileName(interactor) + ".go")
+	f, err := parseGoFile(fp)
+	if err != nil {
+		return nil, err
+	}
+	ifName := typeName(objInteractor, interactor)
+	pkgs := make(map[string]bool)
+	methods, err := interfaceMethodSet(f, ifName, pkgs)
+	if err != nil {
+		return nil, err
+	}
+	imports := importsOf(f, pkgs)
+	for _, path := range dk.imports {
+		imports = append(imports, strconv.Quote(path))
+	}
+	implName := decoratorImplName(kind, interactor)
+	ctorName := "New" + exportedName(kind) + exportedName(ifName)
+	self := receiverName(implName)
+	// Keep the receiver of a decorator generated with another receiver style
+	if old, err := readFile(decoratorFile(basePath, kind, interactor)); err == nil {
+		if recv := implReceiver(old, unexportedName(implName)); recv != "" {
+			self = recv
+		}
+	}
+	var fieldNames []string
+	for _, field := range dk.fields {
+		fieldNames = append(fieldNames, strings.Fields(field)[0])
+	}
+
+	var b bytes.Buffer
+	b.WriteString(decoratorHeader)
+	b.WriteString(packageClause(basePath+relPathInteractor, objInteractor))
+	fmt.Fprintf(&b, "\n\nimport (\n%s)\n", importBlock(imports))
+	b.WriteString("\n" + commentBlock(fmt.Sprintf("%s is a decorator of the %s Interactor which %s the %s Interactor it wraps.", implName, ifName, dk.desc, ifName), ""))
+	fmt.Fprintf(&b, "type %s struct {\n\t%s\n", implName, strings.Join(append([]string{ifName}, dk.fields...), "\n\t"))
+	fmt.Fprintf(&b, "}\n\n// %s wraps next in a %s decorator.\nfunc %s(%s) %s {\n\treturn &%s{\n\t\t%s: next,\n", ctorName, kind, ctorName, strings.Join(append(append([]string{}, dk.fields...), "next "+ifName), ", "), ifName, implName, ifName)
+	for _, name := range fieldNames {
+		fmt.Fprintf(&b, "\t\t%s: %s,\n", name, name)
+	}
+	b.WriteString("\t}\n}\n")
+	for _, m := range methods {
+		var params, names, args []string
+		for i, p := range m.params {
+			if m.names[i] != "" && m.names[i] != "_" {
+				p = m.names[i]
+			}
+			params = append(params, p+" "+m.types[i])
+			names = append(names, p)
+			arg := p
+			if strings.HasPrefix(m.types[i], "...") {
+				arg += "..."
+			}
+			args = append(args, arg)
+		}
+		call := fmt.Sprintf("%s.%s.%s(%s)", self, ifName, m.name, strings.Join(args, ", "))
+		dm := decoratedMethod{interactor: exportedName(interactor), self: self, params: names, mockMethod: m}
+		after := dk.after(dm)
+		var body string
+		if len(m.results) > 0 {
+			var results []string
+			for i := range m.results {
+				results = append(results, fmt.Sprintf("r%d", i))
+			}
+			body = fmt.Sprintf("%s := %s\n%sreturn %s\n", strings.Join(results, ", "), call, after, strings.Join(results, ", "))
+		} else {
+			body = call + "\n" + after
+		}
+		fmt.Fprintf(&b, "\n// %s implements the %s interface method %s by delegating it to the wrapped %s.\nfunc (%s *%s) %s(%s)%s {\n%s%s}\n", m.name, ifName, m.name, ifName, self, implName, m.name, strings.Join(params, ", "), resultList(m.results), dk.before(dm), body)
+	}
+	return gofmt.Source(b.Bytes())
+}
+
+// decoratorImplName returns the name of the decorator of the kind of the interactor e.g. loggingOrder
+func decoratorImplName(kind, interactor string) string {
+	return unexportedName(kind) + exportedName(typeName(objInteractor, interactor))
+}
+
+// mergeDecorator returns the decorator file old updated to have the usecase methods of the freshly generated
+// decorator src: the methods of new usecases are added, along with their imports, and the methods of removed
+// usecases are removed along with the imports only they used. The rest of old, e.g. the edited methods of the
+// existing usecases, is kept.
+func mergeDecorator(old, src []byte, implName string) ([]byte, error) {
+	fset := token.NewFileSet()
+	oldF, err := parseFile(fset, "", old, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	srcFset := token.NewFileSet()
+	srcF, err := parseFile(srcFset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	generated := decoratorMethods(srcF, implName)
+	existing := decoratorMethods(oldF, implName)
+	// Remove the methods of the removed usecases, from the last one so that the offsets stay valid
+	var removed []*ast.FuncDecl
+	for name, fd := range existing {
+		if _, ok := generated[name]; !ok {
+			removed = append(removed, fd)
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool { return removed[i].Pos() > removed[j].Pos() })
+	b := append([]byte{}, old...)
+	for _, fd := range removed {
+		start, end := fset.Position(fd.Pos()).Offset, fset.Position(fd.End()).Offset
+		if fd.Doc != nil {
+			start = fset.Position(fd.Doc.Pos()).Offset
+		}
+		for start > 0 && b[start-1] == '\n' {
+			start--
+		}
+		b = append(b[:start], b[end:]...)
+	}
+	// Add the methods of the new usecases in the order of the Interactor interface
+	var added []*ast.FuncDecl
+	for name, fd := range generated {
+		if _, ok := existing[name]; !ok {
+			added = append(added, fd)
+		}
+	}
+	sort.Slice(added, func(i, j int) bool { return added[i].Pos() < added[j].Pos() })
+	b = bytes.TrimRight(b, "\n")
+	for _, fd := range added {
+		start := srcFset.Position(fd.Doc.Pos()).Offset
+		b = append(append(b, "\n\n"...), src[start:srcFset.Position(fd.End()).Offset]...)
+	}
+	b = append(b, '\n')
+	if len(added) > 0 {
+		for _, spec := range srcF.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				return nil, err
+			}
+			if b, err = ensureImport(b, path); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(removed) > 0 {
+		if b, err = pruneImports(b); err != nil {
+			return nil, err
+		}
+	}
+	return gofmt.Source(b)
+}
+
+// decoratorMethods returns the methods of the decorator implName declared in f by name
+func decoratorMethods(f *ast.File, implName string) map[string]*ast.FuncDecl {
+	methods := make(map[string]*ast.FuncDecl)
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Recv == nil || len(fd.Recv.List) != 1 {
+			continue
+		}
+		if star, ok := fd.Recv.List[0].Type.(*ast.StarExpr); ok {
+			if id, ok := star.X.(*ast.Ident); ok && id.Name == implName {
+				methods[fd.Name.Name] = fd
+			}
+		}
+	}
+	return methods
+}
+
+// addDecorator adds the decorator of the kind, e.g. logging, of the interactor to the interactor folder of the
+// project at basePath. Once added it's kept up to date by updateDecorators.
+func addDecorator(projectPath, basePath, kind, interactor string) error {
+	dk, ok := decoratorKinds[kind]
+	if !ok {
+		return fmt.Errorf("invalid decorator %q, the decorators are %s", kind, strings.Join(decoratorKindNames(), ", "))
+	}
+	if !fileExists(filepath.FromSlash(basePath + relPathInteractor + fileName(interactor) + ".go")) {
+		return fmt.Errorf("the interactor %s doesn't exist", exportedName(interactor))
+	}
+	if dk.minGoVersion > 0 {
+		if err := requireGoVersion(projectPath, dk.minGoVersion, kind+" decorators"); err != nil {
+			return err
+		}
+	}
+	fp := decoratorFile(basePath, kind, interactor)
+	if fileExists(fp) {
+		noopf("the %s decorator of %s already exists", kind, exportedName(interactor))
+		return nil
+	}
+	src, err := decoratorSource(basePath, kind, interactor)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(fp, src); err != nil {
+		return err
+	}
+	if helperFp := filepath.FromSlash(basePath + relPathInteractor + dk.helperFile); dk.helperFile != "" && !fileExists(helperFp) {
+		return writeFile(helperFp, []byte(decoratorHelperHeader+packageClause(basePath+relPathInteractor, objInteractor)+dk.helperSource()))
+	}
+	return nil
+}
+
+// updateDecorators updates the decorators of the interactors of the project at basePath so that they decorate
+// the current usecases, keeping any edits of the methods of the existing usecases. Nothing is done if the
+// command failed.
+func updateDecorators(basePath string) {
+	if len(errorMessages) > 0 || !fileExists(filepath.FromSlash(basePath+relPathInteractor)) {
+		return
+	}
+	interactors, err := interactorUsecases(basePath)
+	if err != nil {
+		return
+	}
+	var names []string
+	for name := range interactors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, interactor := range names {
+		for _, kind := range decoratorKindNames() {
+			fp := decoratorFile(basePath, kind, interactor)
+			if !fileExists(fp) {
+				continue
+			}
+			src, err := decoratorSource(basePath, kind, interactor)
+			if err != nil {
+				failf("Error updating the %s decorator of %s: %s\n\n", kind, exportedName(interactor), err.Error())
+				continue
+			}
+			old, err := readFile(fp)
+			if err == nil {
+				src, err = mergeDecorator(old, src, decoratorImplName(kind, interactor))
+			}
+			if err != nil {
+				failf("Error updating the %s decorator of %s: %s\n\n", kind, exportedName(interactor), err.Error())
+				continue
+			}
+			if bytes.Equal(old, src) {
+				continue
+			}
+			if err := rewriteFile(fp, src); err != nil {
+				failf("Error updating the %s decorator of %s: %s\n\n", kind, exportedName(interactor), err.Error())
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// tracingSpansTest is a test of the generated tracing decorator, run in the generated project, which records
+// the spans with the in-memory exporter of the OpenTelemetry SDK
+const tracingSpansTest = `package interactor
+
+import (
+	"testing"
+
+	"app/clean/usecase/reqmodel"
+	"app/clean/usecase/respmodel"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type stubOrder struct{}
+
+func (stubOrder) AddItem(rqm *reqmodel.AddItem) {}
+
+func (stubOrder) Pay(rqm *reqmodel.Pay) *respmodel.PayErrVal { return &respmodel.PayErrVal{} }
+
+func TestTracingSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	o := NewTracingOrder(stubOrder{})
+	o.AddItem(&reqmodel.AddItem{})
+	o.Pay(&reqmodel.Pay{})
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	if spans[0].Name != "Order.AddItem" || spans[0].Status.Code == codes.Error || len(spans[0].Events) != 0 {
+		t.Errorf("got span %s with status %v and %d events, want Order.AddItem without an error", spans[0].Name, spans[0].Status.Code, len(spans[0].Events))
+	}
+	if spans[1].Name != "Order.Pay" || spans[1].Status.Code != codes.Error || len(spans[1].Events) != 1 {
+		t.Errorf("got span %s with status %v and %d events, want Order.Pay with the error recorded", spans[1].Name, spans[1].Status.Code, len(spans[1].Events))
+	}
+}
+`
+
+// TestGenerateWiresDecoratorsAndRoutes asserts that a usecase added by clean generate from a directive is
+// delegated by the existing decorator and routed like a usecase added by clean add usecase
+func TestGenerateWiresDecoratorsAndRoutes(t *testing.T) {
+	p := newTestProject(t)
+	p.clean("add", "interactor", "Order")
+	p.clean("add", "routes")
+	p.clean("add", "decorator", "tracing", "for", "Order")
+	const fp = "clean/usecase/interactor/order.go"
+	p.write(fp, strings.Replace(p.read(fp), "type Order interface {", "//clean:usecase AddItem\ntype Order interface {", 1))
+	p.clean("generate")
+
+	if got := p.read("clean/usecase/interactor/order_tracing.go"); !strings.Contains(got, "func (t *tracingOrder) AddItem(") {
+		t.Errorf("the tracing decorator doesn't delegate AddItem:\n%s", got)
+	}
+	if got := p.read("clean/ifadapter/controller/routes.go"); !strings.Contains(got, `mux.HandleFunc("/order/add-item"`) {
+		t.Errorf("AddItem isn't routed:\n%s", got)
+	}
+}
+
+// TestTracingDecoratorSpans generates the tracing decorator of an Order with a usecase which can't fail and
+// one presenting an ErrVal, and runs a test in the generated project which asserts that each usecase starts
+// a span and that the failed one records its error. It's skipped if the OpenTelemetry modules can't be
+// downloaded.
+func TestTracingDecoratorSpans(t *testing.T) {
+	if testing.Short() {
+		t.Skip("the test builds the generated project")
+	}
+	p := newTestProject(t)
+	p.clean("add", "interactor", "Order")
+	p.clean("add", "usecase", "AddItem", "to", "Order")
+	p.clean("add", "usecase", "Pay", "to", "Order", "--explicit-errval")
+	p.clean("add", "decorator", "tracing", "for", "Order")
+	p.write("clean/usecase/interactor/tracing_spans_test.go", tracingSpansTest)
+
+	if out, err := p.goRun("get", "go.opentelemetry.io/otel@v1.44.0", "go.opentelemetry.io/otel/sdk@v1.44.0"); err != nil {
+		t.Skipf("the OpenTelemetry modules aren't available: %s\n%s", err.Error(), out)
+	}
+	if out, err := p.goRun("test", "./clean/usecase/interactor/"); err != nil {
+		t.Errorf("the test of the tracing decorator failed: %s\n%s", err.Error(), out)
+	}
+}
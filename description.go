@@ -0,0 +1,169 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// commentWidth is the width at which long lines of descriptions are wrapped
+	commentWidth = 80
+	// docFileName is the name of the file holding the package comment of a generated package
+	docFileName = "doc.go"
+
+	// commentsVerbose is the comment style explaining the role of each generated type and method
+	commentsVerbose = "verbose"
+	// commentsTerse is the comment style with a single line comment per generated type and method
+	commentsTerse = "terse"
+)
+
+// commentBlock returns text as a block of line comments, each preceded by indent and ending with a
+// newline. Each line of text becomes at least one comment line and long lines are wrapped at commentWidth.
+func commentBlock(text, indent string) string {
+	var b bytes.Buffer
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		words := strings.Fields(line)
+		if len(words) == 0 {
+			b.WriteString(indent + "//\n")
+			continue
+		}
+		current := indent + "// " + words[0]
+		for _, w := range words[1:] {
+			if len(current)+1+len(w) > commentWidth {
+				b.WriteString(current + "\n")
+				current = indent + "// " + w
+				continue
+			}
+			current += " " + w
+		}
+		b.WriteString(current + "\n")
+	}
+	return b.String()
+}
+
+// objDoc returns the doc comment of the interface name of a generated object of type objType e.g.
+// Controller, followed by the description desc. Unless --terse is set the comment explains the object.
+func objDoc(name, objType, desc string) string {
+	if *terse {
+		doc := fmt.Sprintf("// %s is %s %s.", name, article(objType), objType)
+		if strings.TrimSpace(desc) != "" {
+			doc += "\n" + typeDesc(desc)
+		}
+		return doc
+	}
+	return fmt.Sprintf("// %s is a Clean Architecture %s object that wraps its related methods.\n%s", name, objType, typeDesc(desc))
+}
+
+// docComment returns the comment verbose, which explains the generated code, unless --terse is set in which
+// case it returns the minimal comment brief. Both must begin with the name of what they document.
+func docComment(verbose, brief string) string {
+	if *terse {
+		return brief
+	}
+	return verbose
+}
+
+// article returns the indefinite article of word e.g. "an" for Interactor
+func article(word string) string {
+	if word != "" && strings.ContainsRune("AEIOUaeiou", rune(word[0])) {
+		return "an"
+	}
+	return "a"
+}
+
+// setCommentStyle sets the style of the generated doc comments to style, which is either verbose or terse.
+// An empty style keeps the verbose default unless --terse is set.
+func setCommentStyle(style string) error {
+	switch style {
+	case "", commentsVerbose:
+	case commentsTerse:
+		*terse = true
+	default:
+		return fmt.Errorf("invalid %s=%s setting in the configuration file, the comment styles are %s and %s", confKeyComments, style, commentsVerbose, commentsTerse)
+	}
+	return nil
+}
+
+// typeDesc returns the comment lines describing a generated type. If desc is empty a TODO is returned.
+func typeDesc(desc string) string {
+	if strings.TrimSpace(desc) == "" {
+		return "// TODO: Add description of what the interface does"
+	}
+	return strings.TrimSuffix(commentBlock(desc, ""), "\n")
+}
+
+// placeholderPackageComment returns the package comment written to new files of the package pkg
+// until its description is set with "clean set desc".
+func placeholderPackageComment(pkg string) string {
+	return fmt.Sprintf("// Package %s provides ...", pkg)
+}
+
+// packageClause returns the package clause of a new file in the package folder dir of the package pkg.
+// Unless the package has a doc.go file the package clause is preceded by a placeholder package comment.
+func packageClause(dir, pkg string) string {
+	if fileExists(filepath.Join(filepath.FromSlash(dir), docFileName)) {
+		return fmt.Sprintf("package %s", pkg)
+	}
+	return fmt.Sprintf("%s\npackage %s", placeholderPackageComment(pkg), pkg)
+}
+
+// setPackageDesc writes desc as the package comment of the doc.go file of the package folder dir
+// and removes the placeholder package comments from the other files of the package.
+func setPackageDesc(dir, pkg, desc string) error {
+	dir = filepath.FromSlash(dir)
+	if !fileExists(dir) {
+		return fmt.Errorf("the package folder %s doesn't exist", dir)
+	}
+	desc = strings.TrimSpace(desc)
+	if !strings.HasPrefix(desc, "Package "+pkg+" ") {
+		desc = "Package " + pkg + " " + desc
+	}
+	content := commentBlock(desc, "") + "package " + pkg + "\n"
+	if err := writeFile(filepath.Join(dir, docFileName), []byte(content)); err != nil {
+		return err
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	placeholder := placeholderPackageComment(pkg)
+	for _, fi := range files {
+		if fi.IsDir() || fi.Name() == docFileName || filepath.Ext(fi.Name()) != ".go" {
+			continue
+		}
+		fp := filepath.Join(dir, fi.Name())
+		b, err := readFile(fp)
+		if err != nil {
+			return err
+		}
+		// The placeholder is the package comment, which follows any build constraints of the file
+		fset := token.NewFileSet()
+		f, err := parseFile(fset, fp, b, parser.PackageClauseOnly|parser.ParseComments)
+		if err != nil || f.Doc == nil {
+			continue
+		}
+		for _, c := range f.Doc.List {
+			if c.Text != placeholder {
+				continue
+			}
+			// Remove the placeholder including any trailing space and the newline
+			start, end := fset.Position(c.Pos()).Offset, fset.Position(c.End()).Offset
+			if ix := bytes.IndexByte(b[end:], '\n'); ix != -1 {
+				end += ix + 1
+			}
+			if err := writeFile(fp, append(append([]byte{}, b[:start]...), b[end:]...)); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around the changed lines of a diff
+const diffContext = 3
+
+const (
+	colorReset = "\x1b[0m"
+	colorBold  = "\x1b[1m"
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorCyan  = "\x1b[36m"
+)
+
+// diffProject prints a unified diff between the files of the interactors of the project at basePath
+// and the stubs Clean would generate for them today. All interactors are diffed if names is empty.
+// The bodies of the functions and methods are ignored, so implemented methods aren't reported.
+func diffProject(projectPath, basePath string, names []string) error {
+	interactors, err := interactorUsecases(basePath)
+	if err != nil {
+		return err
+	}
+	var selectedNames []string
+	if len(names) == 0 {
+		for name := range interactors {
+			selectedNames = append(selectedNames, name)
+		}
+	}
+	for _, name := range names {
+		found := false
+		for v := range interactors {
+			if strings.EqualFold(exportedName(v), exportedName(name)) {
+				selectedNames = append(selectedNames, v)
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("the interactor %s doesn't exist", name)
+		}
+	}
+	sort.Strings(selectedNames)
+	stubs := regenerate(basePath, selectedNames, interactors)
+	color := isTerminal(os.Stdout)
+	var paths []string
+	for fp := range stubs {
+		paths = append(paths, fp)
+	}
+	sort.Strings(paths)
+	differs := false
+	for _, fp := range paths {
+		current, err := readFile(fp)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		current = withBodiesOf(current, stubs[fp])
+		relFp, err := filepath.Rel(filepath.FromSlash(projectPath), fp)
+		if err != nil {
+			relFp = fp
+		}
+		relFp = filepath.ToSlash(relFp)
+		if d := unifiedDiff("a/"+relFp, "b/"+relFp, current, stubs[fp], color); d != "" {
+			fmt.Printf("%s", d)
+			differs = true
+		}
+	}
+	if !differs {
+		fmt.Printf("The files match the generated stubs\n")
+	}
+	return nil
+}
+
+// regenerate generates the files of the interactors and their usecases in memory and returns their
+// content keyed by the paths the files have in the project at basePath. The outcome of the command
+// and the files it has pending with --stdout aren't affected.
+func regenerate(basePath string, names []string, interactors map[string][]string) map[string][]byte {
+	tmpBase := filepath.ToSlash(filepath.Join(os.TempDir(), fmt.Sprintf("clean-diff-%d", os.Getpid()))) + "/clean/"
+	savedStdout, savedChanged, savedNoops := *stdout, changedFiles, noops
+	savedPending, savedOrder, savedCreated := pendingFiles, pendingOrder, createdFiles
+	pendingFiles, pendingOrder, createdFiles = make(map[string][]byte), nil, make(map[string]bool)
+	*stdout = true
+	// Use the package comments of the project so that they don't show up as differences
+	for _, relPath := range packageRelPaths {
+		if b, err := readFile(filepath.FromSlash(basePath + relPath + docFileName)); err == nil {
+			pendingFiles[filepath.FromSlash(tmpBase+relPath+docFileName)] = b
+		}
+	}
+	for _, name := range names {
+		addInteractor(tmpBase, name, "")
+		// Each usecase is added above the ones before it, so the usecases listed in file order are replayed
+		// from the last one to be declared in the same order
+		usecases := interactors[name]
+		for i := len(usecases) - 1; i >= 0; i-- {
+			addUsecase(tmpBase, usecases[i], name)
+			if !*noTest {
+				if err := addUsecaseTests(tmpBase, usecases[i], name); err != nil {
+					failf("Error generating the tests of %s: %s\n", usecases[i], err.Error())
+				}
+			}
+		}
+	}
+	stubs := make(map[string][]byte)
+	for _, fp := range pendingOrder {
+		rel := strings.TrimPrefix(filepath.ToSlash(fp), tmpBase)
+		stubs[filepath.FromSlash(basePath+rel)] = pendingFiles[fp]
+	}
+	*stdout, changedFiles, noops = savedStdout, savedChanged, savedNoops
+	pendingFiles, pendingOrder, createdFiles = savedPending, savedOrder, savedCreated
+	return stubs
+}
+
+// withBodiesOf returns the Go source b with the bodies of its functions and methods replaced by the bodies
+// of the functions and methods of the same names in ref. b is returned as is if either of them doesn't parse.
+func withBodiesOf(b, ref []byte) []byte {
+	fset := token.NewFileSet()
+	f, err := parseFile(fset, "", b, parser.ParseComments)
+	if err != nil {
+		return b
+	}
+	refFset := token.NewFileSet()
+	refFile, err := parseFile(refFset, "", ref, parser.ParseComments)
+	if err != nil {
+		return b
+	}
+	refBodies := make(map[string][]byte)
+	for _, decl := range refFile.Decls {
+		if fd, ok := decl.(*ast.FuncDecl); ok && fd.Body != nil {
+			refBodies[recvTypeName(fd)+"."+fd.Name.Name] = ref[refFset.Position(fd.Body.Pos()).Offset:refFset.Position(fd.Body.End()).Offset]
+		}
+	}
+	newb := append([]byte{}, b...)
+	// Replace the bodies from the end so that the offsets of the preceding bodies stay valid
+	for i := len(f.Decls) - 1; i >= 0; i-- {
+		fd, ok := f.Decls[i].(*ast.FuncDecl)
+		if !ok || fd.Body == nil {
+			continue
+		}
+		body, ok := refBodies[recvTypeName(fd)+"."+fd.Name.Name]
+		if !ok {
+			continue
+		}
+		start, end := fset.Position(fd.Body.Pos()).Offset, fset.Position(fd.Body.End()).Offset
+		newb = append(append(append([]byte{}, newb[:start]...), body...), newb[end:]...)
+	}
+	return newb
+}
+
+// isTerminal reports whether f is a terminal
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// diffOp is an operation of a line diff, which is one of ' ', '-' and '+'
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// diffLines returns the operations turning the lines a into the lines b, based on their longest common subsequence
+func diffLines(a, b []string) []diffOp {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	return ops
+}
+
+// splitLines splits b into lines without their line endings
+func splitLines(b []byte) []string {
+	if len(b) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+}
+
+// unifiedDiff returns the unified diff of a and b, named aName and bName, or an empty string if they're
+// equal. The diff is colorized with ANSI escape codes if color is true.
+func unifiedDiff(aName, bName string, a, b []byte, color bool) string {
+	if bytes.Equal(a, b) {
+		return ""
+	}
+	paint := func(c, s string) string {
+		if !color {
+			return s
+		}
+		return c + s + colorReset
+	}
+	if len(a) == 0 {
+		aName = "/dev/null"
+	}
+	ops := diffLines(splitLines(a), splitLines(b))
+	var out bytes.Buffer
+	out.WriteString(paint(colorBold, fmt.Sprintf("--- %s\n+++ %s", aName, bName)) + "\n")
+	for start := 0; start < len(ops); {
+		// Find the next changed line and the end of the hunk around it
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		first := start - diffContext
+		if first < 0 {
+			first = 0
+		}
+		end, unchanged := start, 0
+		for end < len(ops) && unchanged <= 2*diffContext {
+			if ops[end].kind == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+			end++
+		}
+		if unchanged > diffContext {
+			end -= unchanged - diffContext
+		}
+		// Line numbers of the hunk in a and b
+		aLine, bLine := 1, 1
+		for _, op := range ops[:first] {
+			if op.kind != '+' {
+				aLine++
+			}
+			if op.kind != '-' {
+				bLine++
+			}
+		}
+		var aCount, bCount int
+		for _, op := range ops[first:end] {
+			if op.kind != '+' {
+				aCount++
+			}
+			if op.kind != '-' {
+				bCount++
+			}
+		}
+		if aCount == 0 {
+			aLine--
+		}
+		if bCount == 0 {
+			bLine--
+		}
+		out.WriteString(paint(colorCyan, fmt.Sprintf("@@ -%d,%d +%d,%d @@", aLine, aCount, bLine, bCount)) + "\n")
+		for _, op := range ops[first:end] {
+			line := string(op.kind) + op.line
+			switch op.kind {
+			case '-':
+				line = paint(colorRed, line)
+			case '+':
+				line = paint(colorGreen, line)
+			}
+			out.WriteString(line + "\n")
+		}
+		start = end
+	}
+	return out.String()
+}
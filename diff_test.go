@@ -0,0 +1,26 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestDiffOfFreshProject asserts that a project with several usecases, added one after the other, matches
+// its regenerated stubs, and that a change to a signature still shows up
+func TestDiffOfFreshProject(t *testing.T) {
+	p := newTestProject(t)
+	p.clean("add", "interactor", "Order")
+	p.clean("add", "usecase", "AddItem", "to", "Order")
+	p.clean("add", "usecase", "RemoveItem", "to", "Order")
+	p.clean("add", "usecase", "Checkout", "to", "Order")
+	out := p.clean("diff")
+	if !strings.Contains(out, "The files match the generated stubs") {
+		t.Fatalf("the fresh project differs from the generated stubs:\n%s", out)
+	}
+
+	fp := "clean/usecase/interactor/order.go"
+	p.write(fp, strings.Replace(p.read(fp), "RemoveItem(", "RemoveItems(", 1))
+	if out := p.clean("diff"); strings.Contains(out, "The files match the generated stubs") {
+		t.Errorf("the renamed usecase doesn't show up in the diff:\n%s", out)
+	}
+}
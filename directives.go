@@ -0,0 +1,358 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	verbGenerate = "generate"
+	// directivePrefix starts the directive comments declaring the usecases of an interactor in its Interactor
+	// file e.g. //clean:usecase AddItem fields:"sku:string qty:int"
+	directivePrefix = "//clean:"
+	// goGenerateLine makes go generate ./... run clean generate
+	goGenerateLine = "//go:generate clean generate"
+)
+
+// directive is a usecase declared by a directive comment
+type directive struct {
+	// fp is the file of the directive
+	fp string
+	// pos is the file and line of the directive
+	pos string
+	// interactor is the interactor of the Interactor file the directive is in
+	interactor string
+	spec       namedSpec
+}
+
+// parseDirective parses the text of the directive comment following directivePrefix e.g.
+// usecase AddItem fields:"sku:string qty:int". The fields are separated by spaces and their names and types
+// by colons. The names are exported like the names of the fields read from stdin.
+func parseDirective(text string) (namedSpec, error) {
+	pieces := strings.SplitN(strings.TrimSpace(text), " ", 3)
+	if pieces[0] != objUsecase {
+		return namedSpec{}, fmt.Errorf("unknown directive %s%s, the directives are %s%s", directivePrefix, pieces[0], directivePrefix, objUsecase)
+	}
+	if len(pieces) < 2 || !token.IsIdentifier(pieces[1]) {
+		return namedSpec{}, fmt.Errorf("the directive %s%s must be followed by the name of the usecase", directivePrefix, objUsecase)
+	}
+	spec := namedSpec{Name: exportedName(pieces[1])}
+	if err := checkName(spec.Name); err != nil {
+		return namedSpec{}, err
+	}
+	if len(pieces) == 2 {
+		return spec, nil
+	}
+	opt := strings.TrimSpace(pieces[2])
+	if !strings.HasPrefix(opt, "fields:") {
+		return namedSpec{}, fmt.Errorf("unknown option %q of the usecase %s, the options are fields:\"name:type ...\"", opt, spec.Name)
+	}
+	value, err := strconv.Unquote(strings.TrimPrefix(opt, "fields:"))
+	if err != nil {
+		return namedSpec{}, fmt.Errorf("the fields of the usecase %s must be quoted e.g. fields:\"sku:string qty:int\"", spec.Name)
+	}
+	var fieldSpecs []string
+	for _, f := range strings.Fields(value) {
+		nameType := strings.SplitN(f, ":", 2)
+		if len(nameType) != 2 {
+			return namedSpec{}, fmt.Errorf("the field %q of the usecase %s must be of the form name:type", f, spec.Name)
+		}
+		fieldSpecs = append(fieldSpecs, nameType[0]+" "+nameType[1])
+	}
+	if spec.Fields, err = parseFieldSpecs(strings.Join(fieldSpecs, ",")); err != nil {
+		return namedSpec{}, fmt.Errorf("the usecase %s: %s", spec.Name, err.Error())
+	}
+	return spec, nil
+}
+
+// readDirectives returns the directives of the Interactor files of the project at basePath, ordered by file and
+// line, and whether any of the files already has the go:generate line running clean generate. Each invalid
+// directive is returned as an error prefixed with its file and line.
+func readDirectives(basePath string) ([]directive, bool, []error) {
+	dir := filepath.FromSlash(basePath + relPathInteractor)
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, false, []error{err}
+	}
+	var directives []directive
+	var errs []error
+	wired := false
+	for _, fi := range fis {
+		if fi.IsDir() || filepath.Ext(fi.Name()) != ".go" || strings.HasSuffix(fi.Name(), "_test.go") {
+			continue
+		}
+		fp := filepath.Join(dir, fi.Name())
+		b, err := readFile(fp)
+		if err != nil {
+			return nil, false, []error{err}
+		}
+		if !bytes.Contains(b, []byte(directivePrefix)) && !bytes.Contains(b, []byte(goGenerateLine)) {
+			continue
+		}
+		fset := token.NewFileSet()
+		f, err := parseFile(fset, fp, b, parser.ParseComments)
+		if err != nil {
+			return nil, false, []error{err}
+		}
+		// The interactor of the file is the one of its Interactor interface
+		interactor := ""
+		for _, name := range interfaceNames(f) {
+			if objName, ok := objNameFromTypeName(objInteractor, name, strings.TrimSuffix(fi.Name(), ".go")); ok {
+				interactor = objName
+			}
+		}
+		for _, cg := range f.Comments {
+			for _, c := range cg.List {
+				if strings.TrimSpace(c.Text) == goGenerateLine {
+					wired = true
+				}
+				// The markers of the regions of managed layers aren't directives, see policyManaged
+				if !strings.HasPrefix(c.Text, directivePrefix) || c.Text == managedBegin || c.Text == managedEnd {
+					continue
+				}
+				p := fset.Position(c.Pos())
+				pos := fmt.Sprintf("%s:%d", p.Filename, p.Line)
+				if interactor == "" {
+					errs = append(errs, fmt.Errorf("%s: the directive isn't in the file of an Interactor", pos))
+					continue
+				}
+				spec, err := parseDirective(strings.TrimPrefix(c.Text, directivePrefix))
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: %s", pos, err.Error()))
+					continue
+				}
+				spec.Line = p.Line
+				directives = append(directives, directive{fp: fp, pos: pos, interactor: interactor, spec: spec})
+			}
+		}
+	}
+	return directives, wired, errs
+}
+
+// conflicts returns the reasons why the directives of the interactor, whose existing usecases are usecases, can't
+// be applied. A usecase declared twice with different fields, a field whose type differs from the one of the
+// existing RequestModel and, if the interactor has usecases no directive declares, a directive of a usecase
+// which doesn't exist, which might be a renamed usecase, are reported instead of guessed. The conflicting
+// directives are returned by their usecases.
+func conflicts(basePath, interactor string, directives []directive, usecases []string) (map[string]bool, []string) {
+	conflicting := make(map[string]bool)
+	var reasons []string
+	declared := make(map[string]directive)
+	for _, d := range directives {
+		if prev, ok := declared[d.spec.Name]; ok && fmt.Sprint(prev.spec.Fields) != fmt.Sprint(d.spec.Fields) {
+			conflicting[d.spec.Name] = true
+			reasons = append(reasons, fmt.Sprintf("%s: the usecase %s is declared with other fields at %s", d.pos, d.spec.Name, prev.pos))
+		}
+		declared[d.spec.Name] = d
+	}
+	exists := make(map[string]bool)
+	var undeclared []string
+	for _, usecase := range usecases {
+		exists[usecase] = true
+		if _, ok := declared[usecase]; !ok {
+			undeclared = append(undeclared, usecase)
+		}
+	}
+	for _, name := range sortedDirectiveNames(declared) {
+		d := declared[name]
+		if !exists[name] {
+			if len(undeclared) > 0 {
+				conflicting[name] = true
+				reasons = append(reasons, fmt.Sprintf("%s: the usecase %s doesn't exist but the usecases %s of %s aren't declared by any directive. If one of them was renamed to %s, rename it in the code too, otherwise declare them by directives", d.pos, name, strings.Join(undeclared, ", "), exportedName(interactor), name))
+			}
+			continue
+		}
+		fp := modelFile(basePath, relPathReqModel, name, interactor)
+		if fp == "" {
+			continue
+		}
+		fields, err := structFields(fp, name)
+		if err != nil {
+			conflicting[name] = true
+			reasons = append(reasons, fmt.Sprintf("%s: %s", d.pos, err.Error()))
+			continue
+		}
+		types := make(map[string]string)
+		for _, f := range fields {
+			types[f.Name] = f.Type
+		}
+		for _, f := range d.spec.Fields {
+			if t, ok := types[f.Name]; ok && t != f.Type {
+				conflicting[name] = true
+				reasons = append(reasons, fmt.Sprintf("%s: the directive declares the field %s of %s as %s but the RequestModel declares it as %s", d.pos, f.Name, name, f.Type, t))
+			}
+		}
+	}
+	return conflicting, reasons
+}
+
+// sortedDirectiveNames returns the names of the usecases declared by the directives in alphabetical order
+func sortedDirectiveNames(declared map[string]directive) []string {
+	names := make([]string, 0, len(declared))
+	for name := range declared {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// generateFromDirectives adds the usecases declared by the directives of the Interactor files of the project at
+// basePath which don't exist yet, and the fields of the directives missing from the RequestModels of the
+// existing ones, so running it again changes nothing. The conflicting directives are reported and skipped, see
+// conflicts. The go:generate line running it is added to the first Interactor file with directives unless an
+// Interactor file already has it, so that go generate ./... keeps the project up to date with its directives.
+// It returns whether anything was changed.
+func generateFromDirectives(basePath string) (bool, error) {
+	if verticalLayout {
+		return false, fmt.Errorf("the directives are read from the Interactor files, which the %s layout doesn't have", layoutVertical)
+	}
+	directives, wired, errs := readDirectives(basePath)
+	if len(errs) > 0 {
+		for _, err := range errs {
+			failf("%s\n", err.Error())
+		}
+		return false, fmt.Errorf("the directives are invalid, nothing was generated")
+	}
+	if len(directives) == 0 {
+		noopf("no %s directives were found in the Interactor files", directivePrefix+objUsecase)
+		return false, nil
+	}
+	existing, err := interactorUsecases(basePath)
+	if err != nil {
+		return false, err
+	}
+	byInteractor := make(map[string][]directive)
+	var interactors []string
+	for _, d := range directives {
+		if _, ok := byInteractor[d.interactor]; !ok {
+			interactors = append(interactors, d.interactor)
+		}
+		byInteractor[d.interactor] = append(byInteractor[d.interactor], d)
+	}
+	sort.Strings(interactors)
+	changed := false
+	for _, interactor := range interactors {
+		conflicting, reasons := conflicts(basePath, interactor, byInteractor[interactor], existing[exportedName(interactor)])
+		for _, reason := range reasons {
+			failf("%s\n", reason)
+		}
+		exists := make(map[string]bool)
+		for _, usecase := range existing[exportedName(interactor)] {
+			exists[usecase] = true
+		}
+		for _, d := range byInteractor[interactor] {
+			name := d.spec.Name
+			if conflicting[name] {
+				continue
+			}
+			if !exists[name] {
+				addUsecaseWithExtras(basePath, d.spec, interactor)
+				exists[name] = true
+				fmt.Printf("Added usecase %s to %s\n", name, exportedName(interactor))
+				changed = true
+				continue
+			}
+			added, err := addDirectiveFields(basePath, d.spec, interactor)
+			if err != nil {
+				failf("%s: error adding the fields of %s: %s\n", d.pos, name, err.Error())
+				continue
+			}
+			if len(added) > 0 {
+				fmt.Printf("Added the fields %s to the RequestModel %s of %s\n", strings.Join(added, ", "), name, exportedName(interactor))
+				changed = true
+			}
+		}
+	}
+	if !wired {
+		fp := directives[0].fp
+		if err := addGoGenerateLine(fp); err != nil {
+			return changed, err
+		}
+		fmt.Printf("Added %q to %s so that go generate ./... applies the directives\n", goGenerateLine, fp)
+		changed = true
+	}
+	return changed, nil
+}
+
+// addDirectiveFields adds the fields of the spec missing from the RequestModel of the existing usecase of the
+// interactor, and their checks to the Validator, and returns the names of the added fields
+func addDirectiveFields(basePath string, spec namedSpec, interactor string) ([]string, error) {
+	fp := modelFile(basePath, relPathReqModel, spec.Name, interactor)
+	if fp == "" {
+		return nil, nil
+	}
+	fields, err := structFields(fp, spec.Name)
+	if err != nil {
+		return nil, err
+	}
+	declared := make(map[string]bool)
+	for _, f := range fields {
+		declared[f.Name] = true
+	}
+	var missing []structField
+	var names []string
+	for _, f := range spec.Fields {
+		if !declared[f.Name] {
+			missing = append(missing, f)
+			names = append(names, f.Name)
+		}
+	}
+	if len(missing) == 0 {
+		return nil, nil
+	}
+	if *validatorStyle == validatorTags {
+		missing = tagValidatorFields(missing)
+	}
+	if err := addStructFields(fp, spec.Name, missing); err != nil {
+		return nil, err
+	}
+	if selected(objValidator) && selected("respmodel") {
+		if err := syncValidator(basePath, spec.Name, interactor); err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
+
+// addGoGenerateLine adds the go:generate line running clean generate after the package clause of the Go file fp
+func addGoGenerateLine(fp string) error {
+	b, err := readFile(fp)
+	if err != nil {
+		return err
+	}
+	fset := token.NewFileSet()
+	f, err := parseFile(fset, fp, b, parser.PackageClauseOnly)
+	if err != nil {
+		return err
+	}
+	ix := fset.Position(f.Name.End()).Offset
+	newb := append(append(append([]byte{}, b[:ix]...), "\n\n"+goGenerateLine...), b[ix:]...)
+	return writeFile(fp, newb)
+}
+
+// verifyGoGenerateDir returns an error if clean generate is run by go generate in a folder outside the project
+// at projectPath, which is the Clean Work Directory rather than the folder go generate runs it in
+func verifyGoGenerateDir(projectPath string) error {
+	if os.Getenv("GOFILE") == "" {
+		return nil
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if rel, err := filepath.Rel(filepath.FromSlash(projectPath), wd); err != nil || strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("go generate runs clean generate in %s, which is outside the Clean Work Directory %s. Run \"clean set folder\" in the project first", wd, projectPath)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"text/template"
+)
+
+// entityValidationTmpl is the template of the Validate method and the constructor of a self-validating Entity
+const entityValidationTmpl = `{{if not .HasValidate}}
+
+// Validate returns an error if the {{.Name}} violates any of its invariants.
+func ({{.Recv}} {{.Name}}) Validate() error {
+	// TODO: Check the invariants of the {{.Name}} e.g. that its required fields are set
+	return nil
+}
+{{- end}}
+{{- if not .HasConstructor}}
+
+// New{{.Name}} constructs a new {{.Name}}. Returns an error if the {{.Name}} violates any of its invariants.
+func New{{.Name}}({{range $i, $f := .Fields}}{{if $i}}, {{end}}{{$f.Param}} {{$f.Type}}{{end}}) ({{.Name}}, error) {
+	{{.Recv}} := {{.Name}}{ {{- range .Fields}}
+		{{.Name}}: {{.Param}},
+	{{- end}}{{if .Fields}}
+	{{end -}} }
+	if err := {{.Recv}}.Validate(); err != nil {
+		return {{.Name}}{}, err
+	}
+	return {{.Recv}}, nil
+}
+{{- end}}`
+
+// addEntityValidation adds a Validate method and a New constructor calling it to the entity name, unless
+// they already exist. The constructor takes a parameter per field of the entity and returns the entity
+// together with the error returned by Validate.
+func addEntityValidation(basePath, name string) error {
+	fp := filepath.FromSlash(basePath + relPathEntity + fileName(name) + ".go")
+	ucName := exportedName(name)
+	f, err := parseGoFile(fp)
+	if err != nil {
+		return err
+	}
+	if findTypeSpec(f, ucName) == nil {
+		return fmt.Errorf("the entity %s isn't declared in %s", ucName, fp)
+	}
+	tmplData := struct {
+		Name           string
+		Recv           string
+		HasValidate    bool
+		HasConstructor bool
+		Fields         []struct{ Name, Param, Type string }
+	}{Name: ucName, Recv: receiverName(ucName)}
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		switch {
+		case fd.Name.Name == "Validate" && fd.Recv != nil && recvTypeName(fd) == ucName:
+			tmplData.HasValidate = true
+		case fd.Name.Name == "New"+ucName && fd.Recv == nil:
+			tmplData.HasConstructor = true
+		}
+	}
+	if tmplData.HasValidate && tmplData.HasConstructor {
+		noopf("the validation of the entity %s already exists", ucName)
+		return nil
+	}
+	if tmplData.HasConstructor {
+		fmt.Printf("The constructor New%s already exists. Make it call Validate and return (%s, error).\n", ucName, ucName)
+	}
+	fields, err := structFields(fp, ucName)
+	if err != nil {
+		return err
+	}
+	for _, field := range fields {
+		param := unexportedName(field.Name)
+		if param == tmplData.Recv {
+			param += "Arg"
+		}
+		tmplData.Fields = append(tmplData.Fields, struct{ Name, Param, Type string }{field.Name, param, field.Type})
+	}
+	b, err := readFile(fp)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := render(&buf, template.Must(template.New("validation").Parse(entityValidationTmpl)), tmplData); err != nil {
+		return err
+	}
+	b = append(bytes.TrimRight(b, "\n"), buf.Bytes()...)
+	return writeFile(fp, append(b, '\n'))
+}
+
+// recvTypeName returns the name of the type of the receiver of the method fd
+func recvTypeName(fd *ast.FuncDecl) string {
+	if fd.Recv == nil || len(fd.Recv.List) != 1 {
+		return ""
+	}
+	recv := fd.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+	if id, ok := recv.(*ast.Ident); ok {
+		return id.Name
+	}
+	return ""
+}
@@ -0,0 +1,228 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+const verbEnv = "env"
+
+// envEntry is an item of the effective configuration printed by clean env
+type envEntry struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Source tells where the value comes from e.g. the configuration file setting it
+	Source string `json:"source,omitempty"`
+	// Error tells why the value couldn't be resolved or is invalid
+	Error string `json:"error,omitempty"`
+}
+
+// projectEnv returns the effective configuration of the project in the working directory: the configuration
+// files and their scope, the Clean Work Directory, the module path and where it comes from, the layout, the
+// other settings and the flags. Each item which can't be resolved is annotated with the error rather than
+// leaving out the rest. globalPath is the path of the global configuration file.
+func projectEnv(globalPath string) []envEntry {
+	var env []envEntry
+	add := func(name, value, source string, err error) {
+		e := envEntry{Name: name, Value: value, Source: source}
+		if err != nil {
+			e.Error = err.Error()
+		}
+		env = append(env, e)
+	}
+
+	wd, wdErr := os.Getwd()
+	add("workdir", wd, "", wdErr)
+
+	// The configuration files
+	global := make(map[string]string)
+	var globalErr error
+	if !fileExists(globalPath) {
+		globalErr = fmt.Errorf("the file doesn't exist, run clean init")
+	} else if c, err := readConfig(globalPath); err != nil {
+		globalErr = err
+	} else {
+		global = c
+	}
+	add("config.global", globalPath, "", globalErr)
+	local := make(map[string]string)
+	var localPath string
+	if wdErr == nil {
+		localPath = findLocalConfig(wd)
+	}
+	if localPath != "" {
+		c, err := readConfig(localPath)
+		if err == nil {
+			local = c
+		}
+		add("config.local", localPath, "", err)
+	} else {
+		add("config.local", "", fmt.Sprintf("there's no %s file in the working directory or its parents", localConfName), nil)
+	}
+	if wdErr == nil {
+		scope, err := configScope(globalPath, wd)
+		add("config.scope", scope, "the file clean set writes to", err)
+	}
+
+	// The settings of the project configuration file override the global ones
+	conf := make(map[string]string)
+	sources := make(map[string]string)
+	for k, v := range global {
+		conf[k], sources[k] = v, globalPath
+	}
+	for k, v := range local {
+		conf[k], sources[k] = v, localPath
+	}
+	if _, ok := local[confKeyDirectory]; localPath != "" && !ok {
+		conf[confKeyDirectory] = filepath.Dir(localPath) + string(os.PathSeparator)
+		sources[confKeyDirectory] = "the folder of " + localPath
+	}
+	shown := make(map[string]bool)
+
+	// The project folder and the module path
+	baseDir, ok := projectDir(conf)
+	shown[confKeyDirectory] = true
+	if !ok {
+		add("directory", "", "", fmt.Errorf("the Clean Work Directory isn't configured, run clean init or clean set folder"))
+	} else {
+		add("directory", baseDir, sources[confKeyDirectory], nil)
+		var err error
+		if fi, statErr := os.Stat(filepath.FromSlash(baseDir + "clean")); statErr != nil || !fi.IsDir() {
+			err = fmt.Errorf("the clean folder doesn't exist")
+		}
+		add("project.root", baseDir+"clean/", "", err)
+	}
+	modulePath, source, err := envModulePath(conf, sources, baseDir)
+	add("module", modulePath, source, err)
+	for _, k := range []string{confKeyModule, confKeyModuleRoot, confKeyGoModStamp} {
+		shown[k] = true
+	}
+	if v, ok := conf[confKeyModuleReplace]; ok {
+		add(confKeyModuleReplace, v, sources[confKeyModuleReplace], setImportReplacements(v))
+		shown[confKeyModuleReplace] = true
+	}
+
+	// The layout
+	mode := conf[confKeyLayoutMode]
+	if mode == "" {
+		mode = layoutHorizontal
+	}
+	layoutErr := setLayout(conf)
+	if layoutErr == nil {
+		layoutErr = setLayoutMode(conf)
+	}
+	add(confKeyLayoutMode, mode, envSource(sources, confKeyLayoutMode), layoutErr)
+	shown[confKeyLayoutMode] = true
+	layers := layoutLayers()
+	for _, layer := range sortedKeys(layers) {
+		add(confKeyLayout+layer, layers[layer], envSource(sources, confKeyLayout+layer), nil)
+		shown[confKeyLayout+layer] = true
+	}
+
+	// The other settings, checked by the functions applying them
+	validators := map[string]func(string) error{
+		confKeyReceiver:   setReceiverStyle,
+		confKeyComments:   setCommentStyle,
+		confKeyPagination: setPaginationStyle,
+		confKeyMockEngine: validMockEngine,
+	}
+	for _, k := range sortedKeys(conf) {
+		if shown[k] {
+			continue
+		}
+		var err error
+		if validate, ok := validators[k]; ok {
+			err = validate(conf[k])
+		}
+		add(k, conf[k], sources[k], err)
+	}
+	if err := setLayerPolicies(conf); err != nil {
+		add(strings.TrimSuffix(confKeyPolicy, "."), "", "", err)
+	}
+
+	// The flags, whose defaults some settings change
+	fromConf := map[string]string{"create-only": confKeyCreateOnly, "lint-clean": confKeyLintClean, "mock-engine": confKeyMockEngine}
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	flag.VisitAll(func(f *flag.Flag) {
+		value, source := f.Value.String(), "default"
+		switch k := fromConf[f.Name]; {
+		case set[f.Name]:
+			source = "command line"
+		case k != "" && conf[k] != "":
+			value, source = conf[k], k+" in "+sources[k]
+		}
+		add("flag."+f.Name, value, source, nil)
+	})
+	return env
+}
+
+// envSource returns the source of the setting k or "default" if it isn't set
+func envSource(sources map[string]string, k string) string {
+	if s, ok := sources[k]; ok {
+		return s
+	}
+	return "default"
+}
+
+// envModulePath returns the import path of the project in baseDir, which the generated import paths are based
+// on, and where it comes from: the go.mod file it was resolved from, the configuration file it was set in, or
+// the project's location in the GOPATH
+func envModulePath(conf, sources map[string]string, baseDir string) (string, string, error) {
+	if modulePath := conf[confKeyModule]; modulePath != "" {
+		root := conf[confKeyModuleRoot]
+		if _, resolved := findModule(root); root != "" && resolved == modulePath {
+			source := "go.mod in " + root
+			if goModStamp(root) != conf[confKeyGoModStamp] {
+				source += ", which changed since and is resolved again by the next command"
+			}
+			return modulePath, source, nil
+		}
+		return modulePath, "set in " + sources[confKeyModule], nil
+	}
+	if baseDir == "" {
+		return "", "", fmt.Errorf("the Clean Work Directory isn't configured")
+	}
+	if importPath, ok := gopathImportPath(baseDir); ok {
+		return strings.TrimSuffix(importPath, "/"), "the location of the project in the GOPATH", nil
+	}
+	return "", "", fmt.Errorf("there's no go.mod file and the project isn't in the GOPATH, run clean set module")
+}
+
+// printEnv prints the effective configuration env as a table or, if asJSON is set, as JSON
+func printEnv(env []envEntry, asJSON bool) error {
+	if asJSON {
+		b, err := json.MarshalIndent(env, "", "\t")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\n", b)
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	for _, e := range env {
+		value := e.Value
+		if value == "" {
+			value = "-"
+		}
+		switch {
+		case e.Error != "":
+			fmt.Fprintf(tw, "%s\t%s\t(error: %s)\n", e.Name, value, e.Error)
+		case e.Source != "":
+			fmt.Fprintf(tw, "%s\t%s\t(%s)\n", e.Name, value, e.Source)
+		default:
+			fmt.Fprintf(tw, "%s\t%s\t\n", e.Name, value)
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,188 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	gofmt "go/format"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	objErrors = "errors"
+	objError  = "error"
+	// relPathErrs is the folder of the error catalog
+	relPathErrs = "usecase/errs/"
+	// errsFileName is the name of the file of the error catalog
+	errsFileName = "errs.go"
+	// errsHeader marks the error catalog as generated so that it's rewritten as a whole, in the order of
+	// the codes, whenever an error is added to it
+	errsHeader = "// Code generated by clean. DO NOT EDIT.\n\n"
+	// errCodeArgPrefix is the prefix of the argument of clean add error naming the code e.g. Code=NotFound
+	errCodeArgPrefix = "code="
+)
+
+// catalogError is an entry of the error catalog
+type catalogError struct {
+	// Name is the name of the constructor of the error e.g. NotFound, whose code is CodeNotFound
+	Name    string
+	Message string
+}
+
+// defaultCatalogErrors are the errors of a new error catalog
+var defaultCatalogErrors = []catalogError{
+	{"Conflict", "conflict"},
+	{"Internal", "internal error"},
+	{"InvalidInput", "invalid input"},
+	{"NotFound", "not found"},
+}
+
+// errsFile returns the path of the file of the error catalog
+func errsFile(basePath string) string {
+	return filepath.FromSlash(basePath + relPathErrs + errsFileName)
+}
+
+// errorCode returns the value of the code of the error name e.g. not_found for NotFound
+func errorCode(name string) string {
+	return strings.ToLower(strings.Join(splitWords(name), "_"))
+}
+
+// errsSource returns the source of the error catalog holding the errors in the order of their names
+func errsSource(errs []catalogError) ([]byte, error) {
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Name < errs[j].Name })
+	var b bytes.Buffer
+	b.WriteString(errsHeader)
+	b.WriteString("// Package errs provides the catalog of the errors the usecases fail with.\npackage errs\n")
+	b.WriteString("\n// Code identifies the kind of an Error.\ntype Code string\n\n// The codes of the errors of the catalog.\nconst (\n")
+	for _, e := range errs {
+		fmt.Fprintf(&b, "\tCode%s Code = %q\n", e.Name, errorCode(e.Name))
+	}
+	b.WriteString(")\n")
+	b.WriteString(`
+// Error is an error of the catalog. It's identified by its Code and may wrap the error that caused it.
+type Error struct {
+	Code    Code
+	Message string
+	Err     error
+}
+
+// Error implements the error interface method Error.
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap returns the error that caused e, if any.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is an *Error with the code of e, so that errors.Is(err, errs.NotFound(nil))
+// reports whether err is a NotFound error.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+`)
+	for _, e := range errs {
+		fmt.Fprintf(&b, "\n// %s returns an Error with the code Code%s wrapping err, which may be nil.\nfunc %s(err error) *Error {\n\treturn &Error{Code: Code%s, Message: %q, Err: err}\n}\n", e.Name, e.Name, e.Name, e.Name, e.Message)
+	}
+	return gofmt.Source(b.Bytes())
+}
+
+// catalogErrors returns the errors of the error catalog at fp. The errors are the functions whose code
+// constant is declared by the catalog.
+func catalogErrors(fp string) ([]catalogError, error) {
+	f, err := parseGoFile(fp)
+	if err != nil {
+		return nil, err
+	}
+	codes := make(map[string]bool)
+	for _, decl := range f.Decls {
+		if gd, ok := decl.(*ast.GenDecl); ok && gd.Tok == token.CONST {
+			for _, spec := range gd.Specs {
+				for _, name := range spec.(*ast.ValueSpec).Names {
+					codes[name.Name] = true
+				}
+			}
+		}
+	}
+	var errs []catalogError
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Recv != nil || fd.Body == nil || !codes["Code"+fd.Name.Name] {
+			continue
+		}
+		e := catalogError{Name: fd.Name.Name}
+		ast.Inspect(fd.Body, func(n ast.Node) bool {
+			kv, ok := n.(*ast.KeyValueExpr)
+			if !ok {
+				return true
+			}
+			if key, ok := kv.Key.(*ast.Ident); ok && key.Name == "Message" {
+				if lit, ok := kv.Value.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+					e.Message, _ = strconv.Unquote(lit.Value)
+				}
+			}
+			return false
+		})
+		errs = append(errs, e)
+	}
+	return errs, nil
+}
+
+// addErrorCatalog adds the error catalog with the default errors to the errs folder of the usecase layer
+func addErrorCatalog(basePath string) error {
+	fp := errsFile(basePath)
+	if fileExists(fp) {
+		noopf("the error catalog already exists")
+		return nil
+	}
+	src, err := errsSource(append([]catalogError(nil), defaultCatalogErrors...))
+	if err != nil {
+		return err
+	}
+	if err := mkdirAll(filepath.Dir(fp)); err != nil {
+		return err
+	}
+	return writeFile(fp, src)
+}
+
+// addCatalogError adds the error name with the message to the error catalog, which is added first if it
+// doesn't exist. An error with the same name and message is a noop whereas one with another message fails.
+func addCatalogError(basePath, name, message string) error {
+	fp := errsFile(basePath)
+	if !fileExists(fp) {
+		if err := addErrorCatalog(basePath); err != nil {
+			return err
+		}
+	}
+	errs, err := catalogErrors(fp)
+	if err != nil {
+		return err
+	}
+	for _, e := range errs {
+		if e.Name != name {
+			continue
+		}
+		if e.Message != message {
+			return fmt.Errorf("the error %s already exists with the message %q", name, e.Message)
+		}
+		noopf("the error %s already exists", name)
+		return nil
+	}
+	src, err := errsSource(append(errs, catalogError{name, message}))
+	if err != nil {
+		return err
+	}
+	return rewriteFile(fp, src)
+}
@@ -0,0 +1,156 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	verbSync = "sync"
+	// errFieldSuffix follows the name of a RequestModel field in the name of the ErrVal field describing why it's
+	// invalid e.g. NameErr
+	errFieldSuffix = "Err"
+	// fieldChecksStart starts the checks of the fields in the Validate method of a usecase
+	fieldChecksStart = "\t// Validate the fields of the RequestModel\n"
+	// fieldChecksEnd follows the checks of the fields, which are inserted before it as fields are added
+	fieldChecksEnd = "\tif invalid {\n"
+)
+
+// errValFields returns the fields of the ErrVal ResponseModel describing why each of the RequestModel fields is
+// invalid e.g. NameErr string. The fields holding the ErrVal's own data, e.g. Forbidden, aren't RequestModel
+// fields, so they're never passed in.
+func errValFields(fields []structField) []structField {
+	var errFields []structField
+	for _, f := range fields {
+		errFields = append(errFields, structField{Name: f.Name + errFieldSuffix, Type: "string", Comment: fmt.Sprintf("why %s is invalid, empty if it's valid", f.Name)})
+	}
+	return errFields
+}
+
+// fieldCheck returns the statements of the Validate method which set the ErrVal field of the RequestModel field
+// f if it's invalid. The fields whose zero values can be told apart get a check that they're set as a start.
+func fieldCheck(f structField) string {
+	check := fmt.Sprintf("\t// TODO: Validate %s\n", f.Name)
+	switch {
+	case f.Type == "string":
+		check += fmt.Sprintf("\tif rqm.%s == \"\" {\n", f.Name)
+	case strings.HasPrefix(f.Type, "*") || strings.HasPrefix(f.Type, "[]") || strings.HasPrefix(f.Type, "map[") || f.Type == "interface{}":
+		check += fmt.Sprintf("\tif rqm.%s == nil {\n", f.Name)
+	default:
+		return check
+	}
+	return check + fmt.Sprintf("\t\terrVal.%s%s, invalid = \"%s is required\", true\n\t}\n", f.Name, errFieldSuffix, f.Name)
+}
+
+// fieldChecks returns the checks of the fields inserted at the beginning of the Validate method of the usecase,
+// which return the ErrVal once each of the fields has been checked so that all of the invalid fields are
+// reported together
+func fieldChecks(usecase string, fields []structField) string {
+	checks := fieldChecksStart + fmt.Sprintf("\terrVal, invalid := &respmodel.%sErrVal{}, false\n", exportedName(usecase))
+	for _, f := range fields {
+		checks += fieldCheck(f)
+	}
+	return checks + fieldChecksEnd + "\t\treturn errVal\n\t}\n\n"
+}
+
+// syncValidator aligns the ErrVal ResponseModel and the Validate method of the usecase of the interactor with
+// the fields of its RequestModel. The ErrVal gets an error field of each RequestModel field lacking one, and the
+// Validate method gets a check of each field it doesn't set the error field of yet. Nothing is removed: the
+// error fields whose RequestModel fields are gone are listed so that they can be removed by hand. The checks
+// aren't added to a Validator validating the validate tags of the fields, which reports the failed tags instead.
+func syncValidator(basePath, usecase, interactor string) error {
+	v := exportedName(usecase)
+	rqmFp := modelFile(basePath, relPathReqModel, v, interactor)
+	if rqmFp == "" {
+		return fmt.Errorf("the RequestModel %s of %s doesn't exist", v, exportedName(interactor))
+	}
+	fields, err := structFields(rqmFp, v)
+	if err != nil {
+		return err
+	}
+	rsmFp := modelFile(basePath, relPathRespModel, v, interactor)
+	if rsmFp == "" {
+		return fmt.Errorf("the ResponseModel %s of %s doesn't exist", v, exportedName(interactor))
+	}
+	errVals, err := structFields(rsmFp, v+"ErrVal")
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]bool)
+	for _, f := range fields {
+		byName[f.Name+errFieldSuffix] = true
+	}
+	for _, f := range errVals {
+		if strings.HasSuffix(f.Name, errFieldSuffix) && !byName[f.Name] {
+			fmt.Printf("The field %s of %sErrVal doesn't match any field of the RequestModel, remove it by hand if it's no longer needed\n", f.Name, v)
+		}
+	}
+	if len(fields) == 0 {
+		noopf("the RequestModel %s has no fields", v)
+		return nil
+	}
+	if err := addStructFields(rsmFp, v+"ErrVal", errValFields(fields)); err != nil {
+		return err
+	}
+	if *validatorStyle == validatorTags {
+		return nil
+	}
+	return addFieldChecks(filepath.FromSlash(basePath+relPathValidator+fileName(interactor)+".go"), v, fields)
+}
+
+// addFieldChecks adds the checks of the fields which the Validate method of the usecase in the Validator file fp
+// doesn't check yet. The checks are inserted at the beginning of the method the first time and before the
+// return of the ErrVal afterwards.
+func addFieldChecks(fp, usecase string, fields []structField) error {
+	b, err := readFile(fp)
+	if err != nil {
+		return err
+	}
+	fset := token.NewFileSet()
+	f, err := parseFile(fset, fp, b, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	method := "Validate" + exportedName(usecase)
+	var body []byte
+	var lbrace int
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Recv == nil || fd.Name.Name != method || fd.Body == nil {
+			continue
+		}
+		lbrace = fset.Position(fd.Body.Lbrace).Offset
+		body = b[lbrace:fset.Position(fd.Body.Rbrace).Offset]
+	}
+	if body == nil {
+		return fmt.Errorf("the method %s isn't declared in %s", method, fp)
+	}
+	if !bytes.Contains(body, []byte(fieldChecksStart)) {
+		return prependToMethod(fp, method, fieldChecks(usecase, fields))
+	}
+	var checks string
+	for _, field := range fields {
+		if !bytes.Contains(body, []byte("rqm."+field.Name+" ")) && !bytes.Contains(body, []byte("errVal."+field.Name+errFieldSuffix)) && !bytes.Contains(body, []byte("// TODO: Validate "+field.Name+"\n")) {
+			checks += fieldCheck(field)
+		}
+	}
+	if checks == "" {
+		noopf("the fields of %s are already validated", exportedName(usecase))
+		return nil
+	}
+	ix := bytes.Index(body, []byte(fieldChecksEnd))
+	if ix == -1 {
+		return fmt.Errorf("the checks of the fields of %s in %s don't end with %q, add the checks by hand", method, fp, strings.TrimSpace(fieldChecksEnd))
+	}
+	ix += lbrace
+	newb := append(append(append([]byte{}, b[:ix]...), checks...), b[ix:]...)
+	return writeFile(fp, newb)
+}
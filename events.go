@@ -0,0 +1,204 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	relPathEvent = "usecase/event/"
+	objEvent     = "event"
+	// featureEvents selects the events of the interactors in --only and --skip
+	featureEvents = "events"
+	// eventsField is the name of the field and constructor parameter of an Interactor holding its events
+	eventsField = "ev"
+)
+
+// eventsName returns the name of the events interface of the interactor e.g. OrderEvents
+func eventsName(interactor string) string {
+	return exportedName(interactor) + "Events"
+}
+
+// hasEvents reports whether the events of the interactor have been added to the project at basePath,
+// which makes the usecases added to the interactor afterwards emit events too
+func hasEvents(basePath, interactor string) bool {
+	return fileExists(filepath.FromSlash(basePath + relPathEvent + fileName(interactor) + ".go"))
+}
+
+// eventMethod returns the declarations of the usecase of the events interface ifName, which are
+// the method of the interface and the method of its no-op implementation
+func eventMethod(usecase, ifName string) (string, string) {
+	v := exportedName(usecase)
+	sig := fmt.Sprintf("Emit%sCompleted(e *%sCompleted)", v, v)
+	return fmt.Sprintf("\t// Emit%sCompleted publishes the event that the usecase %s completed.\n\t%s\n", v, v, sig),
+		fmt.Sprintf("\n\n// Emit%sCompleted implements the %s interface method Emit%sCompleted by discarding the event.\nfunc (noop%s) %s {}", v, ifName, v, ifName, sig)
+}
+
+// eventPayload returns the declaration of the payload of the event of the usecase completing
+func eventPayload(usecase string) string {
+	v := exportedName(usecase)
+	return fmt.Sprintf("\n\n// %sCompleted is the domain event which is emitted when the usecase %s completes.\ntype %sCompleted struct {\n\t// TODO: Add struct members\n}", v, v, v)
+}
+
+// addEvents adds the events of the interactor to the event folder of the project at basePath: an events
+// interface with a method per usecase, a no-op implementation of it and the payloads of the events. The
+// Interactor gets a field and constructor parameter of the interface.
+func addEvents(basePath, interactor string) error {
+	itFp := filepath.FromSlash(basePath + relPathInteractor + fileName(interactor) + ".go")
+	f, err := parseGoFile(itFp)
+	if err != nil {
+		return fmt.Errorf("the interactor %s doesn't exist", exportedName(interactor))
+	}
+	usecases, ok := interfaceMethods(f, typeName(objInteractor, interactor))
+	if !ok {
+		return fmt.Errorf("the interface %s isn't declared in %s", typeName(objInteractor, interactor), itFp)
+	}
+	ifName := eventsName(interactor)
+	fp := filepath.FromSlash(basePath + relPathEvent + fileName(interactor) + ".go")
+	if !fileExists(fp) {
+		content := fmt.Sprintf("%s\n\n// %s publishes the domain events of the %s Interactor.\ntype %s interface {\n\t// TODO add interface methods by using Clean. For more info run \"clean help add events\"\n}\n\n// noop%s is an implementation of %s which discards the events.\ntype noop%s struct{}\n\n// NewNoop%s constructs a new %s which discards the events e.g. until they're published.\nfunc NewNoop%s() %s {\n\treturn noop%s{}\n}\n",
+			packageClause(basePath+relPathEvent, objEvent), ifName, typeName(objInteractor, interactor), ifName, ifName, ifName, ifName, ifName, ifName, ifName, ifName, ifName)
+		if err := mkdirAll(filepath.Dir(fp)); err != nil {
+			return err
+		}
+		if err := writeFile(fp, []byte(content)); err != nil {
+			return err
+		}
+	}
+	for _, usecase := range usecases {
+		if err := addEventOfUsecase(basePath, usecase, interactor); err != nil {
+			return err
+		}
+	}
+	return injectEvents(basePath, interactor)
+}
+
+// addEventOfUsecase adds the method of the usecase to the events interface of the interactor and its
+// no-op implementation, and the payload of the event unless another interactor declares it
+func addEventOfUsecase(basePath, usecase, interactor string) error {
+	fp := filepath.FromSlash(basePath + relPathEvent + fileName(interactor) + ".go")
+	b, err := readFile(fp)
+	if err != nil {
+		return err
+	}
+	v := exportedName(usecase)
+	ifName := eventsName(interactor)
+	if bytes.Contains(b, []byte(fmt.Sprintf("Emit%sCompleted(", v))) {
+		noopf("the event of the usecase %s already exists in %s", v, ifName)
+		return nil
+	}
+	sig, method := eventMethod(usecase, ifName)
+	if b, err = addMethodSignatureToInterface(b, fp, sig, ifName); err != nil {
+		return err
+	}
+	b = append(bytes.TrimRight(b, "\n"), method...)
+	// The payloads of all interactors share a package, so a usecase of several interactors reuses the payload
+	if typeDeclFile(filepath.Dir(fp), v+"Completed", fp) == "" && !bytes.Contains(b, []byte(fmt.Sprintf("type %sCompleted struct", v))) {
+		b = append(b, eventPayload(usecase)...)
+	}
+	return writeFile(fp, append(b, '\n'))
+}
+
+// injectEvents adds a field and a constructor parameter of the events interface of the interactor to its
+// Interactor implementation unless it already has them
+func injectEvents(basePath, interactor string) error {
+	return injectInteractorDependency(basePath, interactor, eventsField, objEvent+"."+eventsName(interactor), relPathEvent)
+}
+
+// injectInteractorDependency injects a dependency into the Interactor implementation of the interactor of the
+// project at basePath like injectDependency
+func injectInteractorDependency(basePath, interactor, field, typ, relPath string) error {
+	fp := filepath.FromSlash(basePath + relPathInteractor + fileName(interactor) + ".go")
+	b, err := readFile(fp)
+	if err != nil {
+		return err
+	}
+	if b, err = injectDependency(b, fp, interactor, field, typ, relPath); err != nil {
+		return err
+	}
+	return writeFile(fp, b)
+}
+
+// injectDependency adds a field named field of the type typ, which is declared in the package at relPath, and a
+// constructor parameter setting it to the Interactor implementation of the interactor in b, the content of fp,
+// unless it already has them
+func injectDependency(b []byte, fp, interactor, field, typ, relPath string) ([]byte, error) {
+	ucObjName := typeName(objInteractor, interactor)
+	return injectField(b, fp, unexportedName(ucObjName), "New"+ucObjName, field, typ, layerImportPath(relPath))
+}
+
+// injectField adds the field of type typ to the struct lcObjName in b, the content of fp, and a parameter
+// assigned to it to the constructor ctorName, unless typ is already referred to, and imports importPath
+func injectField(b []byte, fp, lcObjName, ctorName, field, typ, importPath string) ([]byte, error) {
+	if bytes.Contains(b, []byte(typ)) {
+		return b, nil
+	}
+	fset := token.NewFileSet()
+	f, err := parseFile(fset, fp, b, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	type insertion struct {
+		offset int
+		text   string
+	}
+	var insertions []insertion
+	// lineStart returns the offset of the start of the line of pos so that a line can be inserted before it
+	lineStart := func(pos token.Pos) int {
+		p := fset.Position(pos)
+		return p.Offset - p.Column + 1
+	}
+	if ts := findTypeSpec(f, lcObjName); ts != nil {
+		if st, ok := ts.Type.(*ast.StructType); ok {
+			insertions = append(insertions, insertion{lineStart(st.Fields.Closing), fmt.Sprintf("\t%s %s\n", field, typ)})
+		}
+	}
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Recv != nil || fd.Name.Name != ctorName || fd.Body == nil {
+			continue
+		}
+		sep := ", "
+		if len(fd.Type.Params.List) == 0 {
+			sep = ""
+		}
+		insertions = append(insertions, insertion{fset.Position(fd.Type.Params.Closing).Offset, fmt.Sprintf("%s%s %s", sep, field, typ)})
+		ast.Inspect(fd.Body, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.IfStmt:
+				if strings.Contains(types.ExprString(n.Cond), "== nil") {
+					insertions = append(insertions, insertion{fset.Position(n.Cond.End()).Offset, fmt.Sprintf(" || %s == nil", field)})
+				}
+			case *ast.CompositeLit:
+				if id, ok := n.Type.(*ast.Ident); !ok || id.Name != lcObjName {
+					break
+				}
+				if fset.Position(n.Lbrace).Line == fset.Position(n.Rbrace).Line {
+					// An empty literal is on a single line e.g. &order{}, see gofmt
+					insertions = append(insertions, insertion{fset.Position(n.Rbrace).Offset, fmt.Sprintf("\n\t\t%s: %s,\n\t", field, field)})
+				} else {
+					insertions = append(insertions, insertion{lineStart(n.Rbrace), fmt.Sprintf("\t\t%s: %s,\n", field, field)})
+				}
+			}
+			return true
+		})
+	}
+	if len(insertions) < 3 {
+		return nil, fmt.Errorf("the implementation %s in %s doesn't have the field, constructor and composite literal %s is injected into", lcObjName, fp, typ)
+	}
+	sort.Slice(insertions, func(i, j int) bool { return insertions[i].offset > insertions[j].offset })
+	for _, ins := range insertions {
+		b = append(append(append([]byte{}, b[:ins.offset]...), ins.text...), b[ins.offset:]...)
+	}
+	return ensureImport(b, importPath)
+}
@@ -0,0 +1,198 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path/filepath"
+	"strings"
+)
+
+// explainStep is a method or model of the flow of a usecase through the layers
+type explainStep struct {
+	Layer     string `json:"layer"`
+	Name      string `json:"name"`
+	File      string `json:"file,omitempty"`
+	Line      int    `json:"line,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	// Stub is true if the method is empty or still has a TODO comment, or if the model has no fields
+	Stub    bool `json:"stub"`
+	Missing bool `json:"missing,omitempty"`
+}
+
+// explainUsecase returns the steps of the flow of the usecase of the interactor of the project at basePath,
+// in the order they're run: the controller method, the RequestModel, the validator method, the interactor
+// method, the ResponseModels, the presenter methods, the ViewModels and the view methods. The file paths are
+// relative to projectPath.
+func explainUsecase(projectPath, basePath, usecase, interactor string) ([]explainStep, error) {
+	usecases, err := interactorUsecases(basePath)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, u := range usecases[exportedName(interactor)] {
+		found = found || u == usecase
+	}
+	if !found {
+		return nil, fmt.Errorf("the usecase %s of %s doesn't exist", usecase, exportedName(interactor))
+	}
+	e := explainer{projectPath: projectPath, files: make(map[string]*ast.File), fset: token.NewFileSet()}
+	var steps []explainStep
+	methods := func(objType string) {
+		fp := filepath.FromSlash(basePath + objRelPaths[objType] + fileName(interactor) + ".go")
+		for _, m := range usecaseMethods(objType, usecase) {
+			steps = append(steps, e.method(objType, fp, m))
+		}
+	}
+	models := func(relPath string) {
+		dir := filepath.FromSlash(basePath + relPath)
+		for _, m := range usecaseModels(relPath, usecase) {
+			fp := filepath.Join(dir, fileName(interactor)+".go")
+			// The models of all interactors share a package, so the model may be declared by another interactor
+			if f := e.parse(fp); f == nil || findTypeSpec(f, m) == nil {
+				if other := typeDeclFile(dir, m, ""); other != "" {
+					fp = other
+				}
+			}
+			steps = append(steps, e.model(dirNameFromRelPath(relPath), fp, m))
+		}
+	}
+	methods(objController)
+	models(relPathReqModel)
+	methods(objValidator)
+	methods(objInteractor)
+	models(relPathRespModel)
+	methods(objPresenter)
+	models(relPathViewModel)
+	methods(objView)
+	return steps, nil
+}
+
+// explainer extracts the steps of a usecase from the files of a project, each of which is parsed once
+type explainer struct {
+	projectPath string
+	files       map[string]*ast.File
+	fset        *token.FileSet
+}
+
+// parse returns the parsed file fp or nil if it can't be parsed
+func (e explainer) parse(fp string) *ast.File {
+	if f, ok := e.files[fp]; ok {
+		return f
+	}
+	b, err := readFile(fp)
+	var f *ast.File
+	if err == nil {
+		f, _ = parseFile(e.fset, fp, b, parser.ParseComments)
+	}
+	e.files[fp] = f
+	return f
+}
+
+// relPath returns fp relative to the project
+func (e explainer) relPath(fp string) string {
+	if rel, err := filepath.Rel(filepath.FromSlash(e.projectPath), fp); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(fp)
+}
+
+// method returns the step of the method name of the implementation in the file fp of the object of objType
+func (e explainer) method(objType, fp, name string) explainStep {
+	step := explainStep{Layer: objType, Name: name, Missing: true}
+	f := e.parse(fp)
+	if f == nil {
+		return step
+	}
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Recv == nil || fd.Name.Name != name {
+			continue
+		}
+		sig := *fd
+		sig.Doc, sig.Body = nil, nil
+		var b bytes.Buffer
+		printer.Fprint(&b, e.fset, &sig)
+		step.File, step.Line, step.Signature, step.Missing = e.relPath(fp), e.fset.Position(fd.Pos()).Line, b.String(), false
+		step.Stub = fd.Body == nil || len(fd.Body.List) == 0
+		for _, cg := range f.Comments {
+			if fd.Body != nil && cg.Pos() > fd.Body.Lbrace && cg.End() < fd.Body.Rbrace && strings.Contains(cg.Text(), todoMarker) {
+				step.Stub = true
+			}
+		}
+		break
+	}
+	return step
+}
+
+// model returns the step of the struct name declared in the file fp of the model folder layer
+func (e explainer) model(layer, fp, name string) explainStep {
+	step := explainStep{Layer: layer, Name: name, Missing: true}
+	f := e.parse(fp)
+	if f == nil {
+		return step
+	}
+	ts := findTypeSpec(f, name)
+	if ts == nil {
+		return step
+	}
+	st, ok := ts.Type.(*ast.StructType)
+	if !ok {
+		return step
+	}
+	var fields []string
+	for _, field := range st.Fields.List {
+		var b bytes.Buffer
+		printer.Fprint(&b, e.fset, field.Type)
+		if len(field.Names) == 0 {
+			fields = append(fields, b.String())
+		}
+		for _, n := range field.Names {
+			fields = append(fields, n.Name+" "+b.String())
+		}
+	}
+	sig := fmt.Sprintf("type %s struct{}", name)
+	if len(fields) > 0 {
+		sig = fmt.Sprintf("type %s struct{ %s }", name, strings.Join(fields, "; "))
+	}
+	step.File, step.Line, step.Signature, step.Missing, step.Stub = e.relPath(fp), e.fset.Position(ts.Pos()).Line, sig, false, len(fields) == 0
+	return step
+}
+
+// printExplanation prints the steps of the usecase of the interactor as plain text or, if asJSON is true, as JSON
+func printExplanation(usecase, interactor string, steps []explainStep, asJSON bool) error {
+	if asJSON {
+		b, err := json.MarshalIndent(struct {
+			Interactor string        `json:"interactor"`
+			Usecase    string        `json:"usecase"`
+			Steps      []explainStep `json:"steps"`
+		}{exportedName(interactor), usecase, steps}, "", "\t")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\n", b)
+		return nil
+	}
+	fmt.Printf("The usecase %s of %s runs through:\n\n", usecase, exportedName(interactor))
+	for i, s := range steps {
+		switch {
+		case s.Missing:
+			fmt.Printf("%d. %s %s is missing\n", i+1, s.Layer, s.Name)
+			continue
+		case s.Stub:
+			fmt.Printf("%d. %s %s (TODO stub)\n", i+1, s.Layer, s.Name)
+		default:
+			fmt.Printf("%d. %s %s\n", i+1, s.Layer, s.Name)
+		}
+		fmt.Printf("\t%s:%d\n\t%s\n", s.File, s.Line, s.Signature)
+	}
+	fmt.Printf("\n")
+	return nil
+}
@@ -0,0 +1,335 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	gofmt "go/format"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+const (
+	// layoutFlat is the layout whose layers are all generated into the package of the clean folder, see
+	// addFlatInteractor
+	layoutFlat = "flat"
+	// flatPackage is the name of the package of the clean folder in the flat layout
+	flatPackage = "clean"
+	// flatModelsSuffix follows the file name of the interactor in the name of the file of its models e.g.
+	// order_models.go
+	flatModelsSuffix = "_models"
+)
+
+// flatLayout is true if layout=flat is set in the configuration file
+var flatLayout bool
+
+// flatObjTypes are the objects of an interactor in the flat layout, in the order their files are generated
+var flatObjTypes = []string{objController, objInteractor, objPresenter, objValidator, objView}
+
+// flatUnsupportedFlags holds the flags which add objects or packages of their own and aren't supported by the
+// flat layout
+var flatUnsupportedFlags = append([]string{"proto", "unexported-interface"}, verticalUnsupportedFlags...)
+
+// setFlatLayout sets the flat layout, whose types are named after their interactor and their object to tell
+// them apart within the package e.g. OrderController and OrderInteractor
+func setFlatLayout() {
+	flatLayout = true
+	objTypes = flatObjTypes
+	for _, objType := range flatObjTypes {
+		typeSuffixes[objType] = firstCharToUpper(objType)
+	}
+}
+
+// flatObjPath returns the path of the file of the object of objType of the interactor in the flat layout e.g.
+// clean/order_controller.go
+func flatObjPath(basePath, objType, interactor string) string {
+	return filepath.FromSlash(basePath + fileName(interactor) + "_" + objType + ".go")
+}
+
+// flatModelsPath returns the path of the file of the models of the usecases of the interactor in the flat layout
+func flatModelsPath(basePath, interactor string) string {
+	return filepath.FromSlash(basePath + fileName(interactor) + flatModelsSuffix + ".go")
+}
+
+// objectFilePath returns the path of the file of the object of objType of the interactor in the layout of the
+// project at basePath
+func objectFilePath(basePath, objType, interactor string) string {
+	if flatLayout {
+		return flatObjPath(basePath, objType, interactor)
+	}
+	return filepath.FromSlash(basePath + objRelPaths[objType] + fileName(interactor) + ".go")
+}
+
+// flatModels returns the names of the models of the usecase in the flat layout, which are the RequestModel, the
+// ResponseModels and the ViewModels named after the usecase
+func flatModels(usecase string) []string {
+	v := exportedName(usecase)
+	return []string{v + "Request", v + "Response", v + "ResponseErrVal", v + "ViewModel", v + "ViewModelErrVal"}
+}
+
+// flatDep is a dependency of an object held by a field and passed to its constructor
+type flatDep struct {
+	Field, Type string
+}
+
+// flatDeps returns the dependencies of the object of objType of the interactor, which are the objects it calls
+func flatDeps(objType, interactor string) []flatDep {
+	switch objType {
+	case objController:
+		return []flatDep{{"ia", typeName(objInteractor, interactor)}}
+	case objInteractor:
+		return []flatDep{{"ps", typeName(objPresenter, interactor)}, {"val", typeName(objValidator, interactor)}}
+	case objPresenter:
+		return []flatDep{{"vw", typeName(objView, interactor)}}
+	}
+	return nil
+}
+
+// flatObjSource is the template of the file of an object in the flat layout
+const flatObjSource = `{{.Clause}}
+{{if .Deps}}
+import (
+	"errors"
+)
+{{end}}
+{{.Desc}}
+type {{.UcObjName}} interface {
+	// TODO add interface methods by using Clean. For more info run "clean help add usecase"
+}
+
+// {{.LcObjName}} is an implementation of {{.UcObjName}}.
+type {{.LcObjName}} struct {
+{{- range .Deps}}
+	{{.Field}} {{.Type}}
+{{- end}}
+	// TODO define struct fields
+}
+{{if .Deps}}
+// New{{.UcObjName}} constructs a new {{.UcObjName}} and returns a nil error if successful. Otherwise it returns an error.
+func New{{.UcObjName}}({{range $i, $d := .Deps}}{{if $i}}, {{end}}{{$d.Field}} {{$d.Type}}{{end}}) ({{.UcObjName}}, error) {
+	if {{range $i, $d := .Deps}}{{if $i}} || {{end}}{{$d.Field}} == nil{{end}} {
+		return nil, errors.New("Error constructing {{.UcObjName}}")
+	}
+	return &{{.LcObjName}}{
+{{- range .Deps}}
+		{{.Field}}: {{.Field}},
+{{- end}}
+	}, nil
+}
+{{- else}}
+// New{{.UcObjName}} constructs a new {{.UcObjName}}. Returns nil if it fails.
+func New{{.UcObjName}}() {{.UcObjName}} {
+	return &{{.LcObjName}}{}
+}
+{{- end}}
+`
+
+// addFlatInteractor adds the interactor name in the flat layout. Its controller, interactor, presenter,
+// validator and view are generated into the package of the clean folder, each in a file of its own named after
+// the interactor and the object e.g. order_controller.go, and its models into a file of their own e.g.
+// order_models.go. The objects refer to each other directly instead of through imports. Only the objects
+// selected by --only are added.
+func addFlatInteractor(basePath, name, desc string) {
+	tmpl := template.Must(template.New("flat").Parse(flatObjSource))
+	for _, objType := range flatObjTypes {
+		if !selected(objType) {
+			continue
+		}
+		ucObjName := typeName(objType, name)
+		fp := flatObjPath(basePath, objType, name)
+		if f, err := parseGoFile(fp); err == nil && findTypeSpec(f, ucObjName) != nil {
+			noopf("the interactor %s already exists", exportedName(name))
+			continue
+		}
+		data := struct {
+			Clause, Desc, UcObjName, LcObjName string
+			Deps                               []flatDep
+		}{
+			Clause:    packageClause(basePath, flatPackage),
+			Desc:      objDoc(ucObjName, firstCharToUpper(objType), desc),
+			UcObjName: ucObjName,
+			LcObjName: unexportedName(ucObjName),
+			Deps:      flatDeps(objType, name),
+		}
+		var b bytes.Buffer
+		if err := tmpl.Execute(&b, data); err != nil {
+			failf("Error adding the %s of %s: %s\n", objType, exportedName(name), err.Error())
+			return
+		}
+		src, err := gofmt.Source(b.Bytes())
+		if err != nil {
+			failf("Error adding the %s of %s: %s\n", objType, exportedName(name), err.Error())
+			return
+		}
+		if err := writeFile(fp, src); err != nil {
+			failf("Error writing to %s: %s\n", fp, err.Error())
+			return
+		}
+	}
+	if fp := flatModelsPath(basePath, name); !fileExists(fp) {
+		content := fmt.Sprintf("%s\n\n// The RequestModels, ResponseModels and ViewModels of the usecases of %s are named after their usecases\n// e.g. AddItemRequest, AddItemResponse and AddItemViewModel.\n", packageClause(basePath, flatPackage), exportedName(name))
+		if err := writeFile(fp, []byte(content)); err != nil {
+			failf("Error writing to %s: %s\n", fp, err.Error())
+		}
+	}
+}
+
+// flatMethods returns the method signature of the interface and the methods of the implementation of the object
+// of objType for the usecase v in the flat layout. The implementation is lcObjName and its receiver self.
+func flatMethods(objType, v, ucObjName, lcObjName, self string) (string, string) {
+	var sig, method string
+	impl := func(name, params, results, body string) string {
+		return fmt.Sprintf("\n\n// %s implements the %s interface method %s.\nfunc (%s *%s) %s(%s)%s {\n%s}", name, ucObjName, name, self, lcObjName, name, params, results, body)
+	}
+	switch objType {
+	case objController:
+		sig = fmt.Sprintf("\t// %s handles the input of the usecase %s.\n\t%s()\n", v, v, v)
+		method = impl(v, "", "", fmt.Sprintf("\t// TODO: Convert the input to the %sRequest\n\t%s.ia.%s(&%sRequest{})\n", v, self, v, v))
+	case objInteractor:
+		sig = fmt.Sprintf("\t// %s runs the usecase %s.\n\t%s(rqm *%sRequest)\n", v, v, v, v)
+		method = impl(v, fmt.Sprintf("rqm *%sRequest", v), "", fmt.Sprintf("\t// Validate Request Model\n\tif rsm := %s.val.Validate%s(rqm); rsm != nil {\n\t\t%s.ps.Present%sErrVal(rsm)\n\t\treturn\n\t}\n\n\t// TODO: Implement interface method\n\t%s.ps.Present%s(&%sResponse{})\n", self, v, self, v, self, v, v))
+	case objPresenter:
+		sig = fmt.Sprintf("\t// Present%s presents the output of the usecase %s.\n\tPresent%s(rsm *%sResponse)\n\t// Present%sErrVal presents the output of the usecase %s if its input is invalid.\n\tPresent%sErrVal(rsm *%sResponseErrVal)\n", v, v, v, v, v, v, v, v)
+		method = impl("Present"+v, fmt.Sprintf("rsm *%sResponse", v), "", fmt.Sprintf("\t// TODO: Convert the Response to the ViewModel\n\t%s.vw.Render%s(&%sViewModel{})\n", self, v, v)) +
+			impl("Present"+v+"ErrVal", fmt.Sprintf("rsm *%sResponseErrVal", v), "", fmt.Sprintf("\t// TODO: Convert the ResponseErrVal to the ViewModel\n\t%s.vw.Render%sErrVal(&%sViewModelErrVal{})\n", self, v, v))
+	case objValidator:
+		sig = fmt.Sprintf("\t// Validate%s validates rqm. If valid it returns nil otherwise an %sResponseErrVal\n\tValidate%s(rqm *%sRequest) *%sResponseErrVal\n", v, v, v, v, v)
+		method = impl("Validate"+v, fmt.Sprintf("rqm *%sRequest", v), fmt.Sprintf(" *%sResponseErrVal", v), "\t// TODO: Implement interface method\n\treturn nil\n")
+	case objView:
+		sig = fmt.Sprintf("\t// Render%s renders the output of the usecase %s.\n\tRender%s(vm *%sViewModel)\n\t// Render%sErrVal renders the invalid input of the usecase %s.\n\tRender%sErrVal(vm *%sViewModelErrVal)\n", v, v, v, v, v, v, v, v)
+		method = impl("Render"+v, fmt.Sprintf("vm *%sViewModel", v), "", "\t// TODO: Implement interface method\n") +
+			impl("Render"+v+"ErrVal", fmt.Sprintf("vm *%sViewModelErrVal", v), "", "\t// TODO: Implement interface method\n")
+	}
+	return sig, method
+}
+
+// addFlatUsecase adds the usecase spec to the objects of the interactor in the flat layout together with its
+// models, which are named after the usecase e.g. AddItemRequest, and the fields of its RequestModel. Only the
+// objects selected by --only are added to.
+func addFlatUsecase(basePath string, spec namedSpec, interactor string) {
+	v := exportedName(spec.Name)
+	if !fileExists(interactorFilePath(basePath, interactor)) {
+		failf("Error adding the usecase %s: the interactor %s doesn't exist, add it with \"clean add interactor %s\"\n", v, exportedName(interactor), exportedName(interactor))
+		return
+	}
+	for _, objType := range flatObjTypes {
+		if !selected(objType) {
+			continue
+		}
+		fp := flatObjPath(basePath, objType, interactor)
+		b, err := readFile(fp)
+		if err != nil {
+			failf("Error reading %s: %s\n", fp, err.Error())
+			return
+		}
+		ucObjName, lcObjName := fileObjNames(b, objType, interactor)
+		if f, err := parseFile(token.NewFileSet(), fp, b, 0); err == nil {
+			if methods, _ := interfaceMethods(f, ucObjName); len(appendUnique(methods, usecaseMethods(objType, v)[0])) == len(methods) {
+				noopf("the usecase %s already exists in %s", v, ucObjName)
+				continue
+			}
+		}
+		sig, method := flatMethods(objType, v, ucObjName, lcObjName, fileReceiverName(b, objType, ucObjName))
+		nb, err := addMethodSignatureToInterface(b, fp, sig, ucObjName)
+		if err != nil {
+			failf("Error in addMethodSignatureToInterface: %s\n", err.Error())
+			return
+		}
+		if nb, err = addMethodToImpl(nb, method, lcObjName); err != nil {
+			failf("Error in addMethodToImpl: %s\n", err.Error())
+			return
+		}
+		if err := writeFile(fp, nb); err != nil {
+			failf("Error writing to %s: %s\n", fp, err.Error())
+			return
+		}
+	}
+	fp := flatModelsPath(basePath, interactor)
+	if err := addFlatModels(fp, v); err != nil {
+		failf("Error adding the models of %s: %s\n", v, err.Error())
+		return
+	}
+	if len(spec.Fields)+len(spec.Types) > 0 {
+		if err := addStructFields(fp, v+"Request", spec.Fields); err != nil {
+			failf("Error adding the fields of %s: %s\n", v, err.Error())
+		}
+		if err := addStructDecls(fp, spec.Types); err != nil {
+			failf("Error adding the types of the fields of %s: %s\n", v, err.Error())
+		}
+	}
+}
+
+// addFlatModels appends the models of the usecase v which the models file fp doesn't declare yet to it
+func addFlatModels(fp, v string) error {
+	b, err := readFile(fp)
+	if err != nil {
+		return err
+	}
+	f, err := parseFile(token.NewFileSet(), fp, b, 0)
+	if err != nil {
+		return err
+	}
+	docs := map[string]string{
+		v + "Request":         "the RequestModel, i.e. the input, of the usecase %s.",
+		v + "Response":        "the ResponseModel, i.e. the output, of the usecase %s.",
+		v + "ResponseErrVal":  "the ResponseModel of the usecase %s if its input is invalid.",
+		v + "ViewModel":       "the data the View renders the output of the usecase %s from.",
+		v + "ViewModelErrVal": "the data the View renders the invalid input of the usecase %s from.",
+	}
+	var content string
+	for _, name := range flatModels(v) {
+		if findTypeSpec(f, name) != nil {
+			continue
+		}
+		content += fmt.Sprintf("\n// %s is %s\ntype %s struct {\n\t// TODO: Add struct members\n}\n", name, fmt.Sprintf(docs[name], v), name)
+	}
+	if content == "" {
+		return nil
+	}
+	return writeFile(fp, append(append([]byte{}, b...), content...))
+}
+
+// flatInteractorUsecases returns the names of the interactors of the project at basePath in the flat layout
+// mapped to their usecases, which are the methods of their Interactors
+func flatInteractorUsecases(basePath string) (map[string][]string, error) {
+	dir := filepath.FromSlash(basePath)
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	interactors := make(map[string][]string)
+	suffix := "_" + objInteractor + ".go"
+	for _, fi := range files {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), suffix) {
+			continue
+		}
+		f, err := parseGoFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return nil, err
+		}
+		base := strings.TrimSuffix(fi.Name(), suffix)
+		for _, name := range interfaceNames(f) {
+			objName, ok := objNameFromTypeName(objInteractor, name, base)
+			if !ok {
+				continue
+			}
+			methods, _ := interfaceMethods(f, name)
+			interactors[objName] = append([]string{}, methods...)
+		}
+	}
+	return interactors, nil
+}
+
+// flatModelsStatus returns the one line status of the models file of the interactor with the usecases
+func flatModelsStatus(basePath, interactor string, usecases []string) string {
+	var expected []string
+	for _, usecase := range usecases {
+		expected = append(expected, flatModels(usecase)...)
+	}
+	return objectStatus(flatModelsPath(basePath, interactor), expected, structNames, "", nil)
+}
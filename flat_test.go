@@ -0,0 +1,33 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestFlatGolden compares the files of a project initialised with --flat, after an interactor and two
+// usecases were added to it, to the golden file and builds the project
+func TestFlatGolden(t *testing.T) {
+	p := newTestProject(t, "--flat")
+	p.clean("add", "interactor", "Order")
+	p.clean("add", "usecase", "AddItem", "to", "Order")
+	p.clean("add", "usecase", "RemoveItem", "to", "Order")
+
+	files := p.files("clean")
+	for name := range files {
+		if strings.Contains(name, "/") && !strings.HasPrefix(name, "test/") {
+			t.Errorf("%s isn't in the clean folder", name)
+		}
+	}
+	compareGolden(t, "flat", concatenated(files))
+
+	if testing.Short() {
+		return
+	}
+	if out, err := p.goRun("vet", "./..."); err != nil {
+		t.Errorf("the project doesn't compile: %s\n%s", err.Error(), out)
+	}
+}
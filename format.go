@@ -0,0 +1,57 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	gofmt "go/format"
+	"os"
+	"path/filepath"
+)
+
+// formatProject reformats the Go files in basePath in place with gofmt and prints the paths, relative
+// to projectPath, of the files which were reformatted. Files that don't parse are skipped and reported.
+func formatProject(projectPath, basePath string) error {
+	defer startPhase(phaseScan)()
+	var reformatted, skipped int
+	err := filepath.Walk(filepath.FromSlash(basePath), func(fp string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() || filepath.Ext(fp) != ".go" {
+			return nil
+		}
+		relFp, err := filepath.Rel(filepath.FromSlash(projectPath), fp)
+		if err != nil {
+			relFp = fp
+		}
+		b, err := readFile(fp)
+		if err != nil {
+			return err
+		}
+		stopPhase := startPhase(phaseFormat)
+		formatted, err := gofmt.Source(b)
+		stopPhase()
+		if err != nil {
+			fmt.Printf("Skipped %s: %s\n", filepath.ToSlash(relFp), err.Error())
+			skipped++
+			return nil
+		}
+		if bytes.Equal(b, formatted) {
+			return nil
+		}
+		if err := rewriteFile(fp, formatted); err != nil {
+			return err
+		}
+		fmt.Printf("Reformatted %s\n", filepath.ToSlash(relFp))
+		reformatted++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\n%d files reformatted, %d skipped\n\n", reformatted, skipped)
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// fuzzTypes holds the types that can be arguments of a fuzz target mapped to their zero values
+var fuzzTypes = map[string]string{
+	"string":  `""`,
+	"[]byte":  "[]byte{}",
+	"bool":    "false",
+	"byte":    "byte(0)",
+	"rune":    "rune(0)",
+	"int":     "int(0)",
+	"int8":    "int8(0)",
+	"int16":   "int16(0)",
+	"int32":   "int32(0)",
+	"int64":   "int64(0)",
+	"uint":    "uint(0)",
+	"uint8":   "uint8(0)",
+	"uint16":  "uint16(0)",
+	"uint32":  "uint32(0)",
+	"uint64":  "uint64(0)",
+	"float32": "float32(0)",
+	"float64": "float64(0)",
+}
+
+// addValidatorFuzzTarget adds a FuzzValidate[usecase] fuzz target to the validator test file of the
+// interactor. If all fields of the usecase's RequestModel are of types supported by fuzzing, the fields
+// are fuzzed directly. Otherwise the RequestModel is unmarshalled from fuzzed JSON.
+func addValidatorFuzzTarget(basePath, usecase, interactor string) error {
+	v := exportedName(usecase)
+	fp := filepath.FromSlash(basePath + relPathValidator + "test/" + fileName(interactor) + "_test.go")
+	var b []byte
+	if fileExists(fp) {
+		var err error
+		if b, err = readFile(fp); err != nil {
+			return err
+		}
+		if bytes.Contains(b, []byte(fmt.Sprintf("func FuzzValidate%s(", v))) {
+			noopf("the fuzz target FuzzValidate%s already exists", v)
+			return nil
+		}
+	} else {
+		b = []byte("// Package test provides ...\npackage test\n")
+	}
+	fields, err := structFields(filepath.FromSlash(basePath+relPathReqModel+fileName(interactor)+".go"), v)
+	if err != nil {
+		return err
+	}
+	fuzzFields := len(fields) > 0
+	for _, field := range fields {
+		if _, ok := fuzzTypes[field.Type]; !ok {
+			fuzzFields = false
+		}
+	}
+	imports := []string{"testing", layerImportPath(relPathReqModel), layerImportPath(relPathValidator)}
+	valName := typeName(objValidator, interactor)
+	var seeds, params, assignments string
+	if fuzzFields {
+		var zeroes, args []string
+		for _, field := range fields {
+			param := "in" + exportedName(field.Name)
+			zeroes = append(zeroes, fuzzTypes[field.Type])
+			args = append(args, param+" "+field.Type)
+			assignments += fmt.Sprintf("\t\t\t%s: %s,\n", field.Name, param)
+		}
+		seeds = strings.Join(zeroes, ", ")
+		params = strings.Join(args, ", ")
+		assignments = fmt.Sprintf("\t\trqm := &reqmodel.%s{\n%s\t\t}\n", v, assignments)
+	} else {
+		imports = append(imports, "encoding/json")
+		seeds = "[]byte(\"{}\")"
+		params = "data []byte"
+		assignments = fmt.Sprintf("\t\trqm := &reqmodel.%s{}\n\t\tif err := json.Unmarshal(data, rqm); err != nil {\n\t\t\tt.Skip()\n\t\t}\n", v)
+	}
+	for _, path := range imports {
+		if b, err = ensureImport(b, path); err != nil {
+			return err
+		}
+	}
+	target := fmt.Sprintf("\n\n// FuzzValidate%s fuzzes the %s Validator method Validate%s. It fails if Validate%s panics or\n// if it doesn't validate the same RequestModel consistently. Run it with \"go test -fuzz FuzzValidate%s\".\nfunc FuzzValidate%s(f *testing.F) {\n\tval := validator.New%s()\n\tf.Add(%s)\n\tf.Fuzz(func(t *testing.T, %s) {\n%s\t\trsm := val.Validate%s(rqm)\n\t\tif again := val.Validate%s(rqm); (rsm == nil) != (again == nil) {\n\t\t\tt.Fatalf(\"Validate%s validated the same RequestModel differently: %%v and %%v\", rsm, again)\n\t\t}\n\t\t// TODO: Assert that a non-nil rsm describes which fields of rqm are invalid\n\t})\n}\n", v, valName, v, v, v, v, valName, seeds, params, assignments, v, v, v)
+	return writeFile(fp, append(bytes.TrimRight(b, "\n"), target...))
+}
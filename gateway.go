@@ -0,0 +1,213 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// gatewayPortRelPath returns the folder of the Gateway interfaces the Interactors depend on, which is
+// in the usecase layer if --with-gateway-interface-in-usecase is set and in the interface adapters
+// layer if --with-gateway is set. It returns an empty string if the Interactors have no Gateways.
+func gatewayPortRelPath() string {
+	switch {
+	case *gatewayInUsecase:
+		return relPathGatewayPort
+	case *withGateway:
+		return relPathGateway
+	}
+	return ""
+}
+
+// gatewayDriver is a database driver the implementation of a Gateway can hold a handle of
+type gatewayDriver struct {
+	// importPath is the import path of the package of the driver
+	importPath string
+	// field is the field of the handle e.g. "db *sql.DB", which is passed to the Gateway's constructor
+	field string
+}
+
+// gatewayDrivers are the database drivers of the Gateway implementations by the names of --driver
+var gatewayDrivers = map[string]gatewayDriver{
+	"sql":  {"database/sql", "db *sql.DB"},
+	"gorm": {"gorm.io/gorm", "db *gorm.DB"},
+}
+
+// verifyDriver returns an error if --driver is set to anything else than the name of a database driver
+func verifyDriver() error {
+	if _, ok := gatewayDrivers[*driver]; *driver != "" && !ok {
+		return fmt.Errorf("invalid driver %q, the drivers are %s", *driver, strings.Join(driverNames(), ", "))
+	}
+	return nil
+}
+
+// driverNames returns the names of the database drivers in alphabetical order
+func driverNames() []string {
+	var names []string
+	for name := range gatewayDrivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// gatewayTmpl is the template of the Gateway of an Interactor. If PortImport is set, the interface is
+// declared in the usecase layer and only its implementation is generated here.
+const gatewayTmpl = `{{if .Imports}}
+
+import (
+{{.Imports}})
+{{- end}}
+{{- if .Interface}}
+
+{{.Desc}}
+type {{.UcObjName}} interface {
+	// TODO add interface methods
+}
+{{- end}}
+{{- if .Impl}}
+
+// {{.LcObjName}} is an implementation of {{if .PortImport}}the {{.UcObjName}} Gateway of the usecase layer{{else}}{{.UcObjName}}{{end}}.
+type {{.LcObjName}} struct {
+{{- if .DriverField}}
+	{{.DriverField}}
+{{- end}}
+	// TODO define struct fields
+}
+
+// New{{.UcObjName}} constructs a new {{.UcObjName}}. Returns nil if it fails.
+func New{{.UcObjName}}({{.DriverField}}) {{if .PortImport}}port.{{end}}{{.UcObjName}} {
+	return &{{.LcObjName}}{ {{- if .DriverField}}
+		db: db,
+	{{end -}} }
+}
+{{- .Methods}}
+{{- end}}`
+
+// addGateway adds the Gateway of the interactor name. If inUsecase is true its interface is added to the
+// usecase layer, where the Interactor depends on it, and its implementation to the interface adapters
+// layer. Otherwise both are added to the interface adapters layer. Unless driver is empty the implementation
+// holds a handle of the database driver, and it gets a stub of each method the interface already has.
+func addGateway(basePath, name, desc string, inUsecase bool, driver string) {
+	ucObjName := typeName(objGateway, name)
+	tmplData := struct {
+		UcObjName      string
+		LcObjName      string
+		Desc           string
+		InteractorName string
+		PortImport     string
+		Imports        string
+		DriverField    string
+		Methods        string
+		Interface      bool
+		Impl           bool
+	}{
+		UcObjName: ucObjName,
+		LcObjName: unexportedName(ucObjName),
+		Desc: docComment(fmt.Sprintf("// %s is a Clean Architecture Gateway object that wraps its related methods. It gives the\n// %s Interactor access to e.g. a database or an external service.\n%s", ucObjName, typeName(objInteractor, name), typeDesc(desc)),
+			objDoc(ucObjName, "Gateway", desc)),
+		InteractorName: typeName(objInteractor, name),
+	}
+	type gatewayFile struct {
+		relPath string
+		iface   bool
+		impl    bool
+	}
+	files := []gatewayFile{{relPathGateway, !inUsecase, true}}
+	if inUsecase {
+		tmplData.PortImport = projectBaseImportPath + "clean/" + strings.TrimSuffix(relPathGatewayPort, "/")
+		files = []gatewayFile{{relPathGatewayPort, true, false}, {relPathGateway, false, true}}
+	}
+	parsedTmpl := template.Must(template.New("gateway").Parse(gatewayTmpl))
+	for _, f := range files {
+		fp := filepath.FromSlash(basePath + f.relPath + fileName(name) + ".go")
+		if fileExists(fp) {
+			noopf("the gateway %s already exists", ucObjName)
+			continue
+		}
+		tmplData.Interface, tmplData.Impl = f.iface, f.impl
+		tmplData.Imports, tmplData.DriverField, tmplData.Methods = "", "", ""
+		if f.impl {
+			var imports []string
+			if tmplData.PortImport != "" {
+				imports = append(imports, "port "+strconv.Quote(tmplData.PortImport))
+			}
+			if d, ok := gatewayDrivers[driver]; ok {
+				imports = append(imports, strconv.Quote(d.importPath))
+				tmplData.DriverField = d.field
+			}
+			if inUsecase {
+				methods, methodImports, err := gatewayStubs(basePath, name)
+				if err != nil {
+					failf("Error generating %s: %s\n", fp, err.Error())
+					return
+				}
+				tmplData.Methods = methods
+				imports = append(imports, methodImports...)
+			}
+			if len(imports) > 0 {
+				tmplData.Imports = importBlock(imports)
+			}
+		}
+		var b bytes.Buffer
+		b.WriteString(packageClause(basePath+f.relPath, objGateway))
+		if err := render(&b, parsedTmpl, tmplData); err != nil {
+			failf("Error generating %s: %s\n", fp, err.Error())
+			return
+		}
+		b.WriteString("\n")
+		if err := mkdirAll(filepath.Dir(fp)); err != nil {
+			failf("Error creating the folder of %s: %s\n", fp, err.Error())
+			return
+		}
+		if err := writeFile(fp, b.Bytes()); err != nil {
+			failf("Error writing %s: %s\n", fp, err.Error())
+			return
+		}
+	}
+}
+
+// gatewayStubs returns a stub of each method of the Gateway interface of the interactor name declared in the
+// usecase layer of the project at basePath, to be added to its implementation, and the imports they need
+func gatewayStubs(basePath, name string) (string, []string, error) {
+	f, err := parseGoFile(filepath.FromSlash(basePath + relPathGatewayPort + fileName(name) + ".go"))
+	if err != nil {
+		return "", nil, err
+	}
+	ucObjName := typeName(objGateway, name)
+	ts := findTypeSpec(f, ucObjName)
+	if ts == nil {
+		return "", nil, nil
+	}
+	it, ok := ts.Type.(*ast.InterfaceType)
+	if !ok {
+		return "", nil, nil
+	}
+	pkgs := make(map[string]bool)
+	lcObjName := unexportedName(ucObjName)
+	self := receiverName(ucObjName)
+	var b bytes.Buffer
+	for _, m := range it.Methods.List {
+		ft, ok := m.Type.(*ast.FuncType)
+		if !ok {
+			continue
+		}
+		usedPackages(ft, pkgs)
+		body := "\t// TODO: Implement interface method\n"
+		if ft.Results != nil && len(ft.Results.List) > 0 {
+			body += "\tpanic(\"not implemented\")\n"
+		}
+		for _, n := range m.Names {
+			fmt.Fprintf(&b, "\n\n// %s implements the %s interface method %s.\nfunc (%s *%s) %s%s {\n%s}", n.Name, ucObjName, n.Name, self, lcObjName, n.Name, funcSignature(ft), body)
+		}
+	}
+	return useParams(b.String()), importsOf(f, pkgs), nil
+}
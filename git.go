@@ -0,0 +1,48 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// uncommittedFiles returns the lines of "git status --porcelain" of the files among paths which have
+// uncommitted changes, including untracked files. It returns nil if dir isn't inside a git repository or
+// git isn't installed.
+func uncommittedFiles(dir string, paths []string) []string {
+	if len(paths) == 0 {
+		return nil
+	}
+	if err := exec.Command("git", "-C", dir, "rev-parse", "--is-inside-work-tree").Run(); err != nil {
+		return nil
+	}
+	out, err := exec.Command("git", append([]string{"-C", dir, "status", "--porcelain", "--"}, paths...)...).Output()
+	if err != nil {
+		return nil
+	}
+	var lines []string
+	for _, line := range strings.Split(string(bytes.TrimRight(out, "\n")), "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// writeIfCommitted writes the files kept in memory by the command to disk unless any of them has
+// uncommitted changes in the git repository of the project at projectPath, in which case nothing is written.
+func writeIfCommitted(projectPath string) {
+	*stdout = false
+	if len(errorMessages) > 0 {
+		return
+	}
+	if dirty := uncommittedFiles(filepath.FromSlash(projectPath), pendingOrder); len(dirty) > 0 {
+		failf("Nothing was generated since the following files have uncommitted changes:\n\n\t%s\n\nCommit or stash the changes, or leave out --require-clean-git.\n", strings.Join(dirty, "\n\t"))
+		return
+	}
+	writePendingFiles()
+}
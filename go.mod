@@ -0,0 +1,7 @@
+module github.com/strtob01/clean
+
+go 1.17
+
+require github.com/fsnotify/fsnotify v1.9.0
+
+require golang.org/x/sys v0.13.0 // indirect
@@ -0,0 +1,783 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// commandArg is an argument of a command
+type commandArg struct {
+	Name string
+	Desc string
+}
+
+// command describes a verb, or a verb followed by an object e.g. "add usecase", for the help system
+type command struct {
+	// Name is the verb optionally followed by an object e.g. "add usecase"
+	Name string
+	// Aliases holds the short forms of the last word of Name e.g. uc for "add usecase"
+	Aliases []string
+	// Synopsis holds the arguments following the name e.g. "[usecase] to [interactor]"
+	Synopsis string
+	// Short is the one line description listed together with the other verbs or objects
+	Short string
+	// Long is the description shown by "clean help [name]"
+	Long string
+	Args []commandArg
+	// Flags holds the names of the flags the command accepts
+	Flags []string
+	// FlagUsages holds the usages of the flags whose meaning for the command differs from their usage shared by
+	// all commands, keyed by the name of the flag e.g. force
+	FlagUsages map[string]string
+	// Details, if set, returns the part of the description which depends on the configuration and the flags
+	// given e.g. the files the command generates. It follows Long.
+	Details func() string
+}
+
+// globalFlags holds the names of the flags accepted by all commands
+var globalFlags = []string{"create-only", "fail-on-noop", "json", "lint-clean", "no-history", "require-clean-git", "strict", "timings"}
+
+// commands is the registry of the commands of clean, from which the help is rendered
+var commands = []command{
+	{
+		Name:    verbAdd,
+		Aliases: []string{"a"},
+		Short:   "add e.g. new usecase",
+		Long:    "With the -interactive flag Clean prompts for any missing arguments, e.g. for the interactor when running \"clean add usecase AddItem to -interactive\".",
+		Flags:   []string{"interactive"},
+	},
+	{
+		Name:     verbAdd + " " + objEntity,
+		Synopsis: "[name]",
+		Short:    "add entity e.g. Product",
+		Long:     "Adds an Entity struct to the entity folder.",
+		Args: []commandArg{
+			{"name", "name of entity e.g. Product, or - to read one name per line from stdin. A name read from stdin may be followed by a colon and the fields of the entity e.g. \"Product: Name string, Price float64\""},
+		},
+		Flags: []string{"build-tag", "desc", "guess-words", "stdout", "strict-names", "terse", "with-validation"},
+	},
+	{
+		Name:     verbAdd + " " + objDecorator,
+		Synopsis: "[kind] for [interactor]",
+		Short:    "add a decorator of an interactor e.g. for logging, tracing or metrics",
+		Long:     "Adds a decorator of the kind to the interactor folder e.g. order_logging.go for the logging decorator of Order. The decorator wraps an Order Interactor and each of its methods runs the code of the kind around the call of the wrapped method. The logging decorator logs the start, the end and the duration of each usecase with a *slog.Logger, which requires Go 1.21 or later. The tracing decorator records an OpenTelemetry span named e.g. Order.AddItem of each usecase, which is marked as failed if the usecase returns an error or an invalid input. The span continues the trace of the context.Context parameter of the usecase if it has one. Only tracing.go, which is added along with the first tracing decorator, imports OpenTelemetry. The metrics decorator counts the calls and measures the latency of each usecase with the Metrics interface of metrics.go, e.g. as order_add_item_calls_total and order_add_item_duration_seconds, which can be implemented with e.g. Prometheus or expvar. Every add, remove and apply command adds the methods of the new usecases to the decorators and removes the methods of the removed ones. The rest of a decorator, including the methods of the other usecases, is kept as it is so it may be edited.",
+		Args: []commandArg{
+			{"kind", "one of " + strings.Join(decoratorKindNames(), ", ")},
+			{"interactor", "name of an existing interactor e.g. Order"},
+		},
+		Flags: []string{"stdout"},
+	},
+	{
+		Name:     verbAdd + " " + featureEvents,
+		Synopsis: "[interactor]",
+		Short:    "add the domain events an interactor emits",
+		Long:     "Adds an OrderEvents interface, for the interactor Order, with an EmitXCompleted method per usecase X of the interactor, a no-op implementation of it and the XCompleted payloads of the events to the event folder of the usecase layer. The Interactor implementation gets a field and a constructor parameter of the interface. The usecases added to the interactor afterwards are added to its events too, unless --skip events is set.",
+		Args: []commandArg{
+			{"interactor", "name of an existing interactor e.g. Order"},
+		},
+	},
+	{
+		Name:     verbAdd + " " + objViewFactory,
+		Synopsis: "[interactor]",
+		Short:    "add a factory choosing one of the views of an interactor",
+		Long:     "Adds a generated order_factory.go file, for the interactor Order, to the view folder. It has an OrderViewKind enum with a constant of each View of the interactor, e.g. KindOrder and KindWebOrder, and a NewOrderView function constructing the View of a kind, whose parameters are those of the constructors of the Views. The other Views are asserted to satisfy the Order View interface. The factory is recorded in the manifest and regenerated by every add, remove and apply command as Views are added or removed, so don't edit it.",
+		Args: []commandArg{
+			{"interactor", "name of an existing interactor e.g. Order"},
+		},
+		Flags: []string{"stdout"},
+	},
+	{
+		Name:  verbAdd + " " + objGenericRepository,
+		Short: "add the generic Repository of Go 1.18 or later",
+		Long:  "Adds a generic Repository[T, ID] Gateway interface with Get, Save, Delete and List methods, together with an in-memory implementation, to the repository.go file of the gateway folder. Requires the go.mod file of the project to declare Go 1.18 or later.",
+	},
+	{
+		Name:     verbAdd + " " + objGateway,
+		Synopsis: "[name]",
+		Short:    "add a gateway e.g. to a database",
+		Long:     "Adds a Gateway interface and its implementation to the gateway folder of the interface adapters layer. With -with-gateway-interface-in-usecase the interface is added to the gateway folder of the usecase layer instead, and if it already exists the implementation gets a stub of each of its methods. With -driver the implementation holds a handle of the database driver, which is passed to its constructor: *sql.DB for sql and *gorm.DB for gorm.",
+		Args: []commandArg{
+			{"name", "name of the gateway e.g. Order"},
+		},
+		Flags: []string{"build-tag", "desc", "driver", "guess-words", "stdout", "strict-names", "with-gateway-interface-in-usecase"},
+	},
+	{
+		Name:     verbAdd + " " + objInteractor,
+		Aliases:  []string{"ia"},
+		Synopsis: "[name]",
+		Short:    "add interactor e.g. Order",
+		Long:     "Adds the Controller, Presenter, View, Interactor and Validator objects of an interactor, each with a test file in the test folder of its object.",
+		Args: []commandArg{
+			{"name", "name of interactor e.g. Order, or - to read one name per line from stdin"},
+		},
+		Flags:   []string{"build-tag", "desc", "driver", "guess-words", "mock-engine", "only", "repair", "skip", "stdout", "strict-names", "terse", "unexported-interface", "with-clock", "with-gateway", "with-gateway-interface-in-usecase", "with-idgen", "with-integration-test", "with-mocks", "with-uow"},
+		Details: interactorHelpDetails,
+	},
+	{
+		Name:     verbAdd + " " + objMethod,
+		Synopsis: "[method] to [object] [interactor]",
+		Short:    "add method to a single object e.g. RenderAddItemAsCSV",
+		Long:     "Adds a method to the interface and implementation of a single object of the interactor, without touching its other objects. Methods named like the object's usecase methods, e.g. RenderX for a View, get the same parameters as the usecase methods if the model they refer to exists.",
+		Args: []commandArg{
+			{"method", "name of the method e.g. RenderAddItemAsCSV"},
+			{"object", "one of controller, presenter, view, interactor and validator"},
+			{"interactor", "name of interactor e.g. Order"},
+		},
+		Flags: []string{"stdout"},
+	},
+	{
+		Name:     verbAdd + " " + objPresenter,
+		Synopsis: "[name] to [interactor]",
+		Short:    "add another presenter e.g. HTMLOrder",
+		Long:     "Adds another implementation of the interactor's Presenter interface to the presenter folder, e.g. for a different output format, with a stub of each of the interface's methods. It coexists with the interactor's other Presenters. The Interactor depends on the interface, so it can be constructed with either of them. The Presenter is recorded in the manifest of the project, so the usecases added to the interactor later get stubs in it too, and clean status checks it. Without a name, e.g. \"clean add presenter to Order\", the Presenter of an interactor added without it, e.g. with --skip presenter, is added instead. It's backfilled with the methods of the usecases the interactor already has, which also get their models, and the backfilled usecases are listed.",
+		Args: []commandArg{
+			{"name", "name of the presenter e.g. HTMLOrder. Leave it out to add the interactor's own Presenter"},
+			{"interactor", "name of interactor e.g. Order"},
+		},
+		Flags: []string{"build-tag", "stdout", "view"},
+	},
+	{
+		Name:     verbAdd + " " + objView,
+		Synopsis: "[name] to [interactor]",
+		Short:    "add another view e.g. WebOrder",
+		Long:     "Adds another View interface and its implementation to the view folder, e.g. to render the usecases of the interactor to the web as well as to the command line, with a Render method of each of the interactor's usecases. The View is recorded in the manifest of the project, so the usecases added to the interactor later are added to it too. A Presenter holds it if it's added with --view. Without a name, e.g. \"clean add view to Order\", the View of an interactor added without it, e.g. with --skip view, is added instead. It's backfilled with the Render methods of the usecases the interactor already has, which also get their ViewModels, and the backfilled usecases are listed.",
+		Args: []commandArg{
+			{"name", "name of the view e.g. WebOrder. Leave it out to add the interactor's own View"},
+			{"interactor", "name of interactor e.g. Order"},
+		},
+		Flags: []string{"build-tag", "desc", "stdout"},
+	},
+	{
+		Name:     verbAdd + " " + objRepository,
+		Synopsis: "[entity]",
+		Short:    "add the repository of an entity e.g. Product",
+		Long:     "Adds a ProductRepository Gateway interface with Get, Save, Delete and List methods of Product entities to the gateway folder. With -generic it's an alias of the generic Repository instead, which is added if it doesn't exist yet.",
+		Args: []commandArg{
+			{"entity", "name of an existing entity e.g. Product"},
+		},
+		Flags: []string{"build-tag", "generic", "stdout"},
+	},
+	{
+		Name:  verbAdd + " " + objRoutes,
+		Short: "add a RegisterRoutes function routing HTTP requests to the controllers",
+		Long:  "Adds a generated routes.go file to the controller folder. Its RegisterRoutes function registers a handler of each controller method with a net/http ServeMux, routed as in the //clean:route directive of the method's doc comment, e.g. //clean:route GET /orders, or otherwise as POST /[interactor]/[method] e.g. POST /order/add-item. Once added, the file is regenerated by every add, remove and apply command, so don't edit it.",
+	},
+	{
+		Name:  verbAdd + " " + objClock,
+		Short: "add a Clock the interactors get the time from",
+		Long:  "Adds lib/clock/clock.go. Its Clock interface has a Now() time.Time method, and it has an implementation telling the system time, constructed by clock.New(), and a Fake for tests whose time is set with Set and moved with Advance. \"clean add interactor Order --with-clock\" injects a Clock into the Interactor, and the generated Interactor tests construct it with a Fake.",
+	},
+	{
+		Name:  verbAdd + " " + objIDGen,
+		Short: "add an ID Generator the interactors get the IDs of new entities from",
+		Long:  "Adds lib/idgen/idgen.go. Its Generator interface has a NewID() string method, and it has an implementation generating random IDs of 32 hexadecimal digits with crypto/rand, constructed by idgen.New(), and a Sequential fake for tests generating the IDs prefix1, prefix2 and so on. \"clean add interactor Order --with-idgen\" injects a Generator into the Interactor. The Interactor methods of its usecases named Create..., e.g. CreateOrder, then show how to generate the ID in a comment, and the generated Interactor tests construct it with a Sequential fake.",
+	},
+	{
+		Name:  verbAdd + " " + objKernel,
+		Short: "add a shared kernel package for the value types shared by the entities",
+		Long:  "Adds the kernel package to the kernel folder, which holds the value types shared by the entities of several aggregates e.g. IDs and Money. It starts with an ID type, and its gen.go file holds the go:generate directives of the value types. The fields of entities, e.g. \"Order: Customer kernel.ID\" read by \"clean add entity -\", may refer to its types, which imports the kernel into the entity. The kernel must not import any other package of the project. \"clean init --with-kernel\" adds it while initialising the project.",
+	},
+	{
+		Name:  verbAdd + " " + objErrors,
+		Short: "add the catalog of the errors the usecases fail with",
+		Long:  "Adds the generated usecase/errs/errs.go. Its Error type has a Code, a Message and the Err it wraps, and the catalog has a constructor of each of its errors, e.g. errs.NotFound(err), next to its code e.g. CodeNotFound. errors.Is(err, errs.NotFound(nil)) reports whether err is a NotFound error. The catalog starts with the Conflict, Internal, InvalidInput and NotFound errors, and more are added with \"clean add error\". Don't edit it.",
+	},
+	{
+		Name:     verbAdd + " " + objError,
+		Synopsis: "Code=[name] [message]",
+		Short:    "add an error to the error catalog e.g. ConflictingOrder",
+		Long:     "Adds an error to usecase/errs/errs.go, which is added first if it doesn't exist, and keeps the errors of the catalog in the order of their names. Adding an error that already exists with the same message does nothing, while adding it with another message fails.",
+		Args: []commandArg{
+			{"name", "name of the error e.g. Code=ConflictingOrder, whose constructor is errs.ConflictingOrder"},
+			{"message", "message of the error e.g. \"order already exists\""},
+		},
+	},
+	{
+		Name:     verbAdd + " " + objMapper,
+		Synopsis: "[entity] to [model] in [interactor]",
+		Short:    "add a mapper from an entity to a ResponseModel e.g. GetProductFromEntity",
+		Long:     "Adds respmodel/mapping_[model].go, e.g. mapping_getproduct.go, whose GetProductFromEntity(e *entity.Product) *GetProduct function copies the fields of the entity to the ResponseModel which have the same name and type. Both are parsed from their structs. A TODO comment is added for each field which can't be copied, and the unmapped fields are reported. Adding the mapper again regenerates it, e.g. after fields were added, so don't edit it.",
+		Args: []commandArg{
+			{"entity", "name of the entity e.g. Product"},
+			{"model", "name of the ResponseModel e.g. GetProduct"},
+			{"interactor", "name of the interactor of the usecase e.g. Catalog"},
+		},
+		Flags: []string{"stdout"},
+	},
+	{
+		Name:  verbAdd + " " + objUnitOfWork,
+		Short: "add a UnitOfWork giving the usecases transactional access to the repositories",
+		Long:  "Adds uow.go to the gateway folder. Its UnitOfWork interface begins a Tx, which is committed or rolled back and has an accessor of each of the repositories of the gateway folder e.g. OrderRepository(). The file also has a database/sql skeleton implementation and an in-memory implementation for tests. The repositories added afterwards must be added to Tx by hand. \"clean add interactor Order --with-gateway --with-uow\" injects the UnitOfWork into the Interactor instead of its Gateway.",
+	},
+	{
+		Name:     verbAdd + " " + objUsecase,
+		Aliases:  []string{"uc"},
+		Synopsis: "[usecase] to [interactor]",
+		Short:    "add usecase e.g. AddItem",
+		Long:     "Adds a usecase to the objects of the interactor together with its RequestModel, ResponseModels and ViewModels. Since the models of all interactors share a package, interactors sharing a usecase share its models too, which are declared in the model files of the interactor the usecase was first added to. With --idempotent-regenerate the signatures of the methods of an existing usecase are updated in place to the ones generated with the flags of the command, e.g. \"clean add usecase AddItem to Order --bind http --idempotent-regenerate\" makes the controller method take an *http.Request. The bodies of the methods are kept and each updated method is listed, so running it again changes nothing.",
+		Args: []commandArg{
+			{"usecase", "name of usecase e.g. AddItem, or - to read one usecase per line from stdin. A usecase read from stdin may be followed by a colon and the fields of its RequestModel e.g. \"AddItem: ProductID string, Quantity int\""},
+			{"interactor", "name of interactor e.g. Order, or a comma separated list of interactors sharing the usecase e.g. Order,Customer,Invoice"},
+		},
+		Flags: []string{"auth", "bind", "explicit-errval", "force", "fuzz", "golden", "guess-words", "idempotent-regenerate", "metrics", "no-test", "notify", "only", "paginated", "presenter-only-json", "proto", "saga", "schema", "skip", "stdout", "strict-names", "terse", "timeout", "validator", "with-benchmarks"},
+		FlagUsages: map[string]string{
+			"force": "add the usecase even if the interactor has methods or models differing from its ones only in case",
+		},
+	},
+	{
+		Name:     verbApply,
+		Synopsis: "[spec]",
+		Short:    "add the interactors and usecases declared in a spec file",
+		Long:     "Adds the interactors and usecases declared in the spec file which don't already exist in the project. Existing objects and methods are never modified. The spec file is of the form:\n\n\tinteractors:\n\t  Order:\n\t    - AddItem\n\t    - RemoveItem\n\t  Customer: [Register]",
+		Args: []commandArg{
+			{"spec", "path to a spec file e.g. spec.yaml"},
+		},
+		Flags: []string{"stdout"},
+	},
+	{
+		Name:     verbDiff,
+		Synopsis: "[interactor]...",
+		Short:    "show how the files differ from freshly generated stubs",
+		Long:     "Prints a unified diff between the files of the interactors and the stubs Clean would generate for them and their usecases today, which shows what a template upgrade would change. The bodies of the functions and methods are ignored, so implemented methods don't show up as differences. The diff is colorized when printed to a terminal.",
+		Args: []commandArg{
+			{"interactor", "name of an interactor e.g. Order. Defaults to all interactors"},
+		},
+	},
+	{
+		Name:  verbEnv,
+		Short: "print the effective configuration and where each setting comes from",
+		Long:  "Prints the configuration files and which of them clean set writes to, the working directory, the Clean Work Directory and the clean folder of the project, the module path and whether it was resolved from a go.mod file, set in a configuration file or derived from the project's location in the GOPATH, the layout and the folder of each layer, the other settings and the value of each flag. Each value is followed by its source, e.g. the configuration file setting it or default. The items which can't be resolved or are invalid are annotated with the error instead of failing the command. With -json the items are printed as JSON.",
+	},
+	{
+		Name:  verbFormat,
+		Short: "reformat the generated code with gofmt",
+		Long:  "Reformats the Go files of the project in the Clean Work Directory in place with gofmt and lists the files which were reformatted. Files that don't parse are skipped and listed.",
+		Flags: []string{"stdout"},
+	},
+	{
+		Name:  verbHistory,
+		Short: "list the changes made to the project by Clean",
+		Long:  "Lists the commands which changed the project, or had nothing to do or failed, latest first together with the files they touched. The add, remove, apply, format and set commands are logged in the .clean/history.log file of the project unless -no-history is set. The log is rotated to history.log.1 when it grows beyond the history.maxsize key of the configuration file, 1048576 bytes by default.",
+		Flags: []string{"n"},
+	},
+	{
+		Name:  verbInit,
+		Short: "initialise a new Clean Architecture project. Warning! Generates files and folders",
+		Long:  "Initialises a new project in the current folder, i.e. generates the required boilerplate folders and files. It also sets the Clean Work Directory to the folder in which this command is used and, if the folder holds a go.mod file, the module path of the project. With --flat the project is initialised in the flat layout, whose layers are all generated into the package of the clean folder. With --layout hexagonal the layers are generated into the domain, ports and adapters folders of the ports and adapters architecture. With --with-kernel the shared kernel package is added too, see \"clean help add kernel\".",
+		Flags: []string{"flat", "layout", "with-kernel"},
+	},
+	{
+		Name:     verbMocks,
+		Synopsis: "[interactor]",
+		Short:    "regenerate the mocks of the interfaces of an interactor",
+		Long:     "Writes a mock of each of the controller, presenter, view, interactor and validator interfaces of the interactor to the mock_[interactor].go file of the test folder of the object, derived from the current definitions of the interfaces. The mock files are owned by Clean and rewritten as a whole, and each mock is listed as added, updated or current. The interactors using the gomock mock engine, set with the mocks.engine key of the configuration file or --mock-engine gomock when the interactor is added, are mocked by running mockgen on the //go:generate mockgen directive of each interface file instead, which is added if it's missing. Each such mock is listed as generated. --mock-engine switches the interactors to the engine, and the engine of each interactor is recorded in the .clean/manifest.json file of the project.",
+		Args: []commandArg{
+			{"interactor", "name of interactor e.g. Order, or a comma separated list of interactors. Use -all in its place to regenerate the mocks of all interactors"},
+		},
+		Flags: []string{"all", "mock-engine", "stdout"},
+	},
+	{
+		Name:  verbNew,
+		Short: "create a folder and initialise a new project with a go.mod file inside it",
+	},
+	{
+		Name:     verbNew + " project",
+		Synopsis: "[name]",
+		Short:    "create a project e.g. myapp",
+		Long:     "Creates the folder, writes a go.mod file to it and initialises a new project inside it like \"clean init\" does. The folder must either not exist or be empty.",
+		Args: []commandArg{
+			{"name", "name of the folder to create e.g. myapp"},
+		},
+		Flags: []string{"flat", "force", "layout", "module", "with-kernel"},
+		FlagUsages: map[string]string{
+			"force": "initialise the project in the folder even if it exists and isn't empty",
+		},
+	},
+	{
+		Name:    verbRemove,
+		Aliases: []string{"rm"},
+		Short:   "remove e.g. a method from a single object",
+	},
+	{
+		Name:     verbRemove + " " + objMethod,
+		Synopsis: "[method] from [object] [interactor]",
+		Short:    "remove a method from a single object e.g. RenderAddItemAsCSV",
+		Long:     "Removes a method, including its doc comments, from the interface and implementation of a single object of the interactor.",
+		Args: []commandArg{
+			{"method", "name of the method e.g. RenderAddItemAsCSV"},
+			{"object", "one of controller, presenter, view, interactor and validator"},
+			{"interactor", "name of interactor e.g. Order"},
+		},
+		Flags: []string{"stdout"},
+	},
+	{
+		Name:  verbSet,
+		Short: "set the Clean Work Directory, the module path or the description of a package",
+	},
+	{
+		Name:     verbSet + " desc",
+		Synopsis: "[package] [description]",
+		Short:    "set the description of a package",
+		Long:     "Writes the description to the package comment in the doc.go file of the package e.g. \"clean set desc controller converts http requests to RequestModels\" and removes the placeholder package comments from the other files of the package.",
+		Args: []commandArg{
+			{"package", "one of " + strings.Join(sortedKeys(packageRelPaths), ", ")},
+			{"description", "description of the package"},
+		},
+		Flags: []string{"stdout"},
+	},
+	{
+		Name:  verbSet + " folder",
+		Short: "set the Clean Work Directory to the current directory",
+		Long:  "Sets the Clean Work Directory to your current directory. The Clean Work Directory is used by Clean to determine in which folders on the hard drive to add interactors and usecases when using e.g. the \"clean add\" command. It's stored with the directory key of a configuration file. If the directory holds a go.mod file, its module path is stored with the module key and used as the base of the generated import paths. With --global the global configuration file ~/.clean/cleanrc is written, and with --local the project configuration file .cleanrc in the current directory. Without either, the project configuration file is written when run inside a project, i.e. in a folder which holds a clean folder or which is, or is below, a folder holding a .cleanrc file, and the global one otherwise. The file written is printed.",
+		Flags: []string{"global", "local"},
+	},
+	{
+		Name:     verbSet + " module",
+		Synopsis: "[path]",
+		Short:    "set the module path the generated import paths are based on",
+		Long:     "Stores the module path e.g. example.com/myapp with the module key of a configuration file without initialising the project again, e.g. when the generated imports must differ from the module path of the go.mod file. The configuration file is chosen like \"clean set folder\" does and printed. The module path is resolved from the go.mod file again once the go.mod file changes.",
+		Args: []commandArg{
+			{"path", "module path of the project"},
+		},
+		Flags: []string{"global", "local"},
+	},
+	{
+		Name:  verbSetup,
+		Short: "set up a project by answering a few questions",
+		Long:  "Walks through setting up a project: the project folder, the module path, pre-filled from the go.mod file of the folder if it has one, the folder scheme, the layout and the comment style of the generated code. The answers are written to the configuration file and, if wanted, the project is initialised like \"clean new project\" does. Each question has a default, so hitting Enter for all of them sets up a project in the current folder. It must be run in a terminal, use \"clean init\" or \"clean new project\" in scripts.",
+	},
+	{
+		Name:  verbGenerate,
+		Short: "add the usecases declared by the directives of the Interactor files",
+		Long:  "Reads the directive comments of the Interactor files, e.g. //clean:usecase AddItem fields:\"sku:string qty:int\", which declare a usecase of the file's interactor and optionally the fields of its RequestModel as space separated name:type pairs. The usecases which don't exist are added like \"clean add usecase\" does, and the fields missing from the RequestModels of the existing ones are added, so running it again changes nothing. Conflicts are reported and their directives skipped rather than guessed: a usecase declared twice with other fields, a field whose type differs from the RequestModel's and, if the interactor has usecases no directive declares, a directive of a usecase which doesn't exist, since the usecase might have been renamed. The first time, the line //go:generate clean generate is added to the first Interactor file with directives, so that go generate ./... keeps the project up to date with its directives. go generate runs it in the folder of the file, but it generates the project in the Clean Work Directory, which must hold the folder.",
+		Flags: []string{"no-test", "only", "skip", "stdout", "terse", "validator"},
+	},
+	{
+		Name:  verbRegenerate,
+		Short: "add the missing stubs of the interactors and usecases recorded in the manifest",
+		Long:  "The add, remove and apply commands record the interactors and usecases of the project, and the flags they were added with, in the .clean/manifest.json file of the project. The manifest is synced with the Interactor interfaces after each of these commands, so interactors and usecases removed by hand are dropped from it too. Regenerate adds the objects, models and methods recorded in the manifest which are missing from the project, each with the flags it was added with, e.g. after files were deleted or after upgrading clean. Existing declarations, including the implemented methods, are kept as they are. A project without a manifest is recorded as it is.",
+		Flags: []string{"only", "skip", "stdout"},
+	},
+	{
+		Name:  verbStatus,
+		Short: "list the interactors and any objects missing usecases",
+		Long:  "Lists the interactors of the project in the Clean Work Directory and, for each of them, which objects and models are missing or lack any of the interactor's usecases, and which methods are neither usecase methods nor named like them.",
+	},
+	{
+		Name:     verbExplain,
+		Synopsis: "usecase [usecase] in [interactor]",
+		Short:    "explain the flow of a usecase through the layers",
+		Long:     "Prints the methods and models a usecase runs through in order: the controller method, the RequestModel, the validator method, the interactor method, the ResponseModels, the presenter methods, the ViewModels and the view methods. Each is printed with its file, its current signature and whether it's still a TODO stub, i.e. an empty method, a method with a TODO comment or a model without fields. With -json the flow is printed as JSON e.g. for documentation tools.",
+		Args: []commandArg{
+			{"usecase", "name of the usecase e.g. AddItem"},
+			{"interactor", "name of interactor e.g. Order"},
+		},
+	},
+	{
+		Name:  verbPurge,
+		Short: "remove the empty folders of the clean folder",
+		Long:  "Lists the folders of the clean folder which hold no files, neither directly nor in any of their subfolders, and removes them once confirmed. A folder holding any file, including a hand-written one, is never removed.",
+		Flags: []string{"dry-run", "keep-skeleton"},
+	},
+	{
+		Name:     verbSnapshot,
+		Synopsis: "create | list | restore [id]",
+		Short:    "save and restore the clean folder",
+		Long:     "\"clean snapshot create\" saves the clean folder of the project, e.g. before a risky regeneration, as a gzipped tar archive in .clean/snapshots identified by the time it was taken, e.g. 20171107-153012. Only the clean folder is saved. The oldest snapshots are removed beyond the snapshot.retention setting of the configuration file, 10 by default. \"clean snapshot list\" lists the snapshots. \"clean snapshot restore [id]\" replaces the clean folder with the snapshot id, or the newest one if id is omitted, once confirmed. The current clean folder is saved as a snapshot first, so a restore can be undone. Snapshots don't depend on git.",
+	},
+	{
+		Name:  verbSync,
+		Short: "align generated code with the models it depends on",
+	},
+	{
+		Name:     verbSync + " " + objValidator,
+		Synopsis: "[usecase] in [interactor]",
+		Short:    "align the ErrVal and the validator method of a usecase with its RequestModel",
+		Long:     "Reads the fields of the RequestModel of the usecase and adds an error field, e.g. NameErr string, to its ErrVal ResponseModel for each field lacking one. The validator method gets a check of each field it doesn't check yet, which sets the field's error field if it's invalid, and returns the ErrVal once all of the fields are checked. Strings, pointers, slices, maps and interfaces are checked to be set as a start, the other fields get a TODO. Nothing is removed: the error fields whose RequestModel fields are gone are listed. With --validator=tags only the error fields are added. \"clean add usecase\" does the same when the usecase is added with fields.",
+		Args: []commandArg{
+			{"usecase", "name of the usecase e.g. AddItem"},
+			{"interactor", "name of interactor e.g. Order"},
+		},
+		Flags: []string{"stdout"},
+	},
+	{
+		Name:  verbLint,
+		Short: "check the imports against the dependency rule",
+		Long:  "Checks the imports of the Go files of the layers against the dependency rule of Clean Architecture, which forbids a layer to import the layers outside of it, and lists each file importing a package it mustn't e.g. an entity importing a view or a RequestModel importing a presenter. The layers are, from the inside out, the kernel, the entities, the usecase layer and the interface adapters. The kernel imports no package of the project at all. The Interactor may import the presenter and gateway packages since they declare the interfaces it depends on. The tests aren't checked. With -json the violations are printed as JSON e.g. for CI. The command exits with a non-zero status if there are any violations.",
+	},
+	{
+		Name:  verbTodos,
+		Short: "list the TODOs and unimplemented methods",
+		Long:  "Lists the TODO comments and the methods with empty bodies of the generated code grouped by interactor and usecase.",
+		Flags: []string{"fail-over", "format"},
+	},
+	{
+		Name:     verbWatch,
+		Synopsis: "[spec]",
+		Short:    "apply a spec file each time it changes",
+		Long:     "Applies the spec file like \"clean apply\" and then again every time the spec file is saved. Use \"clean help apply\" for more information about spec files.",
+		Args: []commandArg{
+			{"spec", "path to a spec file e.g. spec.yaml"},
+		},
+	},
+}
+
+// findCommand returns the command name from the registry
+func findCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// splitCommandName returns the words of the command name preceding its last word, e.g. "add" of "add usecase",
+// and its last word
+func splitCommandName(name string) (string, string) {
+	if ix := strings.LastIndex(name, " "); ix != -1 {
+		return name[:ix], name[ix+1:]
+	}
+	return "", name
+}
+
+// resolveAlias returns the last word of the command whose name is the command prefix, e.g. "add" or an empty
+// string for the verbs, followed by word, which is either that word or one of its aliases. word is returned as
+// is if it's neither. It returns an error if word is an alias of several commands.
+func resolveAlias(prefix, word string) (string, error) {
+	var matches []string
+	for _, c := range commands {
+		parent, last := splitCommandName(c.Name)
+		if parent != prefix {
+			continue
+		}
+		if last == word {
+			return word, nil
+		}
+		for _, alias := range c.Aliases {
+			if alias == word {
+				matches = append(matches, last)
+			}
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return word, nil
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("The alias %q of \"clean %s\" is ambiguous, it stands for %s.", word, strings.TrimSpace(prefix+" "+word), strings.Join(matches, " and "))
+}
+
+// resolveAliases returns args with the aliases of the verb and of the object following it replaced by their
+// names, e.g. "a uc AddItem to Order" becomes "add usecase AddItem to Order", so that the commands and their
+// error messages only deal with the names. It returns an error if an alias is ambiguous.
+func resolveAliases(args []string) ([]string, error) {
+	resolved := append([]string{}, args...)
+	var prefix string
+	for i := 0; i < len(resolved) && i < 2; i++ {
+		name, err := resolveAlias(prefix, resolved[i])
+		if err != nil {
+			return nil, err
+		}
+		resolved[i] = name
+		prefix = strings.TrimSpace(prefix + " " + name)
+	}
+	return resolved, nil
+}
+
+// shortForm returns the name of the command with each of its words replaced by its first alias, e.g. "a uc" for
+// "add usecase", or an empty string if none of its words has an alias
+func shortForm(name string) string {
+	var words []string
+	var prefix string
+	var aliased bool
+	for _, word := range strings.Fields(name) {
+		prefix = strings.TrimSpace(prefix + " " + word)
+		if c, ok := findCommand(prefix); ok && len(c.Aliases) > 0 {
+			word, aliased = c.Aliases[0], true
+		}
+		words = append(words, word)
+	}
+	if !aliased {
+		return ""
+	}
+	return strings.Join(words, " ")
+}
+
+// commandLabel returns the last word of the name of c followed by its aliases, if any, e.g. "usecase (uc)"
+func commandLabel(c command) string {
+	_, last := splitCommandName(c.Name)
+	if len(c.Aliases) == 0 {
+		return last
+	}
+	return fmt.Sprintf("%s (%s)", last, strings.Join(c.Aliases, ", "))
+}
+
+// subcommands returns the commands of the registry whose names are name followed by an object
+func subcommands(name string) []command {
+	var subs []command
+	for _, c := range commands {
+		if strings.HasPrefix(c.Name, name+" ") {
+			subs = append(subs, c)
+		}
+	}
+	return subs
+}
+
+// helpFlags returns the flags of c sorted by name, with the usages of c replacing the shared ones. The flags
+// are copies, so their defaults are the ones they're defined with rather than the values given on the command line
+// e.g. clean --with-clock help add interactor.
+func helpFlags(c command) []flag.Flag {
+	var flags []flag.Flag
+	for _, name := range c.Flags {
+		f := flag.Lookup(name)
+		if f == nil {
+			continue
+		}
+		cf := *f
+		if usage, ok := c.FlagUsages[name]; ok {
+			cf.Usage = usage
+		}
+		flags = append(flags, cf)
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+	return flags
+}
+
+// isString reports whether v is a string
+func isString(v interface{}) bool {
+	_, ok := v.(string)
+	return ok
+}
+
+// writeFlags writes the flags to b, each followed by its default
+func writeFlags(b *bytes.Buffer, flags []flag.Flag) {
+	for _, f := range flags {
+		fmt.Fprintf(b, "\t-%s\t%s", f.Name, f.Usage)
+		if g, ok := f.Value.(flag.Getter); ok && isString(g.Get()) {
+			fmt.Fprintf(b, " (default %q)\n", f.DefValue)
+		} else {
+			fmt.Fprintf(b, " (default %s)\n", f.DefValue)
+		}
+	}
+}
+
+// usageText returns the help listing all verbs, shown by "clean help"
+func usageText() string {
+	var b bytes.Buffer
+	b.WriteString("Clean is a tool for generating Clean Architecture boilerplate code.\n\nUsage:\n\n\tclean [verb]\n\nNew to Clean? Run \"clean setup\" to set up a project by answering a few questions.\n\nThe verbs are:\n\n")
+	var verbs []command
+	for _, c := range commands {
+		if !strings.Contains(c.Name, " ") {
+			verbs = append(verbs, c)
+		}
+	}
+	sort.Slice(verbs, func(i, j int) bool { return verbs[i].Name < verbs[j].Name })
+	for _, c := range verbs {
+		fmt.Fprintf(&b, "\t%s\t%s\n", commandLabel(c), c.Short)
+	}
+	b.WriteString("\nThe flags of all verbs are:\n\n")
+	writeFlags(&b, helpFlags(command{Name: "clean", Flags: globalFlags}))
+	b.WriteString("\nThe verbs exit with a non-zero status if they fail, and with status 2 if they're entered wrongly.\n\nUse \"clean help [verb]\" for more information about a verb.\n\nCreated by Tobias Strandberg.\n\n")
+	return b.String()
+}
+
+// helpText returns the help of the command name e.g. "add usecase". It returns false if there's no such command.
+func helpText(name string) (string, bool) {
+	c, ok := findCommand(name)
+	if !ok {
+		return "", false
+	}
+	var b bytes.Buffer
+	subs := subcommands(name)
+	if len(subs) > 0 && c.Synopsis == "" {
+		for i, sub := range subs {
+			prefix := "Usage: "
+			if i > 0 {
+				prefix = "       "
+			}
+			fmt.Fprintf(&b, "%sclean %s", prefix, sub.Name)
+			if sub.Synopsis != "" {
+				fmt.Fprintf(&b, " %s", sub.Synopsis)
+			}
+			if len(sub.Flags) > 0 {
+				b.WriteString(" [flags]")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\nThe objects are:\n\n")
+		for _, sub := range subs {
+			fmt.Fprintf(&b, "\t%s\t%s\n", commandLabel(sub), sub.Short)
+		}
+		fmt.Fprintf(&b, "\nUse \"clean help %s [object]\" for more information about an object.\n", name)
+	} else {
+		fmt.Fprintf(&b, "Usage: clean %s", c.Name)
+		if c.Synopsis != "" {
+			fmt.Fprintf(&b, " %s", c.Synopsis)
+		}
+		if len(c.Flags) > 0 {
+			b.WriteString(" [flags]")
+		}
+		b.WriteString("\n")
+		if short := shortForm(c.Name); short != "" {
+			fmt.Fprintf(&b, "Short form: clean %s", short)
+			if c.Synopsis != "" {
+				fmt.Fprintf(&b, " %s", c.Synopsis)
+			}
+			b.WriteString("\n")
+		}
+		if len(c.Args) > 0 {
+			b.WriteString("\n")
+			for _, arg := range c.Args {
+				fmt.Fprintf(&b, "\t%s\t%s\n", arg.Name, arg.Desc)
+			}
+		}
+	}
+	if c.Long != "" {
+		fmt.Fprintf(&b, "\n%s\n", c.Long)
+	}
+	if c.Details != nil {
+		fmt.Fprintf(&b, "\n%s", c.Details())
+	}
+	if len(c.Flags) > 0 {
+		b.WriteString("\nThe flags are:\n\n")
+		writeFlags(&b, helpFlags(c))
+	}
+	b.WriteString("\n")
+	return b.String(), true
+}
+
+// interactorHelpDetails returns the files "clean add interactor" generates with the objects selected by --only
+// and --skip and the flags given before the help verb e.g. clean --with-clock help add interactor
+func interactorHelpDetails() string {
+	var b bytes.Buffer
+	b.WriteString("With the flags given, \"clean add interactor Order\" generates:\n\n")
+	tw := tabwriter.NewWriter(&b, 0, 8, 2, ' ', 0)
+	for _, objType := range objTypes {
+		if selected(objType) {
+			fmt.Fprintf(tw, "\t%s\tclean/%sorder.go and clean/%stest/order_test.go\n", objType, objRelPaths[objType], objRelPaths[objType])
+		}
+	}
+	switch {
+	case *gatewayInUsecase:
+		fmt.Fprintf(tw, "\tgateway\tclean/%sorder.go and clean/%sorder.go\n", relPathGatewayPort, relPathGateway)
+	case *withGateway:
+		fmt.Fprintf(tw, "\tgateway\tclean/%sorder.go\n", relPathGateway)
+	}
+	if *withUow {
+		fmt.Fprintf(tw, "\tunit of work\tclean/%s%s, unless it exists\n", relPathGateway, uowFileName)
+	}
+	if *withClock {
+		fmt.Fprintf(tw, "\tclock\tclean/%s%s.go, unless it exists\n", relPathClock, objClock)
+	}
+	if *withIDGen {
+		fmt.Fprintf(tw, "\tidgen\tclean/%s%s.go, unless it exists\n", relPathIDGen, objIDGen)
+	}
+	if *mockEngine == mockEngineGomock {
+		fmt.Fprintf(tw, "\tmocks\ta //go:generate mockgen directive in each of the files of the objects\n")
+	} else if *withMocks {
+		fmt.Fprintf(tw, "\tmocks\tclean/%s%s\n", relPathInteractor, genFileName)
+	}
+	tw.Flush()
+	b.WriteString("\nThe objects are selected by --only and --skip and the optional files by the flags below.\n")
+	return b.String()
+}
+
+// printHelp prints the help of the command name e.g. "add usecase"
+func printHelp(name string) {
+	text, ok := helpText(name)
+	if !ok {
+		unknownCommandf(name)
+		return
+	}
+	fmt.Printf("%s", text)
+}
+
+// unknownCommandf fails the command with a usage error naming the unknown command name e.g. "frobnicate" or
+// "add widget", and the command closest to it if there's one
+func unknownCommandf(name string) {
+	var suggestion string
+	if closest := closestCommand(name); closest != "" {
+		suggestion = fmt.Sprintf(" Did you mean \"clean %s\"?", closest)
+	}
+	usagef("Unknown command \"clean %s\".%s Run \"clean help\" for a list of the commands.", name, suggestion)
+}
+
+// objectUsageErrorf fails the command with a usage error because the command name, a verb followed by an object
+// e.g. "add presenter", is unknown or, if it's known, its arguments are invalid
+func objectUsageErrorf(name string) {
+	if _, ok := findCommand(name); ok {
+		usageErrorf(name)
+		return
+	}
+	unknownCommandf(name)
+}
+
+// usageErrorf fails the command with a usage error because the arguments of the command name e.g. "add usecase"
+// are missing or invalid
+func usageErrorf(name string) {
+	usagef("Invalid arguments for \"clean %s\". Run \"clean help %s\" for its usage.", name, name)
+}
+
+// closestCommand returns the name of the command of the registry, or help, closest to name, which only differs
+// from it in its last word, by at most a third of its letters. It returns an empty string if no command is that
+// close.
+func closestCommand(name string) string {
+	names := []string{verbHelp}
+	for _, c := range commands {
+		names = append(names, c.Name)
+	}
+	words := strings.Fields(name)
+	if len(words) == 0 {
+		return ""
+	}
+	last := words[len(words)-1]
+	prefix := strings.Join(words[:len(words)-1], " ")
+	closest, min := "", len([]rune(last))/3+1
+	for _, n := range names {
+		w := strings.Fields(n)
+		if len(w) != len(words) || strings.Join(w[:len(w)-1], " ") != prefix {
+			continue
+		}
+		if d := editDistance(last, w[len(w)-1]); d < min {
+			closest, min = n, d
+		}
+	}
+	return closest
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = cur[j-1] + 1
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if prev[j-1]+cost < cur[j] {
+				cur[j] = prev[j-1] + cost
+			}
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
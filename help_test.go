@@ -0,0 +1,104 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"flag"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestEveryCommandHasHelp asserts that every command of the registry has a description and a help text, and
+// only lists flags that exist
+func TestEveryCommandHasHelp(t *testing.T) {
+	for _, c := range commands {
+		if strings.TrimSpace(c.Short) == "" {
+			t.Errorf("%q has no short description", c.Name)
+		}
+		if strings.TrimSpace(c.Long) == "" && c.Details == nil && len(subcommands(c.Name)) == 0 {
+			t.Errorf("%q has no long description", c.Name)
+		}
+		if text, ok := helpText(c.Name); !ok || !strings.HasPrefix(text, "Usage: clean "+c.Name) {
+			t.Errorf("clean help %s doesn't print the usage of %q:\n%s", c.Name, c.Name, text)
+		}
+		for _, name := range c.Flags {
+			if flag.Lookup(name) == nil {
+				t.Errorf("%q lists the unknown flag -%s", c.Name, name)
+			}
+		}
+		listed := make(map[string]bool)
+		for _, name := range c.Flags {
+			listed[name] = true
+		}
+		for name := range c.FlagUsages {
+			if !listed[name] {
+				t.Errorf("%q has a usage of the flag -%s it doesn't list", c.Name, name)
+			}
+		}
+		for _, arg := range c.Args {
+			if strings.TrimSpace(arg.Desc) == "" {
+				t.Errorf("the argument %s of %q has no description", arg.Name, c.Name)
+			}
+		}
+	}
+	for _, name := range globalFlags {
+		if flag.Lookup(name) == nil {
+			t.Errorf("the unknown flag -%s is listed as a flag of all verbs", name)
+		}
+	}
+}
+
+// TestEveryVerbIsRegistered asserts that clean help lists every verb
+func TestEveryVerbIsRegistered(t *testing.T) {
+	usage := usageText()
+	for _, verb := range []string{verbAdd, verbInit, verbSet, verbStatus, verbApply, verbWatch, verbTodos, verbNew, verbRemove, verbFormat, verbHistory, verbDiff, verbMocks, verbPurge, verbExplain, verbRegenerate, verbGenerate, verbSync, verbSnapshot, verbLint, verbSetup} {
+		if _, ok := findCommand(verb); !ok {
+			t.Errorf("%q isn't registered", verb)
+		}
+		if !strings.Contains(usage, "\t"+verb) {
+			t.Errorf("clean help doesn't list %q", verb)
+		}
+	}
+}
+
+// TestVerbsAreSorted asserts that clean help lists the verbs in alphabetical order
+func TestVerbsAreSorted(t *testing.T) {
+	var verbs []string
+	for _, line := range strings.Split(usageText(), "\n") {
+		if strings.HasPrefix(line, "\t") && !strings.HasPrefix(line, "\t-") && strings.Contains(line[1:], "\t") {
+			verbs = append(verbs, strings.Fields(line)[0])
+		}
+	}
+	if len(verbs) == 0 || !sort.StringsAreSorted(verbs) {
+		t.Errorf("clean help doesn't list the verbs in alphabetical order: %s", strings.Join(verbs, ", "))
+	}
+}
+
+// TestFlagHelp asserts that the help of a command shows the usages of the flags for that command, and the
+// defaults the flags are defined with even if they're given before the help verb
+func TestFlagHelp(t *testing.T) {
+	usecaseHelp, _ := helpText(verbAdd + " " + objUsecase)
+	projectHelp, _ := helpText(verbNew + " project")
+	for _, tc := range []struct {
+		text, line string
+	}{
+		{usecaseHelp, "\t-force\tadd the usecase even if the interactor has methods or models differing from its ones only in case (default false)\n"},
+		{usecaseHelp, "\t-bind\t"},
+		{projectHelp, "\t-force\tinitialise the project in the folder even if it exists and isn't empty (default false)\n"},
+	} {
+		if !strings.Contains(tc.text, tc.line) {
+			t.Errorf("the help doesn't contain %q:\n%s", tc.line, tc.text)
+		}
+	}
+	if !strings.Contains(usecaseHelp, "(default \"\")\n") {
+		t.Errorf("the help of add usecase doesn't show the empty defaults of its string flags:\n%s", usecaseHelp)
+	}
+
+	defer func(v bool) { *terse = v }(*terse)
+	*terse = true
+	if text, _ := helpText(verbAdd + " " + objUsecase); !strings.Contains(text, "\t-terse\tgenerate a single line doc comment per type and method instead of the comments explaining them (default false)\n") {
+		t.Errorf("the help of add usecase doesn't show the default of -terse given before the help verb:\n%s", text)
+	}
+}
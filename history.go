@@ -0,0 +1,164 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// historyDir is the folder of the project holding the history log
+	historyDir = ".clean"
+	// historyFileName is the name of the history log. When it grows beyond the configured maximum
+	// size it's renamed to history.log.1, replacing any previous one, and a new log is started.
+	historyFileName = "history.log"
+	// defaultHistoryMaxSize is the default maximum size in bytes of the history log
+	defaultHistoryMaxSize = 1 << 20
+)
+
+// mutatingVerbs holds the verbs which change the project and are logged in the history log
+var mutatingVerbs = map[string]bool{verbAdd: true, verbRemove: true, verbApply: true, verbFormat: true, verbSet: true, verbRegenerate: true, verbSync: true, verbGenerate: true}
+
+// historyEntry is a line of the history log
+type historyEntry struct {
+	Time    time.Time `json:"time"`
+	Version string    `json:"version"`
+	Command string    `json:"command"`
+	Files   []string  `json:"files"`
+	Outcome string    `json:"outcome"`
+}
+
+// historyProject is the folder of the project whose history log the command is logged in.
+// It's empty if the command shouldn't be logged.
+var historyProject string
+
+// historyMaxSize is the maximum size in bytes of the history log
+var historyMaxSize int64 = defaultHistoryMaxSize
+
+// toolVersion returns the version of the clean binary
+func toolVersion() string {
+	if bi, ok := debug.ReadBuildInfo(); ok && bi.Main.Version != "" {
+		return bi.Main.Version
+	}
+	return "(devel)"
+}
+
+// commandLine returns the command line clean was run with, quoting the arguments containing spaces
+func commandLine() string {
+	args := []string{"clean"}
+	for _, arg := range os.Args[1:] {
+		if strings.ContainsAny(arg, " \t\n\"") {
+			arg = strconv.Quote(arg)
+		}
+		args = append(args, arg)
+	}
+	return strings.Join(args, " ")
+}
+
+// logHistory appends an entry for the command with the outcome o to the history log of historyProject
+func logHistory(o string) error {
+	dir := filepath.Join(filepath.FromSlash(historyProject), historyDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	e := historyEntry{
+		Time:    time.Now(),
+		Version: toolVersion(),
+		Command: commandLine(),
+		Files:   []string{},
+		Outcome: o,
+	}
+	for _, fp := range changedFiles {
+		if rel, err := filepath.Rel(filepath.FromSlash(historyProject), fp); err == nil {
+			fp = rel
+		}
+		e.Files = append(e.Files, filepath.ToSlash(fp))
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	fp := filepath.Join(dir, historyFileName)
+	if fi, err := os.Stat(fp); err == nil && fi.Size()+int64(len(b))+1 > historyMaxSize {
+		if err := os.Rename(fp, fp+".1"); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(fp, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(b, '\n'))
+	return err
+}
+
+// readHistory returns the entries of the history log of the project in projectPath, including the
+// rotated log, in chronological order. Lines that can't be parsed are skipped.
+func readHistory(projectPath string) ([]historyEntry, error) {
+	fp := filepath.Join(filepath.FromSlash(projectPath), historyDir, historyFileName)
+	var entries []historyEntry
+	for _, path := range []string{fp + ".1", fp} {
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			var e historyEntry
+			if err := json.Unmarshal(scanner.Bytes(), &e); err == nil {
+				entries = append(entries, e)
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// printHistory prints the last n entries in reverse chronological order, as JSON if asJSON is true
+func printHistory(entries []historyEntry, n int, asJSON bool) error {
+	var last []historyEntry
+	for i := len(entries) - 1; i >= 0 && (n < 0 || len(last) < n); i-- {
+		last = append(last, entries[i])
+	}
+	if asJSON {
+		if last == nil {
+			last = []historyEntry{}
+		}
+		b, err := json.MarshalIndent(last, "", "\t")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\n", b)
+		return nil
+	}
+	if len(last) == 0 {
+		fmt.Printf("No history yet\n\n")
+		return nil
+	}
+	for _, e := range last {
+		fmt.Printf("%s  %-7s  %s  (%s)\n", e.Time.Local().Format("2006-01-02 15:04:05"), e.Outcome, e.Command, "version "+e.Version)
+		for _, f := range e.Files {
+			fmt.Printf("\t%s\n", f)
+		}
+	}
+	fmt.Printf("\n")
+	return nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+const (
+	objIDGen     = "idgen"
+	relPathIDGen = "lib/idgen/"
+	// idGenField is the name of the field and constructor parameter of an Interactor holding the Generator
+	idGenField = "ids"
+	// fakeIDGenArg constructs the fake Generator the generated tests pass to the constructors of the Interactors
+	fakeIDGenArg = "idgen.NewSequential(\"id-\")"
+)
+
+// idGenSource is the content, following the package clause, of the file of the Generator
+const idGenSource = `
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync"
+)
+
+// Generator generates the IDs of new entities. Interactors get the IDs from a Generator instead of
+// generating them so that their tests control them.
+type Generator interface {
+	// NewID returns a new ID.
+	NewID() string
+}
+
+// random is a Generator of random IDs.
+type random struct{}
+
+// New constructs a new Generator of random IDs of 32 hexadecimal digits.
+func New() Generator {
+	return random{}
+}
+
+// NewID implements the Generator interface method NewID.
+func (random) NewID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic("idgen: reading random bytes: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
+
+// Sequential is a Generator for tests of the IDs prefix1, prefix2 and so on.
+type Sequential struct {
+	mu     sync.Mutex
+	prefix string
+	n      int
+}
+
+// NewSequential constructs a new Sequential Generator of IDs starting with prefix.
+func NewSequential(prefix string) *Sequential {
+	return &Sequential{prefix: prefix}
+}
+
+// NewID implements the Generator interface method NewID.
+func (s *Sequential) NewID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.n++
+	return s.prefix + strconv.Itoa(s.n)
+}
+`
+
+// isCreateUsecase reports whether the usecase creates something e.g. CreateOrder
+func isCreateUsecase(usecase string) bool {
+	words := splitWords(usecase)
+	return len(words) > 0 && words[0] == "Create"
+}
+
+// idGenExample returns the commented line of the Interactor method of a create usecase showing how the ID of
+// the new entity is generated. The Interactor implementation is referred to by self.
+func idGenExample(self string) string {
+	return fmt.Sprintf("\t// e.g. id := %s.%s.NewID()\n", self, idGenField)
+}
+
+// addIDGen adds the Generator, its random implementation and its sequential fake to the lib/idgen folder of
+// the project at basePath
+func addIDGen(basePath string) error {
+	fp := filepath.FromSlash(basePath + relPathIDGen + objIDGen + ".go")
+	if fileExists(fp) {
+		noopf("the ID generator already exists")
+		return nil
+	}
+	if err := mkdirAll(filepath.Dir(fp)); err != nil {
+		return err
+	}
+	return writeFile(fp, []byte(packageClause(basePath+relPathIDGen, objIDGen)+idGenSource))
+}
+
+// injectIDGen adds a field and a constructor parameter of the Generator, which is added if it doesn't exist, to
+// the Interactor implementation of the interactor of the project at basePath unless it already has them
+func injectIDGen(basePath, interactor string) error {
+	if !fileExists(filepath.FromSlash(basePath + relPathIDGen + objIDGen + ".go")) {
+		if err := addIDGen(basePath); err != nil {
+			return err
+		}
+	}
+	return injectInteractorDependency(basePath, interactor, idGenField, objIDGen+".Generator", relPathIDGen)
+}
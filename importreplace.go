@@ -0,0 +1,103 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// importReplacement replaces the beginning old of an import path with new
+type importReplacement struct {
+	old, new string
+}
+
+// importReplacements holds the rules of the module.replace setting, longest old path first
+var importReplacements []importReplacement
+
+// setImportReplacements sets the rules replacing the beginnings of the import paths of the generated code from
+// the module.replace setting of the configuration file. The rules are separated by commas and written like the
+// replace directives of go.mod, e.g. example.com/app=>example.com/app/v2, and replace whole path elements only.
+func setImportReplacements(setting string) error {
+	importReplacements = nil
+	for _, rule := range strings.Split(setting, ",") {
+		if strings.TrimSpace(rule) == "" {
+			continue
+		}
+		pieces := strings.SplitN(rule, "=>", 2)
+		if len(pieces) != 2 || strings.TrimSpace(pieces[0]) == "" || strings.TrimSpace(pieces[1]) == "" {
+			return fmt.Errorf("invalid %s rule %q in the configuration file, the rules are of the form old/path=>new/path", confKeyModuleReplace, rule)
+		}
+		importReplacements = append(importReplacements, importReplacement{strings.Trim(strings.TrimSpace(pieces[0]), "/"), strings.Trim(strings.TrimSpace(pieces[1]), "/")})
+	}
+	sort.SliceStable(importReplacements, func(i, j int) bool {
+		return len(importReplacements[i].old) > len(importReplacements[j].old)
+	})
+	return nil
+}
+
+// replaceImportPath returns the import path with its beginning replaced by the first of the importReplacements
+// matching it
+func replaceImportPath(path string) string {
+	for _, r := range importReplacements {
+		if path == r.old || strings.HasPrefix(path, r.old+"/") {
+			return r.new + strings.TrimPrefix(path, r.old)
+		}
+	}
+	return path
+}
+
+// replaceImports returns the content b about to be written to the Go file fp with its import paths replaced by
+// the importReplacements. Clean resolves the imports it adds from the module path, so they're replaced here,
+// where all of the files it writes pass, rather than where each of them is generated. An import which becomes a
+// duplicate of another one is removed.
+func replaceImports(fp string, b []byte) []byte {
+	if len(importReplacements) == 0 || filepath.Ext(fp) != ".go" {
+		return b
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, fp, b, parser.ImportsOnly)
+	if err != nil {
+		return b
+	}
+	importKey := func(name, path string) string {
+		return name + " " + path
+	}
+	seen := make(map[string]bool)
+	for _, spec := range f.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		if replaceImportPath(path) == path {
+			seen[importKey(spec.Name.String(), path)] = true
+		}
+	}
+	out := append([]byte{}, b...)
+	// The imports are replaced from the last one so that the offsets of the others stay valid
+	for i := len(f.Imports) - 1; i >= 0; i-- {
+		spec := f.Imports[i]
+		path, _ := strconv.Unquote(spec.Path.Value)
+		newPath := replaceImportPath(path)
+		if newPath == path {
+			continue
+		}
+		start, end := fset.Position(spec.Path.Pos()).Offset, fset.Position(spec.Path.End()).Offset
+		key := importKey(spec.Name.String(), newPath)
+		if seen[key] && f.Imports[i].Name == nil {
+			// Remove the line of a duplicate in an import declaration with several imports
+			lineStart := strings.LastIndexByte(string(out[:start]), '\n') + 1
+			lineEnd := strings.IndexByte(string(out[end:]), '\n')
+			if lineEnd != -1 && strings.TrimSpace(string(out[lineStart:start])) == "" && strings.TrimSpace(string(out[end:end+lineEnd])) == "" {
+				out = append(out[:lineStart], out[end+lineEnd+1:]...)
+				continue
+			}
+		}
+		seen[key] = true
+		out = append(out[:start], append([]byte(strconv.Quote(newPath)), out[end:]...)...)
+	}
+	return out
+}
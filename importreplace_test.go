@@ -0,0 +1,74 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestReplaceImportPath(t *testing.T) {
+	defer func() { importReplacements = nil }()
+	if err := setImportReplacements("app/clean=>example.com/app/clean, app/clean/usecase/=>example.com/core/usecase,"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path, want string
+	}{
+		{"app/clean", "example.com/app/clean"},
+		{"app/clean/ifadapter/view", "example.com/app/clean/ifadapter/view"},
+		// The longest matching rule wins
+		{"app/clean/usecase/interactor", "example.com/core/usecase/interactor"},
+		// Only whole path elements are replaced
+		{"app/cleaner", "app/cleaner"},
+		{"errors", "errors"},
+	}
+	for _, tt := range tests {
+		if got := replaceImportPath(tt.path); got != tt.want {
+			t.Errorf("replaceImportPath(%s) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+	for _, setting := range []string{"app/clean", "=>example.com/app", "app/clean=> "} {
+		if err := setImportReplacements(setting); err == nil {
+			t.Errorf("setImportReplacements(%q) returned no error", setting)
+		}
+	}
+}
+
+// TestImportReplacementAppliedToAllImports asserts that the module.replace rule is applied to every import of
+// every Go file generated by several commands
+func TestImportReplacementAppliedToAllImports(t *testing.T) {
+	p := newTestProject(t)
+	p.write("../home/.clean/cleanrc", p.read("../home/.clean/cleanrc")+"module.replace=app/clean=>example.com/shop/clean\n")
+	p.clean("add", "interactor", "Order", "--with-gateway", "--with-clock")
+	p.clean("add", "usecase", "AddItem", "to", "Order")
+	p.clean("add", "entity", "Product")
+	p.clean("add", "routes")
+
+	var replaced int
+	for name, content := range p.files("clean") {
+		if !strings.HasSuffix(name, ".go") {
+			continue
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), name, content, parser.ImportsOnly)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err.Error())
+		}
+		for _, spec := range f.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			switch {
+			case strings.HasPrefix(path, "app/"):
+				t.Errorf("%s imports %s, which wasn't replaced", name, path)
+			case strings.HasPrefix(path, "example.com/shop/clean/"):
+				replaced++
+			}
+		}
+	}
+	if replaced == 0 {
+		t.Errorf("no import was replaced")
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"go/token"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+)
+
+// errCancelled is returned when the user ends the input while being prompted
+var errCancelled = errors.New("cancelled")
+
+// prompter asks the user for answers on stdin
+type prompter struct {
+	in *bufio.Reader
+}
+
+// ask prints question and reads answers until valid returns nil for one of them.
+// It returns errCancelled if the input ends before a valid answer is given.
+func (p *prompter) ask(question string, valid func(answer string) error) (string, error) {
+	for {
+		fmt.Printf("%s: ", question)
+		line, err := p.in.ReadString('\n')
+		answer := strings.TrimSpace(line)
+		if err != nil && (err != io.EOF || answer == "") {
+			fmt.Printf("\n")
+			return "", errCancelled
+		}
+		if verr := valid(answer); verr != nil {
+			fmt.Printf("%s\n", verr.Error())
+			continue
+		}
+		return answer, nil
+	}
+}
+
+// askDefault is like ask but shows the answer def, which an empty answer stands for
+func (p *prompter) askDefault(question, def string, valid func(answer string) error) (string, error) {
+	answer, err := p.ask(fmt.Sprintf("%s [%s]", question, def), func(answer string) error {
+		if answer == "" {
+			return valid(def)
+		}
+		return valid(answer)
+	})
+	if err == nil && answer == "" {
+		answer = def
+	}
+	return answer, err
+}
+
+// cancelOnInterrupt makes Ctrl-C end the prompting by failing the command with msg, like the end of the input
+// does. The returned function stops it.
+func cancelOnInterrupt(msg string) func() {
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		if _, ok := <-interrupts; ok {
+			fmt.Printf("\n")
+			failf("%s\n\n", msg)
+			exitWithOutcome()
+		}
+	}()
+	return func() {
+		signal.Stop(interrupts)
+	}
+}
+
+// validName returns an error if name isn't a valid Go identifier
+func validName(name string) error {
+	if !token.IsIdentifier(name) {
+		return fmt.Errorf("%q isn't a valid name, enter e.g. Order", name)
+	}
+	return checkName(name)
+}
+
+// validInteractor returns a function which returns an error if its answer isn't the name of an interactor of
+// the project at basePath
+func validInteractor(basePath string) func(answer string) error {
+	return func(answer string) error {
+		if err := validName(answer); err != nil {
+			return err
+		}
+		if fileExists(interactorFilePath(basePath, answer)) {
+			return nil
+		}
+		interactors, _ := interactorUsecases(basePath)
+		if len(interactors) == 0 {
+			return fmt.Errorf("the interactor %s doesn't exist and the project has no interactors, add one first", exportedName(answer))
+		}
+		var names []string
+		for name := range interactors {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("the interactor %s doesn't exist, enter one of %s", exportedName(answer), strings.Join(names, ", "))
+	}
+}
+
+// validOneOf returns a function which returns an error if its answer isn't one of choices
+func validOneOf(choices ...string) func(answer string) error {
+	return func(answer string) error {
+		for _, c := range choices {
+			if answer == c {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q isn't valid, enter one of %s", answer, strings.Join(choices, ", "))
+	}
+}
+
+// completeAddArgs returns the arguments of an add command, e.g. "add usecase AddItem to", with the missing
+// ones filled in by prompting the user on stdin. The interactors prompted for must exist in the project at
+// basePath. Ctrl-C ends the prompting without adding anything.
+func completeAddArgs(basePath string, args []string) ([]string, error) {
+	defer cancelOnInterrupt("Cancelled, nothing was added")()
+
+	p := &prompter{in: bufio.NewReader(os.Stdin)}
+	// at returns the argument at ix or prompts for it if it's missing
+	at := func(ix int, question string, valid func(string) error) error {
+		if ix < len(args) {
+			return nil
+		}
+		answer, err := p.ask(question, valid)
+		if err != nil {
+			return err
+		}
+		args = append(args, answer)
+		return nil
+	}
+	// The objects are offered together with their aliases, which are accepted too
+	var labels, choices []string
+	for _, obj := range []string{objInteractor, objUsecase, objEntity, objMethod} {
+		c, _ := findCommand(verbAdd + " " + obj)
+		labels = append(labels, commandLabel(c))
+		choices = append(append(choices, obj), c.Aliases...)
+	}
+	if err := at(1, "Object to add ("+strings.Join(labels, ", ")+")", validOneOf(choices...)); err != nil {
+		return nil, err
+	}
+	var err error
+	if args[1], err = resolveAlias(verbAdd, args[1]); err != nil {
+		return nil, err
+	}
+	switch args[1] {
+	case objInteractor:
+		err = at(2, "Interactor name", validName)
+	case objEntity:
+		err = at(2, "Entity name", validName)
+	case objUsecase:
+		if err = at(2, "Usecase name", validName); err != nil {
+			break
+		}
+		if len(args) == 3 {
+			args = append(args, "to")
+		}
+		err = at(4, "Interactor to add the usecase to", validInteractor(basePath))
+	case objMethod:
+		if err = at(2, "Method name", validName); err != nil {
+			break
+		}
+		if len(args) == 3 {
+			args = append(args, "to")
+		}
+		if err = at(4, "Object to add the method to ("+strings.Join(objTypes, ", ")+")", validOneOf(objTypes...)); err != nil {
+			break
+		}
+		err = at(5, "Interactor of the object", validInteractor(basePath))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return args, nil
+}
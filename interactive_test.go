@@ -0,0 +1,48 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestInteractiveInteractorMustExist asserts that --interactive asks again for the interactor of a usecase
+// until an existing one is entered
+func TestInteractiveInteractorMustExist(t *testing.T) {
+	p := newTestProject(t)
+	p.clean("add", "interactor", "Order")
+	stdout, stderr, code := p.runIn(p.dir, "Cart\nOrder\n", "add", "usecase", "AddItem", "to", "--interactive")
+	if code != 0 {
+		t.Fatalf("clean add usecase --interactive exited with %d: %s%s", code, stdout, stderr)
+	}
+	if !strings.Contains(stdout, "the interactor Cart doesn't exist, enter one of Order") {
+		t.Errorf("the missing interactor isn't reported:\n%s", stdout)
+	}
+	if p.exists("clean/usecase/interactor/cart.go") {
+		t.Errorf("the missing interactor was added")
+	}
+	if src := p.read("clean/usecase/interactor/order.go"); !strings.Contains(src, "AddItem(") {
+		t.Errorf("AddItem wasn't added to Order:\n%s", src)
+	}
+}
+
+// TestInteractiveCancelled asserts that ending the input while prompted fails the command, adds nothing and
+// is logged as an error
+func TestInteractiveCancelled(t *testing.T) {
+	p := newTestProject(t)
+	p.clean("add", "interactor", "Order")
+	before := p.files("clean")
+	stdout, stderr, code := p.runIn(p.dir, "Cart\n", "add", "usecase", "AddItem", "to", "--interactive")
+	if code != exitError || !strings.Contains(stdout, "Cancelled, nothing was added") {
+		t.Errorf("the cancelled command exited with %d: %s%s", code, stdout, stderr)
+	}
+	if concatenated(p.files("clean")) != concatenated(before) {
+		t.Errorf("the cancelled command changed the project")
+	}
+	log := p.read(".clean/history.log")
+	if entry := log[strings.LastIndex(strings.TrimSuffix(log, "\n"), "\n")+1:]; !strings.Contains(entry, `"command":"clean add usecase AddItem to --interactive"`) || !strings.Contains(entry, `"outcome":"error"`) {
+		t.Errorf("the cancelled command isn't logged as an error:\n%s", log)
+	}
+}
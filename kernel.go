@@ -0,0 +1,141 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	objKernel     = "kernel"
+	relPathKernel = "kernel/"
+)
+
+// kernelRef matches a type of the kernel package e.g. kernel.ID or []kernel.Money
+var kernelRef = regexp.MustCompile(`\bkernel\.[A-Z]`)
+
+// kernelSources holds the content of each file of a new kernel package
+var kernelSources = []struct{ name, content string }{
+	{docFileName, `// Package kernel is the shared kernel of the project. It holds the value types shared by the entities of
+// several aggregates e.g. IDs and Money, which the entities refer to instead of declaring their own. It's the
+// innermost package and must not import any other package of the project.
+//
+// The go:generate directives of the value types go into gen.go so that "go generate ./..." keeps the code
+// generated for them up to date.
+package kernel
+`},
+	{genFileName, `package kernel
+
+// Add the go:generate directives of the value types of the kernel here e.g.
+// //go:generate stringer -type=Currency
+`},
+	{"id.go", `package kernel
+
+import "errors"
+
+// ErrEmptyID is returned by ParseID for an empty ID.
+var ErrEmptyID = errors.New("kernel: empty ID")
+
+// ID identifies an entity. The entities of different aggregates refer to each other by their IDs rather than
+// by holding each other.
+type ID string
+
+// ParseID returns the ID s. It returns ErrEmptyID if s is empty.
+func ParseID(s string) (ID, error) {
+	if s == "" {
+		return "", ErrEmptyID
+	}
+	return ID(s), nil
+}
+
+// String returns the ID as a string.
+func (id ID) String() string {
+	return string(id)
+}
+
+// IsZero reports whether the ID is unset.
+func (id ID) IsZero() bool {
+	return id == ""
+}
+`},
+}
+
+// addKernel adds the shared kernel package, holding the starter ID type, to the kernel folder of the project at
+// basePath. The files of the package which already exist are kept.
+func addKernel(basePath string) error {
+	dir := filepath.FromSlash(basePath + relPathKernel)
+	var added bool
+	for _, src := range kernelSources {
+		fp := filepath.Join(dir, src.name)
+		if fileExists(fp) {
+			continue
+		}
+		if err := mkdirAll(dir); err != nil {
+			return err
+		}
+		if err := writeFile(fp, []byte(src.content)); err != nil {
+			return err
+		}
+		added = true
+	}
+	if !added {
+		noopf("the kernel already exists")
+	}
+	return nil
+}
+
+// importKernel imports the kernel package into the file fp if any of the fields refer to its types, e.g.
+// ID kernel.ID. It returns an error if the project has no kernel, or if the kernel imports any package of the
+// project, which would make the inner layers referring to it depend on the outer ones.
+func importKernel(basePath, fp string, fields []structField) error {
+	var refers bool
+	for _, field := range fields {
+		if kernelRef.MatchString(field.Type) {
+			refers = true
+		}
+	}
+	if !refers {
+		return nil
+	}
+	dir := filepath.FromSlash(basePath + relPathKernel)
+	if !fileExists(dir) {
+		return fmt.Errorf("the project has no kernel, add it with clean add kernel")
+	}
+	if err := verifyKernelImports(dir); err != nil {
+		return err
+	}
+	b, err := readFile(fp)
+	if err != nil {
+		return err
+	}
+	if b, err = ensureImport(b, layerImportPath(relPathKernel)); err != nil {
+		return err
+	}
+	return writeFile(fp, b)
+}
+
+// verifyKernelImports returns an error if any file of the kernel package in dir imports a package of the project
+func verifyKernelImports(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return err
+	}
+	for _, fp := range files {
+		f, err := parseGoFile(fp)
+		if err != nil {
+			return err
+		}
+		for _, imp := range f.Imports {
+			path, err := strconv.Unquote(imp.Path.Value)
+			if err == nil && projectBaseImportPath != "" && strings.HasPrefix(path, projectBaseImportPath) {
+				return fmt.Errorf("the kernel must not import the packages of the project, but %s imports %s", fp, path)
+			}
+		}
+	}
+	return nil
+}
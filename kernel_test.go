@@ -0,0 +1,65 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestKernel asserts that clean init --with-kernel adds the kernel folder with the starter ID type, that an
+// entity referring to it imports it and that the kernel isn't imported while it imports an inner layer
+func TestKernel(t *testing.T) {
+	p := newTestProject(t, "--with-kernel")
+	for _, relPath := range []string{"clean/kernel/doc.go", "clean/kernel/gen.go", "clean/kernel/id.go"} {
+		if !p.exists(relPath) {
+			t.Errorf("%s wasn't added", relPath)
+		}
+	}
+	id := p.read("clean/kernel/id.go")
+	for _, want := range []string{"package kernel", "type ID string", "func ParseID(s string) (ID, error)"} {
+		if !strings.Contains(id, want) {
+			t.Errorf("the kernel doesn't contain %q:\n%s", want, id)
+		}
+	}
+	if strings.Contains(id, `"app/`) {
+		t.Errorf("the kernel imports a package of the project:\n%s", id)
+	}
+
+	if stdout, stderr, code := p.runIn(p.dir, "Order: Customer kernel.ID, Total int\n", "add", "entity", "-"); code != 0 {
+		t.Fatalf("clean add entity exited with %d: %s%s", code, stdout, stderr)
+	}
+	entity := p.read("clean/entity/order.go")
+	if !strings.Contains(entity, `"app/clean/kernel"`) || !strings.Contains(entity, "kernel.ID") {
+		t.Errorf("the entity doesn't refer to the kernel:\n%s", entity)
+	}
+	if out := p.clean("lint"); strings.Contains(out, "kernel") {
+		t.Errorf("clean lint reports the kernel:\n%s", out)
+	}
+
+	if !testing.Short() {
+		if out, err := p.goRun("vet", "./..."); err != nil {
+			t.Errorf("the project doesn't compile: %s\n%s", err.Error(), out)
+		}
+	}
+
+	// A kernel importing an inner layer isn't imported into the entities
+	p.write("clean/kernel/money.go", "package kernel\n\nimport \"app/clean/entity\"\n\n// Money is an amount.\ntype Money = entity.Order\n")
+	stdout, stderr, code := p.runIn(p.dir, "Invoice: Customer kernel.ID\n", "add", "entity", "-")
+	if code == 0 || !strings.Contains(stdout+stderr, "the kernel must not import the packages of the project") {
+		t.Errorf("clean add entity exited with %d: %s%s", code, stdout, stderr)
+	}
+}
+
+// TestKernelIsOptIn asserts that a project gets no kernel unless --with-kernel is set
+func TestKernelIsOptIn(t *testing.T) {
+	p := newTestProject(t)
+	if p.exists("clean/kernel") {
+		t.Errorf("the kernel was added without --with-kernel")
+	}
+	stdout, stderr, code := p.runIn(p.dir, "Order: Customer kernel.ID\n", "add", "entity", "-")
+	if code == 0 || !strings.Contains(stdout+stderr, "the project has no kernel") {
+		t.Errorf("an entity referring to the missing kernel was added with %d: %s%s", code, stdout, stderr)
+	}
+}
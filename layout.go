@@ -0,0 +1,160 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	// confKeyLayout is followed by a layer e.g. layout.controller and holds the folder of the layer in the
+	// clean folder
+	confKeyLayout = "layout."
+	// layerGatewayPort is the layer of the Gateway interfaces added by --with-gateway-interface-in-usecase
+	layerGatewayPort = "gateway-port"
+	// schemeClean is the folder scheme of the layers of Clean Architecture, which is the default
+	schemeClean = "clean"
+	// schemeHexagonal is the folder scheme of the ports and adapters of the hexagonal architecture
+	schemeHexagonal = "hexagonal"
+)
+
+// defaultLayout holds the default folder of each layer whose folder can be configured
+var defaultLayout = layoutLayers()
+
+// folderSchemes holds the layout.[layer] settings of each folder scheme a project can be initialised with, see
+// clean init --layout
+var folderSchemes = map[string]map[string]string{
+	schemeClean: {},
+	schemeHexagonal: {
+		objEntity: "domain/entity", objInteractor: "domain/interactor", objValidator: "domain/validator",
+		"reqmodel": "ports/reqmodel", "respmodel": "ports/respmodel", layerGatewayPort: "ports/gateway",
+		objController: "adapters/controller", objPresenter: "adapters/presenter", objView: "adapters/view",
+		"viewmodel": "adapters/viewmodel", objGateway: "adapters/gateway",
+	},
+}
+
+// setFolderScheme replaces the layout.[layer] settings of conf with the ones of the folder scheme
+func setFolderScheme(conf map[string]string, scheme string) error {
+	settings, ok := folderSchemes[scheme]
+	if !ok {
+		return fmt.Errorf("invalid --layout %s, the layouts are %s and %s", scheme, schemeClean, schemeHexagonal)
+	}
+	for k := range conf {
+		if strings.HasPrefix(k, confKeyLayout) {
+			delete(conf, k)
+		}
+	}
+	for layer, dir := range settings {
+		conf[confKeyLayout+layer] = dir
+	}
+	return nil
+}
+
+// layoutRelPaths holds the folder of each layer whose folder can be configured
+var layoutRelPaths = map[string]*string{
+	objEntity: &relPathEntity, objController: &relPathController, objPresenter: &relPathPresenter,
+	objView: &relPathView, "viewmodel": &relPathViewModel, objInteractor: &relPathInteractor,
+	"reqmodel": &relPathReqModel, objValidator: &relPathValidator, "respmodel": &relPathRespModel,
+	objGateway: &relPathGateway, layerGatewayPort: &relPathGatewayPort,
+}
+
+// setLayout sets the folders of the layers from the layout.[layer] settings of conf e.g.
+// layout.controller=adapters/http/controller. A folder is relative to the clean folder and the package of a
+// layer keeps the name of the layer whatever its folder is, so that the generated code refers to it the same
+// way. The layers of the settings must exist and their folders must be distinct. The layers without a setting
+// are in their default folders.
+func setLayout(conf map[string]string) error {
+	for layer, relPath := range layoutRelPaths {
+		*relPath = defaultLayout[layer]
+	}
+	var layers []string
+	for k := range conf {
+		if strings.HasPrefix(k, confKeyLayout) {
+			layers = append(layers, strings.TrimPrefix(k, confKeyLayout))
+		}
+	}
+	sort.Strings(layers)
+	for _, layer := range layers {
+		relPath, ok := layoutRelPaths[layer]
+		if !ok {
+			return fmt.Errorf("invalid %s%s setting in the configuration file, the layers are %s", confKeyLayout, layer, strings.Join(sortedKeys(layoutLayers()), ", "))
+		}
+		dir := path.Clean(strings.Replace(strings.TrimSpace(conf[confKeyLayout+layer]), "\\", "/", -1))
+		if dir == "." || path.IsAbs(dir) || dir == ".." || strings.HasPrefix(dir, "../") {
+			return fmt.Errorf("invalid %s%s=%s setting in the configuration file, the folder must be inside the clean folder", confKeyLayout, layer, conf[confKeyLayout+layer])
+		}
+		*relPath = dir + "/"
+	}
+	layerOf := make(map[string]string)
+	for _, layer := range sortedKeys(layoutLayers()) {
+		relPath := *layoutRelPaths[layer]
+		if other, ok := layerOf[relPath]; ok {
+			return fmt.Errorf("the layers %s and %s have the same folder %s, set a distinct %s[layer] in the configuration file", other, layer, relPath, confKeyLayout)
+		}
+		layerOf[relPath] = layer
+	}
+	relPaths = []string{relPathController, relPathPresenter, relPathView, relPathViewModel, relPathInteractor, relPathReqModel, relPathValidator, relPathRespModel}
+	packageRelPaths = map[string]string{objEntity: relPathEntity, objController: relPathController, objGateway: relPathGateway, objPresenter: relPathPresenter, objView: relPathView, "viewmodel": relPathViewModel, objInteractor: relPathInteractor, "reqmodel": relPathReqModel, objValidator: relPathValidator, "respmodel": relPathRespModel, objEvent: relPathEvent}
+	objRelPaths = map[string]string{objController: relPathController, objPresenter: relPathPresenter, objView: relPathView, objInteractor: relPathInteractor, objValidator: relPathValidator}
+	return nil
+}
+
+// layoutLayers returns the folders of the layers whose folder can be configured by their layers
+func layoutLayers() map[string]string {
+	layers := make(map[string]string)
+	for layer, relPath := range layoutRelPaths {
+		layers[layer] = *relPath
+	}
+	return layers
+}
+
+// layerOfRelPath returns the layer whose folder is relPath or an empty string if there's none. If several
+// layers share the folder, the first of them in alphabetical order is returned.
+func layerOfRelPath(relPath string) string {
+	layers := layoutLayers()
+	for _, layer := range sortedKeys(layers) {
+		if layers[layer] == relPath {
+			return layer
+		}
+	}
+	if relPath == relPathEvent {
+		return objEvent
+	}
+	return ""
+}
+
+// layerImportPath returns the import path of the package of the layer folder relPath of the project
+func layerImportPath(relPath string) string {
+	return projectBaseImportPath + "clean/" + strings.TrimSuffix(relPath, "/")
+}
+
+// skeletonDirs returns the folders of the layers, their test folders and the folders holding them, which a new
+// project is initialised with
+func skeletonDirs() []string {
+	if flatLayout {
+		// All of the layers are in the package of the clean folder
+		return []string{"clean", "lib", "cmd"}
+	}
+	dirs := map[string]bool{"clean": true}
+	add := func(dir string) {
+		for ; dir != "." && dir != "clean"; dir = path.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+	for _, relPath := range []string{relPathEntity, relPathGateway, relPathViewModel, relPathReqModel, relPathRespModel} {
+		add("clean/" + strings.TrimSuffix(relPath, "/"))
+	}
+	for _, relPath := range []string{relPathController, relPathGateway, relPathPresenter, relPathView, relPathInteractor, relPathValidator} {
+		add("clean/" + relPath + "test")
+	}
+	var sorted []string
+	for dir := range dirs {
+		sorted = append(sorted, dir)
+	}
+	sort.Strings(sorted)
+	return append(sorted, "lib", "cmd")
+}
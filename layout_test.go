@@ -0,0 +1,145 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCustomLayout initialises a project whose configuration file maps every layer to a folder of its own
+// and asserts that the files are generated into those folders and import each other from them
+func TestCustomLayout(t *testing.T) {
+	root := t.TempDir()
+	p := &testProject{t: t, home: filepath.Join(root, "home"), dir: filepath.Join(root, "app")}
+	p.write("go.mod", "module app\n\ngo 1.21\n")
+	p.write("../home/.clean/cleanrc", strings.Join([]string{
+		"layout.controller=adapters/http/handlers",
+		"layout.presenter=adapters/presenters",
+		"layout.view=adapters/views",
+		"layout.viewmodel=adapters/views/models",
+		"layout.interactor=core/usecases",
+		"layout.reqmodel=core/requests",
+		"layout.validator=core/requests/checks",
+		"layout.respmodel=core/responses",
+		"layout.entity=domain",
+		"layout.gateway=adapters/storage",
+		"layout.gateway-port=core/ports",
+	}, "\n")+"\n")
+	p.clean("--no-history", "init")
+	p.clean("add", "interactor", "Order", "--with-gateway")
+	p.clean("add", "usecase", "AddItem", "to", "Order")
+	p.clean("add", "entity", "Product")
+
+	for _, relPath := range []string{
+		"clean/adapters/http/handlers/order.go",
+		"clean/adapters/presenters/order.go",
+		"clean/adapters/views/order.go",
+		"clean/adapters/views/models/order.go",
+		"clean/core/usecases/order.go",
+		"clean/core/requests/order.go",
+		"clean/core/requests/checks/order.go",
+		"clean/core/responses/order.go",
+		"clean/adapters/storage/order.go",
+		"clean/domain/product.go",
+	} {
+		if !p.exists(relPath) {
+			t.Errorf("%s wasn't generated", relPath)
+		}
+	}
+	for _, dir := range []string{"clean/ifadapter", "clean/usecase", "clean/entity"} {
+		if _, err := os.Stat(p.path(dir)); err == nil {
+			t.Errorf("the default folder %s was created", dir)
+		}
+	}
+	imports := map[string][]string{
+		"clean/adapters/http/handlers/order.go": {`"app/clean/core/usecases"`},
+		"clean/adapters/presenters/order.go":    {`"app/clean/adapters/views"`, `"app/clean/core/responses"`},
+		"clean/adapters/views/order.go":         {`"app/clean/adapters/views/models"`},
+		"clean/core/usecases/order.go":          {`"app/clean/adapters/presenters"`, `"app/clean/core/requests"`, `"app/clean/core/requests/checks"`, `"app/clean/adapters/storage"`},
+		"clean/core/requests/checks/order.go":   {`"app/clean/core/requests"`, `"app/clean/core/responses"`},
+	}
+	for relPath, want := range imports {
+		src := p.read(relPath)
+		for _, imp := range want {
+			if !strings.Contains(src, imp) {
+				t.Errorf("%s doesn't import %s:\n%s", relPath, imp, src)
+			}
+		}
+		if strings.Contains(src, `"app/clean/ifadapter`) || strings.Contains(src, `"app/clean/usecase`) {
+			t.Errorf("%s imports a default folder:\n%s", relPath, src)
+		}
+	}
+
+	if testing.Short() {
+		return
+	}
+	if out, err := p.goRun("vet", "./..."); err != nil {
+		t.Errorf("the project doesn't compile: %s\n%s", err.Error(), out)
+	}
+}
+
+// TestHexagonalLayout asserts that clean init --layout hexagonal creates the domain, ports and adapters tree
+// and that the add commands generate into it
+func TestHexagonalLayout(t *testing.T) {
+	p := newTestProject(t, "--layout", "hexagonal")
+	for _, dir := range []string{
+		"clean/domain/entity",
+		"clean/domain/interactor/test",
+		"clean/domain/validator/test",
+		"clean/ports/reqmodel",
+		"clean/ports/respmodel",
+		"clean/adapters/controller/test",
+		"clean/adapters/presenter/test",
+		"clean/adapters/view/test",
+		"clean/adapters/viewmodel",
+		"clean/adapters/gateway/test",
+		"lib",
+		"cmd",
+	} {
+		if fi, err := os.Stat(p.path(dir)); err != nil || !fi.IsDir() {
+			t.Errorf("the folder %s wasn't created", dir)
+		}
+	}
+	for _, dir := range []string{"clean/ifadapter", "clean/usecase", "clean/entity"} {
+		if p.exists(dir) {
+			t.Errorf("the folder %s of the clean layout was created", dir)
+		}
+	}
+	if conf := p.read("../home/.clean/cleanrc"); !strings.Contains(conf, "layout.interactor=domain/interactor\n") || !strings.Contains(conf, "layout.controller=adapters/controller\n") {
+		t.Errorf("the configuration file doesn't hold the folders of the hexagonal layout:\n%s", conf)
+	}
+
+	p.clean("add", "interactor", "Order", "--with-gateway")
+	p.clean("add", "usecase", "AddItem", "to", "Order")
+	p.clean("add", "entity", "Product")
+	for _, relPath := range []string{
+		"clean/adapters/controller/order.go",
+		"clean/adapters/presenter/order.go",
+		"clean/adapters/view/order.go",
+		"clean/adapters/viewmodel/order.go",
+		"clean/adapters/gateway/order.go",
+		"clean/domain/interactor/order.go",
+		"clean/domain/validator/order.go",
+		"clean/domain/entity/product.go",
+		"clean/ports/reqmodel/order.go",
+		"clean/ports/respmodel/order.go",
+	} {
+		if !p.exists(relPath) {
+			t.Errorf("%s wasn't generated", relPath)
+		}
+	}
+	if src := p.read("clean/domain/interactor/order.go"); !strings.Contains(src, `"app/clean/ports/reqmodel"`) || !strings.Contains(src, `"app/clean/adapters/presenter"`) {
+		t.Errorf("the Interactor doesn't import the ports and adapters:\n%s", src)
+	}
+
+	if testing.Short() {
+		return
+	}
+	if out, err := p.goRun("vet", "./..."); err != nil {
+		t.Errorf("the project doesn't compile: %s\n%s", err.Error(), out)
+	}
+}
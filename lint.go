@@ -0,0 +1,171 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const verbLint = "lint"
+
+// The rings of the layers, innermost first. The packages of a ring may only import the packages of their own
+// ring and of the rings inside it.
+const (
+	// ringKernel is the ring of the shared kernel, which imports no package of the project at all
+	ringKernel = iota
+	ringEntity
+	ringUsecase
+	ringAdapter
+)
+
+// layerRings holds the ring of each layer
+var layerRings = map[string]int{
+	objKernel: ringKernel, objEntity: ringEntity,
+	objInteractor: ringUsecase, "reqmodel": ringUsecase, objValidator: ringUsecase, "respmodel": ringUsecase,
+	layerGatewayPort: ringUsecase, objEvent: ringUsecase, objUsecase: ringUsecase,
+	objController: ringAdapter, objPresenter: ringAdapter, objView: ringAdapter, "viewmodel": ringAdapter,
+	objGateway: ringAdapter,
+}
+
+// lintAllowed holds the layers of the outer rings each layer may import nevertheless, because Clean declares
+// the interfaces of its ports there: the Interactor presents its output with the Presenter interface and
+// reaches the data with the Gateway interface of the gateway folder unless it's in the usecase layer.
+var lintAllowed = map[string][]string{
+	objInteractor: {objPresenter, objGateway},
+}
+
+// violation is an import breaking the dependency rule
+type violation struct {
+	File          string `json:"file"`
+	Import        string `json:"import"`
+	Layer         string `json:"layer"`
+	ImportedLayer string `json:"imported_layer"`
+}
+
+// lintLayer returns the layer of the package folder relDir, relative to the clean folder and ending with a
+// slash, or an empty string if it isn't the folder of a layer e.g. a test folder or one of the lib folders
+func lintLayer(relDir string) string {
+	if relDir == relPathKernel {
+		return objKernel
+	}
+	if layer := layerOfRelPath(relDir); layer != "" {
+		return layer
+	}
+	// The packages of the usecases of the vertical layout are in the folder holding the interactor folder
+	usecaseDir := path.Dir(strings.TrimSuffix(relPathInteractor, "/")) + "/"
+	if verticalLayout && strings.HasPrefix(relDir, usecaseDir) && strings.Count(strings.TrimPrefix(relDir, usecaseDir), "/") == 2 {
+		return objUsecase
+	}
+	return ""
+}
+
+// lintImport returns the violation of the dependency rule of the import path of a file of the layer, if any
+func lintImport(layer, importPath string) (string, bool) {
+	cleanImportPath := projectBaseImportPath + "clean/"
+	if projectBaseImportPath == "" || !strings.HasPrefix(importPath, projectBaseImportPath) {
+		return "", false
+	}
+	if layer == objKernel {
+		// The kernel imports nothing of the project
+		imported := lintLayer(strings.TrimPrefix(importPath, cleanImportPath) + "/")
+		if imported == "" {
+			imported = importPath
+		}
+		return imported, true
+	}
+	if !strings.HasPrefix(importPath, cleanImportPath) {
+		return "", false
+	}
+	imported := lintLayer(strings.TrimPrefix(importPath, cleanImportPath) + "/")
+	if imported == "" || layerRings[imported] <= layerRings[layer] {
+		return "", false
+	}
+	for _, allowed := range lintAllowed[layer] {
+		if imported == allowed {
+			return "", false
+		}
+	}
+	return imported, true
+}
+
+// lintProject returns the imports of the Go files of the layers in basePath which break the dependency rule
+// of Clean Architecture, i.e. which import a package of a layer outside of their own. The tests are skipped.
+// The paths of the files are relative to projectPath.
+func lintProject(projectPath, basePath string) ([]violation, error) {
+	defer startPhase(phaseScan)()
+	var violations []violation
+	err := filepath.Walk(filepath.FromSlash(basePath), func(fp string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() || filepath.Ext(fp) != ".go" || strings.HasSuffix(fp, "_test.go") {
+			return nil
+		}
+		relDir, err := filepath.Rel(filepath.FromSlash(basePath), filepath.Dir(fp))
+		if err != nil {
+			return err
+		}
+		layer := lintLayer(filepath.ToSlash(relDir) + "/")
+		if layer == "" {
+			return nil
+		}
+		f, err := parseGoFile(fp)
+		if err != nil {
+			return err
+		}
+		relFp, err := filepath.Rel(filepath.FromSlash(projectPath), fp)
+		if err != nil {
+			relFp = fp
+		}
+		for _, imp := range f.Imports {
+			importPath, err := strconv.Unquote(imp.Path.Value)
+			if err != nil {
+				continue
+			}
+			if imported, ok := lintImport(layer, importPath); ok {
+				violations = append(violations, violation{filepath.ToSlash(relFp), importPath, layer, imported})
+			}
+		}
+		return nil
+	})
+	return violations, err
+}
+
+// printViolations prints the violations of the dependency rule as text or, if asJSON is set, as JSON
+func printViolations(violations []violation, asJSON bool) error {
+	if asJSON {
+		out := struct {
+			Count      int         `json:"count"`
+			Violations []violation `json:"violations"`
+		}{len(violations), violations}
+		if out.Violations == nil {
+			out.Violations = []violation{}
+		}
+		b, err := json.MarshalIndent(out, "", "\t")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\n", b)
+		return nil
+	}
+	for _, v := range violations {
+		if v.Layer == objKernel {
+			fmt.Printf("%s imports %s: the kernel must not import any package of the project\n", v.File, v.Import)
+			continue
+		}
+		fmt.Printf("%s imports %s: the %s layer must not import the %s layer\n", v.File, v.Import, v.Layer, v.ImportedLayer)
+	}
+	if len(violations) == 0 {
+		fmt.Printf("No violations of the dependency rule\n\n")
+		return nil
+	}
+	fmt.Printf("\n%d violations of the dependency rule\n\n", len(violations))
+	return nil
+}
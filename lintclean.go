@@ -0,0 +1,67 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+)
+
+// useParams returns the generated methods src with a blank assignment of each named parameter their bodies
+// don't use, e.g. _ = rqm, as the first statement of the body, so that linters such as unparam and revive
+// don't flag the parameters of the stubs. src is returned as is unless --lint-clean is set.
+func useParams(src string) string {
+	if !*lintClean {
+		return src
+	}
+	const pkg = "package p\n"
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", pkg+src, parser.ParseComments)
+	if err != nil {
+		return src
+	}
+	type insertion struct {
+		offset int
+		text   string
+	}
+	var insertions []insertion
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Body == nil {
+			continue
+		}
+		used := make(map[string]bool)
+		ast.Inspect(fd.Body, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok {
+				used[id.Name] = true
+			}
+			return true
+		})
+		var text string
+		for _, field := range fd.Type.Params.List {
+			for _, name := range field.Names {
+				if name.Name != "_" && !used[name.Name] {
+					text += "\t_ = " + name.Name + "\n"
+				}
+			}
+		}
+		if text == "" {
+			continue
+		}
+		// Insert the assignments on the line after the opening brace of the body
+		offset := fset.Position(fd.Body.Lbrace).Offset + 1 - len(pkg)
+		if offset < len(src) && src[offset] == '\n' {
+			offset++
+		} else {
+			text = "\n" + text
+		}
+		insertions = append(insertions, insertion{offset, text})
+	}
+	for i := len(insertions) - 1; i >= 0; i-- {
+		in := insertions[i]
+		src = src[:in.offset] + in.text + src[in.offset:]
+	}
+	return src
+}
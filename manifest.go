@@ -0,0 +1,307 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// manifestFileName is the name of the manifest in the .clean folder of the project
+const manifestFileName = "manifest.json"
+
+// manifestIgnoredFlags holds the flags which don't change the generated code and therefore aren't recorded
+// in the manifest
+var manifestIgnoredFlags = map[string]bool{
+	"all": true, "cpuprofile": true, "create-only": true, "dry-run": true, "fail-on-noop": true, "fail-over": true,
+	"force": true, "format": true, "guess-words": true, "idempotent-regenerate": true, "interactive": true, "json": true, "keep-skeleton": true,
+	"memprofile": true, "mock-engine": true, "module": true, "n": true, "no-history": true, "repair": true, "require-clean-git": true, "stdout": true,
+	"strict": true, "strict-names": true, "timings": true,
+}
+
+// manifestUsecase is a usecase of an interactor recorded in the manifest together with the flags it was
+// added with
+type manifestUsecase struct {
+	Name  string            `json:"name"`
+	Flags map[string]string `json:"flags,omitempty"`
+}
+
+// manifestInteractor is an interactor recorded in the manifest together with the flags it was added with
+// and its other Views and Presenters, see addView and addPresenterImpl, and whether it has a view factory, see
+// addViewFactory
+type manifestInteractor struct {
+	Name        string            `json:"name"`
+	Flags       map[string]string `json:"flags,omitempty"`
+	Usecases    []manifestUsecase `json:"usecases"`
+	Views       []string          `json:"views,omitempty"`
+	Presenters  []string          `json:"presenters,omitempty"`
+	ViewFactory bool              `json:"view_factory,omitempty"`
+	// MockEngine is the engine of the mocks of the interactor, see interactorMockEngine. It's empty for the
+	// builtin engine.
+	MockEngine string `json:"mock_engine,omitempty"`
+	// Suffixes are the type suffixes configured when the interactor was added, see verifyTypeSuffixes
+	Suffixes map[string]string `json:"suffixes,omitempty"`
+}
+
+// manifest records the interactors and usecases of a project in the order they were added
+type manifest struct {
+	Interactors []manifestInteractor `json:"interactors"`
+}
+
+// commandFlags returns the flags set on the command line which change the generated code
+func commandFlags() map[string]string {
+	flags := make(map[string]string)
+	flag.Visit(func(f *flag.Flag) {
+		if !manifestIgnoredFlags[f.Name] {
+			flags[f.Name] = f.Value.String()
+		}
+	})
+	return flags
+}
+
+// manifestPath returns the path of the manifest of the project at projectPath
+func manifestPath(projectPath string) string {
+	return filepath.Join(filepath.FromSlash(projectPath), historyDir, manifestFileName)
+}
+
+// readManifest returns the manifest of the project at projectPath, which is empty if it doesn't exist
+func readManifest(projectPath string) (manifest, error) {
+	var m manifest
+	b, err := ioutil.ReadFile(manifestPath(projectPath))
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return m, err
+	}
+	err = json.Unmarshal(b, &m)
+	return m, err
+}
+
+// writeManifest writes m as the manifest of the project at projectPath unless it's unchanged
+func writeManifest(projectPath string, m manifest) error {
+	b, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	fp := manifestPath(projectPath)
+	if old, err := ioutil.ReadFile(fp); err == nil && bytes.Equal(old, b) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(fp), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fp, b, 0600)
+}
+
+// syncManifest returns m with the interactors and usecases of the project at basePath. The Interactor
+// interfaces are the record of which of them exist, so the ones that were removed, or whose files were
+// deleted by hand, are dropped and the ones missing from m are added with flags, in alphabetical order.
+func syncManifest(m manifest, basePath string, flags map[string]string) (manifest, error) {
+	existing, err := interactorUsecases(basePath)
+	if err != nil && !os.IsNotExist(err) {
+		return m, err
+	}
+	synced := manifest{Interactors: []manifestInteractor{}}
+	recorded := make(map[string]bool)
+	for _, it := range m.Interactors {
+		usecases, ok := existing[it.Name]
+		if !ok {
+			continue
+		}
+		recorded[it.Name] = true
+		it.Usecases = syncManifestUsecases(it.Usecases, usecases, flags)
+		it.Views = existingObjs(basePath, relPathView, append(it.Views, addedViews[it.Name]...))
+		it.Presenters = existingObjs(basePath, relPathPresenter, append(it.Presenters, addedPresenters[it.Name]...))
+		it.ViewFactory = (it.ViewFactory || addedViewFactories[it.Name]) && fileExists(viewFactoryPath(basePath, it.Name))
+		if engine, ok := mockEngines[it.Name]; ok {
+			it.MockEngine = recordedMockEngine(engine)
+		}
+		synced.Interactors = append(synced.Interactors, it)
+	}
+	var added []string
+	for name := range existing {
+		if !recorded[name] {
+			added = append(added, name)
+		}
+	}
+	sort.Strings(added)
+	for _, name := range added {
+		synced.Interactors = append(synced.Interactors, manifestInteractor{Name: name, Flags: flags, Usecases: syncManifestUsecases(nil, existing[name], flags), Views: existingObjs(basePath, relPathView, addedViews[name]), Presenters: existingObjs(basePath, relPathPresenter, addedPresenters[name]), ViewFactory: addedViewFactories[name] && fileExists(viewFactoryPath(basePath, name)), MockEngine: recordedMockEngine(interactorMockEngine(basePath, name)), Suffixes: configuredSuffixes()})
+	}
+	return synced, nil
+}
+
+// configuredSuffixes returns the configured type suffixes which aren't empty, or nil if there are none
+func configuredSuffixes() map[string]string {
+	var suffixes map[string]string
+	for objType, suffix := range typeSuffixes {
+		if suffix == "" {
+			continue
+		}
+		if suffixes == nil {
+			suffixes = make(map[string]string)
+		}
+		suffixes[objType] = suffix
+	}
+	return suffixes
+}
+
+// recordedMockEngine returns the mock engine as recorded in the manifest, which leaves out the builtin engine
+func recordedMockEngine(engine string) string {
+	if engine == mockEngineBuiltin {
+		return ""
+	}
+	return engine
+}
+
+// syncManifestUsecases returns the recorded usecases which still exist followed by the existing usecases
+// that weren't recorded, in alphabetical order and with flags
+func syncManifestUsecases(recorded []manifestUsecase, existing []string, flags map[string]string) []manifestUsecase {
+	exists := make(map[string]bool)
+	for _, u := range existing {
+		exists[u] = true
+	}
+	synced := []manifestUsecase{}
+	for _, u := range recorded {
+		if exists[u.Name] {
+			synced = append(synced, u)
+			delete(exists, u.Name)
+		}
+	}
+	var added []string
+	for u := range exists {
+		added = append(added, u)
+	}
+	sort.Strings(added)
+	for _, u := range added {
+		synced = append(synced, manifestUsecase{Name: u, Flags: flags})
+	}
+	return synced
+}
+
+// loadManifest returns the manifest of the project at projectPath or, if it doesn't exist yet, the
+// interactors and usecases of the project at basePath as they are, without any flags
+func loadManifest(projectPath, basePath string) (manifest, error) {
+	if !fileExists(manifestPath(projectPath)) {
+		return syncManifest(manifest{}, basePath, nil)
+	}
+	return readManifest(projectPath)
+}
+
+// updateManifest syncs m, the manifest of the project at projectPath before the command, with the
+// interactors and usecases of the project at basePath. The ones the command added are recorded with flags.
+func updateManifest(projectPath, basePath string, m manifest, flags map[string]string) {
+	// The interactors whose interfaces aren't named according to the configured type suffixes can't be told
+	// apart from removed ones, so they're kept as recorded until they're renamed
+	if verifyTypeSuffixes(basePath) != nil {
+		return
+	}
+	m, err := syncManifest(m, basePath, flags)
+	if err != nil {
+		failf("Error reading the interactors: %s\n", err.Error())
+		return
+	}
+	if err := writeManifest(projectPath, m); err != nil {
+		failf("Error writing the manifest: %s\n", err.Error())
+	}
+}
+
+// withFlags runs fn with the flags which change the generated code set to their defaults overridden by
+// flags. The flags are restored afterwards.
+func withFlags(flags map[string]string, fn func()) error {
+	saved := make(map[string]string)
+	var err error
+	flag.VisitAll(func(f *flag.Flag) {
+		if manifestIgnoredFlags[f.Name] || err != nil {
+			return
+		}
+		saved[f.Name] = f.Value.String()
+		value, ok := flags[f.Name]
+		if !ok {
+			value = f.DefValue
+		}
+		err = f.Value.Set(value)
+	})
+	if err == nil {
+		fn()
+	}
+	for name, value := range saved {
+		flag.Set(name, value)
+	}
+	return err
+}
+
+// regenerateFromManifest adds the objects, models and methods of the interactors and usecases recorded in the manifest
+// of the project at projectPath which are missing from the project at basePath, each with the flags it was
+// added with. Existing declarations, including the implemented methods, are kept as they are, and so are the
+// existing files of an interactor whose files partly exist, as with --repair. A project without a manifest is
+// first recorded as it is.
+func regenerateFromManifest(projectPath, basePath string) error {
+	m, err := loadManifest(projectPath, basePath)
+	if err != nil {
+		return err
+	}
+	savedRepair := *repair
+	*repair = true
+	defer func() { *repair = savedRepair }()
+	nChanged, nNoops := len(changedFiles), len(noops)
+	for _, it := range m.Interactors {
+		err := withFlags(it.Flags, func() {
+			if *withUow {
+				if err := addUnitOfWork(basePath); err != nil {
+					failf("Error adding the unit of work: %s\n", err.Error())
+				}
+			}
+			addInteractorWithExtras(basePath, it.Name)
+			if selected(objView) {
+				for _, view := range it.Views {
+					if err := addView(basePath, view, it.Name); err != nil {
+						failf("Error adding the view %s: %s\n", view, err.Error())
+					}
+				}
+			}
+			if it.ViewFactory && selected(objView) {
+				if err := addViewFactory(basePath, it.Name); err != nil {
+					failf("Error adding the view factory: %s\n", err.Error())
+				}
+			}
+			if selected(objPresenter) {
+				for _, presenter := range it.Presenters {
+					if err := addPresenterImpl(basePath, presenter, it.Name); err != nil {
+						failf("Error adding the presenter %s: %s\n", presenter, err.Error())
+					}
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+		for _, u := range it.Usecases {
+			err := withFlags(u.Flags, func() {
+				specs, err := withPayload([]namedSpec{{Name: u.Name}})
+				if err != nil {
+					failf("Error reading the request payload of %s: %s\n", u.Name, err.Error())
+					return
+				}
+				addUsecaseWithExtras(basePath, specs[0], it.Name)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	// Everything that exists is a noop, so only the project as a whole is reported
+	noops = noops[:nNoops]
+	if len(changedFiles) == nChanged {
+		noopf("the project is up to date with the manifest")
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestRegenerateRestoresDeletedFile deletes the presenter file of an interactor and asserts that regenerate
+// adds it again with the usecases of the manifest, without --repair, and keeps the other files as they are
+func TestRegenerateRestoresDeletedFile(t *testing.T) {
+	p := newTestProject(t)
+	p.clean("add", "interactor", "Order")
+	p.clean("add", "usecase", "AddItem", "to", "Order")
+	const interactorFp = "clean/usecase/interactor/order.go"
+	implemented := strings.Replace(p.read(interactorFp), "\t// TODO: Implement interface method\n", "\t// Implemented by hand\n", 1)
+	p.write(interactorFp, implemented)
+	const fp = "clean/ifadapter/presenter/order.go"
+	want := p.read(fp)
+	if err := os.Remove(p.path(fp)); err != nil {
+		t.Fatal(err)
+	}
+
+	p.clean("regenerate")
+	if !p.exists(fp) {
+		t.Fatalf("regenerate didn't restore %s", fp)
+	}
+	if got := p.read(fp); got != want {
+		t.Errorf("%s differs from the deleted file:\n%s", fp, firstDifference(want, got))
+	}
+	if got := p.read(interactorFp); got != implemented {
+		t.Errorf("regenerate changed %s:\n%s", interactorFp, firstDifference(implemented, got))
+	}
+}
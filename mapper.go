@@ -0,0 +1,126 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	gofmt "go/format"
+	"go/parser"
+	"go/types"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	objMapper = "mapper"
+	// mapperHeader marks the mapper files as generated so that they're rewritten as a whole whenever the
+	// mapper is added again e.g. after the fields of the entity or the model changed
+	mapperHeader = "// Code generated by clean. DO NOT EDIT.\n\n"
+)
+
+// mapperFile returns the path of the file of the mapper to the ResponseModel model e.g. mapping_getproduct.go
+func mapperFile(basePath, model string) string {
+	return filepath.FromSlash(basePath + relPathRespModel + "mapping_" + fileName(model) + ".go")
+}
+
+// qualifiedType returns the type expression typ declared in the package pkg as it's referred to from other
+// packages e.g. *Status becomes *entity.Status. It's returned unchanged if it can't be parsed.
+func qualifiedType(typ, pkg string) string {
+	expr, err := parser.ParseExpr(typ)
+	if err != nil {
+		return typ
+	}
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			// Already qualified by another package
+			return false
+		case *ast.Ident:
+			if n.IsExported() {
+				n.Name = pkg + "." + n.Name
+			}
+		}
+		return true
+	})
+	return types.ExprString(expr)
+}
+
+// mapperSource returns the source of the mapper from the entity to the ResponseModel model, which copies the
+// fields with the same name and type, and the fields it leaves unmapped. Either side having no fields is fine.
+func mapperSource(basePath, entityName, model string, entityFields, modelFields []structField) ([]byte, []string, error) {
+	modelTypes := make(map[string]string)
+	for _, f := range modelFields {
+		modelTypes[f.Name] = f.Type
+	}
+	entityTypes := make(map[string]string)
+	for _, f := range entityFields {
+		entityTypes[f.Name] = qualifiedType(f.Type, objEntity)
+	}
+	var body bytes.Buffer
+	var unmapped []string
+	for _, f := range entityFields {
+		if t, ok := modelTypes[f.Name]; ok && t == entityTypes[f.Name] {
+			fmt.Fprintf(&body, "\t\t%s: e.%s,\n", f.Name, f.Name)
+			continue
+		}
+		fmt.Fprintf(&body, "\t\t// TODO: Map e.%s (%s), %s has no %s field of the same type\n", f.Name, entityTypes[f.Name], model, f.Name)
+		unmapped = append(unmapped, "e."+f.Name)
+	}
+	for _, f := range modelFields {
+		if _, ok := entityTypes[f.Name]; !ok {
+			fmt.Fprintf(&body, "\t\t// TODO: Set %s (%s), %s has no %s field\n", f.Name, f.Type, entityName, f.Name)
+			unmapped = append(unmapped, f.Name)
+		}
+	}
+	var b bytes.Buffer
+	b.WriteString(mapperHeader)
+	b.WriteString(packageClause(basePath+relPathRespModel, "respmodel"))
+	fmt.Fprintf(&b, "\n\nimport (\n\t%q\n)\n", projectBaseImportPath+"clean/"+strings.TrimSuffix(relPathEntity, "/"))
+	fmt.Fprintf(&b, "\n// %sFromEntity returns the %s of the entity e, whose fields of the same name and type\n// are copied. It returns nil if e is nil.\nfunc %sFromEntity(e *entity.%s) *%s {\n\tif e == nil {\n\t\treturn nil\n\t}\n\treturn &%s{\n%s\t}\n}\n",
+		model, model, model, entityName, model, model, body.String())
+	src, err := gofmt.Source(b.Bytes())
+	return src, unmapped, err
+}
+
+// addMapper adds the mapper from the entity to the ResponseModel model of a usecase of the interactor to the
+// respmodel folder. The fields of both are parsed from their structs, and the mapper is regenerated if it's
+// added again. The fields it leaves unmapped are reported.
+func addMapper(basePath, entityName, model, interactor string) error {
+	entityName, model = exportedName(entityName), exportedName(model)
+	entityFp := typeDeclFile(filepath.FromSlash(basePath+relPathEntity), entityName, "")
+	if entityFp == "" {
+		return fmt.Errorf("the entity %s doesn't exist", entityName)
+	}
+	// The models of all interactors share a package, so the model is looked up in the files of the others too
+	modelFp := filepath.FromSlash(basePath + relPathRespModel + fileName(interactor) + ".go")
+	if f, err := parseGoFile(modelFp); err != nil || findTypeSpec(f, model) == nil {
+		if modelFp = typeDeclFile(filepath.FromSlash(basePath+relPathRespModel), model, ""); modelFp == "" {
+			return fmt.Errorf("the ResponseModel %s of %s doesn't exist", model, exportedName(interactor))
+		}
+	}
+	entityFields, err := structFields(entityFp, entityName)
+	if err != nil {
+		return err
+	}
+	modelFields, err := structFields(modelFp, model)
+	if err != nil {
+		return err
+	}
+	src, unmapped, err := mapperSource(basePath, entityName, model, entityFields, modelFields)
+	if err != nil {
+		return err
+	}
+	fp := mapperFile(basePath, model)
+	if old, err := readFile(fp); err == nil && bytes.Equal(old, src) {
+		noopf("the mapper of %s to %s is up to date", entityName, model)
+	} else if err := rewriteFile(fp, src); err != nil {
+		return err
+	}
+	if len(unmapped) > 0 && !*jsonOutput {
+		fmt.Printf("The fields left unmapped by %sFromEntity are: %s\n", model, strings.Join(unmapped, ", "))
+	}
+	return nil
+}
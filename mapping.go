@@ -0,0 +1,55 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// presenterMappingBody returns the body of a Presenter method which maps the fields of the
+// ResponseModel rsm to a new ViewModel of type vmType and calls the View method renderMethod with it.
+// Fields with the same name and type are assigned directly and a TODO is added for the remaining fields.
+func presenterMappingBody(self string, rsmFields, vmFields []structField, vmType, renderMethod string) string {
+	vmFieldTypes := make(map[string]string)
+	for _, f := range vmFields {
+		vmFieldTypes[f.Name] = f.Type
+	}
+	body := fmt.Sprintf("\tvm := &viewmodel.%s{}\n", vmType)
+	if len(rsmFields) == 0 {
+		body += "\t// TODO: Map the fields of rsm to vm\n"
+	}
+	for _, f := range rsmFields {
+		if t, ok := vmFieldTypes[f.Name]; ok && t == f.Type {
+			body += fmt.Sprintf("\tvm.%s = rsm.%s\n", f.Name, f.Name)
+			continue
+		}
+		body += fmt.Sprintf("\t// TODO: Map rsm.%s (%s), the ViewModel has no %s field of the same type\n", f.Name, f.Type, f.Name)
+	}
+	rsmFieldNames := make(map[string]bool)
+	for _, f := range rsmFields {
+		rsmFieldNames[f.Name] = true
+	}
+	for _, f := range vmFields {
+		if !rsmFieldNames[f.Name] {
+			body += fmt.Sprintf("\t// TODO: Set vm.%s (%s)\n", f.Name, f.Type)
+		}
+	}
+	body += fmt.Sprintf("\t%s.vw.%s(vm)\n", self, renderMethod)
+	return body
+}
+
+// presenterMappingBodyForUsecase returns the mapping body of the Presenter method presenting
+// the ResponseModel modelName of the object objectName. See presenterMappingBody.
+func presenterMappingBodyForUsecase(basePath, self, modelName, objectName string) (string, error) {
+	rsmFields, err := structFields(filepath.FromSlash(basePath+relPathRespModel+fileName(objectName)+".go"), modelName)
+	if err != nil {
+		return "", err
+	}
+	vmFields, err := structFields(filepath.FromSlash(basePath+relPathViewModel+fileName(objectName)+".go"), modelName)
+	if err != nil {
+		return "", err
+	}
+	return presenterMappingBody(self, rsmFields, vmFields, modelName, "Render"+modelName), nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// methodPrefixes holds the prefix of the usecase methods of the object types whose method names follow a pattern
+var methodPrefixes = map[string]string{
+	objPresenter: "Present",
+	objView:      "Render",
+	objValidator: "Validate",
+}
+
+// followsUsecasePattern reports whether method is named like the usecase methods of objType e.g. RenderX
+// for a View. Such methods are expected to be added to a single object on purpose.
+func followsUsecasePattern(objType, method string) bool {
+	prefix, ok := methodPrefixes[objType]
+	return ok && strings.HasPrefix(method, prefix) && len(method) > len(prefix)
+}
+
+// methodParams returns the parameters and results of method, added on its own to an object of type objType.
+// Methods following the usecase pattern of objType get the usecase methods' parameters if the model they
+// refer to exists in the project at basePath, e.g. RenderX(vm *viewmodel.X) if the ViewModel X exists.
+func methodParams(basePath, objType, method, objectName string) (string, string) {
+	if !followsUsecasePattern(objType, method) {
+		return "()", ""
+	}
+	model := strings.TrimPrefix(method, methodPrefixes[objType])
+	relPath, param := relPathRespModel, "rsm *respmodel."
+	switch objType {
+	case objView:
+		relPath, param = relPathViewModel, "vm *viewmodel."
+	case objValidator:
+		relPath, param = relPathReqModel, "rqm *reqmodel."
+	}
+	f, err := parseGoFile(filepath.FromSlash(basePath + relPath + fileName(objectName) + ".go"))
+	if err != nil || findTypeSpec(f, model) == nil {
+		return "()", ""
+	}
+	if objType != objValidator {
+		return "(" + param + model + ")", ""
+	}
+	f, err = parseGoFile(filepath.FromSlash(basePath + relPathRespModel + fileName(objectName) + ".go"))
+	if err != nil || findTypeSpec(f, model+"ErrVal") == nil {
+		return "()", ""
+	}
+	return "(" + param + model + ")", " *respmodel." + model + "ErrVal"
+}
+
+// addMethod adds method to the interface and implementation of the object of type objType of the
+// interactor objectName without touching any of the interactor's other objects.
+func addMethod(basePath, objType, method, objectName string) error {
+	fp := filepath.FromSlash(basePath + objRelPaths[objType] + fileName(objectName) + ".go")
+	fileBytes, err := readFile(fp)
+	if err != nil {
+		return err
+	}
+	ucObjName, lcObjName := fileObjNames(fileBytes, objType, objectName)
+	f, err := parseGoFile(fp)
+	if err != nil {
+		return err
+	}
+	methods, ok := interfaceMethods(f, ucObjName)
+	if !ok {
+		return fmt.Errorf("the interface %s isn't declared in %s", ucObjName, fp)
+	}
+	for _, m := range methods {
+		if m == method {
+			noopf("the method %s already exists in %s", method, ucObjName)
+			return nil
+		}
+	}
+	params, results := methodParams(basePath, objType, method, objectName)
+	body := "\t// TODO: Implement interface method\n"
+	if results != "" {
+		body += "\treturn nil\n"
+	}
+	methodSignature := fmt.Sprintf("\t// %s TODO: Add description\n\t%s%s%s\n", method, method, params, results)
+	newFileBytes, err := addMethodSignatureToInterface(fileBytes, fp, methodSignature, ucObjName)
+	if err != nil {
+		return err
+	}
+	impl := fmt.Sprintf("\n\n// %s implements the %s interface method %s.\nfunc (%s *%s) %s%s%s {\n%s}", method, ucObjName, method, fileReceiverName(fileBytes, objType, ucObjName), lcObjName, method, params, results, body)
+	if newFileBytes, err = addMethodToImpl(newFileBytes, impl, lcObjName); err != nil {
+		return err
+	}
+	return writeFile(fp, newFileBytes)
+}
+
+// removeMethod removes method, including its doc comments, from the interface and implementation of
+// the object of type objType of the interactor objectName.
+func removeMethod(basePath, objType, method, objectName string) error {
+	fp := filepath.FromSlash(basePath + objRelPaths[objType] + fileName(objectName) + ".go")
+	b, err := readFile(fp)
+	if err != nil {
+		return err
+	}
+	fset := token.NewFileSet()
+	f, err := parseFile(fset, fp, b, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	ucObjName, lcObjName := fileObjNames(b, objType, objectName)
+	// ranges holds the start and end offsets of the parts of b to remove
+	var ranges [][2]int
+	if ts := findTypeSpec(f, ucObjName); ts != nil {
+		if it, ok := ts.Type.(*ast.InterfaceType); ok {
+			for _, m := range it.Methods.List {
+				if len(m.Names) == 0 || m.Names[0].Name != method {
+					continue
+				}
+				start := m.Pos()
+				if m.Doc != nil {
+					start = m.Doc.Pos()
+				}
+				ranges = append(ranges, wholeLines(b, fset.Position(start).Offset, fset.Position(m.End()).Offset))
+			}
+		}
+	}
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Name.Name != method || fd.Recv == nil || len(fd.Recv.List) != 1 {
+			continue
+		}
+		recv := fd.Recv.List[0].Type
+		if star, ok := recv.(*ast.StarExpr); ok {
+			recv = star.X
+		}
+		if id, ok := recv.(*ast.Ident); !ok || id.Name != lcObjName {
+			continue
+		}
+		start := fd.Pos()
+		if fd.Doc != nil {
+			start = fd.Doc.Pos()
+		}
+		r := wholeLines(b, fset.Position(start).Offset, fset.Position(fd.End()).Offset)
+		// Remove the blank lines separating the method from the preceding declaration
+		for r[0] > 0 && b[r[0]-1] == '\n' && (r[0] < 2 || b[r[0]-2] == '\n') {
+			r[0]--
+		}
+		ranges = append(ranges, r)
+	}
+	if len(ranges) == 0 {
+		return fmt.Errorf("the method %s doesn't exist in %s", method, fp)
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+	var newb []byte
+	last := 0
+	for _, r := range ranges {
+		newb = append(newb, b[last:r[0]]...)
+		last = r[1]
+	}
+	newb = append(newb, b[last:]...)
+	return writeFile(fp, newb)
+}
+
+// wholeLines returns the range from start to end of b extended to the beginning of the line of start
+// and to the beginning of the line following end.
+func wholeLines(b []byte, start, end int) [2]int {
+	for start > 0 && b[start-1] != '\n' {
+		start--
+	}
+	for end < len(b) && b[end] != '\n' {
+		end++
+	}
+	if end < len(b) {
+		end++
+	}
+	return [2]int{start, end}
+}
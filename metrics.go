@@ -0,0 +1,91 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+const (
+	objMetrics     = "metrics"
+	relPathMetrics = "lib/metrics/"
+	// metricsField is the name of the field and constructor parameter of an Interactor holding its metrics
+	metricsField = "mt"
+	// metricsType is the type of the metrics of an Interactor
+	metricsType = "*" + objMetrics + ".Usecases"
+	// prometheusImport is the import path of the Prometheus client
+	prometheusImport = "github.com/prometheus/client_golang/prometheus"
+)
+
+// metricsSource is the content, following the package clause, of the file of the metrics of the usecases
+const metricsSource = `
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Usecases holds the Prometheus metrics of the usecases of an Interactor. The vectors are labelled by the
+// usecase, so all of the usecases of the Interactor share them.
+type Usecases struct {
+	// Requests counts the calls of each usecase.
+	Requests *prometheus.CounterVec
+	// Latency observes the duration of each usecase in seconds.
+	Latency *prometheus.HistogramVec
+}
+
+// New constructs the metrics of the usecases of the Interactor named interactor e.g. Order and registers them
+// with reg, e.g. prometheus.DefaultRegisterer. The metrics of the Interactors only differ by their interactor
+// label, so each Interactor is constructed with its own metrics.
+func New(reg prometheus.Registerer, interactor string) *Usecases {
+	labels := prometheus.Labels{"interactor": interactor}
+	m := &Usecases{
+		Requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "usecase_requests_total",
+			Help:        "Number of calls of the usecases.",
+			ConstLabels: labels,
+		}, []string{"usecase"}),
+		Latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:        "usecase_duration_seconds",
+			Help:        "Duration of the usecases in seconds.",
+			ConstLabels: labels,
+			Buckets:     prometheus.DefBuckets,
+		}, []string{"usecase"}),
+	}
+	reg.MustRegister(m.Requests, m.Latency)
+	return m
+}
+`
+
+// metricsStub returns the statements the Interactor method of the usecase starts with if --metrics is set,
+// which count the call and observe the duration of the method. The Interactor implementation is referred to
+// by self.
+func metricsStub(self, usecase string) string {
+	return fmt.Sprintf("\t// Count the request and observe the latency of the usecase\n\t// TODO: Add labels e.g. the outcome of the usecase\n\t%s.%s.Requests.WithLabelValues(%q).Inc()\n\ttimer := prometheus.NewTimer(%s.%s.Latency.WithLabelValues(%q))\n\tdefer timer.ObserveDuration()\n\n",
+		self, metricsField, usecase, self, metricsField, usecase)
+}
+
+// addMetrics adds the metrics of the usecases to the lib/metrics folder of the project at basePath unless they
+// exist
+func addMetrics(basePath string) error {
+	fp := filepath.FromSlash(basePath + relPathMetrics + objMetrics + ".go")
+	if fileExists(fp) {
+		return nil
+	}
+	if err := mkdirAll(filepath.Dir(fp)); err != nil {
+		return err
+	}
+	return writeFile(fp, []byte(packageClause(basePath+relPathMetrics, objMetrics)+metricsSource))
+}
+
+// injectMetrics adds a field and a constructor parameter of the metrics to the Interactor implementation of the
+// interactor in b, the content of fp, unless it already has them, and imports the Prometheus client the
+// methods observe them with
+func injectMetrics(b []byte, fp, interactor string) ([]byte, error) {
+	b, err := injectDependency(b, fp, interactor, metricsField, metricsType, relPathMetrics)
+	if err != nil {
+		return nil, err
+	}
+	return ensureImport(b, prometheusImport)
+}
@@ -0,0 +1,198 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// The engines generating the mocks of the interfaces of the interactors
+const (
+	// mockEngineBuiltin writes the mocks with the templates of clean mocks
+	mockEngineBuiltin = "builtin"
+	// mockEngineGomock adds a //go:generate mockgen directive to each interface file and runs mockgen
+	mockEngineGomock = "gomock"
+)
+
+// mockgenInstall is how mockgen is installed, shown when it isn't
+const mockgenInstall = "go install go.uber.org/mock/mockgen@latest"
+
+// mockEngines holds the engines the command switched interactors to, which are recorded in the manifest once
+// the command is done
+var mockEngines = make(map[string]string)
+
+// validMockEngine returns an error if engine isn't one of the mock engines. An empty engine is the default.
+func validMockEngine(engine string) error {
+	switch engine {
+	case "", mockEngineBuiltin, mockEngineGomock:
+		return nil
+	}
+	return fmt.Errorf("invalid mock engine %s, the engines are %s and %s", engine, mockEngineBuiltin, mockEngineGomock)
+}
+
+// interactorMockEngine returns the engine of the mocks of the interactor of the project at basePath: the one the
+// command switched it to, the one recorded in the manifest or, for an interactor the manifest doesn't record
+// yet, the engine of the project
+func interactorMockEngine(basePath, interactor string) string {
+	it := exportedName(interactor)
+	if engine := mockEngines[it]; engine != "" {
+		return engine
+	}
+	m, _ := readManifest(strings.TrimSuffix(basePath, "clean/"))
+	for _, mi := range m.Interactors {
+		if mi.Name == it {
+			if mi.MockEngine == "" {
+				return mockEngineBuiltin
+			}
+			return mi.MockEngine
+		}
+	}
+	if *mockEngine == "" {
+		return mockEngineBuiltin
+	}
+	return *mockEngine
+}
+
+// mockgenDirective returns the //go:generate directive of the interface file fp, which makes mockgen write the
+// mocks of the interfaces declared in it to the test folder next to it
+func mockgenDirective(fp string) string {
+	name := filepath.Base(fp)
+	return fmt.Sprintf("//go:generate mockgen -source=%s -destination=test/mock_%s -package=test", name, name)
+}
+
+// addMockgenDirectives adds the //go:generate mockgen directive of each of the controller, presenter, view,
+// interactor and validator files of the interactor below their package clauses, unless they already have it.
+// Running "go generate ./..." then writes the mocks to the test folder of each object.
+func addMockgenDirectives(basePath, interactor string) error {
+	for _, objType := range objTypes {
+		fp := objectFilePath(basePath, objType, interactor)
+		if !fileExists(fp) {
+			continue
+		}
+		b, err := readFile(fp)
+		if err != nil {
+			return err
+		}
+		directive := mockgenDirective(fp)
+		if bytes.Contains(b, []byte(directive)) {
+			continue
+		}
+		fset := token.NewFileSet()
+		f, err := parseFile(fset, fp, b, parser.PackageClauseOnly)
+		if err != nil {
+			return err
+		}
+		ix := fset.Position(f.Name.End()).Offset
+		b = append(append(append([]byte{}, b[:ix]...), "\n\n"+directive...), b[ix:]...)
+		if err := writeFile(fp, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runMockgen adds the missing //go:generate mockgen directives to the interface files of the interactors and
+// runs mockgen on each of them like go generate would. Each mock file is listed as generated. It returns an
+// error if mockgen isn't installed.
+func runMockgen(basePath string, interactors []string) error {
+	mockgen, err := exec.LookPath("mockgen")
+	if err != nil {
+		return fmt.Errorf("the %s mock engine runs mockgen, which isn't installed. Install it with \"%s\" or set %s=%s in the configuration file", mockEngineGomock, mockgenInstall, confKeyMockEngine, mockEngineBuiltin)
+	}
+	for _, interactor := range interactors {
+		if err := addMockgenDirectives(basePath, interactor); err != nil {
+			return err
+		}
+		for _, objType := range objTypes {
+			fp := objectFilePath(basePath, objType, interactor)
+			if !fileExists(fp) {
+				continue
+			}
+			// The arguments are those of the directive
+			args := strings.Fields(mockgenDirective(fp))[2:]
+			cmd := exec.Command(mockgen, args...)
+			cmd.Dir = filepath.Dir(fp)
+			var stderr bytes.Buffer
+			cmd.Stderr = &stderr
+			mockFp := filepath.Join(filepath.Dir(fp), "test", "mock_"+filepath.Base(fp))
+			old, _ := readFile(mockFp)
+			if err := cmd.Run(); err != nil {
+				return fmt.Errorf("mockgen failed on %s: %s", fp, strings.TrimSpace(stderr.String()))
+			}
+			forgetFile(mockFp)
+			if b, err := readFile(mockFp); err == nil && !bytes.Equal(old, b) {
+				changed(mockFp)
+			}
+			fmt.Printf("generated %s %s\n", objType, typeName(objType, interactor))
+		}
+	}
+	return nil
+}
+
+// generateMocks writes the mocks of the interfaces of the interactors of the project at basePath with the engine
+// of each interactor. The engine set with --mock-engine switches the interactors to it.
+func generateMocks(basePath string, interactors []string) error {
+	flagged := false
+	flag.Visit(func(f *flag.Flag) {
+		flagged = flagged || f.Name == "mock-engine"
+	})
+	var builtin, gomock []string
+	for _, interactor := range interactors {
+		if flagged {
+			mockEngines[exportedName(interactor)] = *mockEngine
+		}
+		if interactorMockEngine(basePath, interactor) == mockEngineGomock {
+			gomock = append(gomock, interactor)
+		} else {
+			builtin = append(builtin, interactor)
+		}
+	}
+	if err := regenerateMocks(basePath, builtin); err != nil {
+		return err
+	}
+	if len(gomock) == 0 {
+		return nil
+	}
+	return runMockgen(basePath, gomock)
+}
+
+// mocksStatus returns a one line status of the mocks of the interactor of the project at basePath: its mock
+// engine and the objects whose mocks or mockgen directives are missing. It returns an empty string if the
+// interactor has no mocks with the builtin engine.
+func mocksStatus(basePath, interactor string) string {
+	engine := interactorMockEngine(basePath, interactor)
+	var missing []string
+	found := false
+	for _, objType := range objTypes {
+		fp := objectFilePath(basePath, objType, interactor)
+		if !fileExists(fp) {
+			continue
+		}
+		if engine == mockEngineGomock {
+			if b, err := readFile(fp); err == nil && !bytes.Contains(b, []byte(mockgenDirective(fp))) {
+				missing = append(missing, objType+" directive")
+				continue
+			}
+		}
+		if fileExists(filepath.Join(filepath.Dir(fp), "test", "mock_"+filepath.Base(fp))) {
+			found = true
+		} else {
+			missing = append(missing, objType)
+		}
+	}
+	switch {
+	case engine == mockEngineBuiltin && !found:
+		return ""
+	case len(missing) > 0:
+		return fmt.Sprintf("%s, missing %s, run clean mocks %s", engine, strings.Join(missing, ", "), exportedName(interactor))
+	}
+	return engine + ", ok"
+}
@@ -0,0 +1,254 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	gofmt "go/format"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// genFileName is the name of the file holding the go:generate directives of a package
+const genFileName = "gen.go"
+
+// addMockDirectives adds a //go:generate mockgen directive for each of the controller, presenter, view,
+// interactor and validator interfaces of the interactor to the gen.go file of the interactor folder.
+// Running "go generate ./..." then writes a mock of each interface to the test folder of its object.
+func addMockDirectives(basePath, interactor string) error {
+	dir := filepath.FromSlash(basePath + relPathInteractor)
+	fp := filepath.Join(dir, genFileName)
+	var b []byte
+	if fileExists(fp) {
+		var err error
+		if b, err = readFile(fp); err != nil {
+			return err
+		}
+	} else {
+		b = []byte(packageClause(basePath+relPathInteractor, objInteractor) + "\n")
+	}
+	added := false
+	for _, objType := range objTypes {
+		objDir := filepath.FromSlash(basePath + objRelPaths[objType])
+		destination, err := filepath.Rel(dir, filepath.Join(objDir, "test", "mock_"+fileName(interactor)+".go"))
+		if err != nil {
+			return err
+		}
+		importPath := projectBaseImportPath + "clean/" + strings.TrimSuffix(objRelPaths[objType], "/")
+		directive := fmt.Sprintf("//go:generate mockgen -destination=%s -package=test %s %s\n", filepath.ToSlash(destination), importPath, typeName(objType, interactor))
+		if bytes.Contains(b, []byte(directive)) {
+			continue
+		}
+		if !added {
+			b = append(b, '\n')
+			added = true
+		}
+		b = append(b, directive...)
+	}
+	return writeFile(fp, b)
+}
+
+// mockHeader marks the mock files as generated by "clean mocks"
+const mockHeader = "// Code generated by clean mocks. DO NOT EDIT.\n\n"
+
+// mockMethod is a method of a mocked interface
+type mockMethod struct {
+	name   string
+	params []string
+	// names are the names of the params as declared, which are empty if they're unnamed
+	names   []string
+	types   []string
+	results []string
+}
+
+// interfaceMethodSet returns the methods of the interface name declared in f, including the methods of the
+// interfaces of f it embeds. The packages referred to by the methods are added to pkgs.
+func interfaceMethodSet(f *ast.File, name string, pkgs map[string]bool) ([]mockMethod, error) {
+	ts := findTypeSpec(f, name)
+	if ts == nil {
+		return nil, fmt.Errorf("the interface %s isn't declared", name)
+	}
+	it, ok := ts.Type.(*ast.InterfaceType)
+	if !ok {
+		return nil, fmt.Errorf("%s isn't an interface", name)
+	}
+	var methods []mockMethod
+	for _, m := range it.Methods.List {
+		ft, ok := m.Type.(*ast.FuncType)
+		if !ok {
+			id, ok := m.Type.(*ast.Ident)
+			if !ok {
+				return nil, fmt.Errorf("the interface %s embeds %s, which isn't declared in the same file", name, types.ExprString(m.Type))
+			}
+			embedded, err := interfaceMethodSet(f, id.Name, pkgs)
+			if err != nil {
+				return nil, err
+			}
+			methods = append(methods, embedded...)
+			continue
+		}
+		usedPackages(ft, pkgs)
+		var mm mockMethod
+		for _, field := range ft.Params.List {
+			n := len(field.Names)
+			if n == 0 {
+				n = 1
+			}
+			for i := 0; i < n; i++ {
+				name := ""
+				if i < len(field.Names) {
+					name = field.Names[i].Name
+				}
+				mm.params = append(mm.params, fmt.Sprintf("p%d", len(mm.params)))
+				mm.names = append(mm.names, name)
+				mm.types = append(mm.types, types.ExprString(field.Type))
+			}
+		}
+		if ft.Results != nil {
+			for _, field := range ft.Results.List {
+				n := len(field.Names)
+				if n == 0 {
+					n = 1
+				}
+				for i := 0; i < n; i++ {
+					mm.results = append(mm.results, types.ExprString(field.Type))
+				}
+			}
+		}
+		for _, n := range m.Names {
+			mm.name = n.Name
+			methods = append(methods, mm)
+		}
+	}
+	return methods, nil
+}
+
+// mockSource returns the source of the mock of the interface ifName of the object of type objType declared
+// in the Go file f, whose import path is importPath
+func mockSource(f *ast.File, objType, ifName, importPath string) ([]byte, error) {
+	pkgs := make(map[string]bool)
+	methods, err := interfaceMethodSet(f, ifName, pkgs)
+	if err != nil {
+		return nil, err
+	}
+	imports := append(importsOf(f, pkgs), strconv.Quote(importPath))
+	sort.Strings(imports)
+	mockName := "Mock" + ifName
+	var b bytes.Buffer
+	b.WriteString(mockHeader)
+	fmt.Fprintf(&b, "package test\n\nimport (\n\t%s\n)\n", strings.Join(imports, "\n\t"))
+	fmt.Fprintf(&b, "\n// %s is a mock of the %s %s. Set the function fields to stub its methods. Calls\n// holds the names of the methods in the order they were called.\ntype %s struct {\n", mockName, ifName, firstCharToUpper(objType), mockName)
+	for _, m := range methods {
+		fmt.Fprintf(&b, "\t%sFunc func(%s)%s\n", m.name, strings.Join(m.types, ", "), resultList(m.results))
+	}
+	b.WriteString("\tCalls []string\n}\n")
+	fmt.Fprintf(&b, "\nvar _ %s.%s = (*%s)(nil)\n", objType, ifName, mockName)
+	for _, m := range methods {
+		var params, args []string
+		for i, p := range m.params {
+			params = append(params, p+" "+m.types[i])
+			arg := p
+			if strings.HasPrefix(m.types[i], "...") {
+				arg += "..."
+			}
+			args = append(args, arg)
+		}
+		var named []string
+		for i, r := range m.results {
+			named = append(named, fmt.Sprintf("r%d %s", i, r))
+		}
+		results := ""
+		if len(named) > 0 {
+			results = " (" + strings.Join(named, ", ") + ")"
+		}
+		fmt.Fprintf(&b, "\n// %s records the call and calls %sFunc if it's set.\nfunc (m *%s) %s(%s)%s {\n\tm.Calls = append(m.Calls, %q)\n\tif m.%sFunc != nil {\n", m.name, m.name, mockName, m.name, strings.Join(params, ", "), results, m.name, m.name)
+		if len(m.results) > 0 {
+			fmt.Fprintf(&b, "\t\treturn m.%sFunc(%s)\n\t}\n\treturn\n}\n", m.name, strings.Join(args, ", "))
+		} else {
+			fmt.Fprintf(&b, "\t\tm.%sFunc(%s)\n\t}\n}\n", m.name, strings.Join(args, ", "))
+		}
+	}
+	return gofmt.Source(b.Bytes())
+}
+
+// importsOf returns the import specs of f, e.g. "fmt" or name "path", of the packages pkgs
+func importsOf(f *ast.File, pkgs map[string]bool) []string {
+	var imports []string
+	for _, imp := range f.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		pkg := path[strings.LastIndex(path, "/")+1:]
+		if imp.Name != nil {
+			pkg = imp.Name.Name
+		}
+		if pkgs[pkg] {
+			if imp.Name != nil {
+				imports = append(imports, imp.Name.Name+" "+strconv.Quote(path))
+			} else {
+				imports = append(imports, strconv.Quote(path))
+			}
+		}
+	}
+	return imports
+}
+
+// resultList returns the results of a function type e.g. " error" or " (int, error)"
+func resultList(results []string) string {
+	switch len(results) {
+	case 0:
+		return ""
+	case 1:
+		return " " + results[0]
+	}
+	return " (" + strings.Join(results, ", ") + ")"
+}
+
+// regenerateMocks rewrites the mocks of the controller, presenter, view, interactor and validator
+// interfaces of the interactors from their current definitions and prints whether each mock was
+// added, updated or already current. The mocks are written to the test folder of each object.
+func regenerateMocks(basePath string, interactors []string) error {
+	for _, interactor := range interactors {
+		for _, objType := range objTypes {
+			fp := filepath.FromSlash(basePath + objRelPaths[objType] + fileName(interactor) + ".go")
+			if !fileExists(fp) {
+				continue
+			}
+			f, err := parseGoFile(fp)
+			if err != nil {
+				return err
+			}
+			ifName := typeName(objType, interactor)
+			importPath := projectBaseImportPath + "clean/" + strings.TrimSuffix(objRelPaths[objType], "/")
+			src, err := mockSource(f, objType, ifName, importPath)
+			if err != nil {
+				return fmt.Errorf("%s: %s", fp, err.Error())
+			}
+			mockFp := filepath.FromSlash(basePath + objRelPaths[objType] + "test/mock_" + fileName(interactor) + ".go")
+			status := "added"
+			if fileExists(mockFp) {
+				old, err := readFile(mockFp)
+				if err != nil {
+					return err
+				}
+				if bytes.Equal(old, src) {
+					fmt.Printf("current %s %s\n", objType, ifName)
+					continue
+				}
+				status = "updated"
+			}
+			if err := mkdirAll(filepath.Dir(mockFp)); err != nil {
+				return err
+			}
+			if err := rewriteFile(mockFp, src); err != nil {
+				return err
+			}
+			fmt.Printf("%s %s %s\n", status, objType, ifName)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,478 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// initialisms holds the words that are always written in a consistent case,
+// e.g. URL and never Url. Additional initialisms can be configured by adding
+// a comma separated list to the naming.initialisms key of the configuration file.
+var initialisms = map[string]bool{
+	"ACL":   true,
+	"API":   true,
+	"ASCII": true,
+	"CPU":   true,
+	"CSS":   true,
+	"DNS":   true,
+	"EOF":   true,
+	"GUID":  true,
+	"HTML":  true,
+	"HTTP":  true,
+	"HTTPS": true,
+	"ID":    true,
+	"IP":    true,
+	"JSON":  true,
+	"QPS":   true,
+	"RAM":   true,
+	"RPC":   true,
+	"SLA":   true,
+	"SMTP":  true,
+	"SQL":   true,
+	"SSH":   true,
+	"TCP":   true,
+	"TLS":   true,
+	"TTL":   true,
+	"UDP":   true,
+	"UI":    true,
+	"UID":   true,
+	"UUID":  true,
+	"URI":   true,
+	"URL":   true,
+	"UTF8":  true,
+	"VM":    true,
+	"XML":   true,
+	"XMPP":  true,
+	"XSRF":  true,
+	"XSS":   true,
+}
+
+// typeSuffixes holds the suffix appended to the type names generated for each object type,
+// e.g. OrderController instead of Order if the controller suffix is Controller. The suffixes are
+// configured with the naming.suffix.[object] keys of the configuration file and are empty by default.
+var typeSuffixes = map[string]string{}
+
+// The receiver styles configured with the naming.receiver key of the configuration file
+const (
+	// receiverFirstLetter is the first letter of the type e.g. o for OrderController
+	receiverFirstLetter = "first-letter"
+	// receiverInitials is the initials of the words of the type e.g. oc for OrderController
+	receiverInitials = "initials"
+	// receiverType is the whole type with its first word lower cased e.g. orderController
+	receiverType = "type"
+	// receiverInitial is an alias of receiverFirstLetter
+	receiverInitial = "initial"
+	// receiverShort is a fixed short word per object type e.g. ctrl for the controllers
+	receiverShort = "short"
+	// receiverCustom is followed by the receivers of the object types e.g. custom:controller=c,presenter=p
+	receiverCustom = "custom:"
+)
+
+// receiverStyle is the style of the receivers of the generated methods
+var receiverStyle = receiverFirstLetter
+
+// objReceivers holds the receivers of the methods of the objects by object type in the short and custom
+// receiver styles. The objects of the other types get first-letter receivers.
+var objReceivers map[string]string
+
+// shortReceivers are the receivers of the short receiver style
+var shortReceivers = map[string]string{
+	objController: "ctrl",
+	objPresenter:  "pres",
+	objInteractor: "i",
+	objView:       "v",
+	objValidator:  "val",
+}
+
+// addInitialisms adds words to the set of recognised initialisms
+func addInitialisms(words []string) {
+	for _, w := range words {
+		w = strings.TrimSpace(w)
+		if w == "" {
+			continue
+		}
+		initialisms[strings.ToUpper(w)] = true
+	}
+}
+
+// splitWords splits name into its words. A new word starts at an upper case
+// character following a lower case character or a digit, at the last upper case
+// character in a run of upper case characters followed by a lower case character
+// e.g. HTTPGateway is split into HTTP and Gateway, and at any '_', '-', '.' or space.
+func splitWords(name string) []string {
+	var words []string
+	runes := []rune(name)
+	start := 0
+	for i, r := range runes {
+		if r == '_' || r == '-' || r == '.' || unicode.IsSpace(r) {
+			if i > start {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i + 1
+			continue
+		}
+		if i == start || !unicode.IsUpper(r) {
+			continue
+		}
+		prev := runes[i-1]
+		nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+// exportedName returns name as an exported Go identifier with initialisms
+// in upper case e.g. getUrl becomes GetURL and httpGateway becomes HTTPGateway.
+func exportedName(name string) string {
+	var output string
+	for _, w := range splitWords(name) {
+		if initialisms[strings.ToUpper(w)] {
+			output += strings.ToUpper(w)
+			continue
+		}
+		output += firstCharToUpper(w)
+	}
+	return output
+}
+
+// checkName returns an error if name can't be turned into the exported and unexported Go identifiers
+// of the generated types, which is the case if its first letter has no upper case e.g. 日本
+func checkName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errEmptyName
+	}
+	ucName, lcName := exportedName(name), unexportedName(name)
+	if !token.IsIdentifier(ucName) || !token.IsExported(ucName) || ucName == lcName {
+		return fmt.Errorf("%q isn't a valid name, it must start with a letter that has an upper and a lower case e.g. Order", name)
+	}
+	return nil
+}
+
+// checkNames returns the error of the first of names which isn't valid, see checkName
+func checkNames(names ...string) error {
+	for _, name := range names {
+		if err := checkName(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// errEmptyName is the error of an empty name, which would otherwise generate e.g. a file named .go or a type
+// without a name
+var errEmptyName = errors.New("the name is empty, enter a name e.g. Order")
+
+// emptyNameFile reports whether the Go file fp is named after an empty name e.g. .go, _test.go or
+// _controller.go, which no generated file is
+func emptyNameFile(fp string) bool {
+	base := filepath.Base(fp)
+	return filepath.Ext(base) == ".go" && (strings.HasPrefix(base, ".") || strings.HasPrefix(base, "_"))
+}
+
+// usecaseVerbs holds the verbs the names of usecases commonly start with. They're used to guess the words
+// of a name entered in lower case e.g. additem is guessed to be AddItem.
+var usecaseVerbs = []string{"activate", "add", "approve", "archive", "assign", "cancel", "change", "check", "close", "confirm", "create", "delete", "disable", "edit", "enable", "export", "fetch", "find", "get", "import", "invite", "list", "load", "login", "logout", "open", "pay", "place", "publish", "register", "reject", "remove", "rename", "reset", "save", "search", "send", "set", "start", "stop", "submit", "sync", "update", "upload", "verify"}
+
+// maxWordLength is the length above which a name in lower case looks like several words
+const maxWordLength = 12
+
+// guessWords returns the words of the lower case name if it starts with one of the usecaseVerbs followed by
+// at least two letters e.g. add and item of additem. Otherwise it returns nil.
+func guessWords(name string) []string {
+	var verb string
+	for _, v := range usecaseVerbs {
+		if strings.HasPrefix(name, v) && len(name) >= len(v)+2 && len(v) > len(verb) {
+			verb = v
+		}
+	}
+	if verb == "" {
+		return nil
+	}
+	return []string{verb, name[len(verb):]}
+}
+
+// nameIdentifiers returns the exported identifiers the name of an object of objType becomes e.g. the
+// methods and models of a usecase
+func nameIdentifiers(objType, name string) []string {
+	v := exportedName(name)
+	switch objType {
+	case objInteractor:
+		return []string{v, "New" + typeName(objInteractor, name)}
+	case objUsecase:
+		return []string{v, v + "ErrVal", "Present" + v, "Render" + v, "Validate" + v}
+	}
+	return []string{v}
+}
+
+// checkNameCase returns the name to use for the name of an object of objType. A name entered in lower case
+// that looks like several words, e.g. additem, loses its word boundaries, so a warning naming the identifiers
+// it becomes is printed, together with the guessed CamelCase form if there is one. If --guess-words is set
+// the guessed form is returned instead of name and if --strict-names is set an error is returned.
+func checkNameCase(objType, name string) (string, error) {
+	if len(splitWords(name)) != 1 || name != strings.ToLower(name) {
+		return name, nil
+	}
+	words := guessWords(name)
+	if words == nil && len([]rune(name)) <= maxWordLength {
+		return name, nil
+	}
+	guess := exportedName(strings.Join(words, "_"))
+	switch {
+	case guess != "" && *guessNameWords:
+		fmt.Fprintf(os.Stderr, "Using %s for %q\n", guess, name)
+		return guess, nil
+	case guess != "" && *strictNames:
+		return "", fmt.Errorf("%q looks like several words in lower case, enter it in CamelCase e.g. %s or pass --guess-words", name, guess)
+	case *strictNames:
+		return "", fmt.Errorf("%q looks like several words in lower case, enter it in CamelCase e.g. %s", name, exportedName(name))
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %q is in lower case and becomes %s.", name, strings.Join(nameIdentifiers(objType, name), ", "))
+	if guess != "" {
+		fmt.Fprintf(os.Stderr, " Did you mean %s? Pass --guess-words to use it.", guess)
+	}
+	fmt.Fprintf(os.Stderr, "\n")
+	return name, nil
+}
+
+// unexportedName returns name as an unexported Go identifier, e.g. the name
+// of an interface implementation. HTTPGateway becomes httpGateway and URL becomes url.
+func unexportedName(name string) string {
+	words := splitWords(name)
+	if len(words) == 0 {
+		return ""
+	}
+	first := words[0]
+	if initialisms[strings.ToUpper(first)] {
+		first = strings.ToLower(first)
+	} else {
+		first = firstCharToLower(first)
+	}
+	return first + exportedName(strings.Join(words[1:], "_"))
+}
+
+// fileName returns the all lower case file name, without extension, of the
+// files generated for name e.g. HTTPGateway becomes httpgateway and OrderV2 becomes
+// orderv2. Letters outside ASCII are kept and lower cased rune by rune, so Émetteur
+// becomes émetteur. Since some file systems normalise Unicode file names, a name
+// with letters outside ASCII should always be entered in the same normalisation form.
+func fileName(name string) string {
+	return strings.ToLower(strings.Join(splitWords(name), ""))
+}
+
+// receiverName returns the receiver used by the methods of the implementation of name in the
+// configured receiver style
+func receiverName(name string) string {
+	switch receiverStyle {
+	case receiverInitials:
+		var initials string
+		for _, w := range splitWords(name) {
+			initials += firstCharInWord(w)
+		}
+		return strings.ToLower(initials)
+	case receiverType:
+		return unexportedName(name)
+	}
+	return firstCharInWord(unexportedName(name))
+}
+
+// objReceiverName returns the receiver used by the methods of the implementation of name of the object of
+// type objType in the configured receiver style
+func objReceiverName(objType, name string) string {
+	if recv, ok := objReceivers[objType]; ok {
+		return recv
+	}
+	return receiverName(name)
+}
+
+// fileReceiverName returns the receiver of the methods of the implementation of name of the object of type
+// objType in the Go source b. It's the receiver of the methods the implementation already has, so that the
+// methods added to a file generated with another receiver style keep its receiver, or otherwise the receiver
+// of the configured style.
+func fileReceiverName(b []byte, objType, name string) string {
+	if recv := implReceiver(b, unexportedName(name)); recv != "" {
+		return recv
+	}
+	return objReceiverName(objType, name)
+}
+
+// setReceiverStyle sets the style of the receivers of the generated methods to style.
+// An empty style keeps the default first-letter style.
+func setReceiverStyle(style string) error {
+	switch {
+	case style == "":
+	case style == receiverInitial:
+		receiverStyle = receiverFirstLetter
+	case style == receiverFirstLetter || style == receiverInitials || style == receiverType:
+		receiverStyle = style
+	case style == receiverShort:
+		objReceivers = shortReceivers
+	case strings.HasPrefix(style, receiverCustom):
+		objReceivers = make(map[string]string)
+		for _, pair := range strings.Split(strings.TrimPrefix(style, receiverCustom), ",") {
+			pieces := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+			if len(pieces) != 2 || objRelPaths[pieces[0]] == "" || !token.IsIdentifier(pieces[1]) {
+				return fmt.Errorf("invalid receiver %q of the %s=%s setting in the configuration file, the receivers are of the form object=receiver e.g. controller=ctrl, where the object is one of %s", pair, confKeyReceiver, style, strings.Join(objTypes, ", "))
+			}
+			objReceivers[pieces[0]] = pieces[1]
+		}
+	default:
+		return fmt.Errorf("invalid %s=%s setting in the configuration file, the receiver styles are %s, %s, %s, %s and %s[object=receiver,...]", confKeyReceiver, style, receiverFirstLetter, receiverInitials, receiverType, receiverShort, receiverCustom)
+	}
+	return nil
+}
+
+// typeName returns the name of the interface generated for the object name of type objType
+func typeName(objType, name string) string {
+	return exportedName(name) + typeSuffixes[objType]
+}
+
+// implSuffix is the suffix of the implementations of unexported interfaces, which would otherwise be named
+// like their interfaces e.g. orderImpl of the interface order
+const implSuffix = "Impl"
+
+// objNames returns the names of the interface and of the implementation of the object of objType of name.
+// If unexported is true the interface is unexported e.g. order, whose implementation is orderImpl.
+func objNames(objType, name string, unexported bool) (string, string) {
+	ifName := typeName(objType, name)
+	if unexported {
+		ifName = unexportedName(ifName)
+		return ifName, ifName + implSuffix
+	}
+	return ifName, unexportedName(ifName)
+}
+
+// fileObjNames returns the names of the interface and of the implementation of the object of objType of name
+// declared in the Go source b. The interface is unexported if it was added with --unexported-interface. If the
+// interface isn't named by the configured type suffix, e.g. OrderController of a project which names its
+// objects that way without a naming.suffix setting, the interface is discovered in b, see discoverInterface.
+func fileObjNames(b []byte, objType, name string) (string, string) {
+	f, err := parseFile(token.NewFileSet(), "", b, 0)
+	if err != nil || findTypeSpec(f, typeName(objType, name)) != nil {
+		return objNames(objType, name, false)
+	}
+	if ts := findTypeSpec(f, unexportedName(typeName(objType, name))); ts != nil {
+		_, unexported := ts.Type.(*ast.InterfaceType)
+		return objNames(objType, name, unexported)
+	}
+	ifName, ok := discoverInterface(f, name)
+	if !ok {
+		return objNames(objType, name, false)
+	}
+	implName := unexportedName(ifName)
+	if findTypeSpec(f, implName) == nil && findTypeSpec(f, ifName+implSuffix) != nil {
+		implName = ifName + implSuffix
+	}
+	return ifName, implName
+}
+
+// discoverInterface returns the exported interface of the object name declared in f, which is the only
+// exported interface of f or otherwise the only one whose name starts with name e.g. OrderController. The
+// boolean is false if there's no such interface.
+func discoverInterface(f *ast.File, name string) (string, bool) {
+	names := interfaceNames(f)
+	if len(names) == 1 {
+		return names[0], true
+	}
+	var named []string
+	for _, n := range names {
+		if strings.HasPrefix(fileName(n), fileName(name)) {
+			named = append(named, n)
+		}
+	}
+	if len(named) == 1 {
+		return named[0], true
+	}
+	return "", false
+}
+
+// setTypeSuffixes sets the type suffixes from the naming.suffix.[object] keys of conf
+func setTypeSuffixes(conf map[string]string) {
+	for _, objType := range objTypes {
+		if suffix := conf[confKeySuffix+objType]; suffix != "" {
+			typeSuffixes[objType] = exportedName(suffix)
+		}
+	}
+}
+
+// verifyTypeSuffixes returns an error if the project at basePath contains generated objects whose
+// interfaces aren't named according to the configured type suffixes. This is the case when a
+// naming.suffix.[object] key has been changed after objects were generated, in which case the
+// existing objects must be renamed before Clean can add to them. Without a suffix the interfaces of the
+// existing files are discovered whatever they're named, see fileObjNames, so only the objects of the
+// interactors which the manifest records as added with a suffix that is no longer configured are reported.
+func verifyTypeSuffixes(basePath string) error {
+	defer startPhase(phaseScan)()
+	m, _ := readManifest(strings.TrimSuffix(basePath, "clean/"))
+	recordedSuffixes := make(map[string]map[string]string)
+	for _, it := range m.Interactors {
+		recordedSuffixes[fileName(it.Name)] = it.Suffixes
+	}
+	for _, objType := range objTypes {
+		dir := filepath.FromSlash(basePath + objRelPaths[objType])
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, fi := range files {
+			if fi.IsDir() || filepath.Ext(fi.Name()) != ".go" {
+				continue
+			}
+			fp := filepath.Join(dir, fi.Name())
+			f, err := parseGoFile(fp)
+			if err != nil {
+				continue
+			}
+			base := strings.TrimSuffix(fi.Name(), ".go")
+			generated, consistent := false, false
+			for _, name := range interfaceNames(f) {
+				if !strings.HasPrefix(fileName(name), base) {
+					continue
+				}
+				generated = true
+				if _, ok := objNameFromTypeName(objType, name, base); ok {
+					consistent = true
+				}
+			}
+			if generated && !consistent && typeSuffixes[objType] == "" {
+				if suffix := recordedSuffixes[base][objType]; suffix != "" {
+					return fmt.Errorf("%s was generated with the %s%s=%s setting, which is no longer configured. Either restore the previous setting or rename the types in the existing %s files", fp, confKeySuffix, objType, suffix, objType)
+				}
+				continue
+			}
+			if generated && !consistent {
+				return fmt.Errorf("%s wasn't generated with the configured %s%s=%s setting. Either restore the previous setting or rename the types in the existing %s files", fp, confKeySuffix, objType, typeSuffixes[objType], objType)
+			}
+		}
+	}
+	return nil
+}
+
+// objNameFromTypeName returns the object name of the interface ifName of type objType declared in
+// the file named base. The boolean is false if ifName isn't named according to the configured type suffix.
+func objNameFromTypeName(objType, ifName, base string) (string, bool) {
+	suffix := typeSuffixes[objType]
+	if !strings.HasSuffix(ifName, suffix) {
+		return "", false
+	}
+	objName := strings.TrimSuffix(ifName, suffix)
+	if objName == "" || fileName(objName) != base {
+		return "", false
+	}
+	return objName, true
+}
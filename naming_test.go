@@ -0,0 +1,387 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestNameCasing(t *testing.T) {
+	tests := []struct {
+		name                             string
+		exported, unexported, file, recv string
+	}{
+		{"Order", "Order", "order", "order", "o"},
+		{"getURL", "GetURL", "getURL", "geturl", "g"},
+		{"getUrl", "GetURL", "getURL", "geturl", "g"},
+		{"HTTPGateway", "HTTPGateway", "httpGateway", "httpgateway", "h"},
+		{"httpGateway", "HTTPGateway", "httpGateway", "httpgateway", "h"},
+		{"URL", "URL", "url", "url", "u"},
+		{"userID", "UserID", "userID", "userid", "u"},
+		{"APIKeyID", "APIKeyID", "apiKeyID", "apikeyid", "a"},
+		{"JSONToXML", "JSONToXML", "jsonToXML", "jsontoxml", "j"},
+		{"order_item", "OrderItem", "orderItem", "orderitem", "o"},
+		{"add-item", "AddItem", "addItem", "additem", "a"},
+		{"IDGen", "IDGen", "idGen", "idgen", "i"},
+		{"OrderV2", "OrderV2", "orderV2", "orderv2", "o"},
+		{"Émetteur", "Émetteur", "émetteur", "émetteur", "é"},
+		{"émetteur", "Émetteur", "émetteur", "émetteur", "é"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exportedName(tt.name); got != tt.exported {
+				t.Errorf("exportedName() = %q, want %q", got, tt.exported)
+			}
+			if got := unexportedName(tt.name); got != tt.unexported {
+				t.Errorf("unexportedName() = %q, want %q", got, tt.unexported)
+			}
+			if got := fileName(tt.name); got != tt.file {
+				t.Errorf("fileName() = %q, want %q", got, tt.file)
+			}
+			if got := receiverName(tt.name); got != tt.recv {
+				t.Errorf("receiverName() = %q, want %q", got, tt.recv)
+			}
+			// The names derived from the exported name are the same as those derived from the name
+			if got := fileName(exportedName(tt.name)); got != tt.file {
+				t.Errorf("fileName(exportedName()) = %q, want %q", got, tt.file)
+			}
+			if got := unexportedName(exportedName(tt.name)); got != tt.unexported {
+				t.Errorf("unexportedName(exportedName()) = %q, want %q", got, tt.unexported)
+			}
+		})
+	}
+}
+
+func TestConfiguredInitialisms(t *testing.T) {
+	defer delete(initialisms, "SKU")
+	if got := exportedName("skuCode"); got != "SkuCode" {
+		t.Fatalf("exportedName() = %q before SKU is configured, want SkuCode", got)
+	}
+	addInitialisms([]string{" sku ", ""})
+	if got := exportedName("skuCode"); got != "SKUCode" {
+		t.Errorf("exportedName() = %q, want SKUCode", got)
+	}
+	if got := unexportedName("SKUCode"); got != "skuCode" {
+		t.Errorf("unexportedName() = %q, want skuCode", got)
+	}
+}
+
+// TestReceiverStyles generates an interactor with two usecases in each receiver style and asserts that every
+// method of each of its objects has the receiver of the style
+func TestReceiverStyles(t *testing.T) {
+	all := func(recv string) map[string]string {
+		return map[string]string{objController: recv, objPresenter: recv, objView: recv, objInteractor: recv, objValidator: recv}
+	}
+	tests := []struct {
+		name, style string
+		receivers   map[string]string
+	}{
+		{"default", "", all("o")},
+		{"initials", receiverInitials, all("oi")},
+		{"type", receiverType, all("orderItem")},
+		{"initial", receiverInitial, all("o")},
+		{"short", receiverShort, map[string]string{objController: "ctrl", objPresenter: "pres", objView: "v", objInteractor: "i", objValidator: "val"}},
+		{"custom", receiverCustom + "controller=c,presenter=p", map[string]string{objController: "c", objPresenter: "p", objView: "o", objInteractor: "o", objValidator: "o"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestProject(t)
+			if tt.style != "" {
+				p.write("../home/.clean/cleanrc", p.read("../home/.clean/cleanrc")+confKeyReceiver+"="+tt.style+"\n")
+			}
+			p.clean("add", "interactor", "OrderItem")
+			p.clean("add", "usecase", "Add", "to", "OrderItem")
+			p.clean("add", "usecase", "Remove", "to", "OrderItem")
+			for _, objType := range objTypes {
+				relPath := "clean/" + objRelPaths[objType] + "orderitem.go"
+				f, err := parser.ParseFile(token.NewFileSet(), relPath, p.read(relPath), 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				methods := 0
+				for _, decl := range f.Decls {
+					fd, ok := decl.(*ast.FuncDecl)
+					if !ok || fd.Recv == nil {
+						continue
+					}
+					methods++
+					if got := fd.Recv.List[0].Names[0].Name; got != tt.receivers[objType] {
+						t.Errorf("the receiver of %s.%s in %s is %s, want %s", recvTypeName(fd), fd.Name.Name, relPath, got, tt.receivers[objType])
+					}
+				}
+				if methods < 2 {
+					t.Errorf("%s has %d methods, want the usecases", relPath, methods)
+				}
+			}
+		})
+	}
+}
+
+// TestMixedAcronymFiles asserts that the file, the types and the receiver of an interactor with initialisms
+// are named consistently across the generated layers
+func TestMixedAcronymFiles(t *testing.T) {
+	p := newTestProject(t)
+	p.clean("add", "interactor", "HTTPGateway")
+	p.clean("add", "usecase", "getURL", "to", "HTTPGateway")
+	for _, relPath := range []string{"clean/ifadapter/controller/httpgateway.go", "clean/ifadapter/presenter/httpgateway.go", "clean/ifadapter/view/httpgateway.go", "clean/usecase/interactor/httpgateway.go"} {
+		src := p.read(relPath)
+		for _, want := range []string{"type HTTPGateway interface", "type httpGateway struct", "func (h *httpGateway) "} {
+			if !strings.Contains(src, want) {
+				t.Errorf("%s doesn't contain %q", relPath, want)
+			}
+		}
+	}
+	if src := p.read("clean/usecase/interactor/httpgateway.go"); !strings.Contains(src, "GetURL(rqm *reqmodel.GetURL)") {
+		t.Errorf("the Interactor doesn't declare GetURL:\n%s", src)
+	}
+	if p.exists("clean/usecase/interactor/hTTPGateway.go") {
+		t.Errorf("the file named with firstCharToLower exists")
+	}
+}
+
+func TestCheckName(t *testing.T) {
+	for _, name := range []string{"Order", "OrderV2", "Émetteur", "émetteur", "Ωmega"} {
+		if err := checkName(name); err != nil {
+			t.Errorf("checkName(%q) = %v, want nil", name, err)
+		}
+	}
+	for _, name := range []string{"日本", "2Order", "", " "} {
+		if err := checkName(name); err == nil {
+			t.Errorf("checkName(%q) = nil, want an error", name)
+		}
+	}
+}
+
+// TestUnicodeAndDigitNames asserts that the files and the types of interactors named with letters outside
+// ASCII or with digits are named consistently, and that a name which can't be exported is rejected
+func TestUnicodeAndDigitNames(t *testing.T) {
+	p := newTestProject(t)
+	for _, tt := range []struct{ name, file, impl string }{
+		{"Émetteur", "émetteur", "émetteur"},
+		{"OrderV2", "orderv2", "orderV2"},
+	} {
+		p.clean("add", "interactor", tt.name)
+		for _, relDir := range []string{"clean/ifadapter/controller/", "clean/ifadapter/presenter/", "clean/usecase/interactor/"} {
+			src := p.read(relDir + tt.file + ".go")
+			for _, want := range []string{"type " + tt.name + " interface", "type " + tt.impl + " struct", "func New" + tt.name + "("} {
+				if !strings.Contains(src, want) {
+					t.Errorf("%s%s.go doesn't contain %q", relDir, tt.file, want)
+				}
+			}
+		}
+	}
+	stdout, stderr, code := p.run("add", "interactor", "日本")
+	if code == 0 || !strings.Contains(stdout+stderr, "isn't a valid name") {
+		t.Errorf("clean add interactor 日本 exited with %d: %s%s", code, stdout, stderr)
+	}
+	if p.exists("clean/usecase/interactor/日本.go") {
+		t.Errorf("the files of the rejected name exist")
+	}
+}
+
+func TestObjNames(t *testing.T) {
+	tests := []struct {
+		objType, name    string
+		unexported       bool
+		ifName, implName string
+	}{
+		{objController, "Order", false, "Order", "order"},
+		{objController, "Order", true, "order", "orderImpl"},
+		{objController, "HTTPGateway", true, "httpGateway", "httpGatewayImpl"},
+		{objInteractor, "Order", false, "Order", "order"},
+	}
+	for _, tt := range tests {
+		ifName, implName := objNames(tt.objType, tt.name, tt.unexported)
+		if ifName != tt.ifName || implName != tt.implName {
+			t.Errorf("objNames(%s, %s, %v) = %s, %s, want %s, %s", tt.objType, tt.name, tt.unexported, ifName, implName, tt.ifName, tt.implName)
+		}
+	}
+}
+
+// TestUnexportedInterface asserts that --unexported-interface names the Controller interface and its
+// implementation as unexported, keeps its constructor exported and that the implementation satisfies the
+// interface and the constructor can be called from another package
+func TestUnexportedInterface(t *testing.T) {
+	p := newTestProject(t)
+	p.clean("add", "interactor", "Order", "--unexported-interface")
+	p.clean("add", "usecase", "AddItem", "to", "Order")
+	src := p.read("clean/ifadapter/controller/order.go")
+	for _, want := range []string{"type order interface", "type orderImpl struct", "func (o *orderImpl) AddItem()", "func NewOrder(ia interactor.Order) (order, error)"} {
+		if !strings.Contains(src, want) {
+			t.Errorf("the Controller doesn't contain %q:\n%s", want, src)
+		}
+	}
+	if src := p.read("clean/usecase/interactor/order.go"); !strings.Contains(src, "type Order interface") {
+		t.Errorf("the Interactor interface isn't exported:\n%s", src)
+	}
+
+	if testing.Short() {
+		return
+	}
+	p.write("clean/ifadapter/controller/order_impl_test.go", "package controller\n\nvar _ order = (*orderImpl)(nil)\n")
+	p.write("cmd/app/main.go", `package main
+
+import (
+	"app/clean/ifadapter/controller"
+)
+
+func main() {
+	c, err := controller.NewOrder(nil)
+	if err == nil {
+		c.AddItem()
+	}
+}
+`)
+	if out, err := p.goRun("vet", "./..."); err != nil {
+		t.Errorf("the project doesn't compile: %s\n%s", err.Error(), out)
+	}
+}
+
+func TestFileObjNames(t *testing.T) {
+	tests := []struct {
+		name, src        string
+		ifName, implName string
+	}{
+		{"exact", "package controller\n\ntype Order interface{}\n\ntype order struct{}\n", "Order", "order"},
+		{"unexported", "package controller\n\ntype order interface{}\n\ntype orderImpl struct{}\n", "order", "orderImpl"},
+		{"suffixed", "package controller\n\ntype OrderController interface{}\n\ntype orderController struct{}\n", "OrderController", "orderController"},
+		{"suffixed impl", "package controller\n\ntype OrderController interface{}\n\ntype OrderControllerImpl struct{}\n", "OrderController", "OrderControllerImpl"},
+		{"grouped", "package controller\n\ntype (\n\tOrderHandler interface{}\n\torderHandler struct{}\n)\n", "OrderHandler", "orderHandler"},
+		{"named after the object", "package controller\n\ntype Option interface{}\n\ntype OrderController interface{}\n\ntype orderController struct{}\n", "OrderController", "orderController"},
+		{"ambiguous", "package controller\n\ntype OrderReader interface{}\n\ntype OrderWriter interface{}\n", "Order", "order"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ifName, implName := fileObjNames([]byte(tt.src), objController, "Order")
+			if ifName != tt.ifName || implName != tt.implName {
+				t.Errorf("fileObjNames() = %s, %s, want %s, %s", ifName, implName, tt.ifName, tt.implName)
+			}
+		})
+	}
+}
+
+// TestSuffixedInterfaceNames asserts that a usecase is added to objects whose interfaces are named with a
+// suffix, whether the suffix is configured or the interface was renamed by hand
+func TestSuffixedInterfaceNames(t *testing.T) {
+	p := newTestProject(t)
+	p.clean("add", "interactor", "Order")
+	// Rename the Controller by hand like a project using the suffix convention without configuring it
+	const fp = "clean/ifadapter/controller/order.go"
+	src := p.read(fp)
+	src = strings.Replace(src, "type Order interface", "type OrderController interface", 1)
+	src = strings.Replace(src, "type order struct", "type orderController struct", 1)
+	src = strings.Replace(src, "(Order, error)", "(OrderController, error)", 1)
+	src = strings.Replace(src, "&order{", "&orderController{", 1)
+	p.write(fp, src)
+	p.clean("add", "usecase", "AddItem", "to", "Order")
+	src = p.read(fp)
+	for _, want := range []string{"type OrderController interface {\n\t// AddItem", "func (o *orderController) AddItem()"} {
+		if !strings.Contains(src, want) {
+			t.Errorf("%s doesn't contain %q:\n%s", fp, want, src)
+		}
+	}
+
+	// A configured suffix names the interfaces of the new objects
+	s := newTestProject(t)
+	s.write("../home/.clean/cleanrc", s.read("../home/.clean/cleanrc")+"naming.suffix.presenter=Presenter\n")
+	s.clean("add", "interactor", "Order")
+	s.clean("add", "usecase", "AddItem", "to", "Order")
+	src = s.read("clean/ifadapter/presenter/order.go")
+	for _, want := range []string{"type OrderPresenter interface", "type orderPresenter struct", "func NewOrderPresenter(", "func (o *orderPresenter) PresentAddItem("} {
+		if !strings.Contains(src, want) {
+			t.Errorf("the Presenter doesn't contain %q:\n%s", want, src)
+		}
+	}
+}
+
+// TestRemovedSuffix asserts that once the suffix setting the objects were generated with is removed or changed,
+// adding to them fails naming the setting and writes nothing, and that restoring the setting makes it work again
+func TestRemovedSuffix(t *testing.T) {
+	p := newTestProject(t)
+	conf := p.read("../home/.clean/cleanrc")
+	p.write("../home/.clean/cleanrc", conf+"naming.suffix.controller=Controller\n")
+	p.clean("add", "interactor", "Order")
+	for _, c := range []struct{ name, conf string }{
+		{"removed", conf},
+		{"changed", conf + "naming.suffix.controller=Handler\n"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			p.write("../home/.clean/cleanrc", c.conf)
+			before, manifest := p.files("clean"), p.read(".clean/manifest.json")
+			stdout, stderr, code := p.run("add", "usecase", "AddItem", "to", "Order")
+			if code != 1 || !strings.Contains(stdout, "naming.suffix.controller") || !strings.Contains(stdout, "controller/order.go") {
+				t.Errorf("clean add usecase exited with %d: %s%s", code, stdout, stderr)
+			}
+			if after := p.files("clean"); concatenated(after) != concatenated(before) {
+				t.Errorf("clean add usecase changed the project")
+			}
+			if got := p.read(".clean/manifest.json"); got != manifest {
+				t.Errorf("clean add usecase changed the manifest:\n%s", firstDifference(manifest, got))
+			}
+		})
+	}
+
+	p.write("../home/.clean/cleanrc", conf+"naming.suffix.controller=Controller\n")
+	p.clean("add", "usecase", "AddItem", "to", "Order")
+	if src := p.read("clean/ifadapter/controller/order.go"); !strings.Contains(src, "func (o *orderController) AddItem()") {
+		t.Errorf("AddItem wasn't added to the OrderController:\n%s", src)
+	}
+}
+
+// TestEmptyNames passes "" to the add, remove and explain paths and asserts that each fails with a clear error
+// and writes nothing
+func TestEmptyNames(t *testing.T) {
+	p := newTestProject(t)
+	p.clean("add", "interactor", "Order")
+	p.clean("add", "usecase", "AddItem", "to", "Order")
+	before := p.files("")
+	for _, args := range [][]string{
+		{"add", "interactor", ""},
+		{"add", "interactor", " "},
+		{"add", "usecase", "", "to", "Order"},
+		{"add", "usecase", "RemoveItem", "to", ""},
+		{"add", "entity", ""},
+		{"add", "gateway", ""},
+		{"add", "repository", ""},
+		{"add", "method", "", "to", "controller", "Order"},
+		{"add", "presenter", "", "to", "Order"},
+		{"add", "presenter", "JSON", "to", ""},
+		{"add", "view", "", "to", "Order"},
+		{"remove", "method", "", "from", "controller", "Order"},
+		{"explain", "usecase", "", "in", "Order"},
+		{"explain", "usecase", "AddItem", "in", ""},
+	} {
+		stdout, stderr, code := p.run(args...)
+		if out := stdout + stderr; code == 0 || !strings.Contains(out, "the name is empty") && !strings.Contains(out, "no interactor entered") {
+			t.Errorf("clean %q exited with %d without a clear error: %s", args, code, out)
+		}
+	}
+	stdout, stderr, code := p.runIn(p.dir, "Cart\n:\n", "add", "interactor", "-")
+	if code == 0 {
+		t.Errorf("clean add interactor - accepted an empty name: %s%s", stdout, stderr)
+	}
+	for name, content := range p.files("") {
+		if content != before[name] && !strings.HasPrefix(name, ".clean/") {
+			t.Errorf("the empty names changed %s", name)
+		}
+	}
+}
+
+func TestEmptyNameFile(t *testing.T) {
+	for fp, want := range map[string]bool{
+		"clean/usecase/interactor/.go":           true,
+		"clean/usecase/interactor/test/_test.go": true,
+		"clean/_controller.go":                   true,
+		"clean/usecase/interactor/order.go":      false,
+		"clean/.clean/history.log":               false,
+	} {
+		if got := emptyNameFile(fp); got != want {
+			t.Errorf("emptyNameFile(%s) = %v, want %v", fp, got, want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	gofmt "go/format"
+	"path/filepath"
+)
+
+// normalizeOutput returns the content b about to be written to the file fp in the form every generated
+// file shares, whichever template or splice produced it. Go files are formatted like gofmt does, or written
+// as they are if they don't parse. The other files have their leading blank lines dropped and runs of blank
+// lines collapsed into one, and end with exactly one newline.
+func normalizeOutput(fp string, b []byte) []byte {
+	if len(bytes.TrimSpace(b)) == 0 {
+		return b
+	}
+	if filepath.Ext(fp) == ".go" {
+		if formatted, err := gofmt.Source(b); err == nil {
+			return formatted
+		}
+		return b
+	}
+	var out []byte
+	blank := true
+	for _, line := range bytes.SplitAfter(b, []byte("\n")) {
+		content := bytes.TrimRight(line, "\n")
+		if len(bytes.TrimSpace(content)) == 0 {
+			// Drop the leading blank lines and all but the first of a run of them
+			if !blank {
+				out = append(out, '\n')
+			}
+			blank = true
+			continue
+		}
+		out = append(out, content...)
+		if len(content) < len(line) {
+			out = append(out, '\n')
+		}
+		blank = false
+	}
+	out = bytes.TrimRight(out, "\n")
+	return append(out, '\n')
+}
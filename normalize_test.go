@@ -0,0 +1,73 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	gofmt "go/format"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeOutput(t *testing.T) {
+	tests := []struct {
+		name, fp, in, want string
+	}{
+		{"go file is formatted", "order.go", "\n\npackage order\ntype order struct {\n    vw view.Order\n    ps presenter.Order // the Presenter\n}\nfunc New() *order {\n\treturn &order {}\n}", "package order\n\ntype order struct {\n\tvw view.Order\n\tps presenter.Order // the Presenter\n}\n\nfunc New() *order {\n\treturn &order{}\n}\n"},
+		{"blank line runs of a go file are collapsed", "order.go", "package order\n\n\n\nfunc a() {}\n\n\n", "package order\n\nfunc a() {}\n"},
+		{"go file which doesn't parse is kept", "order.go", "package order\nfunc (", "package order\nfunc ("},
+		{"comments only go file gets a trailing newline", "doc.go", "// Package order provides ...\npackage order", "// Package order provides ...\npackage order\n"},
+		{"other file", "order.golden", "\n\n{\n\n\n  \"a\": 1\n}", "{\n\n  \"a\": 1\n}\n"},
+		{"empty file", "order.go", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(normalizeOutput(tt.fp, []byte(tt.in))); got != tt.want {
+				t.Errorf("normalizeOutput() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestGeneratorGoldens compares the files each generator adds or modifies byte by byte to golden files, and
+// asserts that the Go files are formatted like gofmt does
+func TestGeneratorGoldens(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup are the commands run before the command of the generator
+		setup [][]string
+		args  []string
+	}{
+		{"interactor", nil, []string{"add", "interactor", "Order"}},
+		{"usecase", [][]string{{"add", "interactor", "Order"}}, []string{"add", "usecase", "AddItem", "to", "Order"}},
+		{"second_usecase", [][]string{{"add", "interactor", "Order"}, {"add", "usecase", "AddItem", "to", "Order"}}, []string{"add", "usecase", "RemoveItem", "to", "Order"}},
+		{"entity", nil, []string{"add", "entity", "Product"}},
+		{"gateway", nil, []string{"add", "gateway", "Stock"}},
+		{"events", [][]string{{"add", "interactor", "Order"}, {"add", "usecase", "AddItem", "to", "Order"}}, []string{"add", "events", "Order"}},
+		{"clock_idgen", nil, []string{"add", "interactor", "Order", "--with-clock", "--with-idgen"}},
+		{"auth_metrics", [][]string{{"add", "interactor", "Order"}}, []string{"add", "usecase", "AddItem", "to", "Order", "--auth", "--metrics"}},
+		{"decorator", [][]string{{"add", "interactor", "Order"}, {"add", "usecase", "AddItem", "to", "Order"}}, []string{"add", "decorator", "logging", "for", "Order"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestProject(t)
+			for _, args := range tt.setup {
+				p.clean(args...)
+			}
+			files := p.changedBy(tt.args...)
+			if len(files) == 0 {
+				t.Fatalf("clean %s changed no files", strings.Join(tt.args, " "))
+			}
+			for name, content := range files {
+				if !strings.HasSuffix(name, ".go") {
+					continue
+				}
+				if formatted, err := gofmt.Source([]byte(content)); err != nil || !bytes.Equal(formatted, []byte(content)) {
+					t.Errorf("%s isn't formatted like gofmt does", name)
+				}
+			}
+			compareGolden(t, "generator_"+tt.name, concatenated(files))
+		})
+	}
+}
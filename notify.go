@@ -0,0 +1,84 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	relPathOutbox = "usecase/outbox/"
+	objOutbox     = "outbox"
+	// outboxName is the name of the interface the usecases enqueue their notifications with
+	outboxName = "Outbox"
+	// outboxField is the name of the field and constructor parameter of an Interactor holding the Outbox
+	outboxField = "ob"
+)
+
+// outboxSource is the content, following the package clause, of the file of the Outbox
+const outboxSource = `
+
+// Message is a notification a usecase enqueues once its main work is done.
+type Message struct {
+	// Topic names the kind of the notification e.g. order.add_item
+	Topic string
+	// Payload holds the encoded content of the notification.
+	Payload []byte
+}
+
+// Outbox is a Clean Architecture Gateway which enqueues the notifications of the usecases, e.g. by writing
+// them to an outbox table in the transaction of the usecase from which they're relayed to a message broker.
+type Outbox interface {
+	// Enqueue enqueues m to be delivered once the usecase succeeded.
+	Enqueue(m Message) error
+}
+
+// noopOutbox is an implementation of Outbox which discards the notifications.
+type noopOutbox struct{}
+
+// NewNoopOutbox constructs a new Outbox which discards the notifications e.g. until they're delivered.
+func NewNoopOutbox() Outbox {
+	return noopOutbox{}
+}
+
+// Enqueue implements the Outbox interface method Enqueue by discarding m.
+func (noopOutbox) Enqueue(m Message) error {
+	return nil
+}
+`
+
+// usecaseKey returns the key of the usecase of the interactor e.g. order.add_item, which is the topic of its
+// notifications and the action it's authorized for
+func usecaseKey(interactor, usecase string) string {
+	return strings.ToLower(strings.Join(splitWords(interactor), "_") + "." + strings.Join(splitWords(usecase), "_"))
+}
+
+// notifyStub returns the statements of the method of the usecase of the interactor which enqueue its
+// notification. The Interactor implementation is referred to by self and the usecase returns with ret
+// if the notification can't be enqueued.
+func notifyStub(self, interactor, usecase, ret string) string {
+	return fmt.Sprintf("\n\t// Enqueue the notification once the main work is done\n\tif err := %s.%s.Enqueue(%s.Message{Topic: %q}); err != nil {\n\t\t// TODO: Handle the failure to enqueue the notification\n\t\t%s\n\t}\n",
+		self, outboxField, objOutbox, usecaseKey(interactor, usecase), ret)
+}
+
+// addOutbox adds the Outbox and its no-op implementation to the outbox folder of the project at basePath
+// unless they exist
+func addOutbox(basePath string) error {
+	fp := filepath.FromSlash(basePath + relPathOutbox + objOutbox + ".go")
+	if fileExists(fp) {
+		return nil
+	}
+	if err := mkdirAll(filepath.Dir(fp)); err != nil {
+		return err
+	}
+	return writeFile(fp, []byte(packageClause(basePath+relPathOutbox, objOutbox)+outboxSource))
+}
+
+// injectOutbox adds a field and a constructor parameter of the Outbox to the Interactor implementation of the
+// interactor in b, the content of fp, unless it already has them
+func injectOutbox(b []byte, fp, interactor string) ([]byte, error) {
+	return injectDependency(b, fp, interactor, outboxField, objOutbox+"."+outboxName, relPathOutbox)
+}
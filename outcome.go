@@ -0,0 +1,172 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// exitError is the exit status of a command that failed
+	exitError = 1
+	// exitUsage is the exit status of a command that was entered wrongly e.g. an unknown verb or the wrong
+	// number of arguments
+	exitUsage = 2
+	// exitNoop is the exit status of a command that had nothing to do when --fail-on-noop is set
+	exitNoop = 3
+	// exitSkipped is the exit status of a command that skipped modifying existing files because --create-only is set
+	exitSkipped = 4
+
+	outcomeChanged = "changed"
+	outcomeNoop    = "noop"
+	outcomeError   = "error"
+	outcomeOK      = "ok"
+)
+
+var (
+	// changedFiles holds the paths of the files whose content was changed by the command
+	changedFiles []string
+	// noops holds the notices of the things the command didn't do because they already exist
+	noops []string
+	// skippedFiles holds the files the command didn't modify because --create-only is set, each followed by the reason
+	skippedFiles []string
+	// policySkippedFiles holds the files the command didn't modify because the policies of their layers
+	// don't allow it, each followed by the reason
+	policySkippedFiles []string
+	// errorMessages holds the messages of the errors the command encountered
+	errorMessages []string
+	// ownJSONOutput is true if the command printed its own JSON output in place of its outcome
+	ownJSONOutput bool
+	// usageFailed is true if the command was entered wrongly
+	usageFailed bool
+)
+
+// failf prints an error message and makes the command exit with a non-zero status
+func failf(format string, a ...interface{}) {
+	msg := fmt.Sprintf(format, a...)
+	errorMessages = append(errorMessages, strings.TrimSpace(msg))
+	if !*jsonOutput {
+		fmt.Printf("%s", msg)
+	}
+}
+
+// usagef prints a one line error message about a command that was entered wrongly to stderr and makes the
+// command exit with exitUsage
+func usagef(format string, a ...interface{}) {
+	msg := strings.TrimSpace(fmt.Sprintf(format, a...))
+	errorMessages = append(errorMessages, msg)
+	usageFailed = true
+	if !*jsonOutput {
+		fmt.Fprintf(os.Stderr, "%s\n", msg)
+	}
+}
+
+// noopf records that something wasn't done because it already exists. The notices are printed
+// by exitWithOutcome on a single line if the command changed nothing.
+func noopf(format string, a ...interface{}) {
+	msg := fmt.Sprintf(format, a...)
+	for _, n := range noops {
+		if n == msg {
+			return
+		}
+	}
+	noops = append(noops, msg)
+}
+
+// skippedf records that the file fp wasn't modified for the reason given by format
+func skippedf(fp, format string, a ...interface{}) {
+	msg := fp + ": " + fmt.Sprintf(format, a...)
+	for _, s := range skippedFiles {
+		if s == msg {
+			return
+		}
+	}
+	skippedFiles = append(skippedFiles, msg)
+}
+
+// policySkippedf records that the file fp wasn't modified because the policy of its layer doesn't allow it
+func policySkippedf(fp, format string, a ...interface{}) {
+	msg := fp + ": " + fmt.Sprintf(format, a...)
+	for _, s := range policySkippedFiles {
+		if s == msg {
+			return
+		}
+	}
+	policySkippedFiles = append(policySkippedFiles, msg)
+}
+
+// changed records that the content of the file fp was changed
+func changed(fp string) {
+	for _, f := range changedFiles {
+		if f == fp {
+			return
+		}
+	}
+	changedFiles = append(changedFiles, fp)
+}
+
+// outcome returns the outcome of the command, which is one of error, changed, noop and ok
+func outcome() string {
+	switch {
+	case len(errorMessages) > 0:
+		return outcomeError
+	case len(changedFiles) > 0:
+		return outcomeChanged
+	case len(noops) > 0 || len(policySkippedFiles) > 0:
+		return outcomeNoop
+	}
+	return outcomeOK
+}
+
+// exitWithOutcome prints the notices of a command that had nothing to do, or the outcome of the command
+// as JSON if --json is set, and exits with a status reflecting the outcome. Commands that were entered
+// wrongly exit with exitUsage, commands that failed exit with exitError and, if --fail-on-noop is set, commands that had nothing to do exit with exitNoop.
+func exitWithOutcome() {
+	o := outcome()
+	if historyProject != "" && o != outcomeOK {
+		if err := logHistory(o); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing the history log: %s\n", err.Error())
+		}
+	}
+	if *jsonOutput && !ownJSONOutput {
+		out := struct {
+			Status        string   `json:"status"`
+			Files         []string `json:"files"`
+			Notices       []string `json:"notices,omitempty"`
+			Skipped       []string `json:"skipped,omitempty"`
+			PolicySkipped []string `json:"policy_skipped,omitempty"`
+			Errors        []string `json:"errors,omitempty"`
+		}{o, changedFiles, noops, skippedFiles, policySkippedFiles, errorMessages}
+		if out.Files == nil {
+			out.Files = []string{}
+		}
+		b, err := json.MarshalIndent(out, "", "\t")
+		if err == nil {
+			fmt.Printf("%s\n", b)
+		}
+	} else {
+		for _, s := range skippedFiles {
+			fmt.Printf("Skipped %s\n", s)
+		}
+		for _, s := range policySkippedFiles {
+			fmt.Printf("Skipped by policy %s\n", s)
+		}
+		if o == outcomeNoop && len(noops) > 0 {
+			fmt.Printf("Nothing to do: %s\n", strings.Join(noops, "; "))
+		}
+	}
+	switch {
+	case usageFailed:
+		os.Exit(exitUsage)
+	case o == outcomeError:
+		os.Exit(exitError)
+	case len(skippedFiles) > 0:
+		os.Exit(exitSkipped)
+	case o == outcomeNoop && *failOnNoop:
+		os.Exit(exitNoop)
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// pendingFiles holds the content of the files written while --stdout is set keyed by their paths.
+// Reads of the files are served from pendingFiles so that a file can be modified several times
+// before its final content is printed by printPendingFiles.
+var pendingFiles = make(map[string][]byte)
+
+// pendingOrder holds the paths of pendingFiles in the order they were first written
+var pendingOrder []string
+
+// readFiles holds the content of the files read or written by the command keyed by their paths, so that each
+// file is read from disk once however many generators and usecases read it
+var readFiles = make(map[string][]byte)
+
+// missingFiles holds the errors of the reads of the files found missing by the command keyed by their paths, so
+// that a file looked for by several generators, or before it's created, is only looked for on disk once
+var missingFiles = make(map[string]error)
+
+// fileReads counts the reads from disk of each file since the caches were last reset. The benchmarks use it
+// to check that each file is read once per command.
+var fileReads = make(map[string]int)
+
+// createdFiles holds the paths of the files created by the command, which may be modified again by
+// the command even if --create-only is set
+var createdFiles = make(map[string]bool)
+
+// resetFileCaches forgets the files read, parsed and created so far. A long-running command like clean watch
+// resets them before each pass since the files may have been edited in the meantime.
+func resetFileCaches() {
+	readFiles, missingFiles, fileReads = make(map[string][]byte), make(map[string]error), make(map[string]int)
+	parsedFiles, sourceParses = make(map[parseKey]*ast.File), make(map[parseKey]int)
+	createdFiles = make(map[string]bool)
+}
+
+// mayWrite reports whether the file fp may be written. If --create-only is set only files which didn't
+// exist before the command may be written, and the modification of any other file is reported as skipped.
+// Existing files may further only be modified as allowed by the policy of their layer. rewrite is true
+// if the file is rewritten as a whole.
+func mayWrite(fp string, rewrite bool) bool {
+	if createdFiles[fp] {
+		return true
+	}
+	if !fileExists(fp) {
+		createdFiles[fp] = true
+		return true
+	}
+	if *createOnly {
+		skippedf(fp, "it already exists and --create-only is set")
+		return false
+	}
+	if ok, reason := policyAllows(fp, rewrite); !ok {
+		policySkippedf(fp, "%s", reason)
+		return false
+	}
+	return true
+}
+
+// readFile returns the content of the file fp, which is the pending content if --stdout is set and
+// the file has been written. The file is only read from disk the first time. The callers get a copy of the
+// content they may modify.
+func readFile(fp string) ([]byte, error) {
+	if b, ok := pendingFiles[fp]; ok {
+		return b, nil
+	}
+	if b, ok := readFiles[fp]; ok {
+		return append([]byte{}, b...), nil
+	}
+	if err, ok := missingFiles[fp]; ok {
+		return nil, err
+	}
+	defer startPhase(phaseRead)()
+	b, err := ioutil.ReadFile(fp)
+	fileReads[fp]++
+	switch {
+	case err == nil:
+		readFiles[fp] = append([]byte{}, b...)
+	case os.IsNotExist(err):
+		missingFiles[fp] = err
+	}
+	return b, err
+}
+
+// forgetFile forgets the content of the file fp read so far, or that it's missing, e.g. before it's written
+func forgetFile(fp string) {
+	delete(readFiles, fp)
+	delete(missingFiles, fp)
+}
+
+// writeFile writes b to the file fp. If --stdout is set the content is kept in pendingFiles instead.
+func writeFile(fp string, b []byte) error {
+	defer startPhase(phaseWrite)()
+	if !mayWrite(fp, false) {
+		return nil
+	}
+	return writeAllowedFile(fp, b)
+}
+
+// rewriteFile writes b to the file fp like writeFile but as a rewrite of the whole file, which the
+// policy of its layer must allow
+func rewriteFile(fp string, b []byte) error {
+	defer startPhase(phaseWrite)()
+	if !mayWrite(fp, true) {
+		return nil
+	}
+	return writeAllowedFile(fp, b)
+}
+
+// writeAllowedFile writes b to the file fp once mayWrite has allowed it. All of the generated content is
+// written by it, so it's normalized here, see normalizeOutput.
+func writeAllowedFile(fp string, b []byte) error {
+	if emptyNameFile(fp) {
+		// A name which wasn't checked must not make it into the project
+		return fmt.Errorf("refusing to write %s: %s", fp, errEmptyName.Error())
+	}
+	b = normalizeOutput(fp, replaceImports(fp, constrained(fp, withManagedMarker(fp, b))))
+	old, err := readFile(fp)
+	if err == nil && !createdFiles[fp] {
+		if ok, reason := policyAllowsEdit(fp, old, b); !ok {
+			policySkippedf(fp, "%s", reason)
+			return nil
+		}
+	}
+	if err != nil || !bytes.Equal(old, b) {
+		changed(fp)
+	}
+	if !*stdout {
+		forgetFile(fp)
+		if err := ioutil.WriteFile(fp, b, 0700); err != nil {
+			return err
+		}
+		readFiles[fp] = append([]byte{}, b...)
+		return nil
+	}
+	if _, ok := pendingFiles[fp]; !ok {
+		pendingOrder = append(pendingOrder, fp)
+	}
+	pendingFiles[fp] = b
+	return nil
+}
+
+// mkdirAll creates the folder dir along with any missing parents unless --stdout is set
+func mkdirAll(dir string) error {
+	if *stdout {
+		return nil
+	}
+	return os.MkdirAll(dir, 0700)
+}
+
+// writePendingFiles writes the files kept in memory by the command to disk unless the command failed
+func writePendingFiles() {
+	*stdout = false
+	if len(errorMessages) > 0 {
+		return
+	}
+	for _, fp := range pendingOrder {
+		if err := os.MkdirAll(filepath.Dir(fp), 0700); err != nil {
+			failf("Error creating the folder of %s: %s\n", fp, err.Error())
+			return
+		}
+		if err := ioutil.WriteFile(fp, pendingFiles[fp], 0700); err != nil {
+			failf("Error writing %s: %s\n", fp, err.Error())
+			return
+		}
+	}
+}
+
+// printPendingFiles prints the content of the files written while --stdout is set, each preceded by a
+// "==> path <==" header
+func printPendingFiles() {
+	for i, fp := range pendingOrder {
+		if i > 0 {
+			fmt.Printf("\n")
+		}
+		fmt.Printf("==> %s <==\n%s", fp, pendingFiles[fp])
+		if b := pendingFiles[fp]; len(b) > 0 && b[len(b)-1] != '\n' {
+			fmt.Printf("\n")
+		}
+	}
+}
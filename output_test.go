@@ -0,0 +1,121 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// printedFiles splits the output of clean --stdout into the files it prints, keyed by their paths relative to
+// the project folder
+func printedFiles(p *testProject, out string) map[string]string {
+	files := make(map[string]string)
+	blocks := strings.Split(out, "==> ")[1:]
+	for i, block := range blocks {
+		header := strings.Index(block, " <==\n")
+		if header < 0 {
+			p.t.Fatalf("malformed --stdout output:\n%s", out)
+		}
+		name := strings.TrimPrefix(block[:header], p.dir+"/")
+		content := block[header+len(" <==\n"):]
+		// The blocks are separated by an empty line
+		if i < len(blocks)-1 {
+			content = strings.TrimSuffix(content, "\n")
+		}
+		files[name] = content
+	}
+	return files
+}
+
+// TestStdoutPrintsWhatIsWritten asserts that --stdout prints exactly the content the same command writes
+// without it, writes nothing and composes with --only
+func TestStdoutPrintsWhatIsWritten(t *testing.T) {
+	for _, only := range []string{"", "presenter", "presenter,viewmodel"} {
+		t.Run("only="+only, func(t *testing.T) {
+			args := []string{"add", "usecase", "AddItem", "to", "Order"}
+			if only != "" {
+				args = append([]string{"--only", only}, args...)
+			}
+			printing, writing := newTestProject(t), newTestProject(t)
+			printing.clean("add", "interactor", "Order")
+			writing.clean("add", "interactor", "Order")
+
+			before := printing.files("")
+			printed := printedFiles(printing, printing.clean(append([]string{"--stdout"}, args...)...))
+			after := printing.files("")
+			if concatenated(before) != concatenated(after) {
+				t.Errorf("--stdout changed the project")
+			}
+
+			written := writing.changedBy(args...)
+			if len(printed) == 0 || len(printed) != len(written) {
+				t.Errorf("--stdout printed %d files, the command writes %d", len(printed), len(written))
+			}
+			for name, content := range written {
+				if printed[name] != content {
+					t.Errorf("--stdout printed %s differently than it is written:\n%s", name, firstDifference(content, printed[name]))
+				}
+			}
+			if only == "presenter" {
+				for name := range printed {
+					if !strings.Contains(name, "/presenter/") {
+						t.Errorf("--only presenter printed %s", name)
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkAddUsecasesReads adds three usecases to an interactor, together with their tests, routes and
+// decorators as one command does, and fails if any file is read from disk, or any source parsed, more than
+// once
+func BenchmarkAddUsecasesReads(b *testing.B) {
+	defer func(base string) { projectBaseImportPath = base }(projectBaseImportPath)
+	projectBaseImportPath = "app/"
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer devNull.Close()
+	defer func(stdout *os.File) { os.Stdout = stdout }(os.Stdout)
+	os.Stdout = devNull
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		dir := b.TempDir()
+		for _, relPath := range skeletonDirs() {
+			if err := os.MkdirAll(filepath.Join(dir, filepath.FromSlash(relPath)), 0755); err != nil {
+				b.Fatal(err)
+			}
+		}
+		basePath := filepath.ToSlash(dir) + "/clean/"
+		addInteractor(basePath, "Order", "")
+		resetFileCaches()
+		b.StartTimer()
+		for _, usecase := range []string{"AddItem", "RemoveItem", "Checkout"} {
+			addUsecaseWithExtras(basePath, namedSpec{Name: usecase}, "Order")
+		}
+		updateInteractorCalls(basePath)
+		updateViewFactories(basePath)
+		updateDecorators(basePath)
+		updateRoutes(basePath)
+		b.StopTimer()
+		for fp, n := range fileReads {
+			if n > 1 {
+				b.Errorf("%s was read %d times", fp, n)
+			}
+		}
+		for key, n := range sourceParses {
+			if n > 1 {
+				b.Errorf("a source was parsed %d times:\n%s", n, key.src)
+			}
+		}
+		if len(errorMessages) > 0 {
+			b.Fatalf("adding the usecases failed: %s", strings.Join(errorMessages, "\n"))
+		}
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+)
+
+const (
+	// paginationFileName is the name of the file of the respmodel folder holding the Pagination type
+	paginationFileName = "pagination.go"
+	// paginationOffset paginates by the number of the page
+	paginationOffset = "offset"
+	// paginationCursor paginates by the cursor of the next page
+	paginationCursor = "cursor"
+)
+
+// paginationStyle is the configured style of the paginated usecases, which is offset or cursor
+var paginationStyle = paginationOffset
+
+// setPaginationStyle sets the style of the paginated usecases from the configured style. An empty style
+// is the default offset style.
+func setPaginationStyle(style string) error {
+	switch style {
+	case "":
+	case paginationOffset, paginationCursor:
+		paginationStyle = style
+	default:
+		return fmt.Errorf("invalid %s=%s setting in the configuration file, the pagination styles are %s and %s", confKeyPagination, style, paginationOffset, paginationCursor)
+	}
+	return nil
+}
+
+// paginationSource returns the content, following the package clause, of the file of the Pagination type
+// shared by the paginated ResponseModels
+func paginationSource() string {
+	if paginationStyle == paginationCursor {
+		return `
+
+// Pagination describes the page of a paginated ResponseModel.
+type Pagination struct {
+	// NextCursor is the cursor of the next page or empty if the page is the last one.
+	NextCursor string
+}
+
+// HasNext reports whether the page is followed by another one.
+func (p Pagination) HasNext() bool {
+	return p.NextCursor != ""
+}
+`
+	}
+	return `
+
+// Pagination describes the page of a paginated ResponseModel.
+type Pagination struct {
+	// TotalCount is the number of items of all of the pages.
+	TotalCount int
+	// Page is the number of the page, starting at 1.
+	Page int
+	// PerPage is the maximum number of items per page.
+	PerPage int
+}
+
+// PageCount returns the number of pages.
+func (p Pagination) PageCount() int {
+	if p.PerPage <= 0 {
+		return 0
+	}
+	return (p.TotalCount + p.PerPage - 1) / p.PerPage
+}
+`
+}
+
+// paginationRequestFields returns the pagination fields of a RequestModel. If tags is true they're validated
+// by go-playground/validator.
+func paginationRequestFields(tags bool) []structField {
+	fields := []structField{{Name: "Page", Type: "int"}, {Name: "PerPage", Type: "int"}}
+	rules := []string{"min=1", "min=1,max=100"}
+	if paginationStyle == paginationCursor {
+		fields = []structField{{Name: "Cursor", Type: "string"}, {Name: "Limit", Type: "int"}}
+		rules = []string{"omitempty", "min=1,max=100"}
+	}
+	if tags {
+		for i := range fields {
+			fields[i].Type += fmt.Sprintf(" `%s:%q`", tagValidatorField, rules[i])
+		}
+	}
+	return fields
+}
+
+// modelFile returns the path of the file of the model folder relPath declaring the model of the usecase, which
+// is the file of the interactor unless another interactor sharing the usecase declares it. It returns an
+// empty string if the model isn't declared.
+func modelFile(basePath, relPath, usecase, interactor string) string {
+	fp := filepath.FromSlash(basePath + relPath + fileName(interactor) + ".go")
+	if other := typeDeclFile(filepath.FromSlash(basePath+relPath), exportedName(usecase), fp); other != "" {
+		return other
+	}
+	if f, err := parseGoFile(fp); err == nil && findTypeSpec(f, exportedName(usecase)) != nil {
+		return fp
+	}
+	return ""
+}
+
+// addItemType adds the struct of the items of the page of the usecase to the model file fp unless the model
+// folder already declares it
+func addItemType(fp, usecase string) error {
+	name := exportedName(usecase) + "Item"
+	if typeDeclFile(filepath.Dir(fp), name, "") != "" {
+		return nil
+	}
+	b, err := readFile(fp)
+	if err != nil {
+		return err
+	}
+	decl := fmt.Sprintf("\n\n// %s is an item of the page of the usecase %s.\ntype %s struct {\n\t// TODO: Add struct members\n}\n", name, exportedName(usecase), name)
+	return writeFile(fp, append(bytes.TrimRight(b, "\n"), decl...))
+}
+
+// addPagination paginates the models of the usecase of the interactor selected by --only. The RequestModel
+// gets the page and size, or the cursor and limit, of the page to list, and the ResponseModel and the
+// ViewModel get the items of the page and the total count of items, or the cursor of the next page. The
+// Pagination type of the ResponseModels is added to the respmodel folder the first time.
+func addPagination(basePath, usecase, interactor string) error {
+	v := exportedName(usecase)
+	if fp := modelFile(basePath, relPathReqModel, usecase, interactor); fp != "" && selected("reqmodel") {
+		if err := addStructFields(fp, v, paginationRequestFields(*validatorStyle == validatorTags)); err != nil {
+			return err
+		}
+	}
+	items := structField{Name: "Items", Type: "[]" + v + "Item"}
+	if fp := modelFile(basePath, relPathRespModel, usecase, interactor); fp != "" && selected("respmodel") {
+		paginationFp := filepath.FromSlash(basePath + relPathRespModel + paginationFileName)
+		if !fileExists(paginationFp) {
+			if err := writeFile(paginationFp, []byte(packageClause(basePath+relPathRespModel, "respmodel")+paginationSource())); err != nil {
+				return err
+			}
+		}
+		// The Pagination is embedded, so its fields are promoted to the ResponseModel
+		if err := addStructFields(fp, v, []structField{items, {Name: "Pagination"}}); err != nil {
+			return err
+		}
+		if err := addItemType(fp, usecase); err != nil {
+			return err
+		}
+	}
+	if fp := modelFile(basePath, relPathViewModel, usecase, interactor); fp != "" && selected("viewmodel") {
+		page := structField{Name: "TotalCount", Type: "int"}
+		if paginationStyle == paginationCursor {
+			page = structField{Name: "NextCursor", Type: "string"}
+		}
+		if err := addStructFields(fp, v, []structField{items, page}); err != nil {
+			return err
+		}
+		if err := addItemType(fp, usecase); err != nil {
+			return err
+		}
+	}
+	return nil
+}
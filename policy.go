@@ -0,0 +1,143 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// policyGenerateOnce lets Clean create the files of a layer but never modify them afterwards
+	policyGenerateOnce = "generate-once"
+	// policyManaged lets Clean edit the existing files of a layer, e.g. add the methods of a new usecase, only
+	// between the managedBegin and managedEnd markers, and not rewrite them
+	policyManaged = "managed"
+	// policyRegenerate lets Clean rewrite the existing files of a layer as a whole e.g. with "clean format"
+	policyRegenerate = "regenerate"
+)
+
+const (
+	// managedBegin starts a region of a file of a managed layer which Clean may edit. Clean adds it above the
+	// package clause, and its doc comment, of the files it creates in a managed layer.
+	managedBegin = "//clean:begin"
+	// managedEnd ends the region started by the preceding managedBegin. A region which isn't ended extends to
+	// the end of the file.
+	managedEnd = "//clean:end"
+)
+
+// layerPolicies holds the overwrite policies of the layers keyed by the layer e.g. interactor. Layers
+// without a policy may be modified as needed.
+var layerPolicies = make(map[string]string)
+
+// projectBasePath is the clean folder of the project whose files the policies apply to
+var projectBasePath string
+
+// setLayerPolicies sets the overwrite policies of the layers from the policy.[layer] keys of conf
+func setLayerPolicies(conf map[string]string) error {
+	for _, k := range sortedKeys(conf) {
+		v := conf[k]
+		if !strings.HasPrefix(k, confKeyPolicy) {
+			continue
+		}
+		layer := strings.TrimPrefix(k, confKeyPolicy)
+		if _, ok := packageRelPaths[layer]; !ok {
+			return fmt.Errorf("invalid layer %q in %s, the layers are %s", layer, k, strings.Join(sortedKeys(packageRelPaths), ", "))
+		}
+		switch v {
+		case policyGenerateOnce, policyManaged, policyRegenerate:
+			layerPolicies[layer] = v
+		default:
+			return fmt.Errorf("invalid policy %q in %s, the policies are %s, %s and %s", v, k, policyGenerateOnce, policyManaged, policyRegenerate)
+		}
+	}
+	return nil
+}
+
+// layerOf returns the layer of the file fp e.g. interactor, or an empty string if it's outside the layers
+// of the project. Files in the test folder of a layer belong to the layer.
+func layerOf(fp string) string {
+	if projectBasePath == "" {
+		return ""
+	}
+	rel, err := filepath.Rel(filepath.FromSlash(projectBasePath), fp)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	rel = filepath.ToSlash(rel)
+	// The layers are walked in order so that the first of the layers sharing a folder always wins
+	var layer, longest string
+	for _, name := range sortedKeys(packageRelPaths) {
+		relPath := packageRelPaths[name]
+		if strings.HasPrefix(rel, relPath) && len(relPath) > len(longest) {
+			layer, longest = name, relPath
+		}
+	}
+	if strings.HasPrefix(rel, relPathGatewayPort) {
+		layer = objGateway
+	}
+	return layer
+}
+
+// policyAllows reports whether the policy of the layer of the existing file fp allows it to be modified.
+// rewrite is true if the file is rewritten as a whole rather than having declarations added or removed.
+// The reason is set if the modification isn't allowed.
+func policyAllows(fp string, rewrite bool) (bool, string) {
+	layer := layerOf(fp)
+	p, ok := layerPolicies[layer]
+	switch {
+	case !ok, p == policyRegenerate:
+		return true, ""
+	case p == policyGenerateOnce:
+		return false, fmt.Sprintf("policy.%s is %s", layer, p)
+	case rewrite:
+		return false, fmt.Sprintf("policy.%s is %s, which doesn't allow rewriting the file", layer, p)
+	}
+	return true, ""
+}
+
+// policyAllowsEdit reports whether the policy of the layer of the existing file fp allows its content old to be
+// replaced by b. The files of a managed layer may only change between their managedBegin and managedEnd markers,
+// which must be kept. The reason is set if the edit isn't allowed.
+func policyAllowsEdit(fp string, old, b []byte) (bool, string) {
+	layer := layerOf(fp)
+	if layerPolicies[layer] != policyManaged || bytes.Equal(old, b) {
+		return true, ""
+	}
+	inside := false
+	for _, op := range diffLines(splitLines(old), splitLines(b)) {
+		marker := strings.TrimSpace(op.line)
+		isMarker := marker == managedBegin || marker == managedEnd
+		if op.kind != ' ' && (!inside || isMarker) {
+			return false, fmt.Sprintf("policy.%s is %s, which allows changes only between the %s and %s markers", layer, policyManaged, managedBegin, managedEnd)
+		}
+		if op.kind == ' ' && isMarker {
+			inside = marker == managedBegin
+		}
+	}
+	return true, ""
+}
+
+// withManagedMarker returns the content b of the file fp created by the command with managedBegin added above
+// its package clause and the doc comment of the package clause if fp is in a managed layer, so that Clean may
+// edit the whole file until the region is ended or moved
+func withManagedMarker(fp string, b []byte) []byte {
+	if !createdFiles[fp] || layerPolicies[layerOf(fp)] != policyManaged || bytes.Contains(b, []byte(managedBegin)) {
+		return b
+	}
+	lines := bytes.SplitAfter(b, []byte("\n"))
+	for i, line := range lines {
+		if !bytes.HasPrefix(line, []byte("package ")) {
+			continue
+		}
+		for i > 0 && bytes.HasPrefix(lines[i-1], []byte("//")) {
+			i--
+		}
+		marked := append(append([][]byte{}, lines[:i]...), []byte(managedBegin+"\n\n"))
+		return bytes.Join(append(marked, lines[i:]...), nil)
+	}
+	return b
+}
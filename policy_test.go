@@ -0,0 +1,79 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestManagedPolicyMarkers asserts that the files of a managed layer are created with a region Clean may edit,
+// that usecases are added inside it and that an edit reaching outside of it is skipped by policy
+func TestManagedPolicyMarkers(t *testing.T) {
+	p := newTestProject(t)
+	p.write("../home/.clean/cleanrc", p.read("../home/.clean/cleanrc")+"policy.interactor=managed\n")
+	p.clean("add", "interactor", "Order")
+	const fp = "clean/usecase/interactor/order.go"
+	if src := p.read(fp); !strings.HasPrefix(src, managedBegin+"\n\n// Package interactor") {
+		t.Fatalf("%s doesn't start with the %s marker:\n%s", fp, managedBegin, src)
+	}
+	if src := p.read("clean/ifadapter/controller/order.go"); strings.Contains(src, managedBegin) {
+		t.Errorf("the controller, whose layer has no policy, has the %s marker:\n%s", managedBegin, src)
+	}
+	p.clean("add", "usecase", "AddItem", "to", "Order")
+	if src := p.read(fp); !strings.Contains(src, "func (o *order) AddItem(") {
+		t.Errorf("AddItem wasn't added inside the region:\n%s", src)
+	}
+
+	// End the region above the implementation, where the methods are added
+	p.write(fp, strings.Replace(p.read(fp), "// order is an implementation", managedEnd+"\n\n// order is an implementation", 1))
+	before := p.read(fp)
+	stdout := p.clean("add", "usecase", "RemoveItem", "to", "Order")
+	if !strings.Contains(stdout, "Skipped by policy "+p.path(fp)) {
+		t.Errorf("the edit outside the region isn't reported as skipped by policy:\n%s", stdout)
+	}
+	if got := p.read(fp); got != before {
+		t.Errorf("%s was edited outside its region:\n%s", fp, firstDifference(before, got))
+	}
+	if src := p.read("clean/ifadapter/controller/order.go"); !strings.Contains(src, "RemoveItem(") {
+		t.Errorf("RemoveItem wasn't added to the other layers:\n%s", src)
+	}
+}
+
+// TestPolicyAllowsEdit enumerates edits of a file of a managed layer inside and outside its regions
+func TestPolicyAllowsEdit(t *testing.T) {
+	defer func(base string) { projectBasePath = base }(projectBasePath)
+	projectBasePath = "/app/clean/"
+	defer delete(layerPolicies, objInteractor)
+	layerPolicies[objInteractor] = policyManaged
+	const old = "package interactor\n\ntype a struct{}\n\n//clean:begin\n\ntype b struct{}\n\n//clean:end\n\ntype c struct{}\n"
+	tests := []struct {
+		name, new string
+		allowed   bool
+	}{
+		{"unchanged", old, true},
+		{"added inside", strings.Replace(old, "type b struct{}\n", "type b struct{}\n\ntype d struct{}\n", 1), true},
+		{"changed inside", strings.Replace(old, "type b struct{}", "type b struct{ n int }", 1), true},
+		{"added before the end", strings.Replace(old, "//clean:end", "type d struct{}\n\n//clean:end", 1), true},
+		{"changed before", strings.Replace(old, "type a struct{}", "type a struct{ n int }", 1), false},
+		{"changed after", strings.Replace(old, "type c struct{}", "type c struct{ n int }", 1), false},
+		{"appended", old + "\ntype d struct{}\n", false},
+		{"end removed", strings.Replace(old, "//clean:end\n", "", 1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, reason := policyAllowsEdit("/app/clean/usecase/interactor/order.go", []byte(old), []byte(tt.new))
+			if allowed != tt.allowed {
+				t.Errorf("policyAllowsEdit() = %v, %q, want %v", allowed, reason, tt.allowed)
+			}
+		})
+	}
+	open := strings.Replace(old, "//clean:end\n", "", 1)
+	if allowed, _ := policyAllowsEdit("/app/clean/usecase/interactor/order.go", []byte(open), []byte(open+"\ntype d struct{}\n")); !allowed {
+		t.Errorf("policyAllowsEdit() doesn't allow appending to a region which isn't ended")
+	}
+	if allowed, _ := policyAllowsEdit("/app/clean/ifadapter/controller/order.go", []byte(old), []byte(old+"\ntype d struct{}\n")); !allowed {
+		t.Errorf("policyAllowsEdit() doesn't allow editing a file of a layer without a policy")
+	}
+}
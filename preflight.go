@@ -0,0 +1,53 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// interactorFiles returns the path of the file of the object of type objType of the interactor name and the path
+// of its test file
+func interactorFiles(basePath, objType, name string) (string, string) {
+	dir := basePath + objRelPaths[objType]
+	return filepath.FromSlash(dir + fileName(name) + ".go"), filepath.FromSlash(dir + "test/" + fileName(name) + "_test.go")
+}
+
+// preflightInteractor checks which of the files of the objects of the interactor selected by --only, and of their
+// test files, exist before anything is written, and returns the paths of the files to write. If none of them
+// exist all of them are written, and if all of them exist the interactor already exists. If only some of them
+// exist, which is the case after e.g. an interrupted command or a deleted file, adding the interactor would leave
+// a mix of old and new files behind, so the command fails listing them unless --repair is set, in which case only
+// the missing files are written. ok is false if nothing is to be written.
+func preflightInteractor(basePath, name string) (write map[string]bool, ok bool) {
+	write = make(map[string]bool)
+	var present, missing []string
+	for _, objType := range objTypes {
+		if !selected(objType) {
+			continue
+		}
+		objFp, testFp := interactorFiles(basePath, objType, name)
+		for _, fp := range []string{objFp, testFp} {
+			if fileExists(fp) {
+				present = append(present, fp)
+				continue
+			}
+			missing = append(missing, fp)
+			write[fp] = true
+		}
+	}
+	switch {
+	case len(missing) == 0:
+		noopf("the interactor %s already exists", exportedName(name))
+		return nil, false
+	case len(present) > 0 && !*repair:
+		failf("The interactor %s partly exists.\nExisting files:\n\t%s\nMissing files:\n\t%s\nAdd --repair to add only the missing files.\n\n", exportedName(name), strings.Join(present, "\n\t"), strings.Join(missing, "\n\t"))
+		return nil, false
+	case len(present) > 0:
+		fmt.Printf("Repairing the interactor %s, adding:\n\t%s\n", exportedName(name), strings.Join(missing, "\n\t"))
+	}
+	return write, true
+}
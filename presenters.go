@@ -0,0 +1,270 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// funcSignature returns the parameters and results of the function type ft as written in Go source
+// e.g. "(rsm *respmodel.AddItem) error"
+func funcSignature(ft *ast.FuncType) string {
+	fields := func(fl *ast.FieldList) []string {
+		if fl == nil {
+			return nil
+		}
+		var s []string
+		for _, field := range fl.List {
+			typ := types.ExprString(field.Type)
+			if len(field.Names) == 0 {
+				s = append(s, typ)
+				continue
+			}
+			var names []string
+			for _, n := range field.Names {
+				names = append(names, n.Name)
+			}
+			s = append(s, strings.Join(names, ", ")+" "+typ)
+		}
+		return s
+	}
+	sig := "(" + strings.Join(fields(ft.Params), ", ") + ")"
+	results := fields(ft.Results)
+	switch {
+	case len(results) == 1 && (ft.Results.List[0].Names == nil):
+		sig += " " + results[0]
+	case len(results) > 0:
+		sig += " (" + strings.Join(results, ", ") + ")"
+	}
+	return sig
+}
+
+// usedPackages returns the names of the packages referred to by the expression e
+func usedPackages(e ast.Node, pkgs map[string]bool) {
+	ast.Inspect(e, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				pkgs[id.Name] = true
+			}
+		}
+		return true
+	})
+}
+
+// addedPresenters holds the Presenters added by the command by the interactors they present the usecases of,
+// which are recorded in the manifest once the command is done
+var addedPresenters = make(map[string][]string)
+
+// addPresenterImpl adds another implementation, named name, of the Presenter interface of the interactor
+// e.g. an HTMLOrder Presenter next to the Order Presenter. It gets a stub of each method of the interface. It
+// holds the View of the interactor or the other View of the interactor set by --view, see addView. The
+// Presenter is recorded in the manifest so that the usecases added to the interactor later are added to it too,
+// see syncPresenterImpl.
+func addPresenterImpl(basePath, name, interactor string) error {
+	ifName := typeName(objPresenter, interactor)
+	implName := typeName(objPresenter, name)
+	if fileName(name) == fileName(interactor) {
+		return fmt.Errorf("the presenter %s must be named differently from the interactor", implName)
+	}
+	ifFp := filepath.FromSlash(basePath + relPathPresenter + fileName(interactor) + ".go")
+	if !fileExists(ifFp) {
+		return fmt.Errorf("the interactor %s doesn't have a Presenter", exportedName(interactor))
+	}
+	f, it, err := presenterInterface(ifFp, ifName)
+	if err != nil {
+		return err
+	}
+	fp := filepath.FromSlash(basePath + relPathPresenter + fileName(name) + ".go")
+	if fileExists(fp) {
+		if f, err := parseGoFile(fp); err == nil && findTypeSpec(f, unexportedName(implName)) != nil {
+			noopf("the presenter %s already exists", implName)
+			recordPresenter(name, interactor)
+			return nil
+		}
+		return fmt.Errorf("the file %s already exists", fp)
+	}
+	lcName := unexportedName(implName)
+	self := objReceiverName(objPresenter, implName)
+	vwName := typeName(objView, interactor)
+	if *presenterView != "" {
+		vwName = typeName(objView, *presenterView)
+		vwFp := filepath.FromSlash(basePath + relPathView + fileName(*presenterView) + ".go")
+		if vf, err := parseGoFile(vwFp); err != nil || findTypeSpec(vf, vwName) == nil {
+			return fmt.Errorf("the view %s doesn't exist, add it with \"clean add view %s to %s\"", vwName, exportedName(*presenterView), exportedName(interactor))
+		}
+	}
+	pkgs := map[string]bool{"errors": true, "view": true}
+	methods := presenterStubs(it, ifName, self, lcName, nil, pkgs)
+	// Import the packages of the Presenter's file which the new file refers to
+	var imports []string
+	for _, imp := range importsOf(f, pkgs) {
+		if imp != strconv.Quote("errors") {
+			imports = append(imports, imp)
+		}
+	}
+	imports = append(imports, strconv.Quote("errors"))
+	sort.Strings(imports)
+	var b bytes.Buffer
+	b.WriteString(packageClause(basePath+relPathPresenter, objPresenter))
+	fmt.Fprintf(&b, "\n\nimport (\n\t%s\n)\n", strings.Join(imports, "\n\t"))
+	fmt.Fprintf(&b, "\n// %s is another implementation of the %s Presenter e.g. for a different output format.\n// TODO: Add description of what the implementation does\ntype %s struct {\n\tvw view.%s\n\t// TODO define struct fields\n}\n", lcName, ifName, lcName, vwName)
+	b.Write(methods)
+	fmt.Fprintf(&b, "\n// New%s constructs a new %s Presenter and returns a nil error if successful. Otherwise it returns an error.\nfunc New%s(vw view.%s) (%s, error) {\n\tif vw == nil {\n\t\treturn nil, errors.New(\"Error constructing %s\")\n\t}\n\treturn &%s{\n\t\tvw: vw,\n\t}, nil\n}\n", implName, ifName, implName, vwName, ifName, implName, lcName)
+	if err := mkdirAll(filepath.Dir(fp)); err != nil {
+		return err
+	}
+	if err := writeFile(fp, b.Bytes()); err != nil {
+		return err
+	}
+	recordPresenter(name, interactor)
+	return nil
+}
+
+// recordPresenter records the Presenter name of the interactor to be added to the manifest
+func recordPresenter(name, interactor string) {
+	it := exportedName(interactor)
+	addedPresenters[it] = appendUnique(addedPresenters[it], exportedName(name))
+}
+
+// presenterInterface returns the parsed file fp and the Presenter interface ifName it declares
+func presenterInterface(fp, ifName string) (*ast.File, *ast.InterfaceType, error) {
+	f, err := parseGoFile(fp)
+	if err != nil {
+		return nil, nil, err
+	}
+	ts := findTypeSpec(f, ifName)
+	if ts == nil {
+		return nil, nil, fmt.Errorf("the interface %s isn't declared in %s", ifName, fp)
+	}
+	it, ok := ts.Type.(*ast.InterfaceType)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s isn't an interface", ifName)
+	}
+	return f, it, nil
+}
+
+// presenterStubs returns a stub, implemented by the struct lcName with the receiver self, of each method of the
+// interface it named ifName which isn't in skip. The names of the packages the stubs refer to are added to
+// pkgs.
+func presenterStubs(it *ast.InterfaceType, ifName, self, lcName string, skip map[string]bool, pkgs map[string]bool) []byte {
+	var methods bytes.Buffer
+	for _, m := range it.Methods.List {
+		ft, ok := m.Type.(*ast.FuncType)
+		if !ok {
+			continue
+		}
+		sig := funcSignature(ft)
+		for _, n := range m.Names {
+			if skip[n.Name] {
+				continue
+			}
+			usedPackages(ft, pkgs)
+			body := "\t// TODO: Implement interface method\n"
+			if ft.Results != nil && len(ft.Results.List) > 0 {
+				body += "\tpanic(\"not implemented\")\n"
+			}
+			fmt.Fprintf(&methods, "\n// %s implements the %s interface method %s.\nfunc (%s *%s) %s%s {\n%s}\n", n.Name, ifName, n.Name, self, lcName, n.Name, sig, body)
+		}
+	}
+	return []byte(useParams(methods.String()))
+}
+
+// implMethods returns the names of the methods of the struct structName declared in f
+func implMethods(f *ast.File, structName string) []string {
+	var names []string
+	for _, decl := range f.Decls {
+		if fd, ok := decl.(*ast.FuncDecl); ok && recvTypeName(fd) == structName {
+			names = append(names, fd.Name.Name)
+		}
+	}
+	return names
+}
+
+// syncPresenterImpl adds a stub of each method of the Presenter interface of the interactor which the other
+// Presenter named name lacks, so that it keeps implementing the interface once a usecase is added
+func syncPresenterImpl(basePath, name, interactor string) error {
+	ifName := typeName(objPresenter, interactor)
+	lcName := unexportedName(typeName(objPresenter, name))
+	ifFp := filepath.FromSlash(basePath + relPathPresenter + fileName(interactor) + ".go")
+	if b, err := readFile(ifFp); err == nil {
+		ifName, _ = fileObjNames(b, objPresenter, interactor)
+	}
+	f, it, err := presenterInterface(ifFp, ifName)
+	if err != nil {
+		return err
+	}
+	fp := filepath.FromSlash(basePath + relPathPresenter + fileName(name) + ".go")
+	b, err := readFile(fp)
+	if err != nil {
+		return err
+	}
+	impl, err := parseFile(token.NewFileSet(), fp, b, 0)
+	if err != nil {
+		return err
+	}
+	if findTypeSpec(impl, lcName) == nil {
+		return fmt.Errorf("the presenter %s isn't declared in %s", lcName, fp)
+	}
+	skip := make(map[string]bool)
+	for _, m := range implMethods(impl, lcName) {
+		skip[m] = true
+	}
+	self := implReceiver(b, lcName)
+	if self == "" {
+		self = objReceiverName(objPresenter, typeName(objPresenter, name))
+	}
+	pkgs := make(map[string]bool)
+	methods := presenterStubs(it, ifName, self, lcName, skip, pkgs)
+	if len(methods) == 0 {
+		return nil
+	}
+	b = append(append(bytes.TrimRight(b, "\n"), '\n'), methods...)
+	// Import the packages of the interface's file which the stubs refer to
+	for _, imp := range f.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		name, pkg := "", path[strings.LastIndex(path, "/")+1:]
+		if imp.Name != nil {
+			name, pkg = imp.Name.Name, imp.Name.Name
+		}
+		if !pkgs[pkg] {
+			continue
+		}
+		if b, err = ensureNamedImport(b, name, path); err != nil {
+			return err
+		}
+	}
+	return writeFile(fp, b)
+}
+
+// presentersOf returns the Presenters of the interactor, other than the one named after it, that are recorded
+// in the manifest of the project or were added by the command and still exist
+func presentersOf(basePath, interactor string) []string {
+	return existingObjs(basePath, relPathPresenter, recordedPresenters(basePath, interactor))
+}
+
+// recordedPresenters returns the Presenters of the interactor, other than the one named after it, that are
+// recorded in the manifest of the project or were added by the command
+func recordedPresenters(basePath, interactor string) []string {
+	it := exportedName(interactor)
+	m, _ := readManifest(strings.TrimSuffix(basePath, "clean/"))
+	var presenters []string
+	for _, mi := range m.Interactors {
+		if mi.Name == it {
+			presenters = mi.Presenters
+		}
+	}
+	var recorded []string
+	for _, p := range append(append([]string{}, presenters...), addedPresenters[it]...) {
+		recorded = appendUnique(recorded, p)
+	}
+	return recorded
+}
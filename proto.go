@@ -0,0 +1,435 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// protoScalars maps the scalar types of protobuf to the Go types of the fields of a RequestModel
+var protoScalars = map[string]string{
+	"double": "float64", "float": "float32",
+	"int32": "int32", "sint32": "int32", "sfixed32": "int32",
+	"int64": "int64", "sint64": "int64", "sfixed64": "int64",
+	"uint32": "uint32", "fixed32": "uint32", "uint64": "uint64", "fixed64": "uint64",
+	"bool": "bool", "string": "string", "bytes": "[]byte",
+}
+
+// protoToken is a token of a .proto file
+type protoToken struct {
+	text string
+	line int
+}
+
+// protoField is a field of a protobuf message
+type protoField struct {
+	label  string
+	typ    string
+	name   string
+	number int
+	line   int
+}
+
+// protoFile holds the messages and enums of a .proto file by their names, which are qualified by the names
+// of the messages they're nested in e.g. Order.Line
+type protoFile struct {
+	fp       string
+	pkg      string
+	messages map[string][]protoField
+	enums    map[string]bool
+}
+
+// protoParser is a minimal parser of the messages of a .proto file. It isn't a compiler, so the imports
+// aren't followed and the options are skipped.
+type protoParser struct {
+	pf     protoFile
+	tokens []protoToken
+	pos    int
+}
+
+// tokenizeProto splits the content of a .proto file into its identifiers, numbers, strings and punctuation,
+// skipping the comments
+func tokenizeProto(src string) ([]protoToken, error) {
+	var tokens []protoToken
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case unicode.IsSpace(rune(c)):
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(src) && src[j] != c && src[j] != '\n' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) || src[j] != c {
+				return nil, fmt.Errorf("line %d: unterminated string", line)
+			}
+			tokens = append(tokens, protoToken{src[i : j+1], line})
+			i = j + 1
+		case c == '_' || c == '.' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)) || c == '-' || c == '+':
+			j := i + 1
+			for j < len(src) && (src[j] == '_' || src[j] == '.' || unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j]))) {
+				j++
+			}
+			tokens = append(tokens, protoToken{src[i:j], line})
+			i = j
+		default:
+			tokens = append(tokens, protoToken{string(c), line})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+// parseProto parses the messages and enums of the .proto file fp
+func parseProto(fp string) (protoFile, error) {
+	pf := protoFile{fp: fp, messages: make(map[string][]protoField), enums: make(map[string]bool)}
+	b, err := readFile(fp)
+	if err != nil {
+		return pf, err
+	}
+	tokens, err := tokenizeProto(string(b))
+	if err != nil {
+		return pf, fmt.Errorf("%s, %s", fp, err.Error())
+	}
+	p := &protoParser{pf: pf, tokens: tokens}
+	if err := p.parseBody("", true); err != nil {
+		return pf, fmt.Errorf("%s, %s", fp, err.Error())
+	}
+	return p.pf, nil
+}
+
+// next returns the next token, which is empty at the end of the file
+func (p *protoParser) next() protoToken {
+	if p.pos >= len(p.tokens) {
+		line := 0
+		if len(p.tokens) > 0 {
+			line = p.tokens[len(p.tokens)-1].line
+		}
+		return protoToken{"", line}
+	}
+	p.pos++
+	return p.tokens[p.pos-1]
+}
+
+// peek returns the next token without consuming it
+func (p *protoParser) peek() protoToken {
+	t := p.next()
+	if t.text != "" {
+		p.pos--
+	}
+	return t
+}
+
+// expect consumes the next token and returns an error unless it's text
+func (p *protoParser) expect(text string) (protoToken, error) {
+	t := p.next()
+	if t.text != text {
+		return t, fmt.Errorf("line %d: expected %q, found %q", t.line, text, t.text)
+	}
+	return t, nil
+}
+
+// skipStatement skips the tokens up to and including the ; ending the statement
+func (p *protoParser) skipStatement() error {
+	for {
+		switch t := p.next(); t.text {
+		case ";":
+			return nil
+		case "":
+			return fmt.Errorf("line %d: unexpected end of file", t.line)
+		}
+	}
+}
+
+// skipBlock skips the tokens up to and including the } ending the block whose { is the next token
+func (p *protoParser) skipBlock() error {
+	for p.peek().text != "{" {
+		if t := p.next(); t.text == "" || t.text == ";" {
+			return fmt.Errorf("line %d: expected a block", t.line)
+		}
+	}
+	depth := 0
+	for {
+		switch t := p.next(); t.text {
+		case "{":
+			depth++
+		case "}":
+			if depth--; depth == 0 {
+				return nil
+			}
+		case "":
+			return fmt.Errorf("line %d: unexpected end of file", t.line)
+		}
+	}
+}
+
+// parseBody parses the statements of the file, if top is true, or of the body of the message scope up to and
+// including its }
+func (p *protoParser) parseBody(scope string, top bool) error {
+	for {
+		t := p.next()
+		switch t.text {
+		case "":
+			if top {
+				return nil
+			}
+			return fmt.Errorf("line %d: unexpected end of file in message %s", t.line, scope)
+		case "}":
+			if top {
+				return fmt.Errorf("line %d: unexpected }", t.line)
+			}
+			return nil
+		case ";":
+		case "syntax", "edition", "import", "option", "reserved", "extensions":
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+		case "package":
+			p.pf.pkg = p.next().text
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+		case "service", "extend":
+			if err := p.skipBlock(); err != nil {
+				return err
+			}
+		case "message", "enum":
+			name := p.next().text
+			if scope != "" {
+				name = scope + "." + name
+			}
+			if t.text == "enum" {
+				p.pf.enums[name] = true
+				if err := p.skipBlock(); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := p.expect("{"); err != nil {
+				return err
+			}
+			p.pf.messages[name] = []protoField{}
+			if err := p.parseBody(name, false); err != nil {
+				return err
+			}
+		case "oneof":
+			return fmt.Errorf("line %d: the oneof %s of message %s isn't supported yet, replace it by its fields", t.line, p.peek().text, scope)
+		default:
+			if top {
+				return fmt.Errorf("line %d: unexpected %q", t.line, t.text)
+			}
+			p.pos--
+			if err := p.parseField(scope); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// parseField parses a field of the message scope e.g. repeated string tags = 3 [packed = true];
+func (p *protoParser) parseField(scope string) error {
+	f := protoField{line: p.peek().line}
+	if l := p.peek().text; l == "repeated" || l == "optional" || l == "required" {
+		f.label = p.next().text
+	}
+	f.typ = p.next().text
+	switch {
+	case f.typ == "map":
+		return fmt.Errorf("line %d: the map field of message %s isn't supported yet, replace it by a repeated message of the keys and values", f.line, scope)
+	case f.typ == "group":
+		return fmt.Errorf("line %d: the group of message %s isn't supported, replace it by a nested message", f.line, scope)
+	}
+	f.name = p.next().text
+	if _, err := p.expect("="); err != nil {
+		return err
+	}
+	t := p.next()
+	n, err := strconv.Atoi(t.text)
+	if err != nil {
+		return fmt.Errorf("line %d: invalid field number %q of %s.%s", t.line, t.text, scope, f.name)
+	}
+	f.number = n
+	if p.peek().text == "[" {
+		// The field options e.g. [deprecated = true] are skipped
+		for t := p.next(); t.text != "]"; t = p.next() {
+			if t.text == "" {
+				return fmt.Errorf("line %d: unexpected end of file", t.line)
+			}
+		}
+	}
+	if _, err := p.expect(";"); err != nil {
+		return err
+	}
+	p.pf.messages[scope] = append(p.pf.messages[scope], f)
+	return nil
+}
+
+// resolve returns the name of the message or enum typ referenced from the message scope, looking it up in
+// the scope and then in its enclosing ones like protoc does. It returns an empty string if the file doesn't
+// declare it.
+func (pf protoFile) resolve(scope, typ string) string {
+	if strings.HasPrefix(typ, ".") {
+		typ = strings.TrimPrefix(strings.TrimPrefix(typ, "."+pf.pkg), ".")
+		scope = ""
+	}
+	for {
+		name := typ
+		if scope != "" {
+			name = scope + "." + typ
+		}
+		if _, ok := pf.messages[name]; ok || pf.enums[name] {
+			return name
+		}
+		if scope == "" {
+			break
+		}
+		if i := strings.LastIndex(scope, "."); i >= 0 {
+			scope = scope[:i]
+		} else {
+			scope = ""
+		}
+	}
+	if pf.pkg != "" && strings.HasPrefix(typ, pf.pkg+".") {
+		return pf.resolve("", "."+typ)
+	}
+	return ""
+}
+
+// protoRequest holds the fields of the RequestModel of the usecase read from the message of a .proto file and
+// the structs of the messages they reference
+type protoRequest struct {
+	pf      protoFile
+	usecase string
+	message string
+	fields  []structField
+	types   []structDecl
+}
+
+// typeName returns the name of the struct of the message, which is the RequestModel itself if it's the
+// message of the usecase and otherwise prefixed by the usecase instead of the message of the usecase it's
+// nested in e.g. AddItemLine for AddItemRequest.Line
+func (pr *protoRequest) typeName(message string) string {
+	if message == pr.message {
+		return exportedName(pr.usecase)
+	}
+	return exportedName(pr.usecase) + exportedName(strings.TrimPrefix(message, pr.message+"."))
+}
+
+// structFields returns the fields of the struct of the message and adds the structs of the messages they
+// reference to pr.types
+func (pr *protoRequest) structFields(message string, added map[string]bool) ([]structField, error) {
+	var fields []structField
+	for _, f := range pr.pf.messages[message] {
+		typ, ok := protoScalars[f.typ]
+		if !ok {
+			ref := pr.pf.resolve(message, f.typ)
+			switch {
+			case ref == "":
+				return nil, fmt.Errorf("%s, line %d: the type %s of %s.%s isn't declared in the file", pr.pf.fp, f.line, f.typ, message, f.name)
+			case pr.pf.enums[ref]:
+				// The enums are represented by their numbers
+				typ = "int32"
+			default:
+				typ = pr.typeName(ref)
+				if f.label != "repeated" {
+					typ = "*" + typ
+				}
+				if ref != pr.message && !added[ref] {
+					added[ref] = true
+					// The struct is added before the ones it references, so they're declared in reading order
+					i := len(pr.types)
+					pr.types = append(pr.types, structDecl{
+						Name: pr.typeName(ref),
+						Doc:  fmt.Sprintf("// %s is the %s message of %s.", pr.typeName(ref), ref, pr.pf.fp),
+					})
+					nested, err := pr.structFields(ref, added)
+					if err != nil {
+						return nil, err
+					}
+					pr.types[i].Fields = nested
+				}
+			}
+		}
+		switch {
+		case f.label == "repeated":
+			typ = "[]" + typ
+		case f.label == "optional" && !strings.HasPrefix(typ, "*") && !strings.HasPrefix(typ, "[]"):
+			// An optional field is set or not like the ones of the messages of the generated protobuf code
+			typ = "*" + typ
+		}
+		fields = append(fields, structField{Name: exportedName(f.name), Type: typ, Comment: fmt.Sprintf("proto field %d", f.number)})
+	}
+	return fields, nil
+}
+
+// readProto returns the fields of the RequestModel of the usecase and the structs of the messages they
+// reference, read from arg which is the path of a .proto file and the name of a message separated by a colon
+// e.g. api/order.proto:AddItemRequest
+func readProto(arg, usecase string) ([]structField, []structDecl, error) {
+	i := strings.LastIndex(arg, ":")
+	if i <= 0 || i == len(arg)-1 {
+		return nil, nil, fmt.Errorf("invalid --proto %q, expected the path of a .proto file and a message e.g. api/order.proto:AddItemRequest", arg)
+	}
+	fp, message := arg[:i], arg[i+1:]
+	pf, err := parseProto(fp)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, ok := pf.messages[message]; !ok {
+		return nil, nil, fmt.Errorf("%s doesn't declare the message %s", fp, message)
+	}
+	pr := &protoRequest{pf: pf, usecase: usecase, message: message}
+	fields, err := pr.structFields(message, make(map[string]bool))
+	return fields, pr.types, err
+}
+
+// withProto returns specs with the fields of their RequestModels, and the structs they reference, read from
+// the message arg of a .proto file
+func withProto(specs []namedSpec, arg string) ([]namedSpec, error) {
+	for i := range specs {
+		fields, types, err := readProto(arg, specs[i].Name)
+		if err != nil {
+			return nil, err
+		}
+		specs[i].Fields = append(specs[i].Fields, fields...)
+		specs[i].Types = types
+	}
+	return specs, nil
+}
+
+// withPayload returns specs with the fields of their RequestModels read from the --schema or the --proto
+// description of the request payload, if any
+func withPayload(specs []namedSpec) ([]namedSpec, error) {
+	switch {
+	case *schema != "" && *proto != "":
+		return nil, fmt.Errorf("--schema and --proto are mutually exclusive")
+	case *schema != "":
+		return withSchema(specs, *schema)
+	case *proto != "":
+		return withProto(specs, *proto)
+	}
+	return specs, nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadProto(t *testing.T) {
+	fields, types, err := readProto(filepath.Join("testdata", "proto", "order.proto")+":AddItemRequest", "AddItem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []structField{
+		{Name: "OrderID", Type: "string", Comment: "proto field 1"},
+		{Name: "Lines", Type: "[]AddItemLine", Comment: "proto field 2"},
+		{Name: "CouponCode", Type: "*string", Comment: "proto field 3"},
+		{Name: "GiftWrap", Type: "bool", Comment: "proto field 5"},
+		{Name: "Note", Type: "[]byte", Comment: "proto field 6"},
+		{Name: "Tags", Type: "[]string", Comment: "proto field 7"},
+		{Name: "Shipping", Type: "*AddItemMoney", Comment: "proto field 11"},
+		{Name: "WeightKg", Type: "float64", Comment: "proto field 12"},
+	}
+	compareFields(t, "AddItem", fields, want)
+	if len(types) != 2 || types[0].Name != "AddItemLine" || types[1].Name != "AddItemMoney" {
+		t.Fatalf("got the structs %+v, want AddItemLine and AddItemMoney", types)
+	}
+	compareFields(t, "AddItemLine", types[0].Fields, []structField{
+		{Name: "Sku", Type: "string", Comment: "proto field 1"},
+		{Name: "Quantity", Type: "uint32", Comment: "proto field 2"},
+		{Name: "UnitPrice", Type: "*AddItemMoney", Comment: "proto field 3"},
+		{Name: "Status", Type: "int32", Comment: "proto field 4"},
+	})
+	compareFields(t, "AddItemMoney", types[1].Fields, []structField{
+		{Name: "CurrencyCode", Type: "string", Comment: "proto field 1"},
+		{Name: "Units", Type: "int64", Comment: "proto field 2"},
+		{Name: "Nanos", Type: "int32", Comment: "proto field 3"},
+	})
+}
+
+// compareFields compares the fields of the struct name to want
+func compareFields(t *testing.T, name string, got, want []structField) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s has %d fields, want %d: %+v", name, len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i].Name != want[i].Name || got[i].Type != want[i].Type || got[i].Comment != want[i].Comment {
+			t.Errorf("field %d of %s is %+v, want %+v", i, name, got[i], want[i])
+		}
+	}
+}
+
+func TestReadProtoErrors(t *testing.T) {
+	tests := []struct {
+		arg, want string
+	}{
+		{"testdata/proto/map.proto:AddItemRequest", "line 5: the map field of message AddItemRequest isn't supported yet"},
+		{"testdata/proto/oneof.proto:AddItemRequest", "line 5: the oneof payment of message AddItemRequest isn't supported yet"},
+		{"testdata/proto/order.proto:RemoveItemRequest", "doesn't declare the message RemoveItemRequest"},
+		{"testdata/proto/order.proto", "invalid --proto"},
+		{"testdata/proto/missing.proto:AddItemRequest", "missing.proto"},
+	}
+	for _, tt := range tests {
+		_, _, err := readProto(filepath.FromSlash(tt.arg), "AddItem")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("readProto(%s) returned %v, want an error containing %q", tt.arg, err, tt.want)
+		}
+	}
+}
+
+// TestProtoRequestModelGolden compares the RequestModel file generated from the message of the fixture to its
+// golden file, and asserts that a rejected message adds nothing
+func TestProtoRequestModelGolden(t *testing.T) {
+	p := newTestProject(t)
+	p.clean("add", "interactor", "Order")
+	for _, name := range []string{"order.proto", "map.proto"} {
+		b, err := ioutil.ReadFile(filepath.Join("testdata", "proto", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.write("api/"+name, string(b))
+	}
+	p.clean("add", "usecase", "AddItem", "to", "Order", "--proto", "api/order.proto:AddItemRequest")
+	compareGolden(t, "proto_reqmodel", p.read("clean/usecase/reqmodel/order.go"))
+
+	before := concatenated(p.files("clean"))
+	if stdout, stderr, code := p.run("add", "usecase", "RemoveItem", "to", "Order", "--proto", "api/map.proto:AddItemRequest"); code == 0 {
+		t.Errorf("a map field was accepted: %s%s", stdout, stderr)
+	}
+	if concatenated(p.files("clean")) != before {
+		t.Errorf("the rejected usecase changed the project")
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// purgeableDirs returns the folders of the clean folder of the project at projectPath which hold no files,
+// neither directly nor in any of their subfolders, with the deepest folders first. The clean folder itself
+// isn't included, nor are the folders of the layer skeleton the project was initialised with if
+// keepSkeleton is true. Since any file keeps its folder, hand-written files are never purged.
+func purgeableDirs(projectPath string, keepSkeleton bool) ([]string, error) {
+	root := filepath.Join(projectPath, "clean")
+	keep := map[string]bool{root: true}
+	if keepSkeleton {
+		for _, dir := range skeletonDirs() {
+			keep[filepath.Join(projectPath, filepath.FromSlash(dir))] = true
+		}
+	}
+	// hasFiles holds the folders which hold a file in their subtree
+	hasFiles := make(map[string]bool)
+	var dirs []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			dirs = append(dirs, path)
+			return nil
+		}
+		for dir := filepath.Dir(path); strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+			hasFiles[dir] = true
+			if dir == root {
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var purgeable []string
+	for _, dir := range dirs {
+		if !hasFiles[dir] && !keep[dir] {
+			purgeable = append(purgeable, dir)
+		}
+	}
+	// Remove the subfolders before their parents
+	sort.Slice(purgeable, func(i, j int) bool { return purgeable[i] > purgeable[j] })
+	return purgeable, nil
+}
+
+// purge removes the empty folders of the clean folder of the project at projectPath after listing them and
+// asking for confirmation. If dryRun is true they're only listed.
+func purge(projectPath string, keepSkeleton, dryRun bool) error {
+	dirs, err := purgeableDirs(projectPath, keepSkeleton)
+	if err != nil {
+		return err
+	}
+	if len(dirs) == 0 {
+		noopf("there are no empty folders to purge")
+		return nil
+	}
+	for _, dir := range dirs {
+		rel, err := filepath.Rel(projectPath, dir)
+		if err != nil {
+			rel = dir
+		}
+		fmt.Printf("%s\n", filepath.ToSlash(rel))
+	}
+	if dryRun {
+		fmt.Printf("\n%d empty folders would be removed\n", len(dirs))
+		return nil
+	}
+	p := &prompter{in: bufio.NewReader(os.Stdin)}
+	answer, err := p.ask(fmt.Sprintf("\nRemove the %d empty folders? [y/N]", len(dirs)), validOneOf("y", "Y", "n", "N", ""))
+	if err != nil || strings.ToLower(answer) != "y" {
+		fmt.Printf("Cancelled, nothing was removed\n\n")
+		return nil
+	}
+	for _, dir := range dirs {
+		// os.Remove refuses to remove a folder which isn't empty, e.g. if a file was added meanwhile
+		if err := os.Remove(dir); err != nil {
+			return err
+		}
+		changed(dir)
+	}
+	fmt.Printf("Removed %d empty folders\n\n", len(dirs))
+	return nil
+}
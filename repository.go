@@ -0,0 +1,173 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// repositoryFileName is the name of the file of the generic Repository in the gateway folder
+	repositoryFileName = "repository.go"
+	// minGenericsVersion is the minor version of the first Go release with type parameters
+	minGenericsVersion = 18
+)
+
+// genericRepositoryContent is the content, following the package clause, of the file of the generic
+// Repository and its in-memory implementation
+const genericRepositoryContent = `
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrNotFound is returned by a Repository if the entity doesn't exist.
+var ErrNotFound = errors.New("not found")
+
+// Repository is a Clean Architecture Gateway which stores the entities of type T identified by IDs of type ID.
+type Repository[T any, ID comparable] interface {
+	// Get returns the entity identified by id or ErrNotFound if it doesn't exist.
+	Get(ctx context.Context, id ID) (*T, error)
+	// Save adds the entity or replaces the entity with the same ID.
+	Save(ctx context.Context, entity *T) error
+	// Delete removes the entity identified by id. It returns ErrNotFound if it doesn't exist.
+	Delete(ctx context.Context, id ID) error
+	// List returns all of the entities.
+	List(ctx context.Context) ([]*T, error)
+}
+
+// memoryRepository is an in-memory implementation of Repository e.g. for tests.
+type memoryRepository[T any, ID comparable] struct {
+	mu       sync.RWMutex
+	id       func(*T) ID
+	ids      []ID
+	entities map[ID]*T
+}
+
+// NewMemoryRepository constructs a new in-memory Repository. id returns the ID of an entity.
+func NewMemoryRepository[T any, ID comparable](id func(*T) ID) Repository[T, ID] {
+	return &memoryRepository[T, ID]{
+		id:       id,
+		entities: make(map[ID]*T),
+	}
+}
+
+// Get implements the Repository interface method Get.
+func (m *memoryRepository[T, ID]) Get(ctx context.Context, id ID) (*T, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entity, ok := m.entities[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return entity, nil
+}
+
+// Save implements the Repository interface method Save.
+func (m *memoryRepository[T, ID]) Save(ctx context.Context, entity *T) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := m.id(entity)
+	if _, ok := m.entities[id]; !ok {
+		m.ids = append(m.ids, id)
+	}
+	m.entities[id] = entity
+	return nil
+}
+
+// Delete implements the Repository interface method Delete.
+func (m *memoryRepository[T, ID]) Delete(ctx context.Context, id ID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.entities[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.entities, id)
+	for i, v := range m.ids {
+		if v == id {
+			m.ids = append(m.ids[:i], m.ids[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// List implements the Repository interface method List. The entities are listed in the order they were first saved.
+func (m *memoryRepository[T, ID]) List(ctx context.Context) ([]*T, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entities := make([]*T, 0, len(m.ids))
+	for _, id := range m.ids {
+		entities = append(entities, m.entities[id])
+	}
+	return entities, nil
+}
+`
+
+// requireGoVersion returns an error if the go.mod file of the project at projectPath declares an older
+// Go version than 1.[minor], which is required by what e.g. "generic repositories"
+func requireGoVersion(projectPath string, minor int, what string) error {
+	v, err := goModGoVersion(projectPath)
+	if err != nil {
+		return fmt.Errorf("%s require Go 1.%d or later, but the Go version of the project is unknown: %s", what, minor, err.Error())
+	}
+	if v < minor {
+		return fmt.Errorf("%s require Go 1.%d or later, but the go.mod file of the project declares go 1.%d", what, minor, v)
+	}
+	return nil
+}
+
+// addGenericRepository adds the generic Repository interface and its in-memory implementation to the
+// gateway folder of the project at projectPath
+func addGenericRepository(projectPath, basePath string) error {
+	if err := requireGoVersion(projectPath, minGenericsVersion, "generic repositories"); err != nil {
+		return err
+	}
+	fp := filepath.FromSlash(basePath + relPathGateway + repositoryFileName)
+	if fileExists(fp) {
+		noopf("the generic repository already exists")
+		return nil
+	}
+	if err := mkdirAll(filepath.Dir(fp)); err != nil {
+		return err
+	}
+	return writeFile(fp, []byte(packageClause(basePath+relPathGateway, objGateway)+genericRepositoryContent))
+}
+
+// addRepository adds the ProductRepository Gateway, for the entity Product if name is Product, to the gateway
+// folder. If generic is true it's an alias of the generic Repository, which is added if it doesn't exist.
+// Otherwise it's an interface of its own.
+func addRepository(projectPath, basePath, name string, generic bool) error {
+	entityName := exportedName(name)
+	entityFp := filepath.FromSlash(basePath + relPathEntity + fileName(name) + ".go")
+	if f, err := parseGoFile(entityFp); err != nil || findTypeSpec(f, entityName) == nil {
+		return fmt.Errorf("the entity %s doesn't exist, add it with \"clean add entity %s\"", entityName, entityName)
+	}
+	repoName := entityName + "Repository"
+	fp := filepath.FromSlash(basePath + relPathGateway + fileName(repoName) + ".go")
+	if fileExists(fp) {
+		noopf("the repository %s already exists", repoName)
+		return nil
+	}
+	if generic {
+		if err := addGenericRepository(projectPath, basePath); err != nil {
+			return err
+		}
+	}
+	entityImport := projectBaseImportPath + "clean/" + strings.TrimSuffix(relPathEntity, "/")
+	content := packageClause(basePath+relPathGateway, objGateway)
+	if generic {
+		content += fmt.Sprintf("\n\nimport (\n\t%q\n)\n\n// %s is the Repository of the %s entities, which are identified by string IDs.\ntype %s = Repository[entity.%s, string]\n", entityImport, repoName, entityName, repoName, entityName)
+	} else {
+		content += fmt.Sprintf("\n\nimport (\n\t\"context\"\n\n\t%q\n)\n\n// %s is a Clean Architecture Gateway which stores the %s entities.\ntype %s interface {\n\t// Get returns the %s identified by id.\n\tGet(ctx context.Context, id string) (*entity.%s, error)\n\t// Save adds the %s or replaces the %s with the same ID.\n\tSave(ctx context.Context, %s *entity.%s) error\n\t// Delete removes the %s identified by id.\n\tDelete(ctx context.Context, id string) error\n\t// List returns all of the %s entities.\n\tList(ctx context.Context) ([]*entity.%s, error)\n}\n", entityImport, repoName, entityName, repoName, entityName, entityName, entityName, entityName, unexportedName(entityName), entityName, entityName, entityName, entityName)
+	}
+	if err := mkdirAll(filepath.Dir(fp)); err != nil {
+		return err
+	}
+	return writeFile(fp, []byte(content))
+}
@@ -0,0 +1,229 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	gofmt "go/format"
+	"go/types"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// routesFileName is the name of the file of the controller folder holding the RegisterRoutes function
+	routesFileName = "routes.go"
+	// routesHeader marks the routes file as generated so that it's rewritten as a whole whenever it's updated
+	routesHeader = "// Code generated by clean. DO NOT EDIT.\n\n"
+	// routeDirective precedes the method and path of the route of a controller method in its doc comment
+	// e.g. //clean:route GET /orders
+	routeDirective = "//clean:route "
+	// defaultRouteMethod is the HTTP method of the controller methods without a route directive
+	defaultRouteMethod = "POST"
+)
+
+// routeArgs are the arguments of a controller method call, by the type of the parameter, which a
+// route can pass from the HTTP request it handles
+var routeArgs = map[string]string{
+	"http.ResponseWriter": "w",
+	"*http.Request":       "r",
+	"context.Context":     "r.Context()",
+}
+
+// route is the HTTP route of a controller method
+type route struct {
+	method string
+	path   string
+	// param is the name of the RegisterRoutes parameter of the controller
+	param string
+	// call is the call of the controller method e.g. orderHandler.AddItem(w, r)
+	call string
+	// name is the name of the controller method e.g. OrderHandler.AddItem
+	name string
+}
+
+// kebabCase returns name in lower case with its words separated by '-' e.g. AddItem becomes add-item
+func kebabCase(name string) string {
+	return strings.ToLower(strings.Join(splitWords(name), "-"))
+}
+
+// controllerRoutes returns the routes of the methods of the controllers of the project at basePath and the
+// controller interfaces they belong to. A method is routed as in the route directive of its doc comment e.g.
+// //clean:route GET /orders and otherwise as POST /order/add-item for the method AddItem of Order.
+func controllerRoutes(basePath string) ([]route, []string, error) {
+	dir := filepath.FromSlash(basePath + relPathController)
+	files := make(map[string]bool)
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, fi := range fis {
+		files[filepath.Join(dir, fi.Name())] = !fi.IsDir()
+	}
+	// Include the controllers written while --stdout is set
+	for _, fp := range pendingOrder {
+		if filepath.Dir(fp) == dir {
+			files[fp] = true
+		}
+	}
+	var paths []string
+	for fp, isFile := range files {
+		if isFile && filepath.Ext(fp) == ".go" && filepath.Base(fp) != routesFileName && filepath.Base(fp) != docFileName {
+			paths = append(paths, fp)
+		}
+	}
+	sort.Strings(paths)
+	var routes []route
+	var controllers []string
+	for _, fp := range paths {
+		f, err := parseGoFile(fp)
+		if err != nil {
+			return nil, nil, err
+		}
+		base := strings.TrimSuffix(filepath.Base(fp), ".go")
+		for _, ifName := range interfaceNames(f) {
+			objName, ok := objNameFromTypeName(objController, ifName, base)
+			if !ok {
+				continue
+			}
+			controllers = append(controllers, ifName)
+			param := unexportedName(ifName)
+			it := findTypeSpec(f, ifName).Type.(*ast.InterfaceType)
+			for _, m := range it.Methods.List {
+				ft, ok := m.Type.(*ast.FuncType)
+				if !ok {
+					continue
+				}
+				var args []string
+				for _, p := range ft.Params.List {
+					arg, ok := routeArgs[types.ExprString(p.Type)]
+					if !ok {
+						return nil, nil, fmt.Errorf("%s: the parameter of type %s of a %s method can't be passed from an HTTP request", fp, types.ExprString(p.Type), ifName)
+					}
+					n := len(p.Names)
+					if n == 0 {
+						n = 1
+					}
+					for i := 0; i < n; i++ {
+						args = append(args, arg)
+					}
+				}
+				for _, n := range m.Names {
+					rt := route{
+						method: defaultRouteMethod,
+						path:   "/" + kebabCase(objName) + "/" + kebabCase(n.Name),
+						param:  param,
+						call:   fmt.Sprintf("%s.%s(%s)", param, n.Name, strings.Join(args, ", ")),
+						name:   ifName + "." + n.Name,
+					}
+					if m.Doc != nil {
+						for _, c := range m.Doc.List {
+							if !strings.HasPrefix(c.Text, routeDirective) {
+								continue
+							}
+							fields := strings.Fields(strings.TrimPrefix(c.Text, routeDirective))
+							if len(fields) != 2 || !strings.HasPrefix(fields[1], "/") {
+								return nil, nil, fmt.Errorf("%s: the route of %s must be of the form %sMETHOD /path", fp, rt.name, routeDirective)
+							}
+							rt.method, rt.path = strings.ToUpper(fields[0]), fields[1]
+						}
+					}
+					routes = append(routes, rt)
+				}
+			}
+		}
+	}
+	return routes, controllers, nil
+}
+
+// routesSource returns the source of the routes file of the project at basePath, whose RegisterRoutes
+// function registers a handler of each of the routes of the controller methods with a net/http ServeMux
+func routesSource(basePath string) ([]byte, error) {
+	routes, controllers, err := controllerRoutes(basePath)
+	if err != nil {
+		return nil, err
+	}
+	byPath := make(map[string][]route)
+	var paths []string
+	for _, rt := range routes {
+		for _, other := range byPath[rt.path] {
+			if other.method == rt.method {
+				return nil, fmt.Errorf("both %s and %s are routed from %s %s", other.name, rt.name, rt.method, rt.path)
+			}
+		}
+		if _, ok := byPath[rt.path]; !ok {
+			paths = append(paths, rt.path)
+		}
+		byPath[rt.path] = append(byPath[rt.path], rt)
+	}
+	sort.Strings(paths)
+	params := []string{"mux *http.ServeMux"}
+	for _, c := range controllers {
+		params = append(params, unexportedName(c)+" "+c)
+	}
+	var b bytes.Buffer
+	b.WriteString(routesHeader)
+	b.WriteString(packageClause(basePath+relPathController, objController))
+	b.WriteString("\n\nimport (\n\t\"net/http\"\n)\n")
+	b.WriteString("\n// RegisterRoutes registers a handler of each of the routes of the controller methods with mux. A method is\n// routed as in the //clean:route directive of its doc comment, e.g. //clean:route GET /orders, and otherwise\n// as POST /[interactor]/[method]. A request of a method the path has no route of is answered with status 405.\n")
+	if len(routes) > 0 {
+		b.WriteString("//\n")
+		for _, p := range paths {
+			for _, rt := range byPath[p] {
+				fmt.Fprintf(&b, "//\t%s %s -> %s\n", rt.method, rt.path, rt.name)
+			}
+		}
+	}
+	fmt.Fprintf(&b, "func RegisterRoutes(%s) {\n", strings.Join(params, ", "))
+	for _, p := range paths {
+		fmt.Fprintf(&b, "\tmux.HandleFunc(%q, func(w http.ResponseWriter, r *http.Request) {\n\t\tswitch r.Method {\n", p)
+		var allow []string
+		for _, rt := range byPath[p] {
+			fmt.Fprintf(&b, "\t\tcase %q:\n\t\t\t%s\n", rt.method, rt.call)
+			allow = append(allow, rt.method)
+		}
+		fmt.Fprintf(&b, "\t\tdefault:\n\t\t\tw.Header().Set(\"Allow\", %q)\n\t\t\thttp.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)\n\t\t}\n\t})\n", strings.Join(allow, ", "))
+	}
+	b.WriteString("}\n")
+	return gofmt.Source(b.Bytes())
+}
+
+// addRoutes adds the routes file holding the RegisterRoutes function to the controller folder of the project
+// at basePath. Once added it's kept up to date by updateRoutes.
+func addRoutes(basePath string) error {
+	fp := filepath.FromSlash(basePath + relPathController + routesFileName)
+	if fileExists(fp) {
+		noopf("the routes already exist")
+		return nil
+	}
+	src, err := routesSource(basePath)
+	if err != nil {
+		return err
+	}
+	return writeFile(fp, src)
+}
+
+// updateRoutes regenerates the routes file of the project at basePath, if it has one, so that it routes
+// the current controller methods. Nothing is done if the command failed.
+func updateRoutes(basePath string) {
+	fp := filepath.FromSlash(basePath + relPathController + routesFileName)
+	if _, pending := pendingFiles[fp]; len(errorMessages) > 0 || (!pending && !fileExists(fp)) {
+		return
+	}
+	src, err := routesSource(basePath)
+	if err != nil {
+		failf("Error updating the routes: %s\n\n", err.Error())
+		return
+	}
+	if old, err := readFile(fp); err == nil && bytes.Equal(old, src) {
+		return
+	}
+	if err := rewriteFile(fp, src); err != nil {
+		failf("Error updating the routes: %s\n\n", err.Error())
+	}
+}
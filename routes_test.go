@@ -0,0 +1,32 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestRegisterRoutesGolden compares the routes file, regenerated after a route directive was added to a
+// controller method and another usecase was added, to its golden file
+func TestRegisterRoutesGolden(t *testing.T) {
+	p := newTestProject(t)
+	p.clean("add", "interactor", "Order")
+	p.clean("add", "interactor", "Cart")
+	p.clean("add", "usecase", "AddItem", "to", "Order")
+	p.clean("add", "usecase", "GetItem", "to", "Order")
+	p.clean("add", "routes")
+
+	const fp = "clean/ifadapter/controller/order.go"
+	src := p.read(fp)
+	if !strings.Contains(src, "\tGetItem()\n") {
+		t.Fatalf("%s doesn't declare GetItem():\n%s", fp, src)
+	}
+	p.write(fp, strings.Replace(src, "\tGetItem()\n", "\t//clean:route GET /orders/item\n\tGetItem()\n", 1))
+	// The file is regenerated as a whole, so hand edits are dropped
+	p.write("clean/ifadapter/controller/routes.go", p.read("clean/ifadapter/controller/routes.go")+"\n// edited by hand\n")
+	p.clean("add", "usecase", "Checkout", "to", "Cart")
+
+	compareGolden(t, "routes", p.read("clean/ifadapter/controller/routes.go"))
+}
@@ -0,0 +1,142 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// sagaField is the field of the ErrVal ResponseModel of a usecase run as a saga which holds the state the saga
+// was left in if one of its steps failed
+const sagaField = "Saga"
+
+// sagaStep is a step of the saga of a usecase and the Gateway it calls, if any
+type sagaStep struct {
+	Name    string
+	Gateway string
+	// relPath is the folder of the interface of the Gateway
+	relPath string
+}
+
+// sagaSteps returns the steps of --saga, e.g. ReserveStock,ChargePayment:Payment. It returns an error if a step
+// isn't a valid name, if a step is given twice or if the Gateway named after the colon of a step isn't declared
+// in either of the gateway folders of the project at basePath.
+func sagaSteps(basePath string) ([]sagaStep, error) {
+	if strings.TrimSpace(*saga) == "" {
+		return nil, nil
+	}
+	var steps []sagaStep
+	seen := make(map[string]bool)
+	for _, s := range strings.Split(*saga, ",") {
+		pieces := strings.SplitN(strings.TrimSpace(s), ":", 2)
+		step := sagaStep{Name: exportedName(pieces[0])}
+		if err := checkName(pieces[0]); err != nil {
+			return nil, fmt.Errorf("invalid step of --saga: %s", err.Error())
+		}
+		if seen[step.Name] {
+			return nil, fmt.Errorf("the step %s is given twice in --saga", step.Name)
+		}
+		seen[step.Name] = true
+		if len(pieces) == 2 {
+			if err := checkName(pieces[1]); err != nil {
+				return nil, fmt.Errorf("invalid Gateway of the step %s of --saga: %s", step.Name, err.Error())
+			}
+			step.Gateway = pieces[1]
+			for _, relPath := range []string{relPathGatewayPort, relPathGateway} {
+				f, err := parseGoFile(filepath.FromSlash(basePath + relPath + fileName(step.Gateway) + ".go"))
+				if err == nil && findTypeSpec(f, typeName(objGateway, step.Gateway)) != nil {
+					step.relPath = relPath
+					break
+				}
+			}
+			if step.relPath == "" {
+				return nil, fmt.Errorf("the Gateway %s of the step %s of --saga doesn't exist, add it with clean add gateway %s", typeName(objGateway, step.Gateway), step.Name, exportedName(step.Gateway))
+			}
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// sagaStateName returns the name of the ResponseModel of the state the saga of the usecase was left in
+func sagaStateName(usecase string) string {
+	return exportedName(usecase) + "SagaState"
+}
+
+// sagaGatewayField returns the name of the field and constructor parameter of the Interactor holding the
+// Gateway of a step
+func sagaGatewayField(step sagaStep) string {
+	return unexportedName(typeName(objGateway, step.Gateway))
+}
+
+// sagaBody returns the statements of the method of the usecase which run the steps of the saga in order. If a
+// step fails, the steps completed before it are compensated in reverse order and the ErrVal ResponseModel is
+// presented with the state the saga was left in. The Interactor implementation is referred to by self and the
+// usecase returns the ResponseModel rsm with ret.
+func sagaBody(self, usecase string, steps []sagaStep, ret string) string {
+	v := exportedName(usecase)
+	body := "\t// Run the steps of the saga in order. If a step fails, the steps completed before it are compensated\n\t// in reverse order.\n"
+	body += "\tsteps := []struct {\n\t\tname       string\n\t\trun        func() error\n\t\tcompensate func() error\n\t}{\n"
+	for _, step := range steps {
+		run := fmt.Sprintf("// TODO: Run the step %s", step.Name)
+		if step.Gateway != "" {
+			run += fmt.Sprintf(" with %s.%s", self, sagaGatewayField(step))
+		}
+		body += fmt.Sprintf("\t\t{\n\t\t\tname: %q,\n\t\t\trun: func() error {\n\t\t\t\t%s\n\t\t\t\treturn nil\n\t\t\t},\n\t\t\tcompensate: func() error {\n\t\t\t\t// TODO: Undo the step %s\n\t\t\t\treturn nil\n\t\t\t},\n\t\t},\n", step.Name, run, step.Name)
+	}
+	body += "\t}\n"
+	// The loop variables mustn't shadow the receiver e.g. i of an Inventory
+	i, j := "i", "j"
+	switch self {
+	case i:
+		i = "k"
+	case j:
+		j = "k"
+	}
+	body += fmt.Sprintf("\tfor %s, step := range steps {\n\t\tif err := step.run(); err != nil {\n\t\t\trsm := &respmodel.%sErrVal{%s: &respmodel.%s{FailedStep: step.name, Err: err.Error()}}\n", i, v, sagaField, sagaStateName(v))
+	body += fmt.Sprintf("\t\t\tfor %s := %s - 1; %s >= 0; %s-- {\n\t\t\t\tif err := steps[%s].compensate(); err != nil {\n\t\t\t\t\t// TODO: Retry or alert, the step is still applied\n\t\t\t\t\trsm.%s.Uncompensated = append(rsm.%s.Uncompensated, steps[%s].name)\n\t\t\t\t\tcontinue\n\t\t\t\t}\n\t\t\t\trsm.%s.Compensated = append(rsm.%s.Compensated, steps[%s].name)\n\t\t\t}\n", j, i, j, j, j, sagaField, sagaField, j, sagaField, sagaField, j)
+	body += fmt.Sprintf("\t\t\t%s.ps.Present%sErrVal(rsm)\n\t\t\t%s\n\t\t}\n\t}\n", self, v, ret)
+	return body
+}
+
+// injectSagaGateways adds a field and a constructor parameter of each Gateway of the steps to the Interactor
+// implementation of the interactor in b, the content of fp, unless it already has them
+func injectSagaGateways(b []byte, fp, interactor string, steps []sagaStep) ([]byte, error) {
+	var err error
+	for _, step := range steps {
+		if step.Gateway == "" {
+			continue
+		}
+		if b, err = injectDependency(b, fp, interactor, sagaGatewayField(step), objGateway+"."+typeName(objGateway, step.Gateway), step.relPath); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// addSagaState adds the ResponseModel of the state the saga of the usecase of the interactor of the project at
+// basePath was left in, and a field of it to the usecase's ErrVal
+func addSagaState(basePath, usecase, interactor string) error {
+	fp := modelFile(basePath, relPathRespModel, usecase, interactor)
+	if fp == "" {
+		return nil
+	}
+	v, name := exportedName(usecase), sagaStateName(usecase)
+	err := addStructDecls(fp, []structDecl{{
+		Name: name,
+		Doc:  fmt.Sprintf("// %s is the state the saga of the usecase %s was left in when one of its steps failed.", name, v),
+		Fields: []structField{
+			{Name: "FailedStep", Type: "string", Comment: "the step which failed"},
+			{Name: "Err", Type: "string", Comment: "why the step failed"},
+			{Name: "Compensated", Type: "[]string", Comment: "the steps completed before the failure which were undone, latest first"},
+			{Name: "Uncompensated", Type: "[]string", Comment: "the steps completed before the failure which couldn't be undone"},
+		},
+	}})
+	if err != nil {
+		return err
+	}
+	return addStructFields(fp, v+"ErrVal", []structField{{Name: sagaField, Type: "*" + name, Comment: "the state of the saga if one of its steps failed"}})
+}
@@ -0,0 +1,327 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/token"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// schemaKeywords holds the keywords of the subset of JSON Schema draft-07 which RequestModels are generated
+// from. Annotations such as description are included since they don't constrain the payload.
+var schemaKeywords = map[string]bool{
+	"$id": true, "$schema": true, "default": true, "description": true, "enum": true, "examples": true,
+	"format": true, "items": true, "maxLength": true, "maximum": true, "minLength": true, "minimum": true,
+	"properties": true, "required": true, "title": true, "type": true,
+}
+
+// schemaFormatTags maps the formats of JSON Schema strings to the go-playground/validator tags validating them
+var schemaFormatTags = map[string]string{"email": "email", "hostname": "hostname", "ipv4": "ipv4", "ipv6": "ipv6", "uri": "uri", "uuid": "uuid"}
+
+// schemaNode is an object of a JSON Schema whose keywords are decoded on demand
+type schemaNode map[string]json.RawMessage
+
+// requestSchema is the RequestModel of a usecase generated from a JSON Schema
+type requestSchema struct {
+	// fp is the path of the schema file
+	fp string
+	// fields holds the fields of the RequestModel with their json tags and, with the tags validator,
+	// their validate tags
+	fields []structField
+	// required holds the required fields, which are checked by the hand-written Validate methods
+	required []structField
+	// tags is true if the RequestModels are validated with go-playground/validator
+	tags bool
+}
+
+// readSchema returns the RequestModel described by the object schema of the JSON Schema file fp. The
+// constructs outside the supported subset of draft-07 are reported as warnings, which are prefixed by the
+// JSON pointer of their node, and their fields get the closest Go type. If tags is true the fields get the
+// validate tags of the constraints of the schema.
+func readSchema(fp string, tags bool) (requestSchema, error) {
+	rs := requestSchema{fp: fp, tags: tags}
+	b, err := readFile(fp)
+	if err != nil {
+		return rs, err
+	}
+	var root schemaNode
+	if err := json.Unmarshal(b, &root); err != nil {
+		return rs, fmt.Errorf("%s isn't a JSON Schema: %s", fp, err.Error())
+	}
+	rs.warnUnsupported(root, "#")
+	if types, _ := root.types(); len(types) != 1 || types[0] != "object" {
+		return rs, fmt.Errorf("%s must describe an object", fp)
+	}
+	required := make(map[string]bool)
+	var names []string
+	if raw, ok := root["required"]; ok {
+		if err := json.Unmarshal(raw, &names); err != nil {
+			return rs, fmt.Errorf("%s#/required must be an array of strings", fp)
+		}
+	}
+	for _, name := range names {
+		required[name] = true
+	}
+	props, err := objectKeys(root["properties"])
+	if err != nil {
+		return rs, fmt.Errorf("%s#/properties must be an object", fp)
+	}
+	var properties schemaNode
+	if len(props) > 0 {
+		json.Unmarshal(root["properties"], &properties)
+	}
+	for _, name := range props {
+		ptr := "#/properties/" + escapePointer(name)
+		var node schemaNode
+		if err := json.Unmarshal(properties[name], &node); err != nil {
+			rs.warnf(ptr, "the schema of the property isn't an object, the field is an interface{}")
+			node = schemaNode{}
+		}
+		field := structField{Name: exportedName(name), Type: rs.goType(node, ptr)}
+		if !token.IsIdentifier(field.Name) {
+			rs.warnf(ptr, "the property can't be a field of the RequestModel, it's skipped")
+			continue
+		}
+		tag := fmt.Sprintf("json:%q", name)
+		if tags {
+			tag += fmt.Sprintf(" %s:%q", tagValidatorField, rs.validateTag(node, ptr, required[name]))
+		}
+		if required[name] {
+			rs.required = append(rs.required, field)
+		}
+		field.Type += " `" + tag + "`"
+		rs.fields = append(rs.fields, field)
+	}
+	return rs, nil
+}
+
+// warnf prints a warning about the node at the JSON pointer ptr of the schema
+func (rs requestSchema) warnf(ptr, format string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, "Warning: %s%s: %s\n", rs.fp, ptr, fmt.Sprintf(format, a...))
+}
+
+// warnUnsupported warns about the keywords of node, whose JSON pointer is ptr, that aren't supported
+func (rs requestSchema) warnUnsupported(node schemaNode, ptr string) {
+	var keys []string
+	for k := range node {
+		if !schemaKeywords[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		rs.warnf(ptr+"/"+escapePointer(k), "%s isn't supported, it's ignored", k)
+	}
+}
+
+// types returns the types of node, which are given by either a string or an array of strings
+func (node schemaNode) types() ([]string, error) {
+	raw, ok := node["type"]
+	if !ok {
+		return nil, nil
+	}
+	var t string
+	if err := json.Unmarshal(raw, &t); err == nil {
+		return []string{t}, nil
+	}
+	var ts []string
+	if err := json.Unmarshal(raw, &ts); err != nil {
+		return nil, errors.New("type must be a string or an array of strings")
+	}
+	return ts, nil
+}
+
+// goType returns the Go type of the values described by node, whose JSON pointer is ptr. A type which may
+// also be null is a pointer.
+func (rs requestSchema) goType(node schemaNode, ptr string) string {
+	rs.warnUnsupported(node, ptr)
+	types, err := node.types()
+	if err != nil {
+		rs.warnf(ptr+"/type", "%s, the field is an interface{}", err.Error())
+		return "interface{}"
+	}
+	nullable := false
+	if len(types) == 2 && (types[0] == "null" || types[1] == "null") {
+		nullable = true
+		if types[0] == "null" {
+			types = types[1:]
+		} else {
+			types = types[:1]
+		}
+	}
+	if len(types) != 1 {
+		if len(types) == 0 {
+			rs.warnf(ptr, "the type is missing, the field is an interface{}")
+		} else {
+			rs.warnf(ptr+"/type", "the types %s aren't supported, the field is an interface{}", strings.Join(types, ", "))
+		}
+		return "interface{}"
+	}
+	var t string
+	switch types[0] {
+	case "string":
+		t = "string"
+	case "integer":
+		t = "int"
+	case "number":
+		t = "float64"
+	case "boolean":
+		t = "bool"
+	case "array":
+		var items schemaNode
+		if err := json.Unmarshal(node["items"], &items); err != nil {
+			rs.warnf(ptr, "an array without the schema of its items isn't supported, the field is an []interface{}")
+			return "[]interface{}"
+		}
+		return "[]" + rs.goType(items, ptr+"/items")
+	case "object":
+		rs.warnf(ptr, "nested objects aren't supported, the field is a map[string]interface{}")
+		return "map[string]interface{}"
+	default:
+		rs.warnf(ptr+"/type", "the type %s isn't supported, the field is an interface{}", types[0])
+		return "interface{}"
+	}
+	if nullable {
+		t = "*" + t
+	}
+	return t
+}
+
+// validateTag returns the go-playground/validator tag of the constraints of node, whose JSON pointer is ptr,
+// e.g. required,max=10. The other constraints are only checked if the value is set unless it's required.
+func (rs requestSchema) validateTag(node schemaNode, ptr string, required bool) string {
+	var rules []string
+	number := func(key, rule string) {
+		var n float64
+		if raw, ok := node[key]; ok {
+			if err := json.Unmarshal(raw, &n); err != nil {
+				rs.warnf(ptr+"/"+key, "%s must be a number, it's ignored", key)
+				return
+			}
+			rules = append(rules, rule+"="+strconv.FormatFloat(n, 'f', -1, 64))
+		}
+	}
+	number("minLength", "min")
+	number("maxLength", "max")
+	number("minimum", "gte")
+	number("maximum", "lte")
+	if raw, ok := node["enum"]; ok {
+		var values []interface{}
+		json.Unmarshal(raw, &values)
+		var words []string
+		for _, v := range values {
+			w := fmt.Sprint(v)
+			if strings.ContainsAny(w, " ,|") || v == nil {
+				rs.warnf(ptr+"/enum", "the value %q can't be validated by a oneof tag, the enum is ignored", w)
+				words = nil
+				break
+			}
+			words = append(words, w)
+		}
+		if len(words) > 0 {
+			rules = append(rules, "oneof="+strings.Join(words, " "))
+		}
+	}
+	if raw, ok := node["format"]; ok {
+		var format string
+		json.Unmarshal(raw, &format)
+		if tag, ok := schemaFormatTags[format]; ok {
+			rules = append(rules, tag)
+		} else if format != "date-time" && format != "date" && format != "time" {
+			rs.warnf(ptr+"/format", "the format %s isn't validated", format)
+		}
+	}
+	switch {
+	case required:
+		rules = append([]string{"required"}, rules...)
+	case len(rules) > 0:
+		rules = append([]string{"omitempty"}, rules...)
+	}
+	return strings.Join(rules, ",")
+}
+
+// requiredChecks returns the statements of the hand-written Validate method of the usecase which return its
+// ErrVal ResponseModel if any of the required fields is missing. The fields whose zero values can't be told
+// apart from missing ones get a TODO comment instead.
+func (rs requestSchema) requiredChecks(usecase string) string {
+	if len(rs.required) == 0 {
+		return ""
+	}
+	checks := "\t// Check the fields the schema requires\n"
+	for _, f := range rs.required {
+		cond := ""
+		switch {
+		case f.Type == "string":
+			cond = fmt.Sprintf("rqm.%s == \"\"", f.Name)
+		case strings.HasPrefix(f.Type, "*") || strings.HasPrefix(f.Type, "[]") || strings.HasPrefix(f.Type, "map[") || f.Type == "interface{}":
+			cond = fmt.Sprintf("rqm.%s == nil", f.Name)
+		default:
+			checks += fmt.Sprintf("\t// TODO: Check that %s is set, its zero value can't be told apart from a missing one\n", f.Name)
+			continue
+		}
+		checks += fmt.Sprintf("\tif %s {\n\t\treturn &respmodel.%sErrVal{}\n\t}\n", cond, exportedName(usecase))
+	}
+	return checks + "\n"
+}
+
+// addRequiredChecks inserts the checks of the required fields at the beginning of the hand-written Validate
+// method of the usecase in the Validator file fp unless it already has them
+func (rs requestSchema) addRequiredChecks(fp, usecase string) error {
+	checks := rs.requiredChecks(usecase)
+	if rs.tags || checks == "" {
+		return nil
+	}
+	return prependToMethod(fp, "Validate"+exportedName(usecase), checks)
+}
+
+// withSchema returns the specs with the fields of the RequestModel described by the JSON Schema file fp
+// appended to their fields. See readSchema.
+func withSchema(specs []namedSpec, fp string) ([]namedSpec, error) {
+	rs, err := readSchema(fp, *validatorStyle == validatorTags)
+	if err != nil {
+		return nil, err
+	}
+	for i := range specs {
+		specs[i].Fields = append(specs[i].Fields, rs.fields...)
+		specs[i].Schema = &rs
+	}
+	return specs, nil
+}
+
+// escapePointer escapes the reference token s of a JSON pointer
+func escapePointer(s string) string {
+	return strings.Replace(strings.Replace(s, "~", "~0", -1), "/", "~1", -1)
+}
+
+// objectKeys returns the keys of the JSON object raw in the order they're written. It returns nil if raw
+// is empty.
+func objectKeys(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil, errors.New("not an object")
+	}
+	var keys []string
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, t.(string))
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,174 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const verbSetup = "setup"
+
+// setupAnswers holds the answers given to the questions of clean setup
+type setupAnswers struct {
+	dir        string
+	modulePath string
+	scheme     string
+	mode       string
+	comments   string
+	init       bool
+}
+
+// askSetup asks the questions of clean setup. Each question has a default answer, which is taken by hitting
+// Enter, so that the defaults of all of them make a working setup of the project in the working directory.
+func askSetup(p *prompter, wd string) (setupAnswers, error) {
+	var a setupAnswers
+	var err error
+	fmt.Printf("Answer the questions or hit Enter to take the answer in brackets.\n\n")
+	if a.dir, err = p.askDefault("Project folder", wd, validSetupDir); err != nil {
+		return a, err
+	}
+	if a.dir, err = filepath.Abs(a.dir); err != nil {
+		return a, err
+	}
+	_, modulePath := findModule(a.dir)
+	if modulePath == "" {
+		modulePath = filepath.Base(a.dir)
+	}
+	if a.modulePath, err = p.askDefault("Module path", modulePath, validModulePath); err != nil {
+		return a, err
+	}
+	schemes := []string{schemeClean, schemeHexagonal}
+	if a.scheme, err = p.askDefault("Folder scheme ("+strings.Join(schemes, ", ")+")", schemeClean, validOneOf(schemes...)); err != nil {
+		return a, err
+	}
+	// The flat layout generates all of the layers into the clean folder, so it has no folder scheme
+	modes := []string{layoutHorizontal, layoutVertical, layoutFlat}
+	if a.scheme != schemeClean {
+		modes = modes[:2]
+	}
+	if a.mode, err = p.askDefault("Layout ("+strings.Join(modes, ", ")+")", layoutHorizontal, validOneOf(modes...)); err != nil {
+		return a, err
+	}
+	styles := []string{commentsVerbose, commentsTerse}
+	if a.comments, err = p.askDefault("Comment style of the generated code ("+strings.Join(styles, ", ")+")", commentsVerbose, validOneOf(styles...)); err != nil {
+		return a, err
+	}
+	// Initialising an existing project again would only report that its folders exist
+	initDefault := "yes"
+	if fileExists(filepath.Join(a.dir, "clean")) {
+		initDefault = "no"
+	}
+	answer, err := p.askDefault("Initialise the project now (yes, no)", initDefault, validOneOf("yes", "no"))
+	if err != nil {
+		return a, err
+	}
+	a.init = answer == "yes"
+	return a, nil
+}
+
+// validSetupDir returns an error if dir exists but isn't a folder
+func validSetupDir(dir string) error {
+	if fi, err := os.Stat(dir); err == nil && !fi.IsDir() {
+		return fmt.Errorf("%q isn't a folder", dir)
+	}
+	return nil
+}
+
+// validModulePath returns an error if modulePath can't be the path of a module
+func validModulePath(modulePath string) error {
+	if modulePath == "" || strings.ContainsAny(modulePath, " \t\\") || strings.HasPrefix(modulePath, "/") || strings.HasSuffix(modulePath, "/") {
+		return fmt.Errorf("%q isn't a valid module path, enter e.g. github.com/john/shop", modulePath)
+	}
+	return nil
+}
+
+// setup walks the user through setting up the project: it asks for the project folder, the module path, the
+// folder scheme, the layout and the comment style, writes them to the configuration file at confPath and
+// initialises the project if the user wants to. It refuses to run unless stdin is a terminal, the projects
+// are set up non-interactively with clean init and clean new project.
+func setup(confDir, confPath string) {
+	if !isTerminal(os.Stdin) {
+		failf("clean setup asks questions and must be run in a terminal. Use clean init or clean new project to set up a project non-interactively\n\n")
+		return
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		failf("Error determining current working directory\n")
+		return
+	}
+	stop := cancelOnInterrupt("Cancelled, nothing was set up")
+	a, err := askSetup(&prompter{in: bufio.NewReader(os.Stdin)}, wd)
+	stop()
+	if err != nil {
+		fmt.Printf("Cancelled, nothing was set up\n\n")
+		return
+	}
+	fmt.Printf("\n")
+
+	// The settings are written before initialising the project so that its skeleton follows them
+	if !fileExists(confPath) && !mkdir(confDir) {
+		return
+	}
+	var schemeErr error
+	if err := updateConfig(confPath, func(conf map[string]string) {
+		conf[confKeyDirectory] = filepath.FromSlash(a.dir) + "/"
+		setSetupConfig(conf, a)
+		schemeErr = setFolderScheme(conf, a.scheme)
+	}); err != nil || schemeErr != nil {
+		if err == nil {
+			err = schemeErr
+		}
+		failf("Error writing the config file: %s\n", err.Error())
+		return
+	}
+	if !a.init {
+		fmt.Printf("Configuration written to %s. Run clean init in %s to generate the project\n\n", confPath, a.dir)
+		return
+	}
+
+	// Initialise the project like clean new project does
+	if err := os.MkdirAll(a.dir, 0700); err != nil {
+		failf("Error creating the folder '%s': %s\n", a.dir, err.Error())
+		return
+	}
+	if err := os.Chdir(a.dir); err != nil {
+		failf("Error changing directory to '%s': %s\n", a.dir, err.Error())
+		return
+	}
+	if _, modulePath := findModule(a.dir); modulePath == "" {
+		if err := ioutil.WriteFile(goModFileName, []byte(goModContent(a.modulePath)), 0700); err != nil {
+			failf("Error creating %s: %s\n", goModFileName, err.Error())
+			return
+		}
+	}
+	*folderScheme = a.scheme
+	*flat = a.mode == layoutFlat
+	initProject(confDir, confPath)
+	if len(errorMessages) > 0 {
+		return
+	}
+	// initProject resolves the module path from go.mod, which an existing go.mod may declare differently
+	if err := updateConfig(confPath, func(conf map[string]string) {
+		setSetupConfig(conf, a)
+	}); err != nil {
+		failf("Error writing the config file: %s\n", err.Error())
+	}
+}
+
+// setSetupConfig sets the module path, the layout and the comment style of the answers a in conf. The
+// module path is set explicitly only if it differs from the one of the go.mod file of the project folder.
+func setSetupConfig(conf map[string]string, a setupAnswers) {
+	if _, modulePath := findModule(a.dir); modulePath == a.modulePath {
+		setModuleConfig(conf, a.dir, "")
+	} else {
+		setModuleConfig(conf, a.dir, a.modulePath)
+	}
+	conf[confKeyLayoutMode] = a.mode
+	conf[confKeyComments] = a.comments
+}
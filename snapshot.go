@@ -0,0 +1,312 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	verbSnapshot = "snapshot"
+	// snapshotsDir is the folder of the .clean folder of the project holding the snapshots of its clean folder
+	snapshotsDir = "snapshots"
+	// snapshotExt is the extension of the files of the snapshots, which are gzipped tar archives
+	snapshotExt = ".tar.gz"
+	// snapshotIDLayout is the layout of the time a snapshot is identified by
+	snapshotIDLayout = "20060102-150405"
+	// defaultSnapshotRetention is the default number of snapshots kept
+	defaultSnapshotRetention = 10
+	// confKeySnapshotRetention is the number of snapshots kept, beyond which the oldest are removed
+	confKeySnapshotRetention = "snapshot.retention"
+)
+
+// snapshotRetention returns the number of snapshots to keep set by conf
+func snapshotRetention(conf map[string]string) (int, error) {
+	v, ok := conf[confKeySnapshotRetention]
+	if !ok {
+		return defaultSnapshotRetention, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid %s=%s setting in the configuration file, it must be a positive number of snapshots", confKeySnapshotRetention, v)
+	}
+	return n, nil
+}
+
+// snapshotPath returns the path of the file of the snapshot id of the project at projectPath
+func snapshotPath(projectPath, id string) string {
+	return filepath.Join(filepath.FromSlash(projectPath), historyDir, snapshotsDir, id+snapshotExt)
+}
+
+// snapshots returns the IDs of the snapshots of the project at projectPath from the oldest to the newest
+func snapshots(projectPath string) ([]string, error) {
+	fis, err := ioutil.ReadDir(filepath.Join(filepath.FromSlash(projectPath), historyDir, snapshotsDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, fi := range fis {
+		if !fi.IsDir() && strings.HasSuffix(fi.Name(), snapshotExt) {
+			ids = append(ids, strings.TrimSuffix(fi.Name(), snapshotExt))
+		}
+	}
+	// The IDs start with the time they were taken at, so they sort in the order they were taken in
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// createSnapshot archives the clean folder of the project at projectPath into a new snapshot and removes the
+// oldest snapshots beyond the retention. It returns the ID of the snapshot. Only the regular files and the
+// folders of the clean folder are archived, by their paths relative to the project.
+func createSnapshot(projectPath string, retention int) (string, error) {
+	root := filepath.Join(filepath.FromSlash(projectPath), "clean")
+	if _, err := os.Stat(root); err != nil {
+		return "", err
+	}
+	id := time.Now().UTC().Format(snapshotIDLayout)
+	for n := 2; fileExists(snapshotPath(projectPath, id)); n++ {
+		id = fmt.Sprintf("%s-%d", time.Now().UTC().Format(snapshotIDLayout), n)
+	}
+	fp := snapshotPath(projectPath, id)
+	if err := os.MkdirAll(filepath.Dir(fp), 0700); err != nil {
+		return "", err
+	}
+	f, err := os.Create(fp)
+	if err != nil {
+		return "", err
+	}
+	err = archiveDir(f, filepath.FromSlash(projectPath), root)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(fp)
+		return "", err
+	}
+	ids, err := snapshots(projectPath)
+	if err != nil {
+		return "", err
+	}
+	for len(ids) > retention {
+		if err := os.Remove(snapshotPath(projectPath, ids[0])); err != nil {
+			return "", err
+		}
+		ids = ids[1:]
+	}
+	return id, nil
+}
+
+// archiveDir writes a gzipped tar archive of the folder root, by the paths relative to base, to w
+func archiveDir(w io.Writer, base, root string) error {
+	zw := gzip.NewWriter(w)
+	tw := tar.NewWriter(zw)
+	err := filepath.Walk(root, func(fp string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(base, fp)
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		src, err := os.Open(fp)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// snapshotEntry returns the slash separated path of the entry of a snapshot relative to the project, or an
+// error if the entry isn't a regular file or folder inside the clean folder
+func snapshotEntry(hdr *tar.Header) (string, error) {
+	name := path.Clean(hdr.Name)
+	if name != "clean" && !strings.HasPrefix(name, "clean/") {
+		return "", fmt.Errorf("the entry %s is outside the clean folder", hdr.Name)
+	}
+	if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeDir {
+		return "", fmt.Errorf("the entry %s isn't a regular file or folder", hdr.Name)
+	}
+	return name, nil
+}
+
+// readSnapshot calls fn with each entry of the snapshot id of the project at projectPath, by its path relative
+// to the project, after verifying that the entry belongs to the clean folder
+func readSnapshot(projectPath, id string, fn func(name string, hdr *tar.Header, r io.Reader) error) error {
+	f, err := os.Open(snapshotPath(projectPath, id))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name, err := snapshotEntry(hdr)
+		if err != nil {
+			return err
+		}
+		if err := fn(name, hdr, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// restoreSnapshot replaces the clean folder of the project at projectPath with the snapshot id, or the newest
+// snapshot if id is empty, after asking for confirmation. The current clean folder is snapshotted first, so
+// the restore can be undone by restoring that snapshot.
+func restoreSnapshot(projectPath, id string, retention int) error {
+	ids, err := snapshots(projectPath)
+	if err != nil {
+		return err
+	}
+	if id == "" {
+		if len(ids) == 0 {
+			return fmt.Errorf("there are no snapshots, take one with \"clean snapshot create\"")
+		}
+		id = ids[len(ids)-1]
+	}
+	if !fileExists(snapshotPath(projectPath, id)) {
+		return fmt.Errorf("the snapshot %s doesn't exist, list the snapshots with \"clean snapshot list\"", id)
+	}
+	// Verify the whole snapshot before anything is removed
+	var nFiles int
+	if err := readSnapshot(projectPath, id, func(name string, hdr *tar.Header, r io.Reader) error {
+		if hdr.Typeflag == tar.TypeReg {
+			nFiles++
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("the snapshot %s is invalid: %s", id, err.Error())
+	}
+	p := &prompter{in: bufio.NewReader(os.Stdin)}
+	answer, err := p.ask(fmt.Sprintf("Replace the clean folder with the %d files of the snapshot %s? [y/N]", nFiles, id), validOneOf("y", "Y", "n", "N", ""))
+	if err != nil || strings.ToLower(answer) != "y" {
+		fmt.Printf("Cancelled, nothing was restored\n\n")
+		return nil
+	}
+	root := filepath.Join(filepath.FromSlash(projectPath), "clean")
+	if _, err := os.Stat(root); err == nil {
+		current, err := createSnapshot(projectPath, retention+1)
+		if err != nil {
+			return fmt.Errorf("error taking a snapshot of the current clean folder: %s", err.Error())
+		}
+		fmt.Printf("The current clean folder was saved as the snapshot %s\n", current)
+		if err := os.RemoveAll(root); err != nil {
+			return err
+		}
+	}
+	err = readSnapshot(projectPath, id, func(name string, hdr *tar.Header, r io.Reader) error {
+		fp := filepath.Join(filepath.FromSlash(projectPath), filepath.FromSlash(name))
+		if hdr.Typeflag == tar.TypeDir {
+			return os.MkdirAll(fp, 0755)
+		}
+		if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+			return err
+		}
+		dst, err := os.OpenFile(fp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode).Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(dst, r); err != nil {
+			dst.Close()
+			return err
+		}
+		changed(fp)
+		return dst.Close()
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Restored the snapshot %s\n\n", id)
+	return nil
+}
+
+// runSnapshot runs the snapshot subcommand of args, which are create, list and restore [id], for the project
+// at projectPath
+func runSnapshot(projectPath string, args []string, retention int) error {
+	switch {
+	case len(args) == 1 && args[0] == "create":
+		id, err := createSnapshot(projectPath, retention)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Saved the clean folder as the snapshot %s\n\n", id)
+	case len(args) == 1 && args[0] == "list":
+		ids, err := snapshots(projectPath)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			fmt.Printf("There are no snapshots. Use \"clean snapshot create\" to take one.\n\n")
+			return nil
+		}
+		for _, id := range ids {
+			size := ""
+			if fi, err := os.Stat(snapshotPath(projectPath, id)); err == nil {
+				size = fmt.Sprintf("\t%d bytes", fi.Size())
+			}
+			fmt.Printf("%s%s\n", id, size)
+		}
+		fmt.Printf("\n")
+	case len(args) <= 2 && len(args) > 0 && args[0] == "restore":
+		id := ""
+		if len(args) == 2 {
+			id = args[1]
+		}
+		return restoreSnapshot(projectPath, id, retention)
+	default:
+		usageErrorf(verbSnapshot)
+	}
+	return nil
+}
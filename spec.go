@@ -0,0 +1,211 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	// watchDebounce is how long the spec file must be left unchanged before it's applied
+	// so that rapid successive saves only trigger one regeneration
+	watchDebounce = 500 * time.Millisecond
+)
+
+// specInteractor is an interactor and its usecases as declared in a spec file
+type specInteractor struct {
+	Name     string
+	Usecases []string
+}
+
+// parseSpec parses a spec file declaring the interactors of a project and their usecases.
+// The spec file is a subset of YAML of the form:
+//
+//	interactors:
+//	  Order:
+//	    - AddItem
+//	    - RemoveItem
+//	  Customer: [Register, Unregister]
+//
+// Comments start with a '#'.
+func parseSpec(b []byte) ([]specInteractor, error) {
+	var interactors []specInteractor
+	inInteractors := false
+	for i, line := range strings.Split(string(b), "\n") {
+		if ix := strings.Index(line, "#"); ix != -1 {
+			line = line[:ix]
+		}
+		line = strings.TrimRight(line, " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		lineNo := i + 1
+		indented := line[0] == ' ' || line[0] == '\t'
+		switch {
+		case !indented:
+			if trimmed != "interactors:" {
+				return nil, fmt.Errorf("line %d: unknown key %q, expected \"interactors:\"", lineNo, trimmed)
+			}
+			inInteractors = true
+		case !inInteractors:
+			return nil, fmt.Errorf("line %d: expected \"interactors:\" before %q", lineNo, trimmed)
+		case strings.HasPrefix(trimmed, "- "):
+			if len(interactors) == 0 {
+				return nil, fmt.Errorf("line %d: usecase %q doesn't belong to an interactor", lineNo, trimmed)
+			}
+			last := &interactors[len(interactors)-1]
+			usecase := strings.TrimSpace(trimmed[2:])
+			if err := checkName(usecase); err != nil {
+				return nil, fmt.Errorf("line %d: %s", lineNo, err.Error())
+			}
+			last.Usecases = append(last.Usecases, usecase)
+		default:
+			pieces := strings.SplitN(trimmed, ":", 2)
+			if len(pieces) != 2 || strings.TrimSpace(pieces[0]) == "" {
+				return nil, fmt.Errorf("line %d: expected an interactor name followed by ':', found %q", lineNo, trimmed)
+			}
+			interactor := specInteractor{Name: strings.TrimSpace(pieces[0])}
+			if err := checkName(interactor.Name); err != nil {
+				return nil, fmt.Errorf("line %d: %s", lineNo, err.Error())
+			}
+			if list := strings.TrimSpace(pieces[1]); list != "" {
+				if !strings.HasPrefix(list, "[") || !strings.HasSuffix(list, "]") {
+					return nil, fmt.Errorf("line %d: expected a list of usecases e.g. [AddItem, RemoveItem], found %q", lineNo, list)
+				}
+				for _, usecase := range strings.Split(list[1:len(list)-1], ",") {
+					if usecase = strings.TrimSpace(usecase); usecase != "" {
+						if err := checkName(usecase); err != nil {
+							return nil, fmt.Errorf("line %d: %s", lineNo, err.Error())
+						}
+						interactor.Usecases = append(interactor.Usecases, usecase)
+					}
+				}
+			}
+			interactors = append(interactors, interactor)
+		}
+	}
+	return interactors, nil
+}
+
+// applySpec adds the interactors and usecases declared in the spec file specPath which don't already
+// exist in the project at basePath. Existing objects and methods are never modified. It returns false
+// if there was nothing to add.
+func applySpec(basePath, specPath string) (bool, error) {
+	b, err := ioutil.ReadFile(specPath)
+	if err != nil {
+		return false, err
+	}
+	interactors, err := parseSpec(b)
+	if err != nil {
+		return false, fmt.Errorf("%s: %s", specPath, err.Error())
+	}
+	existing, err := interactorUsecases(basePath)
+	if err != nil {
+		return false, err
+	}
+	changed := false
+	for _, interactor := range interactors {
+		usecases, ok := existing[exportedName(interactor.Name)]
+		if !ok {
+			addInteractor(basePath, interactor.Name, "")
+			fmt.Printf("Added interactor %s\n", interactor.Name)
+			changed = true
+		}
+		exists := make(map[string]bool)
+		for _, usecase := range usecases {
+			exists[usecase] = true
+		}
+		for _, usecase := range interactor.Usecases {
+			if exists[exportedName(usecase)] {
+				continue
+			}
+			addUsecase(basePath, usecase, interactor.Name)
+			exists[exportedName(usecase)] = true
+			fmt.Printf("Added usecase %s to %s\n", exportedName(usecase), interactor.Name)
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+// watchSpec applies the spec file specPath to the project at basePath, whose root folder is projectPath, and
+// then again each time it changes.
+// It watches the folder of the spec file, since editors often save a file by replacing it, and waits for the
+// spec file to be left unchanged for watchDebounce before applying it. It never returns unless the spec file
+// can't be read or watched.
+func watchSpec(projectPath, basePath, specPath string) error {
+	if _, err := os.Stat(specPath); err != nil {
+		return err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	if err := watcher.Add(filepath.Dir(specPath)); err != nil {
+		return err
+	}
+	applyWatchedSpec(projectPath, basePath, specPath)
+	fmt.Printf("Watching %s for changes. Press Ctrl-C to stop.\n\n", filepath.Base(specPath))
+	name := filepath.Clean(specPath)
+	debounce := time.NewTimer(watchDebounce)
+	debounce.Stop()
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			// A removed or renamed spec file is waited for to be created again
+			if filepath.Clean(event.Name) == name && event.Has(fsnotify.Write|fsnotify.Create) {
+				debounce.Reset(watchDebounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Printf("Error watching %s: %s\n", specPath, err.Error())
+		case <-debounce.C:
+			fmt.Printf("%s changed\n", filepath.Base(specPath))
+			applyWatchedSpec(projectPath, basePath, specPath)
+			fmt.Printf("\n")
+		}
+	}
+}
+
+// applyWatchedSpec applies the spec file specPath to the project at basePath and prints the outcome. The
+// routes, decorators, view factories, constructor calls and manifest are updated after a pass that adds
+// anything, like after the apply verb, since watchSpec never returns to run the deferred updates of main.
+func applyWatchedSpec(projectPath, basePath, specPath string) {
+	// The project may have been edited since the last pass, e.g. a usecase implemented
+	resetFileCaches()
+	m, err := loadManifest(projectPath, basePath)
+	if err != nil {
+		fmt.Printf("Error reading the manifest: %s\n", err.Error())
+		return
+	}
+	changed, err := applySpec(basePath, specPath)
+	if err != nil {
+		fmt.Printf("Error applying %s: %s\n", specPath, err.Error())
+	} else if !changed {
+		fmt.Printf("Nothing to add, the project is up to date with %s\n", specPath)
+	}
+	if !changed {
+		return
+	}
+	// In the order of the deferred updates of the other verbs
+	updateInteractorCalls(basePath)
+	updateViewFactories(basePath)
+	updateDecorators(basePath)
+	updateRoutes(basePath)
+	updateManifest(projectPath, basePath, m, commandFlags())
+}
@@ -0,0 +1,52 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestWatchUpdatesRoutesAndManifest asserts that the usecases each pass of clean watch adds are routed and
+// recorded in the manifest while it keeps watching
+func TestWatchUpdatesRoutesAndManifest(t *testing.T) {
+	p := newTestProject(t)
+	p.clean("add", "interactor", "Order")
+	p.clean("add", "routes")
+	p.write("spec.yaml", "interactors:\n  Order: [AddItem]\n")
+
+	cmd := exec.Command(cleanBin, "watch", "spec.yaml")
+	cmd.Dir = p.dir
+	cmd.Env = append(os.Environ(), "HOME="+p.home)
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+	// waitFor waits for the file fp to contain s
+	waitFor := func(fp, s string) {
+		t.Helper()
+		for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+			if b, err := ioutil.ReadFile(p.path(fp)); err == nil && strings.Contains(string(b), s) {
+				return
+			}
+		}
+		t.Fatalf("%s doesn't contain %q", fp, s)
+	}
+	waitFor("clean/ifadapter/controller/routes.go", `"/order/add-item"`)
+	waitFor(".clean/manifest.json", `"AddItem"`)
+
+	p.write("spec.yaml", "interactors:\n  Order: [AddItem, RemoveItem]\n")
+	waitFor("clean/ifadapter/controller/routes.go", `"/order/remove-item"`)
+	waitFor(".clean/manifest.json", `"RemoveItem"`)
+}
@@ -0,0 +1,223 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// usecaseMethods returns the names of the methods an object of type objType has for usecase
+func usecaseMethods(objType, usecase string) []string {
+	switch objType {
+	case objPresenter:
+		return []string{"Present" + usecase, "Present" + usecase + "ErrVal"}
+	case objView:
+		return []string{"Render" + usecase, "Render" + usecase + "ErrVal"}
+	case objValidator:
+		return []string{"Validate" + usecase}
+	}
+	return []string{usecase}
+}
+
+// usecaseModels returns the names of the structs the model folder relPath holds for usecase
+func usecaseModels(relPath, usecase string) []string {
+	if relPath == relPathReqModel {
+		return []string{usecase}
+	}
+	return []string{usecase, usecase + "ErrVal"}
+}
+
+// interactorUsecases returns the names of the interactors of the project at basePath mapped to their usecases
+func interactorUsecases(basePath string) (map[string][]string, error) {
+	defer startPhase(phaseScan)()
+	if verticalLayout {
+		return verticalInteractorUsecases(basePath)
+	}
+	if flatLayout {
+		return flatInteractorUsecases(basePath)
+	}
+	dir := filepath.FromSlash(basePath + relPathInteractor)
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	interactors := make(map[string][]string)
+	for _, fi := range files {
+		if fi.IsDir() || filepath.Ext(fi.Name()) != ".go" {
+			continue
+		}
+		f, err := parseGoFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return nil, err
+		}
+		base := strings.TrimSuffix(fi.Name(), ".go")
+		for _, name := range interfaceNames(f) {
+			objName, ok := objNameFromTypeName(objInteractor, name, base)
+			if !ok {
+				continue
+			}
+			usecases, _ := interfaceMethods(f, name)
+			interactors[objName] = usecases
+		}
+	}
+	return interactors, nil
+}
+
+// printStatus prints, for each interactor of the project at basePath, the objects and models
+// that are missing or lack any of the interactor's usecases.
+func printStatus(basePath string) {
+	if err := verifyTypeSuffixes(basePath); err != nil {
+		failf("%s\n\n", err.Error())
+		return
+	}
+	interactors, err := interactorUsecases(basePath)
+	if err != nil {
+		failf("Error reading the interactors: %s\n", err.Error())
+		return
+	}
+	if len(interactors) == 0 {
+		fmt.Printf("No interactors found. Use \"clean add interactor [name]\" to add one.\n\n")
+		return
+	}
+	names := make([]string, 0, len(interactors))
+	for name := range interactors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		usecases := interactors[name]
+		fmt.Printf("%s (%d usecases)\n", name, len(usecases))
+		for _, objType := range objTypes {
+			fp := objectFilePath(basePath, objType, name)
+			ifName := typeName(objType, name)
+			if b, err := readFile(fp); err == nil {
+				ifName, _ = fileObjNames(b, objType, name)
+			}
+			var missing []string
+			for _, usecase := range usecases {
+				missing = append(missing, usecaseMethods(objType, usecase)...)
+			}
+			fmt.Printf("\t%s\t%s\n", objType, objectStatus(fp, missing, func(f *ast.File) []string {
+				methods, ok := interfaceMethods(f, ifName)
+				if !ok {
+					return nil
+				}
+				return methods
+			}, ifName, func(method string) bool {
+				return followsUsecasePattern(objType, method)
+			}))
+		}
+		// The other Presenters of the interactor must implement every usecase too, see addPresenterImpl
+		for _, presenter := range recordedPresenters(basePath, name) {
+			fp := filepath.FromSlash(basePath + relPathPresenter + fileName(presenter) + ".go")
+			lcName := unexportedName(typeName(objPresenter, presenter))
+			var missing []string
+			for _, usecase := range usecases {
+				missing = append(missing, usecaseMethods(objPresenter, usecase)...)
+			}
+			fmt.Printf("\t%s %s\t%s\n", objPresenter, exportedName(presenter), objectStatus(fp, missing, func(f *ast.File) []string {
+				return implMethods(f, lcName)
+			}, "", func(method string) bool {
+				return followsUsecasePattern(objPresenter, method)
+			}))
+		}
+		if status := mocksStatus(basePath, name); status != "" {
+			fmt.Printf("\tmocks\t%s\n", status)
+		}
+		models := []string{relPathReqModel, relPathRespModel, relPathViewModel}
+		if flatLayout {
+			fmt.Printf("\tmodels\t%s\n", flatModelsStatus(basePath, name, usecases))
+			models = nil
+		}
+		if verticalLayout {
+			// The request and response models are those of the packages of the usecases
+			models = []string{relPathViewModel}
+			for _, usecase := range usecases {
+				fp := filepath.FromSlash(basePath + usecasePackageRelPath(name, usecase) + fileName(usecase) + ".go")
+				fmt.Printf("\tusecase %s\t%s\n", usecase, objectStatus(fp, []string{"Request", "Response", "ResponseErrVal", "Usecase"}, structNames, "", nil))
+			}
+		}
+		for _, relPath := range models {
+			fp := filepath.FromSlash(basePath + relPath + fileName(name) + ".go")
+			var missing []string
+			for _, usecase := range usecases {
+				missing = append(missing, usecaseModels(relPath, usecase)...)
+			}
+			fmt.Printf("\t%s\t%s\n", dirNameFromRelPath(relPath), objectStatus(fp, missing, structNames, "", nil))
+		}
+		fmt.Printf("\n")
+	}
+}
+
+// objectStatus returns a one line status of the file fp which should declare the names in expected.
+// declared returns the names declared in the parsed file. If ifName isn't empty the file must declare
+// the interface ifName. If allowed isn't nil, the declared names which aren't expected are reported
+// as extra unless allowed returns true for them.
+func objectStatus(fp string, expected []string, declared func(f *ast.File) []string, ifName string, allowed func(name string) bool) string {
+	if !fileExists(fp) {
+		if len(expected) == 0 && ifName == "" {
+			return "ok"
+		}
+		return "missing file " + fp
+	}
+	f, err := parseGoFile(fp)
+	if err != nil {
+		return "error " + err.Error()
+	}
+	if ifName != "" {
+		if _, ok := interfaceMethods(f, ifName); !ok {
+			return "missing interface " + ifName
+		}
+	}
+	names := make(map[string]bool)
+	declaredNames := declared(f)
+	for _, n := range declaredNames {
+		names[n] = true
+	}
+	isExpected := make(map[string]bool)
+	var missing, extra []string
+	for _, n := range expected {
+		isExpected[n] = true
+		if !names[n] {
+			missing = append(missing, n)
+		}
+	}
+	if allowed != nil {
+		for _, n := range declaredNames {
+			if !isExpected[n] && !allowed(n) {
+				extra = append(extra, n)
+			}
+		}
+	}
+	var status []string
+	if len(missing) > 0 {
+		status = append(status, "missing "+strings.Join(missing, ", "))
+	}
+	if len(extra) > 0 {
+		status = append(status, "extra "+strings.Join(extra, ", "))
+	}
+	if len(status) == 0 {
+		return "ok"
+	}
+	return strings.Join(status, "; ")
+}
+
+// structNames returns the names of the struct types declared in f
+func structNames(f *ast.File) []string {
+	var names []string
+	ast.Inspect(f, func(n ast.Node) bool {
+		if ts, ok := n.(*ast.TypeSpec); ok {
+			if _, ok := ts.Type.(*ast.StructType); ok {
+				names = append(names, ts.Name.Name)
+			}
+		}
+		return true
+	})
+	return names
+}
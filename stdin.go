@@ -0,0 +1,138 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// stdinName is the name argument which makes clean read the names from stdin
+const stdinName = "-"
+
+// namedSpec is a name read from stdin together with the struct fields following it
+type namedSpec struct {
+	Line   int
+	Name   string
+	Fields []structField
+	// Schema is the JSON Schema the fields of a RequestModel were read from, if any
+	Schema *requestSchema
+	// Types are the structs referenced by the fields, e.g. the nested messages of a protobuf message
+	Types []structDecl
+}
+
+// readNamedSpecs reads one name per line from r. Empty lines and lines starting with a '#' are skipped.
+// A name may be followed by a colon and a comma separated list of fields e.g.
+//
+//	AddItem: ProductID string, Quantity int
+//
+// Each invalid line is returned as an error prefixed with its line number. If allowFields is false
+// lines with fields are invalid.
+func readNamedSpecs(r io.Reader, allowFields bool) ([]namedSpec, []error) {
+	var specs []namedSpec
+	var errs []error
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pieces := strings.SplitN(line, ":", 2)
+		spec := namedSpec{Line: lineNo, Name: strings.TrimSpace(pieces[0])}
+		if !token.IsIdentifier(spec.Name) {
+			errs = append(errs, fmt.Errorf("line %d: %q isn't a valid name", lineNo, spec.Name))
+			continue
+		}
+		if err := checkName(spec.Name); err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %s", lineNo, err.Error()))
+			continue
+		}
+		if len(pieces) == 2 {
+			if !allowFields {
+				errs = append(errs, fmt.Errorf("line %d: %s can't have fields", lineNo, spec.Name))
+				continue
+			}
+			fields, err := parseFieldSpecs(pieces[1])
+			if err != nil {
+				errs = append(errs, fmt.Errorf("line %d: %s", lineNo, err.Error()))
+				continue
+			}
+			spec.Fields = fields
+		}
+		specs = append(specs, spec)
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return specs, errs
+}
+
+// parseFieldSpecs parses a comma separated list of fields of the form "name type"
+func parseFieldSpecs(s string) ([]structField, error) {
+	var fields []structField
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		pieces := strings.Fields(f)
+		if len(pieces) < 2 {
+			return nil, fmt.Errorf("the field %q must be of the form \"name type\"", f)
+		}
+		name, typ := pieces[0], strings.Join(pieces[1:], " ")
+		if !token.IsIdentifier(name) {
+			return nil, fmt.Errorf("%q isn't a valid field name", name)
+		}
+		if _, err := parser.ParseExpr(typ); err != nil {
+			return nil, fmt.Errorf("%q isn't a valid type of the field %s", typ, name)
+		}
+		fields = append(fields, structField{Name: exportedName(name), Type: typ})
+	}
+	return fields, nil
+}
+
+// namedSpecsFromArg returns the names of the objects of objType given by the argument arg. If arg is "-" the
+// names are read from stdin and any invalid lines are printed, in which case none of the names are returned.
+// Otherwise arg, without any .go extension, is the only name. The names are checked by checkNameCase.
+func namedSpecsFromArg(objType, arg string, allowFields bool) []namedSpec {
+	var specs []namedSpec
+	if arg != stdinName {
+		name := strings.TrimSuffix(arg, filepath.Ext(arg))
+		if err := checkName(name); err != nil {
+			failf("%s\n\nNothing was added\n\n", err.Error())
+			return nil
+		}
+		specs = []namedSpec{{Name: name}}
+	} else {
+		var errs []error
+		specs, errs = readNamedSpecs(os.Stdin, allowFields)
+		for _, err := range errs {
+			failf("Error reading stdin: %s\n", err.Error())
+		}
+		if len(errs) > 0 {
+			fmt.Printf("Nothing was added\n\n")
+			return nil
+		}
+	}
+	failed := false
+	for i := range specs {
+		name, err := checkNameCase(objType, specs[i].Name)
+		if err != nil {
+			failf("%s\n", err.Error())
+			failed = true
+		}
+		specs[i].Name = name
+	}
+	if failed {
+		fmt.Printf("\nNothing was added\n\n")
+		return nil
+	}
+	return specs
+}
@@ -0,0 +1,168 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// packageDecls returns the names declared at the package level by the files of the package pkg in dir,
+// including the files written while --stdout is set
+func packageDecls(dir, pkg string) map[string]bool {
+	var paths []string
+	if fis, err := ioutil.ReadDir(dir); err == nil {
+		for _, fi := range fis {
+			paths = append(paths, filepath.Join(dir, fi.Name()))
+		}
+	}
+	for _, fp := range pendingOrder {
+		if filepath.Dir(fp) == filepath.Clean(dir) {
+			paths = append(paths, fp)
+		}
+	}
+	decls := make(map[string]bool)
+	for _, fp := range paths {
+		if filepath.Ext(fp) != ".go" {
+			continue
+		}
+		f, err := parseGoFile(fp)
+		if err != nil || f.Name.Name != pkg {
+			continue
+		}
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Recv == nil {
+					decls[d.Name.Name] = true
+				}
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch s := spec.(type) {
+					case *ast.TypeSpec:
+						decls[s.Name.Name] = true
+					case *ast.ValueSpec:
+						for _, n := range s.Names {
+							decls[n.Name] = true
+						}
+					}
+				}
+			}
+		}
+	}
+	return decls
+}
+
+// unresolvedRefs returns the references of the Go file fp that can't be resolved, each prefixed by its
+// position: identifiers that are declared neither in the package of fp nor in the universe, packages that
+// are referred to but not imported, imports that aren't used and identifiers of the packages of the project
+// at projectPath that those packages don't declare. The types of the expressions aren't checked, so e.g. a
+// missing method of a type isn't found.
+func unresolvedRefs(projectPath, fp string) ([]string, error) {
+	b, err := readFile(fp)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	f, err := parseFile(fset, fp, b, 0)
+	if err != nil {
+		return nil, err
+	}
+	var refs []string
+	report := func(pos token.Pos, format string, a ...interface{}) {
+		p := fset.Position(pos)
+		relFp, err := filepath.Rel(filepath.FromSlash(projectPath), p.Filename)
+		if err != nil {
+			relFp = p.Filename
+		}
+		refs = append(refs, fmt.Sprintf("%s:%d: %s", filepath.ToSlash(relFp), p.Line, fmt.Sprintf(format, a...)))
+	}
+	imports := make(map[string]string)
+	for _, spec := range f.Imports {
+		if name, path := importName(spec); name != "_" && name != "." {
+			imports[name] = path
+		}
+	}
+	// used holds the names of the imports referred to and qualifiers the names of the packages referred to
+	used, qualifiers := make(map[string]bool), make(map[string]bool)
+	// The identifiers of the project packages are looked up in the packages, which are read once
+	pkgDecls := make(map[string]map[string]bool)
+	ast.Inspect(f, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		id, ok := sel.X.(*ast.Ident)
+		if !ok || id.Obj != nil {
+			return true
+		}
+		qualifiers[id.Name] = true
+		path, ok := imports[id.Name]
+		if !ok {
+			return true
+		}
+		used[id.Name] = true
+		if !strings.HasPrefix(path, projectBaseImportPath) {
+			return true
+		}
+		decls, ok := pkgDecls[path]
+		if !ok {
+			decls = packageDecls(filepath.FromSlash(projectPath+strings.TrimPrefix(path, projectBaseImportPath)), path[strings.LastIndex(path, "/")+1:])
+			pkgDecls[path] = decls
+		}
+		if !decls[sel.Sel.Name] {
+			report(sel.Pos(), "undefined: %s.%s", id.Name, sel.Sel.Name)
+		}
+		return true
+	})
+	ownDecls := packageDecls(filepath.Dir(fp), f.Name.Name)
+	for _, id := range f.Unresolved {
+		switch {
+		case used[id.Name] || ownDecls[id.Name] || types.Universe.Lookup(id.Name) != nil:
+		case imports[id.Name] != "":
+			// A package referred to other than by a selector e.g. passed as a value
+			report(id.Pos(), "use of package %s without selector", id.Name)
+		case qualifiers[id.Name]:
+			report(id.Pos(), "undefined: %s, which isn't imported", id.Name)
+		default:
+			report(id.Pos(), "undefined: %s", id.Name)
+		}
+	}
+	for _, spec := range f.Imports {
+		if name, path := importName(spec); name != "_" && name != "." && !used[name] {
+			report(spec.Pos(), "%q imported and not used", path)
+		}
+	}
+	return refs, nil
+}
+
+// verifyResolved fails the command if any of the Go files it changed in the project at projectPath has
+// references that can't be resolved. See unresolvedRefs.
+func verifyResolved(projectPath string) {
+	if len(errorMessages) > 0 {
+		return
+	}
+	var refs []string
+	for _, fp := range changedFiles {
+		if filepath.Ext(fp) != ".go" {
+			continue
+		}
+		fileRefs, err := unresolvedRefs(projectPath, fp)
+		if err != nil {
+			refs = append(refs, fmt.Sprintf("%s: %s", filepath.ToSlash(fp), err.Error()))
+			continue
+		}
+		refs = append(refs, fileRefs...)
+	}
+	if len(refs) > 0 {
+		sort.Strings(refs)
+		failf("The generated code has references that can't be resolved:\n\n\t%s\n\nNothing was written since --strict is set.\n", strings.Join(refs, "\n\t"))
+	}
+}
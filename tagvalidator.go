@@ -0,0 +1,115 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+const (
+	// validatorTags is the --validator style of the Validators backed by go-playground/validator
+	validatorTags = "tags"
+	// tagValidatorImport is the import path of go-playground/validator
+	tagValidatorImport = "github.com/go-playground/validator/v10"
+	// tagValidatorName is the name go-playground/validator is imported as, which doesn't clash with the
+	// validator package of the Validators
+	tagValidatorName = "playvalidator"
+	// tagValidatorField is the field of a Validator holding the go-playground/validator instance
+	tagValidatorField = "validate"
+)
+
+// verifyValidatorStyle returns an error if --validator is set to anything else than tags
+func verifyValidatorStyle() error {
+	if *validatorStyle != "" && *validatorStyle != validatorTags {
+		return fmt.Errorf("invalid validator %q, the only validator is %s", *validatorStyle, validatorTags)
+	}
+	return nil
+}
+
+// tagValidatorMethod returns the Validate method of the usecase v of the Validator lcObjName, which validates
+// the validate tags of the fields of the RequestModel
+func tagValidatorMethod(v, ucObjName, lcObjName, self string) string {
+	return fmt.Sprintf("\n\n// Validate%s implements the %s interface method Validate%s by validating the validate tags of the fields of rqm.\nfunc (%s *%s) Validate%s(rqm *reqmodel.%s) *respmodel.%sErrVal {\n\tif err := %s.%s.Struct(rqm); err != nil {\n\t\t// TODO: Describe the failed validations of err in the ErrVal\n\t\treturn &respmodel.%sErrVal{}\n\t}\n\treturn nil\n}",
+		v, ucObjName, v, self, lcObjName, v, v, v, self, tagValidatorField, v)
+}
+
+// tagValidatorFields returns the fields with a validate tag placeholder e.g. ProductID string `validate:""`,
+// which is valid while it's empty. The fields which already have tags, e.g. the ones read from a JSON
+// Schema, are kept as they are.
+func tagValidatorFields(fields []structField) []structField {
+	var tagged []structField
+	for _, f := range fields {
+		if strings.HasSuffix(f.Type, "`") {
+			tagged = append(tagged, f)
+			continue
+		}
+		tagged = append(tagged, structField{Name: f.Name, Type: f.Type + " `" + tagValidatorField + ":\"\"`", Comment: f.Comment})
+	}
+	return tagged
+}
+
+// injectTagValidator returns the Validator file b with a field holding a go-playground/validator instance
+// added to the Validator lcObjName and initialised by its constructor, unless it already has it
+func injectTagValidator(b []byte, fp, ucObjName, lcObjName string) ([]byte, error) {
+	if bytes.Contains(b, []byte(tagValidatorName+".Validate")) {
+		return b, nil
+	}
+	fset := token.NewFileSet()
+	f, err := parseFile(fset, fp, b, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	type insertion struct {
+		offset int
+		text   string
+	}
+	var insertions []insertion
+	// lineStart returns the offset of the start of the line of pos so that a line can be inserted before it
+	lineStart := func(pos token.Pos) int {
+		p := fset.Position(pos)
+		return p.Offset - p.Column + 1
+	}
+	if ts := findTypeSpec(f, lcObjName); ts != nil {
+		if st, ok := ts.Type.(*ast.StructType); ok && fset.Position(st.Fields.Opening).Line < fset.Position(st.Fields.Closing).Line {
+			insertions = append(insertions, insertion{lineStart(st.Fields.Closing), fmt.Sprintf("\t%s *%s.Validate\n", tagValidatorField, tagValidatorName)})
+		}
+	}
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Recv != nil || fd.Name.Name != "New"+ucObjName || fd.Body == nil {
+			continue
+		}
+		ast.Inspect(fd.Body, func(n ast.Node) bool {
+			cl, ok := n.(*ast.CompositeLit)
+			if !ok {
+				return true
+			}
+			if id, ok := cl.Type.(*ast.Ident); !ok || id.Name != lcObjName {
+				return true
+			}
+			init := fmt.Sprintf("%s: %s.New(),", tagValidatorField, tagValidatorName)
+			if fset.Position(cl.Lbrace).Line == fset.Position(cl.Rbrace).Line {
+				// Break up the literal e.g. &validator{} over multiple lines
+				insertions = append(insertions, insertion{fset.Position(cl.Rbrace).Offset, "\n\t\t" + init + "\n\t"})
+			} else {
+				insertions = append(insertions, insertion{lineStart(cl.Rbrace), "\t\t" + init + "\n"})
+			}
+			return false
+		})
+	}
+	if len(insertions) < 2 {
+		return nil, fmt.Errorf("the %s Validator implementation in %s doesn't have the struct and constructor the go-playground/validator instance is injected into", ucObjName, fp)
+	}
+	sort.Slice(insertions, func(i, j int) bool { return insertions[i].offset > insertions[j].offset })
+	for _, ins := range insertions {
+		b = append(append(append([]byte{}, b[:ins.offset]...), ins.text...), b[ins.offset:]...)
+	}
+	return ensureNamedImport(b, tagValidatorName, tagValidatorImport)
+}
@@ -0,0 +1,487 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// testDecl is a declaration of a test file which is added unless the file already contains its marker
+type testDecl struct {
+	marker  string
+	content string
+}
+
+// addTestDecls adds the imports and the declarations missing from the test file fp, which is created if
+// it doesn't exist. It returns false if all of the declarations already exist.
+func addTestDecls(fp string, imports []string, decls []testDecl) (bool, error) {
+	var b []byte
+	if fileExists(fp) {
+		var err error
+		if b, err = readFile(fp); err != nil {
+			return false, err
+		}
+	} else {
+		// A new file gets its imports in a single declaration, sorted and grouped like gofmt and goimports
+		var paths []string
+		seen := make(map[string]bool)
+		for _, path := range imports {
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+		b = []byte("// Package test provides ...\npackage test" + importDecl(paths) + "\n")
+	}
+	var added string
+	for _, d := range decls {
+		if !bytes.Contains(b, []byte(d.marker)) && !strings.Contains(added, d.marker) {
+			added += "\n\n" + d.content
+		}
+	}
+	if added == "" {
+		return false, nil
+	}
+	var err error
+	for _, path := range imports {
+		if b, err = ensureImport(b, path); err != nil {
+			return false, err
+		}
+	}
+	if err := mkdirAll(filepath.Dir(fp)); err != nil {
+		return false, err
+	}
+	return true, writeFile(fp, append(append(bytes.TrimRight(b, "\n"), added...), '\n'))
+}
+
+// constructorArgs returns the arguments of a call to the constructor fn declared in the Go file fp. The
+// arguments are looked up in args by the package of the type of the parameters e.g. presenter, and
+// any other arguments are nil.
+func constructorArgs(fp, fn string, args map[string]string) ([]string, error) {
+	f, err := parseGoFile(fp)
+	if err != nil {
+		return nil, err
+	}
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Recv != nil || fd.Name.Name != fn {
+			continue
+		}
+		var callArgs []string
+		for _, param := range fd.Type.Params.List {
+			typ := types.ExprString(param.Type)
+			arg, ok := args[strings.SplitN(strings.TrimPrefix(typ, "*"), ".", 2)[0]]
+			if !ok {
+				arg = "nil /* TODO: Pass a " + typ + " */"
+			}
+			for range param.Names {
+				callArgs = append(callArgs, arg)
+			}
+		}
+		return callArgs, nil
+	}
+	return nil, fmt.Errorf("the constructor %s isn't declared in %s", fn, fp)
+}
+
+// interactorConstructorArgs returns the arguments of a call to the constructor of the Interactor of the interactor
+// in a test, which passes ps as the Presenter, and the imports of the packages the arguments refer to
+func interactorConstructorArgs(basePath, interactor, ps string) ([]string, []string, error) {
+	importPath := projectBaseImportPath + "clean/"
+	args, err := constructorArgs(filepath.FromSlash(basePath+relPathInteractor+fileName(interactor)+".go"), "New"+typeName(objInteractor, interactor), map[string]string{
+		"presenter": ps,
+		"validator": "validator.New" + typeName(objValidator, interactor) + "()",
+		"event":     "event.NewNoop" + eventsName(interactor) + "()",
+		"outbox":    "outbox.NewNoopOutbox()",
+		"auth":      "auth.NewAllowAll()",
+		"clock":     fakeClockArg,
+		"idgen":     fakeIDGenArg,
+		"metrics":   fmt.Sprintf("metrics.New(prometheus.NewRegistry(), %q)", exportedName(interactor)),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return args, append([]string{importPath + strings.TrimSuffix(relPathValidator, "/")}, constructorArgImports(args)...), nil
+}
+
+// constructorArgImports returns the imports of the packages the arguments of interactorConstructorArgs refer to,
+// apart from the Validator
+func constructorArgImports(args []string) []string {
+	importPath := projectBaseImportPath + "clean/"
+	var imports []string
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, objEvent+"."):
+			imports = append(imports, importPath+strings.TrimSuffix(relPathEvent, "/"))
+		case strings.HasPrefix(arg, objOutbox+"."):
+			imports = append(imports, importPath+strings.TrimSuffix(relPathOutbox, "/"))
+		case strings.HasPrefix(arg, objAuth+"."):
+			imports = append(imports, importPath+strings.TrimSuffix(relPathAuth, "/"))
+		case arg == fakeClockArg:
+			imports = append(imports, "time", importPath+strings.TrimSuffix(relPathClock, "/"))
+		case arg == fakeIDGenArg:
+			imports = append(imports, importPath+strings.TrimSuffix(relPathIDGen, "/"))
+		case strings.HasPrefix(arg, objMetrics+"."):
+			imports = append(imports, prometheusImport, importPath+strings.TrimSuffix(relPathMetrics, "/"))
+		}
+	}
+	return imports
+}
+
+// updateInteractorCalls appends the arguments missing from the calls to the constructors of the Interactors in
+// the Go files of the project at basePath, e.g. the generated tests, benchmarks and integration tests, once a
+// dependency has been injected into the Interactor. The arguments already passed are kept. A test whose
+// constructor gets an argument it can't construct is skipped with a TODO. Nothing is done if the command failed.
+func updateInteractorCalls(basePath string) {
+	if len(errorMessages) > 0 || !fileExists(filepath.FromSlash(basePath+relPathInteractor)) {
+		return
+	}
+	interactors, err := interactorUsecases(basePath)
+	if err != nil {
+		return
+	}
+	ctorArgs := make(map[string][]string)
+	for name := range interactors {
+		if args, _, err := interactorConstructorArgs(basePath, name, "ps"); err == nil {
+			ctorArgs["New"+typeName(objInteractor, name)] = args
+		}
+	}
+	var paths []string
+	seen := make(map[string]bool)
+	filepath.Walk(filepath.FromSlash(basePath), func(fp string, fi os.FileInfo, err error) error {
+		if err == nil && !fi.IsDir() && filepath.Ext(fp) == ".go" {
+			paths = append(paths, fp)
+			seen[fp] = true
+		}
+		return nil
+	})
+	// The files kept in memory by --stdout aren't on disk yet
+	for _, fp := range pendingOrder {
+		if !seen[fp] && filepath.Ext(fp) == ".go" && strings.HasPrefix(filepath.ToSlash(fp), basePath) {
+			paths = append(paths, fp)
+		}
+	}
+	sort.Strings(paths)
+	for _, fp := range paths {
+		if err := updateConstructorCalls(fp, ctorArgs); err != nil {
+			failf("Error updating the calls of the Interactor constructors in %s: %s\n\n", fp, err.Error())
+		}
+	}
+}
+
+// updateConstructorCalls appends the arguments missing from the calls to the Interactor constructors in the Go
+// file fp, whose arguments ctorArgs holds keyed by the constructor e.g. NewOrder, and imports the packages the
+// appended arguments refer to
+func updateConstructorCalls(fp string, ctorArgs map[string][]string) error {
+	b, err := readFile(fp)
+	if err != nil {
+		return err
+	}
+	if !bytes.Contains(b, []byte("interactor.New")) {
+		return nil
+	}
+	fset := token.NewFileSet()
+	f, err := parseFile(fset, fp, b, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	type insertion struct {
+		offset int
+		text   string
+	}
+	var insertions []insertion
+	var imports []string
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Body == nil {
+			continue
+		}
+		skipped := bytes.Contains(b[fset.Position(fd.Body.Lbrace).Offset:fset.Position(fd.Body.Rbrace).Offset], []byte(".Skip("))
+		ast.Inspect(fd.Body, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || call.Ellipsis.IsValid() {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			args, ok := ctorArgs[sel.Sel.Name]
+			if pkg, isIdent := sel.X.(*ast.Ident); !ok || !isIdent || pkg.Name != objInteractor || len(call.Args) >= len(args) {
+				return true
+			}
+			missing := args[len(call.Args):]
+			offset, sep := fset.Position(call.Rparen).Offset, ""
+			if len(call.Args) > 0 {
+				offset, sep = fset.Position(call.Args[len(call.Args)-1].End()).Offset, ", "
+			}
+			insertions = append(insertions, insertion{offset, sep + strings.Join(missing, ", ")})
+			imports = append(imports, constructorArgImports(missing)...)
+			for _, arg := range missing {
+				// The constructors fail for the arguments the test can't construct
+				if tb := testingParam(fd); strings.HasPrefix(arg, "nil") && tb != "" && !skipped {
+					insertions = append(insertions, insertion{fset.Position(fd.Body.Lbrace).Offset + 1, fmt.Sprintf("\n\t%s.Skip(\"TODO: Pass the arguments of %s.%s\")", tb, objInteractor, sel.Sel.Name)})
+					skipped = true
+				}
+			}
+			return true
+		})
+	}
+	if len(insertions) == 0 {
+		return nil
+	}
+	sort.Slice(insertions, func(i, j int) bool { return insertions[i].offset > insertions[j].offset })
+	for _, ins := range insertions {
+		b = append(append(append([]byte{}, b[:ins.offset]...), ins.text...), b[ins.offset:]...)
+	}
+	for _, path := range imports {
+		if b, err = ensureImport(b, path); err != nil {
+			return err
+		}
+	}
+	return writeFile(fp, b)
+}
+
+// testingParam returns the name of the *testing.T or *testing.B parameter of the test or benchmark fd, or an
+// empty string if it has none
+func testingParam(fd *ast.FuncDecl) string {
+	for _, param := range fd.Type.Params.List {
+		if typ := types.ExprString(param.Type); (typ == "*testing.T" || typ == "*testing.B") && len(param.Names) > 0 {
+			return param.Names[0].Name
+		}
+	}
+	return ""
+}
+
+// addUsecaseBenchmark adds a benchmark of the Interactor method of the usecase to the benchmark file of the
+// interactor in the test folder of the Interactor. The Interactor presents to a Presenter which discards the
+// ResponseModels, so the benchmark compiles and runs while the method is still a stub.
+func addUsecaseBenchmark(basePath, usecase, interactor string) error {
+	if !selected(objInteractor) || !fileExists(filepath.FromSlash(basePath+relPathInteractor+fileName(interactor)+".go")) {
+		return nil
+	}
+	v := exportedName(usecase)
+	importPath := projectBaseImportPath + "clean/"
+	itName, psName := typeName(objInteractor, interactor), typeName(objPresenter, interactor)
+	discardPs := "discard" + psName + "Presenter"
+	if typeSuffixes[objPresenter] != "" {
+		discardPs = "discard" + psName
+	}
+	args, argImports, err := interactorConstructorArgs(basePath, interactor, "&"+discardPs+"{}")
+	if err != nil {
+		return err
+	}
+	// The constructor fails for the arguments the benchmark can't construct
+	var skip string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "nil") {
+			skip = fmt.Sprintf("\tb.Skip(\"TODO: Pass the arguments of New%s\")\n", itName)
+		}
+	}
+	ok, err := addTestDecls(filepath.FromSlash(basePath+relPathInteractor+"test/"+fileName(interactor)+"_bench_test.go"),
+		append([]string{"testing", importPath + strings.TrimSuffix(relPathPresenter, "/"), importPath + strings.TrimSuffix(relPathInteractor, "/"), importPath + strings.TrimSuffix(relPathReqModel, "/"), importPath + strings.TrimSuffix(relPathRespModel, "/")}, argImports...),
+		[]testDecl{
+			{fmt.Sprintf("type %s struct", discardPs), fmt.Sprintf("// %s is a %s Presenter which discards the ResponseModels so that the benchmarks measure the\n// Interactor. Calling any of its methods that isn't implemented below panics.\ntype %s struct {\n\tpresenter.%s\n}", discardPs, psName, discardPs, psName)},
+			{fmt.Sprintf(") Present%s(", v), fmt.Sprintf("// Present%s discards rsm.\nfunc (%s) Present%s(rsm *respmodel.%s) {}\n\n// Present%sErrVal discards rsm.\nfunc (%s) Present%sErrVal(rsm *respmodel.%sErrVal) {}", v, discardPs, v, v, v, discardPs, v, v)},
+			{fmt.Sprintf("func Benchmark%s_%s(", itName, v), fmt.Sprintf("// Benchmark%s_%s benchmarks the %s Interactor method %s.\nfunc Benchmark%s_%s(b *testing.B) {\n%s\tit, err := interactor.New%s(%s)\n\tif err != nil {\n\t\tb.Fatal(err)\n\t}\n\trqm := &reqmodel.%s{\n\t\t// TODO: Set the fields of the RequestModel of the path to measure\n\t}\n\tb.ResetTimer()\n\tfor i := 0; i < b.N; i++ {\n\t\tit.%s(rqm)\n\t}\n}", itName, v, itName, v, itName, v, skip, itName, strings.Join(args, ", "), v, v)},
+		})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		noopf("the benchmark of the usecase %s already exists in %s", v, exportedName(interactor))
+	}
+	return nil
+}
+
+// addUsecaseTests adds the tests of the usecase to the test files of the interactor's Interactor and
+// Validator and, if --golden is set, a golden file test to the test file of its Presenter. The tests are
+// skipped until they're implemented.
+func addUsecaseTests(basePath, usecase, interactor string) error {
+	v := exportedName(usecase)
+	importPath := projectBaseImportPath + "clean/"
+	itName, psName, valName, vwName := typeName(objInteractor, interactor), typeName(objPresenter, interactor), typeName(objValidator, interactor), typeName(objView, interactor)
+	fakePs, fakeVw := "fake"+psName+"Presenter", "fake"+vwName+"View"
+	if typeSuffixes[objPresenter] != "" {
+		fakePs = "fake" + psName
+	}
+	if typeSuffixes[objView] != "" {
+		fakeVw = "fake" + vwName
+	}
+	added := false
+
+	if selected(objInteractor) && fileExists(filepath.FromSlash(basePath+relPathInteractor+fileName(interactor)+".go")) {
+		args, argImports, err := interactorConstructorArgs(basePath, interactor, "ps")
+		if err != nil {
+			return err
+		}
+		imports := append([]string{"testing", importPath + strings.TrimSuffix(relPathPresenter, "/"), importPath + strings.TrimSuffix(relPathInteractor, "/"), importPath + strings.TrimSuffix(relPathReqModel, "/"), importPath + strings.TrimSuffix(relPathRespModel, "/")}, argImports...)
+		ok, err := addTestDecls(filepath.FromSlash(basePath+relPathInteractor+"test/"+fileName(interactor)+"_test.go"),
+			imports,
+			[]testDecl{
+				{fmt.Sprintf("type %s struct", fakePs), fmt.Sprintf("// %s is a fake of the %s Presenter which records the ResponseModels it's called with.\n// Calling any of its methods that isn't implemented below panics.\ntype %s struct {\n\tpresenter.%s\n\tcalls []interface{}\n}", fakePs, psName, fakePs, psName)},
+				{fmt.Sprintf(") Present%s(", v), fmt.Sprintf("// Present%s records rsm.\nfunc (f *%s) Present%s(rsm *respmodel.%s) {\n\tf.calls = append(f.calls, rsm)\n}\n\n// Present%sErrVal records rsm.\nfunc (f *%s) Present%sErrVal(rsm *respmodel.%sErrVal) {\n\tf.calls = append(f.calls, rsm)\n}", v, fakePs, v, v, v, fakePs, v, v)},
+				{fmt.Sprintf("func Test%s_%s(", itName, v), fmt.Sprintf("// Test%s_%s tests the %s Interactor method %s.\nfunc Test%s_%s(t *testing.T) {\n\tt.Skip(\"TODO: Implement the test of the usecase %s\")\n\tps := &%s{}\n\tit, err := interactor.New%s(%s)\n\tif err != nil {\n\t\tt.Fatal(err)\n\t}\n\trqm := &reqmodel.%s{\n\t\t// TODO: Set the fields of the RequestModel\n\t}\n\tit.%s(rqm)\n\t// TODO: Assert that the Presenter was called with the expected ResponseModel\n\tif len(ps.calls) != 1 {\n\t\tt.Fatalf(\"got %%d calls of the Presenter, want 1\", len(ps.calls))\n\t}\n}", itName, v, itName, v, itName, v, v, fakePs, itName, strings.Join(args, ", "), v, v)},
+			})
+		if err != nil {
+			return err
+		}
+		added = added || ok
+	}
+
+	if selected(objValidator) && fileExists(filepath.FromSlash(basePath+relPathValidator+fileName(interactor)+".go")) {
+		ok, err := addTestDecls(filepath.FromSlash(basePath+relPathValidator+"test/"+fileName(interactor)+"_test.go"),
+			[]string{"testing", importPath + strings.TrimSuffix(relPathReqModel, "/"), importPath + strings.TrimSuffix(relPathValidator, "/")},
+			[]testDecl{
+				{fmt.Sprintf("func Test%s_Validate%s(", valName, v), fmt.Sprintf("// Test%s_Validate%s tests the %s Validator method Validate%s.\nfunc Test%s_Validate%s(t *testing.T) {\n\tt.Skip(\"TODO: Add the test cases of Validate%s\")\n\ttests := []struct {\n\t\tname    string\n\t\trqm     *reqmodel.%s\n\t\twantErr bool\n\t}{\n\t\t{\"valid\", &reqmodel.%s{}, false},\n\t\t// TODO: Add test cases of invalid RequestModels\n\t}\n\tval := validator.New%s()\n\tfor _, tt := range tests {\n\t\tt.Run(tt.name, func(t *testing.T) {\n\t\t\tif rsm := val.Validate%s(tt.rqm); (rsm != nil) != tt.wantErr {\n\t\t\t\tt.Errorf(\"Validate%s() = %%v, want an error: %%v\", rsm, tt.wantErr)\n\t\t\t}\n\t\t})\n\t}\n}", valName, v, valName, v, valName, v, v, v, v, valName, v, v)},
+			})
+		if err != nil {
+			return err
+		}
+		added = added || ok
+	}
+
+	if *golden && selected(objPresenter) && fileExists(filepath.FromSlash(basePath+relPathPresenter+fileName(interactor)+".go")) {
+		goldenName := fileName(interactor) + "_present_" + strings.ToLower(strings.Join(splitWords(v), "_"))
+		ok, err := addTestDecls(filepath.FromSlash(basePath+relPathPresenter+"test/"+fileName(interactor)+"_test.go"),
+			[]string{"bytes", "encoding/json", "flag", "io/ioutil", "path/filepath", "testing", importPath + strings.TrimSuffix(relPathPresenter, "/"), importPath + strings.TrimSuffix(relPathView, "/"), importPath + strings.TrimSuffix(relPathViewModel, "/"), importPath + strings.TrimSuffix(relPathRespModel, "/")},
+			[]testDecl{
+				{"var update = flag.Bool(", "// update makes the golden file tests write the golden files instead of comparing with them\nvar update = flag.Bool(\"update\", false, \"update the golden files\")"},
+				{"func compareGolden(", "// compareGolden compares the JSON encoding of got with the golden file testdata/[name].golden, which is\n// written instead if -update is set\nfunc compareGolden(t *testing.T, name string, got interface{}) {\n\tt.Helper()\n\tb, err := json.MarshalIndent(got, \"\", \"\\t\")\n\tif err != nil {\n\t\tt.Fatal(err)\n\t}\n\tfp := filepath.Join(\"testdata\", name+\".golden\")\n\tif *update {\n\t\tif err := ioutil.WriteFile(fp, b, 0644); err != nil {\n\t\t\tt.Fatal(err)\n\t\t}\n\t\treturn\n\t}\n\twant, err := ioutil.ReadFile(fp)\n\tif err != nil {\n\t\tt.Fatal(err)\n\t}\n\tif !bytes.Equal(b, want) {\n\t\tt.Errorf(\"got %s, want %s\", b, want)\n\t}\n}"},
+				{fmt.Sprintf("type %s struct", fakeVw), fmt.Sprintf("// %s is a fake of the %s View which records the ViewModels it's called with.\n// Calling any of its methods that isn't implemented below panics.\ntype %s struct {\n\tview.%s\n\tcalls []interface{}\n}", fakeVw, vwName, fakeVw, vwName)},
+				{fmt.Sprintf(") Render%s(", v), fmt.Sprintf("// Render%s records vm.\nfunc (f *%s) Render%s(vm *viewmodel.%s) {\n\tf.calls = append(f.calls, vm)\n}\n\n// Render%sErrVal records vm.\nfunc (f *%s) Render%sErrVal(vm *viewmodel.%sErrVal) {\n\tf.calls = append(f.calls, vm)\n}", v, fakeVw, v, v, v, fakeVw, v, v)},
+				{fmt.Sprintf("func Test%s_Present%s(", psName, v), fmt.Sprintf("// Test%s_Present%s compares the ViewModel the %s Presenter method Present%s calls the View with\n// to the golden file testdata/%s.golden. Run \"go test -update\" to write the golden file.\nfunc Test%s_Present%s(t *testing.T) {\n\tt.Skip(\"TODO: Set the fields of the ResponseModel and write the golden file\")\n\tvw := &%s{}\n\tps, err := presenter.New%s(vw)\n\tif err != nil {\n\t\tt.Fatal(err)\n\t}\n\tps.Present%s(&respmodel.%s{\n\t\t// TODO: Set the fields of the ResponseModel\n\t})\n\tcompareGolden(t, %q, vw.calls)\n}", psName, v, psName, v, goldenName, psName, v, fakeVw, psName, v, v, goldenName)},
+			})
+		if err != nil {
+			return err
+		}
+		if err := mkdirAll(filepath.FromSlash(basePath + relPathPresenter + "test/testdata")); err != nil {
+			return err
+		}
+		added = added || ok
+	}
+	if !added {
+		noopf("the tests of the usecase %s already exist in %s", v, exportedName(interactor))
+	}
+	return nil
+}
+
+// constructorReturnsError reports whether the constructor fn declared in the Go file fp returns an error as its
+// last result
+func constructorReturnsError(fp, fn string) (bool, error) {
+	f, err := parseGoFile(fp)
+	if err != nil {
+		return false, err
+	}
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Recv != nil || fd.Name.Name != fn {
+			continue
+		}
+		results := fd.Type.Results
+		if results == nil || len(results.List) == 0 {
+			return false, nil
+		}
+		return types.ExprString(results.List[len(results.List)-1].Type) == "error", nil
+	}
+	return false, fmt.Errorf("the constructor %s isn't declared in %s", fn, fp)
+}
+
+// addIntegrationTest adds a test of the interactor to the test folder of the clean folder which constructs its
+// whole chain of objects with their real implementations: the View, the Presenter rendering it, the Interactor
+// presenting to the Presenter and the Controller calling the Interactor. The test only asserts the construction,
+// so it compiles and passes while the methods are still stubs, and leaves exercising a usecase end-to-end as a
+// TODO.
+func addIntegrationTest(basePath, interactor string) error {
+	importPath := projectBaseImportPath + "clean/"
+	chain := []struct {
+		objType, varName string
+		args             map[string]string
+	}{
+		{objView, "vw", nil},
+		{objPresenter, "ps", map[string]string{objView: "vw"}},
+		{objInteractor, "it", nil},
+		{objController, "ct", map[string]string{objInteractor: "it"}},
+	}
+	imports := []string{"testing"}
+	var skip string
+	var calls, varNames []string
+	var returnErrs []bool
+	used := make(map[string]bool)
+	for _, obj := range chain {
+		fp := objectFilePath(basePath, obj.objType, interactor)
+		if !fileExists(fp) {
+			return fmt.Errorf("the %s of %s doesn't exist, the integration test needs its controller, interactor, presenter and view", obj.objType, exportedName(interactor))
+		}
+		ctor := "New" + typeName(obj.objType, interactor)
+		var args []string
+		var err error
+		if obj.objType == objInteractor {
+			var argImports []string
+			args, argImports, err = interactorConstructorArgs(basePath, interactor, "ps")
+			imports = append(imports, argImports...)
+		} else {
+			args, err = constructorArgs(fp, ctor, obj.args)
+		}
+		if err != nil {
+			return err
+		}
+		returnsErr, err := constructorReturnsError(fp, ctor)
+		if err != nil {
+			return err
+		}
+		for _, arg := range args {
+			used[arg] = true
+			// The constructors fail for the arguments the test can't construct
+			if strings.HasPrefix(arg, "nil") {
+				skip = fmt.Sprintf("\tt.Skip(\"TODO: Pass the arguments of %s.%s\")\n", obj.objType, ctor)
+			}
+		}
+		imports = append(imports, importPath+strings.TrimSuffix(objRelPaths[obj.objType], "/"))
+		calls = append(calls, fmt.Sprintf("%s.%s(%s)", obj.objType, ctor, strings.Join(args, ", ")))
+		varNames = append(varNames, obj.varName)
+		returnErrs = append(returnErrs, returnsErr)
+	}
+	// The objects which aren't passed to the next constructor, e.g. a View of a Presenter constructed without
+	// one, are only constructed
+	used["ct"] = true
+	var body string
+	errDeclared := false
+	for i, call := range calls {
+		varName := varNames[i]
+		if !used[varName] {
+			varName = "_"
+		}
+		switch {
+		case returnErrs[i] && (varName != "_" || !errDeclared):
+			body += fmt.Sprintf("\t%s, err := %s\n\tif err != nil {\n\t\tt.Fatal(err)\n\t}\n", varName, call)
+			errDeclared = true
+		case returnErrs[i]:
+			body += fmt.Sprintf("\tif _, err = %s; err != nil {\n\t\tt.Fatal(err)\n\t}\n", call)
+		case varName == "_":
+			body += fmt.Sprintf("\t_ = %s\n", call)
+		default:
+			body += fmt.Sprintf("\t%s := %s\n", varName, call)
+		}
+	}
+	name := exportedName(interactor)
+	ok, err := addTestDecls(filepath.FromSlash(basePath+"test/"+fileName(interactor)+"_integration_test.go"), imports, []testDecl{
+		{fmt.Sprintf("func Test%sIntegration(", name), fmt.Sprintf("// Test%sIntegration constructs the Controller, Interactor, Presenter and View of %s with their real\n// implementations, wired like in production.\nfunc Test%sIntegration(t *testing.T) {\n%s%s\tif ct == nil {\n\t\tt.Fatal(\"got a nil Controller\")\n\t}\n\t// TODO: Call a method of the Controller and assert what the View renders\n}", name, name, name, skip, body)},
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		noopf("the integration test of %s already exists", name)
+	}
+	return nil
+}
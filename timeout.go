@@ -0,0 +1,57 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// timeoutConstName returns the name of the constant of the latency budget of the usecases of the interactor
+// e.g. orderTimeout
+func timeoutConstName(interactor string) string {
+	return unexportedName(interactor) + "Timeout"
+}
+
+// durationExpr returns the Go expression of d in the largest unit it's a whole number of e.g. 5 * time.Second
+func durationExpr(d time.Duration) string {
+	units := []struct {
+		d    time.Duration
+		name string
+	}{{time.Hour, "Hour"}, {time.Minute, "Minute"}, {time.Second, "Second"}, {time.Millisecond, "Millisecond"}, {time.Microsecond, "Microsecond"}}
+	for _, u := range units {
+		if d%u.d == 0 {
+			if d == u.d {
+				return "time." + u.name
+			}
+			return fmt.Sprintf("%d * time.%s", d/u.d, u.name)
+		}
+	}
+	return fmt.Sprintf("%d * time.Nanosecond", d)
+}
+
+// timeoutPrelude returns the statements the Interactor method of a usecase of the interactor starts with if
+// --timeout is set, which derive a context with the deadline of the latency budget of the interactor
+func timeoutPrelude(interactor string) string {
+	return fmt.Sprintf("\tctx, cancel := context.WithTimeout(context.Background(), %s)\n\tdefer cancel()\n\t// TODO: Pass ctx to the Gateways\n\t_ = ctx\n\n", timeoutConstName(interactor))
+}
+
+// addTimeoutConst returns the Interactor file b with the constant of the latency budget d of the usecases of
+// the interactor and the imports of the timeout prelude. The constant is only added once, so the budget of
+// the usecases added later is changed by editing it.
+func addTimeoutConst(b []byte, interactor string, d time.Duration) ([]byte, error) {
+	var err error
+	for _, path := range []string{"context", "time"} {
+		if b, err = ensureImport(b, path); err != nil {
+			return nil, err
+		}
+	}
+	name := timeoutConstName(interactor)
+	if bytes.Contains(b, []byte("const "+name+" ")) {
+		return b, nil
+	}
+	decl := fmt.Sprintf("\n\n// %s is the latency budget of the usecases of the %s Interactor.\nconst %s = %s\n", name, typeName(objInteractor, interactor), name, durationExpr(d))
+	return append(bytes.TrimRight(b, "\n"), decl...), nil
+}
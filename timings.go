@@ -0,0 +1,143 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"text/tabwriter"
+	"text/template"
+	"time"
+)
+
+const (
+	phaseConfig = "config load"
+	phaseScan   = "project scan"
+	phaseRead   = "file read"
+	phaseParse  = "parse"
+	phaseRender = "render"
+	phaseFormat = "format"
+	phaseWrite  = "write"
+)
+
+// phase holds the time spent in a phase of the command, e.g. parsing, and how many times it was entered
+type phase struct {
+	name  string
+	total time.Duration
+	count int
+}
+
+// activePhase is a phase that has been entered but not left yet
+type activePhase struct {
+	p     *phase
+	since time.Time
+}
+
+var (
+	// startTime is the time the command started
+	startTime = time.Now()
+	// phases holds the phases in the order they were first entered
+	phases []*phase
+	// activePhases is the stack of entered phases. Only the time of the innermost phase is counted so
+	// that e.g. the parsing done while scanning the project isn't counted twice.
+	activePhases []activePhase
+)
+
+// startPhase enters the phase name if --timings is set and returns the function that leaves it.
+// Phases may be nested but must be left in the reverse order they were entered.
+func startPhase(name string) func() {
+	if !*timings {
+		return func() {}
+	}
+	now := time.Now()
+	if n := len(activePhases); n > 0 {
+		outer := activePhases[n-1]
+		outer.p.total += now.Sub(outer.since)
+	}
+	var p *phase
+	for _, v := range phases {
+		if v.name == name {
+			p = v
+		}
+	}
+	if p == nil {
+		p = &phase{name: name}
+		phases = append(phases, p)
+	}
+	p.count++
+	activePhases = append(activePhases, activePhase{p, now})
+	return func() {
+		now := time.Now()
+		n := len(activePhases)
+		inner := activePhases[n-1]
+		inner.p.total += now.Sub(inner.since)
+		activePhases = activePhases[:n-1]
+		if n > 1 {
+			activePhases[n-2].since = now
+		}
+	}
+}
+
+// render executes the template t with data, measured as the render phase
+func render(w io.Writer, t *template.Template, data interface{}) error {
+	defer startPhase(phaseRender)()
+	return t.Execute(w, data)
+}
+
+// printTimings prints the time spent in each phase of the command to stderr if --timings is set
+func printTimings() {
+	if !*timings {
+		return
+	}
+	total := time.Since(startTime)
+	var measured time.Duration
+	tw := tabwriter.NewWriter(os.Stderr, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "phase\tcalls\ttime\t\n")
+	for _, p := range phases {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t\n", p.name, p.count, p.total.Round(time.Microsecond))
+		measured += p.total
+	}
+	fmt.Fprintf(tw, "other\t\t%s\t\n", (total - measured).Round(time.Microsecond))
+	fmt.Fprintf(tw, "total\t\t%s\t\n", total.Round(time.Microsecond))
+	tw.Flush()
+}
+
+// startProfiles starts writing a CPU profile to --cpuprofile if it's set and returns the function that
+// stops it and writes a heap profile to --memprofile if it's set
+func startProfiles() (func(), error) {
+	var cpuFile *os.File
+	if *cpuProfile != "" {
+		f, err := os.Create(*cpuProfile)
+		if err != nil {
+			return nil, err
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return nil, err
+		}
+		cpuFile = f
+	}
+	return func() {
+		if cpuFile != nil {
+			pprof.StopCPUProfile()
+			cpuFile.Close()
+		}
+		if *memProfile == "" {
+			return
+		}
+		f, err := os.Create(*memProfile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing the memory profile: %s\n", err.Error())
+			return
+		}
+		defer f.Close()
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(f); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing the memory profile: %s\n", err.Error())
+		}
+	}, nil
+}
@@ -0,0 +1,248 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	todoKindTodo = "todo"
+	todoKindStub = "stub"
+	todoMarker   = "TODO"
+)
+
+// todo is a TODO comment or an unimplemented method found in the generated code
+type todo struct {
+	Interactor string `json:"interactor,omitempty"`
+	Usecase    string `json:"usecase,omitempty"`
+	File       string `json:"file"`
+	Line       int    `json:"line"`
+	Kind       string `json:"kind"`
+	Text       string `json:"text"`
+}
+
+// findTodos returns the TODO comments and the methods with empty bodies of the Go files in basePath.
+// The file paths of the returned todos are relative to projectPath. Each todo belongs to the interactor
+// the file is named after and, if it's inside a method or model of one of its usecases, to the usecase.
+func findTodos(projectPath, basePath string) ([]todo, error) {
+	defer startPhase(phaseScan)()
+	interactors, err := interactorUsecases(basePath)
+	if err != nil {
+		return nil, err
+	}
+	byFile := make(map[string]string)
+	for name := range interactors {
+		byFile[fileName(name)] = name
+	}
+	var todos []todo
+	err = filepath.Walk(filepath.FromSlash(basePath), func(fp string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() || filepath.Ext(fp) != ".go" {
+			return nil
+		}
+		relFp, err := filepath.Rel(filepath.FromSlash(projectPath), fp)
+		if err != nil {
+			relFp = fp
+		}
+		interactor := byFile[strings.TrimSuffix(strings.TrimSuffix(fi.Name(), ".go"), "_test")]
+		fileTodos, err := findFileTodos(fp, interactors[interactor])
+		if err != nil {
+			fmt.Printf("Skipping %s: %s\n", relFp, err.Error())
+			return nil
+		}
+		for _, t := range fileTodos {
+			t.Interactor = interactor
+			t.File = filepath.ToSlash(relFp)
+			todos = append(todos, t)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(todos, func(i, j int) bool {
+		if todos[i].Interactor != todos[j].Interactor {
+			return todos[i].Interactor < todos[j].Interactor
+		}
+		if todos[i].Usecase != todos[j].Usecase {
+			return todos[i].Usecase < todos[j].Usecase
+		}
+		if todos[i].File != todos[j].File {
+			return todos[i].File < todos[j].File
+		}
+		return todos[i].Line < todos[j].Line
+	})
+	return todos, nil
+}
+
+// findFileTodos returns the todos of the Go file fp whose methods and models may belong to usecases.
+// Methods with empty bodies are reported as stubs, and the TODO comments inside them aren't reported separately.
+func findFileTodos(fp string, usecases []string) ([]todo, error) {
+	fset := token.NewFileSet()
+	f, err := parseFile(fset, fp, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	isUsecase := make(map[string]bool)
+	for _, u := range usecases {
+		isUsecase[u] = true
+	}
+	var todos []todo
+	// stubs holds the position ranges of the stub methods' bodies
+	var stubs []*ast.BlockStmt
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Body == nil || len(fd.Body.List) != 0 {
+			continue
+		}
+		stubs = append(stubs, fd.Body)
+		todos = append(todos, todo{
+			Usecase: usecaseOfName(fd.Name.Name, isUsecase),
+			Line:    fset.Position(fd.Pos()).Line,
+			Kind:    todoKindStub,
+			Text:    fd.Name.Name + " isn't implemented",
+		})
+	}
+	for _, cg := range f.Comments {
+		for _, c := range cg.List {
+			ix := strings.Index(c.Text, todoMarker)
+			if ix == -1 || insideAny(c.Pos(), stubs) {
+				continue
+			}
+			todos = append(todos, todo{
+				Usecase: usecaseAt(f, c.Pos(), isUsecase),
+				Line:    fset.Position(c.Pos()).Line,
+				Kind:    todoKindTodo,
+				Text:    strings.TrimSpace(c.Text[ix:]),
+			})
+		}
+	}
+	return todos, nil
+}
+
+// insideAny reports whether pos is inside any of the blocks
+func insideAny(pos token.Pos, blocks []*ast.BlockStmt) bool {
+	for _, b := range blocks {
+		if pos >= b.Pos() && pos <= b.End() {
+			return true
+		}
+	}
+	return false
+}
+
+// usecaseAt returns the usecase of the method or type declaration enclosing pos, or of the
+// declaration pos is the doc comment of. It returns an empty string if there's no such usecase.
+func usecaseAt(f *ast.File, pos token.Pos, isUsecase map[string]bool) string {
+	for _, decl := range f.Decls {
+		start := decl.Pos()
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+			if pos >= start && pos <= d.End() {
+				return usecaseOfName(d.Name.Name, isUsecase)
+			}
+		case *ast.GenDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+			if pos < start || pos > d.End() {
+				continue
+			}
+			for _, spec := range d.Specs {
+				ts, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+				// Methods of the interface
+				if it, ok := ts.Type.(*ast.InterfaceType); ok {
+					for _, m := range it.Methods.List {
+						start := m.Pos()
+						if m.Doc != nil {
+							start = m.Doc.Pos()
+						}
+						if pos >= start && pos <= m.End() && len(m.Names) > 0 {
+							return usecaseOfName(m.Names[0].Name, isUsecase)
+						}
+					}
+					continue
+				}
+				return usecaseOfName(ts.Name.Name, isUsecase)
+			}
+		}
+	}
+	return ""
+}
+
+// usecaseOfName returns the usecase the method or model name is generated for, e.g. AddItem
+// for PresentAddItemErrVal. It returns an empty string if name doesn't belong to a usecase.
+func usecaseOfName(name string, isUsecase map[string]bool) string {
+	name = strings.TrimSuffix(name, "ErrVal")
+	if isUsecase[name] {
+		return name
+	}
+	for _, prefix := range []string{"Present", "Render", "Validate"} {
+		if u := strings.TrimPrefix(name, prefix); u != name && isUsecase[u] {
+			return u
+		}
+	}
+	return ""
+}
+
+// printTodos prints todos grouped by interactor and usecase followed by a summary.
+// format is either text or json.
+func printTodos(todos []todo, format string) error {
+	if format == "json" {
+		out := struct {
+			Count int    `json:"count"`
+			Todos []todo `json:"todos"`
+		}{len(todos), todos}
+		if out.Todos == nil {
+			out.Todos = []todo{}
+		}
+		b, err := json.MarshalIndent(out, "", "\t")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\n", b)
+		return nil
+	}
+	if format != "text" {
+		return fmt.Errorf("unknown format %q, the formats are text and json", format)
+	}
+	interactors := 0
+	for i, t := range todos {
+		if i == 0 || t.Interactor != todos[i-1].Interactor {
+			name := t.Interactor
+			if name == "" {
+				name = "(not part of an interactor)"
+			} else {
+				interactors++
+			}
+			fmt.Printf("%s\n", name)
+		}
+		if i == 0 || t.Interactor != todos[i-1].Interactor || t.Usecase != todos[i-1].Usecase {
+			usecase := t.Usecase
+			if usecase == "" {
+				usecase = "(not part of a usecase)"
+			}
+			fmt.Printf("\t%s\n", usecase)
+		}
+		fmt.Printf("\t\t%s:%d: %s\n", t.File, t.Line, t.Text)
+	}
+	fmt.Printf("\n%d TODOs in %d interactors\n\n", len(todos), interactors)
+	return nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	gofmt "go/format"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	objUnitOfWork = "unitofwork"
+	// uowFileName is the name of the file of the UnitOfWork in the gateway folder
+	uowFileName = "uow.go"
+	// uowName is the name of the UnitOfWork interface and of the Gateway the Interactors get with --with-uow
+	uowName = "UnitOfWork"
+	// uowField is the name of the field and constructor parameter of an Interactor holding the UnitOfWork
+	uowField = "uow"
+)
+
+// verifyUow returns an error if --with-uow is set without the Interactors getting their Gateways from the
+// interface adapters layer, where the UnitOfWork is declared
+func verifyUow() error {
+	if *withUow && (!*withGateway || *gatewayInUsecase) {
+		return errors.New("--with-uow requires --with-gateway and can't be combined with --with-gateway-interface-in-usecase")
+	}
+	return nil
+}
+
+// projectRepositories returns the names of the repositories declared in the gateway folder of the project at
+// basePath, e.g. OrderRepository, in alphabetical order. The generic Repository isn't included.
+func projectRepositories(basePath string) ([]string, error) {
+	dir := filepath.FromSlash(basePath + relPathGateway)
+	var paths []string
+	if fis, err := ioutil.ReadDir(dir); err == nil {
+		for _, fi := range fis {
+			paths = append(paths, filepath.Join(dir, fi.Name()))
+		}
+	}
+	// Include the repositories written while --stdout is set
+	for _, fp := range pendingOrder {
+		if filepath.Dir(fp) == filepath.Clean(dir) {
+			paths = append(paths, fp)
+		}
+	}
+	seen := make(map[string]bool)
+	var repos []string
+	for _, fp := range paths {
+		if filepath.Ext(fp) != ".go" || filepath.Base(fp) == uowFileName || seen[fp] {
+			continue
+		}
+		seen[fp] = true
+		f, err := parseGoFile(fp)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range interfaceNames(f) {
+			if strings.HasSuffix(name, "Repository") && name != "Repository" {
+				repos = append(repos, name)
+			}
+		}
+		// The repositories of the generic Repository are aliases rather than interfaces
+		for _, name := range typeAliases(f) {
+			if strings.HasSuffix(name, "Repository") {
+				repos = append(repos, name)
+			}
+		}
+	}
+	sort.Strings(repos)
+	return repos, nil
+}
+
+// uowSource returns the source of the UnitOfWork file, following its package clause, whose transactions give
+// access to the repositories
+func uowSource(repos []string) []byte {
+	sqlUow, sqlTx, memUow, memTx := "sqlUnitOfWork", "sqlTx", "memoryUnitOfWork", "memoryTx"
+	var b bytes.Buffer
+	b.WriteString("\n\nimport (\n\t\"context\"\n\t\"database/sql\"\n)\n")
+	fmt.Fprintf(&b, "\n// %s is a Clean Architecture Gateway which begins the transactions that the steps of a usecase run in.\ntype %s interface {\n\t// Begin begins a transaction, which must be ended by committing or rolling it back.\n\tBegin(ctx context.Context) (Tx, error)\n}\n", uowName, uowName)
+	b.WriteString("\n// Tx is a transaction begun by a UnitOfWork. The repositories it gives access to read and write within it.\ntype Tx interface {\n\t// Commit commits the changes made within the transaction.\n\tCommit() error\n\t// Rollback discards the changes made within the transaction.\n\tRollback() error\n")
+	for _, repo := range repos {
+		fmt.Fprintf(&b, "\t// %s returns the %s of the transaction.\n\t%s() %s\n", repo, repo, repo, repo)
+	}
+	b.WriteString("}\n")
+
+	// The database/sql skeleton
+	self := receiverName(sqlUow)
+	fmt.Fprintf(&b, "\n// %s is an implementation of %s which begins database/sql transactions.\ntype %s struct {\n\tdb *sql.DB\n}\n", sqlUow, uowName, sqlUow)
+	fmt.Fprintf(&b, "\n// NewSQL%s constructs a new %s which begins the transactions of db.\nfunc NewSQL%s(db *sql.DB) %s {\n\treturn &%s{db: db}\n}\n", uowName, uowName, uowName, uowName, sqlUow)
+	fmt.Fprintf(&b, "\n// Begin implements the %s interface method Begin.\nfunc (%s *%s) Begin(ctx context.Context) (Tx, error) {\n\ttx, err := %s.db.BeginTx(ctx, nil)\n\tif err != nil {\n\t\treturn nil, err\n\t}\n\treturn &%s{tx: tx}, nil\n}\n", uowName, self, sqlUow, self, sqlTx)
+	self = receiverName(sqlTx)
+	fmt.Fprintf(&b, "\n// %s is an implementation of Tx which wraps a database/sql transaction.\ntype %s struct {\n\ttx *sql.Tx\n}\n", sqlTx, sqlTx)
+	for _, m := range []string{"Commit", "Rollback"} {
+		fmt.Fprintf(&b, "\n// %s implements the Tx interface method %s.\nfunc (%s *%s) %s() error {\n\treturn %s.tx.%s()\n}\n", m, m, self, sqlTx, m, self, m)
+	}
+	for _, repo := range repos {
+		fmt.Fprintf(&b, "\n// %s implements the Tx interface method %s.\nfunc (%s *%s) %s() %s {\n\t// TODO: Return a %s which reads and writes with %s.tx\n\tpanic(\"not implemented\")\n}\n", repo, repo, self, sqlTx, repo, repo, repo, self)
+	}
+
+	// The in-memory implementation
+	var fields, params []string
+	for _, repo := range repos {
+		fields = append(fields, fmt.Sprintf("\t%s %s\n", unexportedName(repo), repo))
+		params = append(params, fmt.Sprintf("%s %s", unexportedName(repo), repo))
+	}
+	self = receiverName(memUow)
+	fmt.Fprintf(&b, "\n// %s is an in-memory implementation of %s e.g. for tests. Its transactions give access to the\n// repositories it's constructed with, which make the changes at once, so Rollback doesn't discard them.\ntype %s struct {\n%s}\n", memUow, uowName, memUow, strings.Join(fields, ""))
+	fmt.Fprintf(&b, "\n// NewMemory%s constructs a new in-memory %s whose transactions give access to the repositories.\nfunc NewMemory%s(%s) %s {\n\treturn &%s{\n", uowName, uowName, uowName, strings.Join(params, ", "), uowName, memUow)
+	for _, repo := range repos {
+		fmt.Fprintf(&b, "\t\t%s: %s,\n", unexportedName(repo), unexportedName(repo))
+	}
+	b.WriteString("\t}\n}\n")
+	fmt.Fprintf(&b, "\n// Begin implements the %s interface method Begin.\nfunc (%s *%s) Begin(ctx context.Context) (Tx, error) {\n\treturn %s{uow: %s}, nil\n}\n", uowName, self, memUow, memTx, self)
+	self = receiverName(memTx)
+	fmt.Fprintf(&b, "\n// %s is a transaction of a %s.\ntype %s struct {\n\tuow *%s\n}\n", memTx, memUow, memTx, memUow)
+	for _, m := range []string{"Commit", "Rollback"} {
+		fmt.Fprintf(&b, "\n// %s implements the Tx interface method %s.\nfunc (%s %s) %s() error {\n\treturn nil\n}\n", m, m, self, memTx, m)
+	}
+	for _, repo := range repos {
+		fmt.Fprintf(&b, "\n// %s implements the Tx interface method %s.\nfunc (%s %s) %s() %s {\n\treturn %s.uow.%s\n}\n", repo, repo, self, memTx, repo, repo, self, unexportedName(repo))
+	}
+	return b.Bytes()
+}
+
+// addUnitOfWork adds the UnitOfWork, whose transactions give access to the repositories of the project at
+// basePath, and its database/sql and in-memory implementations to the uow.go file of the gateway folder
+func addUnitOfWork(basePath string) error {
+	fp := filepath.FromSlash(basePath + relPathGateway + uowFileName)
+	if fileExists(fp) {
+		noopf("the unit of work already exists")
+		return nil
+	}
+	repos, err := projectRepositories(basePath)
+	if err != nil {
+		return err
+	}
+	content, err := gofmt.Source(append([]byte(packageClause(basePath+relPathGateway, objGateway)), uowSource(repos)...))
+	if err != nil {
+		return err
+	}
+	if err := mkdirAll(filepath.Dir(fp)); err != nil {
+		return err
+	}
+	return writeFile(fp, content)
+}
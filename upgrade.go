@@ -0,0 +1,232 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"sort"
+	"strings"
+)
+
+// signatureEdit is the replacement of the parameters and results of a method of a Go source
+type signatureEdit struct {
+	start, end int
+	text       string
+	// method is the method whose signature is replaced e.g. OrderController.AddItem
+	method string
+}
+
+// upgradeSignatures updates the signatures of the methods of the usecase in the interfaces of the interactor
+// and in their implementations in the project at basePath to the ones Clean generates today with the flags of
+// the command, e.g. AddItem(r *http.Request) of the Controller with --bind http. The bodies and doc comments
+// of the methods are kept, and so are the names of the parameters whose types are unchanged. The imports the
+// new signatures need are added and those only the old ones needed are removed. Each updated method is listed.
+func upgradeSignatures(basePath, usecase, interactor string) error {
+	v := exportedName(usecase)
+	interactors, err := interactorUsecases(basePath)
+	if err != nil {
+		return err
+	}
+	var its []string
+	for name := range interactors {
+		its = append(its, name)
+	}
+	sort.Strings(its)
+	found := false
+	for _, name := range its {
+		for _, u := range interactors[name] {
+			if strings.EqualFold(exportedName(name), exportedName(interactor)) && u == v {
+				interactor, found = name, true
+			}
+		}
+	}
+	if !found {
+		return fmt.Errorf("the usecase %s doesn't exist in %s", v, exportedName(interactor))
+	}
+	names := make(map[string]bool)
+	for _, objType := range []string{objController, objPresenter, objView, objInteractor, objValidator} {
+		for _, name := range usecaseMethods(objType, v) {
+			names[name] = true
+		}
+	}
+	stubs := regenerate(basePath, []string{interactor}, map[string][]string{interactor: {v}})
+	var paths []string
+	for fp := range stubs {
+		paths = append(paths, fp)
+	}
+	sort.Strings(paths)
+	var updated []string
+	for _, fp := range paths {
+		b, err := readFile(fp)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		newb, methods, err := upgradeFileSignatures(b, stubs[fp], names)
+		if err != nil {
+			return fmt.Errorf("%s: %s", fp, err.Error())
+		}
+		if len(methods) == 0 {
+			continue
+		}
+		if err := writeFile(fp, newb); err != nil {
+			return err
+		}
+		updated = append(updated, methods...)
+	}
+	if len(updated) == 0 {
+		noopf("the signatures of the usecase %s are up to date in %s", v, exportedName(interactor))
+		return nil
+	}
+	if !*jsonOutput {
+		for _, method := range updated {
+			fmt.Printf("updated %s\n", method)
+		}
+	}
+	return nil
+}
+
+// upgradeFileSignatures returns the Go source b with the signatures of the methods called any of names
+// replaced by those of the same methods in stub, along with the updated methods and their new signatures.
+// The methods are those of the interfaces and of the receivers declared in both of them.
+func upgradeFileSignatures(b, stub []byte, names map[string]bool) ([]byte, []string, error) {
+	fset := token.NewFileSet()
+	f, err := parseFile(fset, "", b, parser.ParseComments)
+	if err != nil {
+		return nil, nil, err
+	}
+	stubFset := token.NewFileSet()
+	stubFile, err := parseFile(stubFset, "", stub, parser.ParseComments)
+	if err != nil {
+		return nil, nil, err
+	}
+	stubSigs := make(map[string]*ast.FuncType)
+	eachMethod(stubFile, names, func(method string, ft *ast.FuncType) {
+		stubSigs[method] = ft
+	})
+	src := func(fset *token.FileSet, b []byte, n ast.Node) string {
+		return string(b[fset.Position(n.Pos()).Offset:fset.Position(n.End()).Offset])
+	}
+	var edits []signatureEdit
+	eachMethod(f, names, func(method string, ft *ast.FuncType) {
+		stubFt, ok := stubSigs[method]
+		if !ok || signatureTypes(fset, b, ft) == signatureTypes(stubFset, stub, stubFt) {
+			return
+		}
+		// Keep the names of the current parameters of the same types, which the body may refer to
+		var current [][2]string
+		for _, field := range ft.Params.List {
+			for _, name := range field.Names {
+				current = append(current, [2]string{name.Name, normalizedType(src(fset, b, field.Type))})
+			}
+		}
+		var params []string
+		for _, field := range stubFt.Params.List {
+			typ := src(stubFset, stub, field.Type)
+			for _, name := range field.Names {
+				paramName := name.Name
+				for i, c := range current {
+					if c[1] == normalizedType(typ) {
+						paramName = c[0]
+						current = append(current[:i], current[i+1:]...)
+						break
+					}
+				}
+				params = append(params, paramName+" "+typ)
+			}
+			if len(field.Names) == 0 {
+				params = append(params, typ)
+			}
+		}
+		text := "(" + strings.Join(params, ", ") + ")"
+		if stubFt.Results != nil {
+			text += " " + src(stubFset, stub, stubFt.Results)
+		}
+		edits = append(edits, signatureEdit{fset.Position(ft.Params.Pos()).Offset, fset.Position(ft.End()).Offset, text, method + text})
+	})
+	if len(edits) == 0 {
+		return b, nil, nil
+	}
+	// Replace the signatures from the end so that the offsets of the preceding ones stay valid
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	var methods []string
+	for _, e := range edits {
+		b = append(append(append([]byte{}, b[:e.start]...), e.text...), b[e.end:]...)
+		methods = append([]string{e.method}, methods...)
+	}
+	for _, spec := range stubFile.Imports {
+		name, path := importName(spec)
+		if spec.Name == nil {
+			name = ""
+		}
+		if b, err = ensureNamedImport(b, name, path); err != nil {
+			return nil, nil, err
+		}
+	}
+	if b, err = pruneImports(b); err != nil {
+		return nil, nil, err
+	}
+	return b, methods, nil
+}
+
+// eachMethod calls fn with each method called any of names of the interfaces and receivers declared in f, which
+// is named after its interface or receiver e.g. OrderController.AddItem, and its signature
+func eachMethod(f *ast.File, names map[string]bool, fn func(method string, ft *ast.FuncType)) {
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if recv := recvTypeName(d); recv != "" && names[d.Name.Name] {
+				fn(recv+"."+d.Name.Name, d.Type)
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				ts, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+				it, ok := ts.Type.(*ast.InterfaceType)
+				if !ok {
+					continue
+				}
+				for _, m := range it.Methods.List {
+					ft, ok := m.Type.(*ast.FuncType)
+					if ok && len(m.Names) == 1 && names[m.Names[0].Name] {
+						fn(ts.Name.Name+"."+m.Names[0].Name, ft)
+					}
+				}
+			}
+		}
+	}
+}
+
+// signatureTypes returns the types of the parameters and results of the signature ft of the Go source b, which
+// the signatures are compared by so that renamed parameters don't count as a change
+func signatureTypes(fset *token.FileSet, b []byte, ft *ast.FuncType) string {
+	types := func(fl *ast.FieldList) string {
+		if fl == nil {
+			return ""
+		}
+		var ts []string
+		for _, field := range fl.List {
+			typ := normalizedType(string(b[fset.Position(field.Type.Pos()).Offset:fset.Position(field.Type.End()).Offset]))
+			for n := len(field.Names); n > 1; n-- {
+				ts = append(ts, typ)
+			}
+			ts = append(ts, typ)
+		}
+		return strings.Join(ts, ", ")
+	}
+	return "(" + types(ft.Params) + ") (" + types(ft.Results) + ")"
+}
+
+// normalizedType returns the source of a type without the whitespace gofmt may have changed
+func normalizedType(typ string) string {
+	return strings.Join(strings.Fields(typ), "")
+}
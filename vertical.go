@@ -0,0 +1,307 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+const (
+	// confKeyLayoutMode is the layout of the usecases, which is horizontal or vertical
+	confKeyLayoutMode = "layout"
+	// layoutHorizontal is the layout whose usecases are the methods of the Interactor, the Validator and the
+	// models of the layers shared by all of the usecases of an interactor
+	layoutHorizontal = "horizontal"
+	// layoutVertical is the layout whose usecases are packages of their own, see addVerticalUsecase
+	layoutVertical = "vertical"
+)
+
+// verticalLayout is true if layout=vertical is set in the configuration file
+var verticalLayout bool
+
+// verticalUnsupportedFlags holds the flags which change the Interactor, the Validator or the models of the
+// horizontal layout and aren't supported by the vertical layout
+var verticalUnsupportedFlags = []string{"auth", "bind", "explicit-errval", "fuzz", "golden", "metrics", "notify", "paginated", "presenter-only-json", "saga", "schema", "timeout", "validator", "with-benchmarks", "with-clock", "with-gateway", "with-gateway-interface-in-usecase", "with-idgen", "with-integration-test", "with-mocks", "with-uow"}
+
+// setLayoutMode sets the layout of the usecases from the layout setting of conf. In the vertical layout the
+// interactors and validators and the request and response models aren't generated, so the layers left are the
+// adapters shared by the usecases of an interactor: controller, presenter, view and viewmodel.
+func setLayoutMode(conf map[string]string) error {
+	switch mode := strings.TrimSpace(conf[confKeyLayoutMode]); mode {
+	case "", layoutHorizontal:
+		verticalLayout = false
+	case layoutVertical:
+		verticalLayout = true
+		objTypes = []string{objController, objPresenter, objView}
+		relPaths = []string{relPathController, relPathPresenter, relPathView, relPathViewModel}
+	case layoutFlat:
+		setFlatLayout()
+	default:
+		return fmt.Errorf("invalid %s=%s setting in the configuration file, the layouts are %s, %s and %s", confKeyLayoutMode, mode, layoutHorizontal, layoutVertical, layoutFlat)
+	}
+	return nil
+}
+
+// verifyLayoutMode returns an error if any flag the layout set in the configuration file doesn't support is set,
+// or if the flat layout, which only has interactors and usecases, is asked to add another object
+func verifyLayoutMode(object string) error {
+	mode, unsupported := layoutVertical, verticalUnsupportedFlags
+	switch {
+	case flatLayout:
+		if object != "" && object != objInteractor && object != objUsecase {
+			return fmt.Errorf("the %s layout set in the configuration file only has interactors and usecases, it can't add a %s", layoutFlat, object)
+		}
+		mode, unsupported = layoutFlat, flatUnsupportedFlags
+	case !verticalLayout:
+		return nil
+	}
+	for _, name := range unsupported {
+		if f := flag.Lookup(name); f != nil && f.Value.String() != f.DefValue {
+			return fmt.Errorf("--%s isn't supported by the %s layout set in the configuration file", name, mode)
+		}
+	}
+	return nil
+}
+
+// interactorFilePath returns the path of the file whose existence means that the interactor exists, which is its
+// Interactor or, in the vertical layout without Interactors, its Presenter
+func interactorFilePath(basePath, interactor string) string {
+	if flatLayout {
+		return flatObjPath(basePath, objInteractor, interactor)
+	}
+	if verticalLayout {
+		return filepath.FromSlash(basePath + relPathPresenter + fileName(interactor) + ".go")
+	}
+	return filepath.FromSlash(basePath + relPathInteractor + fileName(interactor) + ".go")
+}
+
+// usecasePackageRelPath returns the folder of the package of the usecase of the interactor in the vertical
+// layout, which is in the folder holding the interactor folder e.g. usecase/order/additem/
+func usecasePackageRelPath(interactor, usecase string) string {
+	return path.Dir(strings.TrimSuffix(relPathInteractor, "/")) + "/" + fileName(interactor) + "/" + fileName(usecase) + "/"
+}
+
+// usecasePackage returns the name of the package of the usecase in the vertical layout e.g. additem
+func usecasePackage(usecase string) string {
+	return fileName(usecase)
+}
+
+// responseTypes returns the types of the parameters of the Present methods of the usecase of the interactor
+// e.g. respmodel.AddItem and respmodel.AddItemErrVal, or additem.Response and additem.ResponseErrVal in the
+// vertical layout, and the import path of their package
+func responseTypes(usecase, interactor string) (string, string, string) {
+	v := exportedName(usecase)
+	if verticalLayout {
+		pkg := usecasePackage(usecase)
+		return pkg + ".Response", pkg + ".ResponseErrVal", layerImportPath(usecasePackageRelPath(interactor, usecase))
+	}
+	return "respmodel." + v, "respmodel." + v + "ErrVal", layerImportPath(relPathRespModel)
+}
+
+// verticalUsecaseSource is the template of the file of the package of a usecase in the vertical layout
+const verticalUsecaseSource = `{{.Clause}}
+
+import (
+	"errors"
+)
+
+{{.RequestDoc}}
+type Request struct {
+	// TODO: Add struct members
+}
+
+{{.ResponseDoc}}
+type Response struct {
+	// TODO: Add struct members
+}
+
+// ResponseErrVal is the output of the usecase {{.Usecase}} if its input is invalid.
+type ResponseErrVal struct {
+	// TODO: Add struct members
+}
+
+// Presenter presents the outcome of the usecase {{.Usecase}}. The {{.Presenter}} Presenter of the presenter folder
+// implements it.
+type Presenter interface {
+	Present{{.Usecase}}(rsm *Response)
+	Present{{.Usecase}}ErrVal(rsm *ResponseErrVal)
+}
+
+// Usecase implements the usecase {{.Usecase}} of {{.Interactor}}.
+// TODO: Add description of what the usecase does
+type Usecase struct {
+	ps Presenter
+	// TODO define struct fields
+}
+
+// New constructs a new Usecase and returns a nil error if successful. Otherwise it returns an error.
+func New(ps Presenter) (*Usecase, error) {
+	if ps == nil {
+		return nil, errors.New("Error constructing {{.Usecase}}")
+	}
+	return &Usecase{
+		ps: ps,
+	}, nil
+}
+
+// Validate returns the ResponseErrVal of rqm if it's invalid. Otherwise it returns nil.
+func Validate(rqm *Request) *ResponseErrVal {
+	// TODO: Validate the Request
+	return nil
+}
+
+// Execute performs the usecase {{.Usecase}} with the input rqm and presents its outcome.
+func ({{.Recv}} *Usecase) Execute(rqm *Request) {
+	if errVal := Validate(rqm); errVal != nil {
+		{{.Recv}}.ps.Present{{.Usecase}}ErrVal(errVal)
+		return
+	}
+	// TODO: Implement the usecase
+	{{.Recv}}.ps.Present{{.Usecase}}(&Response{})
+}
+`
+
+// verticalUsecaseTestSource is the template of the test file of the package of a usecase in the vertical layout
+const verticalUsecaseTestSource = `package {{.Package}}
+
+import (
+	"testing"
+)
+
+// fakePresenter is a fake of the Presenter which records the Responses it's called with.
+type fakePresenter struct {
+	calls []interface{}
+}
+
+// Present{{.Usecase}} records rsm.
+func (f *fakePresenter) Present{{.Usecase}}(rsm *Response) {
+	f.calls = append(f.calls, rsm)
+}
+
+// Present{{.Usecase}}ErrVal records rsm.
+func (f *fakePresenter) Present{{.Usecase}}ErrVal(rsm *ResponseErrVal) {
+	f.calls = append(f.calls, rsm)
+}
+
+// TestExecute tests the usecase {{.Usecase}}.
+func TestExecute(t *testing.T) {
+	t.Skip("TODO: Implement the test of the usecase {{.Usecase}}")
+	ps := &fakePresenter{}
+	uc, err := New(ps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uc.Execute(&Request{
+		// TODO: Set the fields of the Request
+	})
+	// TODO: Assert that the Presenter was called with the expected Response
+	if len(ps.calls) != 1 {
+		t.Fatalf("got %d calls of the Presenter, want 1", len(ps.calls))
+	}
+}
+`
+
+// addVerticalUsecase adds the usecase of the interactor in the vertical layout. The usecase gets a package of
+// its own e.g. usecase/order/additem holding its Request, Response and ResponseErrVal, the Presenter interface
+// it presents them with, its Validate function and its Usecase type, which is constructed by New and performs
+// the usecase by Execute, together with a test unless --no-test is set. The usecase is added to the shared
+// controller, presenter, view and viewmodel of the interactor like in the horizontal layout, with the
+// controller holding the Usecase and the Presenter implementing the Presenter interface of the package.
+func addVerticalUsecase(basePath string, spec namedSpec, interactor string) {
+	v := exportedName(spec.Name)
+	if !fileExists(interactorFilePath(basePath, interactor)) {
+		failf("Error adding the usecase %s: the interactor %s doesn't exist, add it with \"clean add interactor %s\"\n", v, exportedName(interactor), exportedName(interactor))
+		return
+	}
+	relPath := usecasePackageRelPath(interactor, v)
+	fp := filepath.FromSlash(basePath + relPath + fileName(v) + ".go")
+	data := struct {
+		Clause, Package, Usecase, Interactor, Presenter, Recv string
+		RequestDoc, ResponseDoc                               string
+	}{
+		Clause:     packageClause(basePath+relPath, usecasePackage(v)),
+		Package:    usecasePackage(v),
+		Usecase:    v,
+		Interactor: exportedName(interactor),
+		Presenter:  typeName(objPresenter, interactor),
+		Recv:       receiverName("Usecase"),
+		RequestDoc: docComment(fmt.Sprintf("// Request is the input of the usecase %s.\n// It's the only input argument of Execute, which constitutes the usecase.", v),
+			fmt.Sprintf("// Request is the input of the usecase %s.", v)),
+		ResponseDoc: docComment(fmt.Sprintf("// Response is the output of the usecase %s.\n// It's the input of the Presenter method presenting the outcome of the usecase.", v),
+			fmt.Sprintf("// Response is the output of the usecase %s.", v)),
+	}
+	render := func(fp, src string) error {
+		var b bytes.Buffer
+		if err := template.Must(template.New("usecase").Parse(src)).Execute(&b, data); err != nil {
+			return err
+		}
+		if err := mkdirAll(filepath.Dir(fp)); err != nil {
+			return err
+		}
+		return writeFile(fp, b.Bytes())
+	}
+	if fileExists(fp) {
+		noopf("the usecase package %s already exists", relPath)
+	} else if err := render(fp, verticalUsecaseSource); err != nil {
+		failf("Error adding the package of the usecase %s: %s\n", v, err.Error())
+		return
+	}
+	if len(spec.Fields)+len(spec.Types) > 0 {
+		if err := addStructFields(fp, "Request", spec.Fields); err != nil {
+			failf("Error adding the fields of %s: %s\n", v, err.Error())
+		}
+		if err := addStructDecls(fp, spec.Types); err != nil {
+			failf("Error adding the types of the fields of %s: %s\n", v, err.Error())
+		}
+	}
+	if testFp := filepath.FromSlash(basePath + relPath + fileName(v) + "_test.go"); !*noTest && !fileExists(testFp) {
+		if err := render(testFp, verticalUsecaseTestSource); err != nil {
+			failf("Error adding the test of the usecase %s: %s\n", v, err.Error())
+		}
+	}
+	addUsecase(basePath, v, interactor)
+}
+
+// verticalInteractorUsecases returns the names of the interactors of the project at basePath in the vertical
+// layout mapped to their usecases, which are the usecases presented by their Presenters
+func verticalInteractorUsecases(basePath string) (map[string][]string, error) {
+	dir := filepath.FromSlash(basePath + relPathPresenter)
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	interactors := make(map[string][]string)
+	for _, fi := range files {
+		if fi.IsDir() || filepath.Ext(fi.Name()) != ".go" {
+			continue
+		}
+		f, err := parseGoFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return nil, err
+		}
+		base := strings.TrimSuffix(fi.Name(), ".go")
+		for _, name := range interfaceNames(f) {
+			objName, ok := objNameFromTypeName(objPresenter, name, base)
+			if !ok {
+				continue
+			}
+			methods, _ := interfaceMethods(f, name)
+			usecases := []string{}
+			for _, m := range methods {
+				if followsUsecasePattern(objPresenter, m) && !strings.HasSuffix(m, "ErrVal") {
+					usecases = append(usecases, strings.TrimPrefix(m, methodPrefixes[objPresenter]))
+				}
+			}
+			interactors[objName] = usecases
+		}
+	}
+	return interactors, nil
+}
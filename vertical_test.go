@@ -0,0 +1,76 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestVerticalLayoutFlow runs the full flow of a project in the vertical layout: it adds an interactor and two
+// usecases, checks their status, regenerates a deleted usecase package and builds and tests the project
+func TestVerticalLayoutFlow(t *testing.T) {
+	p := newTestProject(t)
+	p.write("../home/.clean/cleanrc", p.read("../home/.clean/cleanrc")+"layout=vertical\n")
+	p.clean("add", "interactor", "Order")
+	for _, relPath := range []string{"clean/usecase/interactor/order.go", "clean/usecase/reqmodel/validator/order.go", "clean/usecase/reqmodel/order.go"} {
+		if p.exists(relPath) {
+			t.Errorf("%s was added in the vertical layout", relPath)
+		}
+	}
+	p.clean("add", "usecase", "AddItem", "to", "Order")
+	p.clean("add", "usecase", "RemoveItem", "to", "Order")
+
+	pkg := p.read("clean/usecase/order/additem/additem.go")
+	for _, want := range []string{"package additem", "type Request struct", "type Response struct", "type ResponseErrVal struct", "type Presenter interface", "func New(ps Presenter) (*Usecase, error)", "func Validate(rqm *Request) *ResponseErrVal", "func (u *Usecase) Execute(rqm *Request)"} {
+		if !strings.Contains(pkg, want) {
+			t.Errorf("the usecase package doesn't contain %q:\n%s", want, pkg)
+		}
+	}
+	if !p.exists("clean/usecase/order/additem/additem_test.go") {
+		t.Errorf("the test of the usecase package wasn't added")
+	}
+	controller := p.read("clean/ifadapter/controller/order.go")
+	for _, want := range []string{`"app/clean/usecase/order/additem"`, "addItem    *additem.Usecase", "o.addItem.Execute(&additem.Request{})", "func NewOrder(addItem *additem.Usecase, removeItem *removeitem.Usecase) (Order, error)"} {
+		if !strings.Contains(controller, want) {
+			t.Errorf("the Controller doesn't contain %q:\n%s", want, controller)
+		}
+	}
+	presenter := p.read("clean/ifadapter/presenter/order.go")
+	for _, want := range []string{"PresentAddItem(rsm *additem.Response)", "PresentRemoveItemErrVal(rsm *removeitem.ResponseErrVal)"} {
+		if !strings.Contains(presenter, want) {
+			t.Errorf("the Presenter doesn't contain %q:\n%s", want, presenter)
+		}
+	}
+
+	status := p.clean("status")
+	for _, want := range []string{"Order (2 usecases)", "usecase AddItem\tok", "usecase RemoveItem\tok", "controller\tok"} {
+		if !strings.Contains(status, want) {
+			t.Errorf("the status doesn't contain %q:\n%s", want, status)
+		}
+	}
+
+	if err := os.Remove(p.path("clean/usecase/order/removeitem/removeitem.go")); err != nil {
+		t.Fatal(err)
+	}
+	p.clean("regenerate")
+	if !p.exists("clean/usecase/order/removeitem/removeitem.go") {
+		t.Errorf("regenerate didn't restore the usecase package")
+	}
+
+	if stdout, stderr, code := p.run("add", "interactor", "Cart", "--with-gateway"); code == 0 || !strings.Contains(stdout+stderr, "isn't supported by the vertical layout") {
+		t.Errorf("--with-gateway exited with %d in the vertical layout: %s%s", code, stdout, stderr)
+	}
+
+	if testing.Short() {
+		return
+	}
+	if out, err := p.goRun("vet", "./..."); err != nil {
+		t.Fatalf("the project doesn't compile: %s\n%s", err.Error(), out)
+	}
+	if out, err := p.goRun("test", "./..."); err != nil {
+		t.Errorf("the tests of the project fail: %s\n%s", err.Error(), out)
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	gofmt "go/format"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// objViewFactory is the factory constructing one of the Views of an interactor by its kind
+	objViewFactory = "viewfactory"
+	// viewFactorySuffix follows the file name of the interactor in the name of the file of its view factory
+	// e.g. order_factory.go
+	viewFactorySuffix = "_factory.go"
+	// viewFactoryHeader marks the view factory as generated so that it's rewritten as a whole whenever it's
+	// updated
+	viewFactoryHeader = "// Code generated by clean. DO NOT EDIT.\n\n"
+)
+
+// addedViewFactories holds the interactors whose view factories were added by the command, which are recorded
+// in the manifest once the command is done
+var addedViewFactories = make(map[string]bool)
+
+// viewFactoryPath returns the path of the file of the view factory of the interactor
+func viewFactoryPath(basePath, interactor string) string {
+	return filepath.FromSlash(basePath + relPathView + fileName(interactor) + viewFactorySuffix)
+}
+
+// viewConstructor is the constructor of a View of an interactor
+type viewConstructor struct {
+	// view is the name of the View's interface e.g. WebOrder
+	view string
+	// name is the name of the constructor e.g. NewWebOrder
+	name string
+	// params are the names of the parameters of the constructor
+	params []string
+	// withErr is true if the constructor returns an error too
+	withErr bool
+}
+
+// viewConstructorOf returns the constructor of the View ifName declared in the file fp, which is the function
+// returning the interface. The types of its parameters are added to deps by their names, and the names of the
+// packages they refer to are added to pkgs.
+func viewConstructorOf(fp, ifName string, deps map[string]string, pkgs map[string]bool) (viewConstructor, *ast.File, error) {
+	f, err := parseGoFile(fp)
+	if err != nil {
+		return viewConstructor{}, nil, err
+	}
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Recv != nil || !strings.HasPrefix(fd.Name.Name, "New") || fd.Type.Results == nil {
+			continue
+		}
+		results := fd.Type.Results.List
+		if len(results) == 0 || len(results) > 2 || types.ExprString(results[0].Type) != ifName {
+			continue
+		}
+		c := viewConstructor{view: ifName, name: fd.Name.Name, withErr: len(results) == 2}
+		if c.withErr && types.ExprString(results[1].Type) != "error" {
+			continue
+		}
+		for i, p := range fd.Type.Params.List {
+			typ := types.ExprString(p.Type)
+			names := p.Names
+			if len(names) == 0 {
+				names = []*ast.Ident{ast.NewIdent(fmt.Sprintf("dep%d", i))}
+			}
+			for _, n := range names {
+				if t, ok := deps[n.Name]; ok && t != typ {
+					return viewConstructor{}, nil, fmt.Errorf("the parameter %s of %s is a %s but a %s in another constructor, rename one of them", n.Name, c.name, typ, t)
+				}
+				deps[n.Name] = typ
+				c.params = append(c.params, n.Name)
+			}
+			usedPackages(p.Type, pkgs)
+		}
+		return c, f, nil
+	}
+	return viewConstructor{}, nil, fmt.Errorf("%s doesn't declare a constructor of %s", fp, ifName)
+}
+
+// viewFactorySource returns the content of the view factory of the interactor. It has an [Interactor]ViewKind
+// enum with a constant of each View of the interactor e.g. KindOrder and KindWebOrder, and a New[Interactor]View
+// function constructing the View of a kind, whose parameters are those of the constructors of the Views. The
+// other Views are asserted to satisfy the interactor's View interface, which the function returns.
+func viewFactorySource(basePath, interactor string) ([]byte, error) {
+	fp := filepath.FromSlash(basePath + relPathView + fileName(interactor) + ".go")
+	b, err := readFile(fp)
+	if err != nil {
+		return nil, fmt.Errorf("the interactor %s doesn't have a View", exportedName(interactor))
+	}
+	ifName, _ := fileObjNames(b, objView, interactor)
+	deps := make(map[string]string)
+	pkgs := make(map[string]bool)
+	var ctors []viewConstructor
+	var files []*ast.File
+	for i, view := range append([]string{interactor}, viewsOf(basePath, interactor)...) {
+		vfp, vwName := fp, ifName
+		if i > 0 {
+			vfp = filepath.FromSlash(basePath + relPathView + fileName(view) + ".go")
+			vb, err := readFile(vfp)
+			if err != nil {
+				return nil, err
+			}
+			vwName, _ = fileObjNames(vb, objView, view)
+		}
+		c, f, err := viewConstructorOf(vfp, vwName, deps, pkgs)
+		if err != nil {
+			return nil, err
+		}
+		ctors = append(ctors, c)
+		files = append(files, f)
+	}
+	kindType := exportedName(interactor) + "ViewKind"
+	factory := "New" + exportedName(interactor) + "View"
+	for _, c := range ctors {
+		if c.name == factory {
+			factory += "OfKind"
+		}
+	}
+	// Import the packages of the Views' files which the parameters refer to
+	imports := []string{`"fmt"`}
+	for _, f := range files {
+		for _, imp := range importsOf(f, pkgs) {
+			imports = appendUnique(imports, imp)
+		}
+	}
+	sort.Strings(imports)
+	var buf bytes.Buffer
+	buf.WriteString(viewFactoryHeader)
+	buf.WriteString(packageClause(basePath+relPathView, objView))
+	buf.WriteString("\n\nimport (\n")
+	for _, imp := range imports {
+		fmt.Fprintf(&buf, "\t%s\n", imp)
+	}
+	buf.WriteString(")\n")
+	fmt.Fprintf(&buf, "\n// %s is the kind of a View of the %s interactor\ntype %s int\n\nconst (\n", kindType, exportedName(interactor), kindType)
+	for i, c := range ctors {
+		if i == 0 {
+			fmt.Fprintf(&buf, "\t// Kind%s is the kind of the %s View\n\tKind%s %s = iota\n", c.view, c.view, c.view, kindType)
+			continue
+		}
+		fmt.Fprintf(&buf, "\t// Kind%s is the kind of the %s View\n\tKind%s\n", c.view, c.view, c.view)
+	}
+	buf.WriteString(")\n")
+	if len(ctors) > 1 {
+		buf.WriteString("\n// The other Views render the same usecases, so they satisfy the interface too\nvar (\n")
+		for _, c := range ctors[1:] {
+			fmt.Fprintf(&buf, "\t_ %s = %s(nil)\n", ifName, c.view)
+		}
+		buf.WriteString(")\n")
+	}
+	names := make([]string, 0, len(deps))
+	for name := range deps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	params := []string{"kind " + kindType}
+	for _, name := range names {
+		params = append(params, name+" "+deps[name])
+	}
+	fmt.Fprintf(&buf, "\n// %s constructs the View of the kind and returns a nil error if successful. Otherwise it returns an error.\nfunc %s(%s) (%s, error) {\n\tswitch kind {\n", factory, factory, strings.Join(params, ", "), ifName)
+	for _, c := range ctors {
+		call := fmt.Sprintf("%s(%s)", c.name, strings.Join(c.params, ", "))
+		if c.withErr {
+			fmt.Fprintf(&buf, "\tcase Kind%s:\n\t\tvw, err := %s\n\t\treturn vw, err\n", c.view, call)
+			continue
+		}
+		fmt.Fprintf(&buf, "\tcase Kind%s:\n\t\treturn %s, nil\n", c.view, call)
+	}
+	fmt.Fprintf(&buf, "\t}\n\treturn nil, fmt.Errorf(\"unknown %s %%d\", kind)\n}\n", kindType)
+	return gofmt.Source(buf.Bytes())
+}
+
+// addViewFactory adds the view factory of the interactor to the view folder of the project at basePath. It's
+// recorded in the manifest and kept up to date by updateViewFactories as Views are added or removed.
+func addViewFactory(basePath, interactor string) error {
+	interactors, err := interactorUsecases(basePath)
+	if err != nil {
+		return err
+	}
+	if _, ok := interactors[exportedName(interactor)]; !ok {
+		return fmt.Errorf("the interactor %s doesn't exist", exportedName(interactor))
+	}
+	fp := viewFactoryPath(basePath, interactor)
+	if fileExists(fp) {
+		noopf("the view factory of %s already exists", exportedName(interactor))
+		addedViewFactories[exportedName(interactor)] = true
+		return nil
+	}
+	src, err := viewFactorySource(basePath, interactor)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(fp, src); err != nil {
+		return err
+	}
+	addedViewFactories[exportedName(interactor)] = true
+	return nil
+}
+
+// updateViewFactories regenerates the view factories recorded in the manifest of the project at basePath, or
+// added by the command, so that they construct the current Views of their interactors. The factory of an
+// interactor whose View was removed is left as it is. Nothing is done if the command failed.
+func updateViewFactories(basePath string) {
+	if len(errorMessages) > 0 {
+		return
+	}
+	m, _ := readManifest(strings.TrimSuffix(basePath, "clean/"))
+	var recorded []string
+	for _, mi := range m.Interactors {
+		if mi.ViewFactory {
+			recorded = appendUnique(recorded, mi.Name)
+		}
+	}
+	for it := range addedViewFactories {
+		recorded = appendUnique(recorded, it)
+	}
+	sort.Strings(recorded)
+	for _, it := range recorded {
+		if !fileExists(filepath.FromSlash(basePath + relPathView + fileName(it) + ".go")) {
+			continue
+		}
+		src, err := viewFactorySource(basePath, it)
+		if err != nil {
+			failf("Error updating the view factory of %s: %s\n\n", it, err.Error())
+			continue
+		}
+		fp := viewFactoryPath(basePath, it)
+		if old, err := readFile(fp); err == nil && bytes.Equal(old, src) {
+			continue
+		}
+		if err := rewriteFile(fp, src); err != nil {
+			failf("Error updating the view factory of %s: %s\n\n", it, err.Error())
+		}
+	}
+}
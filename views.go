@@ -0,0 +1,78 @@
+// Copyright 2017 strtob01. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// addedViews holds the Views added by the command by the interactors they render the usecases of, which are
+// recorded in the manifest once the command is done
+var addedViews = make(map[string][]string)
+
+// addView adds another View, named name, of the interactor e.g. a WebOrder View next to the Order View. It gets a
+// Render method of each of the interactor's usecases. The View is recorded in the manifest so that the
+// usecases added to the interactor later are added to it too, see viewsOf.
+func addView(basePath, name, interactor string) error {
+	if fileName(name) == fileName(interactor) {
+		return fmt.Errorf("the view %s must be named differently from the interactor", typeName(objView, name))
+	}
+	interactors, err := interactorUsecases(basePath)
+	if err != nil {
+		return err
+	}
+	usecases, ok := interactors[exportedName(interactor)]
+	if !ok {
+		return fmt.Errorf("the interactor %s doesn't exist", exportedName(interactor))
+	}
+	if fp := filepath.FromSlash(basePath + relPathView + fileName(name) + ".go"); fileExists(fp) {
+		if f, err := parseGoFile(fp); err != nil || findTypeSpec(f, typeName(objView, name)) == nil {
+			return fmt.Errorf("the file %s already exists", fp)
+		}
+	}
+	addObjToProject(basePath+relPathView, objView, name, *desc, true)
+	for _, usecase := range usecases {
+		addUsecaseToObject(basePath, relPathView, usecase, name)
+	}
+	it := exportedName(interactor)
+	addedViews[it] = appendUnique(addedViews[it], exportedName(name))
+	return nil
+}
+
+// viewsOf returns the Views of the interactor, other than the one named after it, that are recorded in the
+// manifest of the project or were added by the command and still exist
+func viewsOf(basePath, interactor string) []string {
+	it := exportedName(interactor)
+	m, _ := readManifest(strings.TrimSuffix(basePath, "clean/"))
+	var views []string
+	for _, mi := range m.Interactors {
+		if mi.Name == it {
+			views = mi.Views
+		}
+	}
+	return existingObjs(basePath, relPathView, append(append([]string{}, views...), addedViews[it]...))
+}
+
+// existingObjs returns the objects of names whose files exist in the layer folder relPath, without duplicates
+func existingObjs(basePath, relPath string, names []string) []string {
+	var existing []string
+	for _, v := range names {
+		if fileExists(filepath.FromSlash(basePath + relPath + fileName(v) + ".go")) {
+			existing = appendUnique(existing, v)
+		}
+	}
+	return existing
+}
+
+// appendUnique appends s to list unless list already holds it
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}